@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func apiKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage team API keys for CI and automation",
+	}
+
+	cmd.AddCommand(apiKeyListCmd())
+	cmd.AddCommand(apiKeyCreateCmd())
+	cmd.AddCommand(apiKeyRevokeCmd())
+
+	return cmd
+}
+
+func apiKeyListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List active API keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp struct {
+				Items []map[string]any `json:"items"`
+			}
+			if err := c.get("/api/api-keys", &resp); err != nil {
+				return err
+			}
+			keys := resp.Items
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(keys)
+			}
+
+			if len(keys) == 0 {
+				fmt.Println("No API keys.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tNAME\tROLE\tEXPIRES\tLAST USED")
+			for _, k := range keys {
+				id, _ := k["id"].(string)
+				name, _ := k["name"].(string)
+				role, _ := k["role"].(string)
+				expires, _ := k["expires_at"].(string)
+				lastUsed, _ := k["last_used_at"].(string)
+				if expires == "" {
+					expires = "never"
+				}
+				if lastUsed == "" {
+					lastUsed = "never"
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, name, role, expires, lastUsed)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func apiKeyCreateCmd() *cobra.Command {
+	var role, expiresIn string
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an API key (the key is shown once)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp map[string]any
+			if err := c.post("/api/api-keys", map[string]string{
+				"name":       args[0],
+				"role":       role,
+				"expires_in": expiresIn,
+			}, &resp); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(resp)
+			}
+
+			id, _ := resp["id"].(string)
+			key, _ := resp["key"].(string)
+			fmt.Printf("API key %q created (id %s).\n", args[0], id)
+			fmt.Fprintln(os.Stderr, "Store this key now — it will not be shown again:")
+			fmt.Println(key)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&role, "role", "member", "Team role granted to the key (member or admin)")
+	cmd.Flags().StringVar(&expiresIn, "expires-in", "", "Key lifetime as a duration, e.g. 720h (default: never expires)")
+	return cmd
+}
+
+func apiKeyRevokeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke an API key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			if err := c.delete("/api/api-keys/" + args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("API key %s revoked.\n", args[0])
+			return nil
+		},
+	}
+}
@@ -133,7 +133,12 @@ func authFilePath() string {
 	return filepath.Join(home, ".fleetlift", "auth.json")
 }
 
+// loadToken returns FLEETLIFT_API_KEY when set (for CI), otherwise the token
+// saved by `fleetlift auth login`.
 func loadToken() string {
+	if key := os.Getenv("FLEETLIFT_API_KEY"); key != "" {
+		return key
+	}
 	data, err := os.ReadFile(authFilePath())
 	if err != nil {
 		return ""
@@ -100,3 +100,12 @@ func TestStreamSSE_LargeDataLine(t *testing.T) {
 	require.Len(t, received, 2)
 	assert.Len(t, received[0], 100*1024)
 }
+
+func TestLoadToken_PrefersAPIKeyEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, saveToken("session-token"))
+	assert.Equal(t, "session-token", loadToken())
+
+	t.Setenv("FLEETLIFT_API_KEY", "fl_ci-key")
+	assert.Equal(t, "fl_ci-key", loadToken())
+}
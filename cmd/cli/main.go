@@ -24,8 +24,11 @@ func main() {
 		authCmd(),
 		workflowCmd(),
 		runCmd(),
+		scheduleCmd(),
+		triggerCmd(),
 		inboxCmd(),
 		credentialCmd(),
+		apiKeyCmd(),
 		knowledgeCmd(),
 		initLocalCmd(),
 	)
@@ -24,6 +24,7 @@ func runCmd() *cobra.Command {
 	cmd.AddCommand(runRejectCmd())
 	cmd.AddCommand(runSteerCmd())
 	cmd.AddCommand(runCancelCmd())
+	cmd.AddCommand(runRetryCmd())
 
 	return cmd
 }
@@ -39,17 +40,7 @@ func runStartCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			workflowID := args[0]
 
-			parameters := map[string]any{}
-			for _, p := range params {
-				key, val := splitParam(p)
-				// Try to parse as JSON, fall back to string
-				var v any
-				if json.Unmarshal([]byte(val), &v) == nil {
-					parameters[key] = v
-				} else {
-					parameters[key] = val
-				}
-			}
+			parameters := parseParams(params)
 
 			c := newClient()
 			var result map[string]string
@@ -140,6 +131,9 @@ func runGetCmd() *cobra.Command {
 			fmt.Printf("Run:      %v\n", run["id"])
 			fmt.Printf("Workflow: %v\n", run["workflow_title"])
 			fmt.Printf("Status:   %v\n", run["status"])
+			if parent, ok := run["parent_run_id"].(string); ok {
+				fmt.Printf("Retry of: %s\n", parent)
+			}
 			fmt.Println()
 
 			if len(steps) > 0 {
@@ -150,6 +144,9 @@ func runGetCmd() *cobra.Command {
 					stepID, _ := step["step_id"].(string)
 					status, _ := step["status"].(string)
 					pr, _ := step["pr_url"].(string)
+					if action, _ := step["pr_action"].(string); pr != "" && action != "" && action != "created" {
+						pr += " (" + action + ")"
+					}
 					_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", stepID, status, pr)
 				}
 				_ = w.Flush()
@@ -245,6 +242,43 @@ func runCancelCmd() *cobra.Command {
 	}
 }
 
+func runRetryCmd() *cobra.Command {
+	var from string
+	var follow bool
+
+	cmd := &cobra.Command{
+		Use:   "retry <id>",
+		Short: "Start a new run that reuses the completed steps of a finished run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var result map[string]any
+			if err := c.post("/api/runs/"+args[0]+"/retry", map[string]any{"from": from}, &result); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(result)
+			}
+
+			runID, _ := result["id"].(string)
+			fmt.Printf("Run started: %s (retry of %s)\n", runID, args[0])
+			if seeded, _ := result["seeded_steps"].([]any); len(seeded) > 0 {
+				fmt.Printf("Reusing:     %v\n", seeded)
+			}
+
+			if follow {
+				return streamLogs(c, runID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Re-run from this step even if it completed (default: the steps that failed)")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow logs after starting")
+	return cmd
+}
+
 func streamLogs(c *apiClient, runID string) error {
 	fmt.Printf("Streaming logs for run %s...\n\n", runID)
 	return c.streamSSE("/api/runs/"+runID+"/events", func(eventType, data string) bool {
@@ -274,6 +308,22 @@ func streamLogs(c *apiClient, runID string) error {
 	})
 }
 
+// parseParams converts key=value flags to run parameters. Values that parse as
+// JSON keep their type; anything else is a string.
+func parseParams(params []string) map[string]any {
+	parameters := map[string]any{}
+	for _, p := range params {
+		key, val := splitParam(p)
+		var v any
+		if json.Unmarshal([]byte(val), &v) == nil {
+			parameters[key] = v
+		} else {
+			parameters[key] = val
+		}
+	}
+	return parameters
+}
+
 func splitParam(s string) (string, string) {
 	for i, c := range s {
 		if c == '=' {
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func scheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage cron schedules that start workflow runs",
+	}
+
+	cmd.AddCommand(scheduleListCmd())
+	cmd.AddCommand(scheduleCreateCmd())
+	cmd.AddCommand(scheduleGetCmd())
+	cmd.AddCommand(scheduleUpdateCmd())
+	cmd.AddCommand(scheduleSetEnabledCmd("enable", "Resume a paused schedule", true))
+	cmd.AddCommand(scheduleSetEnabledCmd("disable", "Pause a schedule without deleting it", false))
+	cmd.AddCommand(scheduleDeleteCmd())
+	cmd.AddCommand(scheduleTriggerCmd())
+	cmd.AddCommand(scheduleRunsCmd())
+
+	return cmd
+}
+
+func scheduleListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp struct {
+				Items []map[string]any `json:"items"`
+			}
+			if err := c.get("/api/schedules", &resp); err != nil {
+				return err
+			}
+			schedules := resp.Items
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(schedules)
+			}
+
+			if len(schedules) == 0 {
+				fmt.Println("No schedules.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tNAME\tWORKFLOW\tCRON\tTIMEZONE\tENABLED")
+			for _, s := range schedules {
+				id, _ := s["id"].(string)
+				name, _ := s["name"].(string)
+				wf, _ := s["workflow_id"].(string)
+				cron, _ := s["cron"].(string)
+				tz, _ := s["timezone"].(string)
+				enabled, _ := s["enabled"].(bool)
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", id, name, wf, cron, tz, enabled)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func scheduleCreateCmd() *cobra.Command {
+	var cron, timezone, model string
+	var params []string
+	var disabled bool
+
+	cmd := &cobra.Command{
+		Use:   "create <name> <workflow-id>",
+		Short: "Create a schedule that runs a workflow on a cron expression",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			enabled := !disabled
+			var resp map[string]any
+			if err := c.post("/api/schedules", map[string]any{
+				"name":        args[0],
+				"workflow_id": args[1],
+				"cron":        cron,
+				"timezone":    timezone,
+				"model":       model,
+				"parameters":  parseParams(params),
+				"enabled":     enabled,
+			}, &resp); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(resp)
+			}
+
+			id, _ := resp["id"].(string)
+			fmt.Printf("Schedule %q created (id %s).\n", args[0], id)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cron, "cron", "", "Cron expression, e.g. \"0 2 * * *\" or @daily (required)")
+	cmd.Flags().StringVar(&timezone, "timezone", "UTC", "IANA time zone the cron expression is evaluated in")
+	cmd.Flags().StringVar(&model, "model", "", "Model override for scheduled runs")
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "Parameter in key=value format")
+	cmd.Flags().BoolVar(&disabled, "disabled", false, "Create the schedule paused")
+	_ = cmd.MarkFlagRequired("cron")
+	return cmd
+}
+
+func scheduleGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <id>",
+		Short: "Show a schedule and its next run times",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp struct {
+				Schedule     map[string]any `json:"schedule"`
+				NextRunTimes []string       `json:"next_run_times"`
+			}
+			if err := c.get("/api/schedules/"+args[0], &resp); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(resp)
+			}
+
+			s := resp.Schedule
+			fmt.Printf("ID:        %v\n", s["id"])
+			fmt.Printf("Name:      %v\n", s["name"])
+			fmt.Printf("Workflow:  %v\n", s["workflow_id"])
+			fmt.Printf("Cron:      %v (%v)\n", s["cron"], s["timezone"])
+			fmt.Printf("Enabled:   %v\n", s["enabled"])
+			if m, ok := s["model"].(string); ok && m != "" {
+				fmt.Printf("Model:     %s\n", m)
+			}
+			if p, ok := s["parameters"].(map[string]any); ok && len(p) > 0 {
+				b, _ := json.Marshal(p)
+				fmt.Printf("Params:    %s\n", b)
+			}
+			if len(resp.NextRunTimes) > 0 {
+				fmt.Println("Next runs:")
+				for _, t := range resp.NextRunTimes {
+					fmt.Printf("  %s\n", t)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func scheduleUpdateCmd() *cobra.Command {
+	var name, cron, timezone, model string
+	var params []string
+
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Update a schedule; only the flags given are changed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body := map[string]any{}
+			flags := cmd.Flags()
+			if flags.Changed("name") {
+				body["name"] = name
+			}
+			if flags.Changed("cron") {
+				body["cron"] = cron
+			}
+			if flags.Changed("timezone") {
+				body["timezone"] = timezone
+			}
+			if flags.Changed("model") {
+				body["model"] = model
+			}
+			if flags.Changed("param") {
+				body["parameters"] = parseParams(params)
+			}
+			if len(body) == 0 {
+				return fmt.Errorf("nothing to update")
+			}
+
+			c := newClient()
+			if err := c.patch("/api/schedules/"+args[0], body); err != nil {
+				return err
+			}
+			fmt.Printf("Schedule %s updated.\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "New schedule name")
+	cmd.Flags().StringVar(&cron, "cron", "", "New cron expression")
+	cmd.Flags().StringVar(&timezone, "timezone", "", "New IANA time zone")
+	cmd.Flags().StringVar(&model, "model", "", "New model override (empty clears it)")
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "Parameter in key=value format; replaces all parameters")
+	return cmd
+}
+
+func scheduleSetEnabledCmd(use, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <id>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			if err := c.patch("/api/schedules/"+args[0], map[string]any{"enabled": enabled}); err != nil {
+				return err
+			}
+			fmt.Printf("Schedule %s %sd.\n", args[0], use)
+			return nil
+		},
+	}
+}
+
+func scheduleDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a schedule (past runs are kept)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			if err := c.delete("/api/schedules/" + args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Schedule %s deleted.\n", args[0])
+			return nil
+		},
+	}
+}
+
+func scheduleTriggerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "trigger <id>",
+		Short: "Start a run from a schedule now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			if err := c.post("/api/schedules/"+args[0]+"/trigger", nil, nil); err != nil {
+				return err
+			}
+			fmt.Printf("Schedule %s triggered.\n", args[0])
+			return nil
+		},
+	}
+}
+
+func scheduleRunsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "runs <id>",
+		Short: "List runs started by a schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp struct {
+				Items []map[string]any `json:"items"`
+			}
+			if err := c.get("/api/schedules/"+args[0]+"/runs", &resp); err != nil {
+				return err
+			}
+			runs := resp.Items
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(runs)
+			}
+
+			if len(runs) == 0 {
+				fmt.Println("No runs yet.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tSTATUS\tCREATED")
+			for _, r := range runs {
+				id, _ := r["id"].(string)
+				status, _ := r["status"].(string)
+				created, _ := r["created_at"].(string)
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", id, status, created)
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func triggerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trigger",
+		Short: "Manage GitHub webhook triggers that start workflow runs",
+	}
+
+	cmd.AddCommand(triggerListCmd())
+	cmd.AddCommand(triggerCreateCmd())
+	cmd.AddCommand(triggerGetCmd())
+	cmd.AddCommand(triggerUpdateCmd())
+	cmd.AddCommand(triggerSetEnabledCmd("enable", "Resume a disabled trigger", true))
+	cmd.AddCommand(triggerSetEnabledCmd("disable", "Ignore deliveries without deleting the trigger", false))
+	cmd.AddCommand(triggerDeleteCmd())
+	cmd.AddCommand(triggerDeliveriesCmd())
+
+	return cmd
+}
+
+// parseMappings converts key=template flags to trigger parameter mappings. Unlike
+// run parameters, values are kept as strings; they are rendered per delivery.
+func parseMappings(params []string) map[string]any {
+	out := map[string]any{}
+	for _, p := range params {
+		key, val := splitParam(p)
+		out[key] = val
+	}
+	return out
+}
+
+func triggerListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List webhook triggers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp struct {
+				Items []map[string]any `json:"items"`
+			}
+			if err := c.get("/api/triggers", &resp); err != nil {
+				return err
+			}
+			triggers := resp.Items
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(triggers)
+			}
+
+			if len(triggers) == 0 {
+				fmt.Println("No triggers.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tNAME\tWORKFLOW\tEVENT\tREPO\tENABLED")
+			for _, t := range triggers {
+				id, _ := t["id"].(string)
+				name, _ := t["name"].(string)
+				wf, _ := t["workflow_id"].(string)
+				event, _ := t["event"].(string)
+				repo, _ := t["repo"].(string)
+				enabled, _ := t["enabled"].(bool)
+				if actions, _ := t["actions"].([]any); len(actions) > 0 {
+					parts := make([]string, 0, len(actions))
+					for _, a := range actions {
+						parts = append(parts, fmt.Sprint(a))
+					}
+					event += "." + strings.Join(parts, "|")
+				}
+				if repo == "" {
+					repo = "*"
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", id, name, wf, event, repo, enabled)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func triggerCreateCmd() *cobra.Command {
+	var event, repo, model string
+	var actions, params []string
+	var disabled bool
+
+	cmd := &cobra.Command{
+		Use:   "create <name> <workflow-id>",
+		Short: "Create a trigger (the webhook secret is shown once)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			enabled := !disabled
+			var resp map[string]any
+			if err := c.post("/api/triggers", map[string]any{
+				"name":        args[0],
+				"workflow_id": args[1],
+				"event":       event,
+				"repo":        repo,
+				"actions":     actions,
+				"parameters":  parseMappings(params),
+				"model":       model,
+				"enabled":     enabled,
+			}, &resp); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(resp)
+			}
+
+			id, _ := resp["id"].(string)
+			path, _ := resp["webhook_path"].(string)
+			secret, _ := resp["secret"].(string)
+			fmt.Printf("Trigger %q created (id %s).\n", args[0], id)
+			fmt.Printf("Payload URL:  %s%s\n", serverURL, path)
+			fmt.Println("Content type: application/json")
+			fmt.Fprintln(os.Stderr, "Store this webhook secret now — it will not be shown again:")
+			fmt.Println(secret)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&event, "event", "", "GitHub event name, e.g. pull_request or issues (required)")
+	cmd.Flags().StringVar(&repo, "repo", "", "Only react to this repository (owner/name, globs allowed, e.g. acme/*)")
+	cmd.Flags().StringArrayVar(&actions, "action", nil, "Only react to this payload action, e.g. opened. Repeatable")
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "Parameter mapping key=template, e.g. pr_number={{ .Event.pull_request.number }}")
+	cmd.Flags().StringVar(&model, "model", "", "Model override for triggered runs")
+	cmd.Flags().BoolVar(&disabled, "disabled", false, "Create the trigger disabled")
+	_ = cmd.MarkFlagRequired("event")
+	return cmd
+}
+
+func triggerGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <id>",
+		Short: "Show a trigger",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var t map[string]any
+			if err := c.get("/api/triggers/"+args[0], &t); err != nil {
+				return err
+			}
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(t)
+			}
+
+			fmt.Printf("ID:        %v\n", t["id"])
+			fmt.Printf("Name:      %v\n", t["name"])
+			fmt.Printf("Workflow:  %v\n", t["workflow_id"])
+			fmt.Printf("Event:     %v\n", t["event"])
+			if actions, _ := t["actions"].([]any); len(actions) > 0 {
+				fmt.Printf("Actions:   %v\n", actions)
+			}
+			if repo, _ := t["repo"].(string); repo != "" {
+				fmt.Printf("Repo:      %s\n", repo)
+			}
+			fmt.Printf("Enabled:   %v\n", t["enabled"])
+			fmt.Printf("URL:       %s%v\n", serverURL, t["webhook_path"])
+			if p, ok := t["parameters"].(map[string]any); ok && len(p) > 0 {
+				fmt.Println("Parameters:")
+				for k, v := range p {
+					fmt.Printf("  %s: %v\n", k, v)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func triggerUpdateCmd() *cobra.Command {
+	var name, event, repo, model string
+	var actions, params []string
+
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Update a trigger; only the flags given are changed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body := map[string]any{}
+			flags := cmd.Flags()
+			if flags.Changed("name") {
+				body["name"] = name
+			}
+			if flags.Changed("event") {
+				body["event"] = event
+			}
+			if flags.Changed("repo") {
+				body["repo"] = repo
+			}
+			if flags.Changed("action") {
+				body["actions"] = actions
+			}
+			if flags.Changed("param") {
+				body["parameters"] = parseMappings(params)
+			}
+			if flags.Changed("model") {
+				body["model"] = model
+			}
+			if len(body) == 0 {
+				return fmt.Errorf("nothing to update")
+			}
+
+			c := newClient()
+			if err := c.patch("/api/triggers/"+args[0], body); err != nil {
+				return err
+			}
+			fmt.Printf("Trigger %s updated.\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "New trigger name")
+	cmd.Flags().StringVar(&event, "event", "", "New GitHub event name")
+	cmd.Flags().StringVar(&repo, "repo", "", "New repository filter (empty matches any)")
+	cmd.Flags().StringArrayVar(&actions, "action", nil, "Action filter; replaces all actions")
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "Parameter mapping key=template; replaces all mappings")
+	cmd.Flags().StringVar(&model, "model", "", "New model override (empty clears it)")
+	return cmd
+}
+
+func triggerSetEnabledCmd(use, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <id>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			if err := c.patch("/api/triggers/"+args[0], map[string]any{"enabled": enabled}); err != nil {
+				return err
+			}
+			fmt.Printf("Trigger %s %sd.\n", args[0], use)
+			return nil
+		},
+	}
+}
+
+func triggerDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a trigger (runs it started are kept)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			if err := c.delete("/api/triggers/" + args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Trigger %s deleted.\n", args[0])
+			return nil
+		},
+	}
+}
+
+func triggerDeliveriesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "deliveries <id>",
+		Short: "List recent deliveries that started runs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := newClient()
+			var resp struct {
+				Items []map[string]any `json:"items"`
+			}
+			if err := c.get("/api/triggers/"+args[0]+"/deliveries", &resp); err != nil {
+				return err
+			}
+			deliveries := resp.Items
+
+			if outputJSON {
+				return json.NewEncoder(os.Stdout).Encode(deliveries)
+			}
+
+			if len(deliveries) == 0 {
+				fmt.Println("No deliveries yet.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "DELIVERY\tEVENT\tRUN\tRECEIVED")
+			for _, d := range deliveries {
+				delivery, _ := d["delivery_id"].(string)
+				event, _ := d["event"].(string)
+				runID, _ := d["run_id"].(string)
+				received, _ := d["received_at"].(string)
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", delivery, event, runID, received)
+			}
+			return w.Flush()
+		},
+	}
+}
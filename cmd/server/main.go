@@ -11,6 +11,7 @@ import (
 
 	"go.temporal.io/sdk/client"
 
+	"github.com/tinkerloft/fleetlift/internal/artifact"
 	"github.com/tinkerloft/fleetlift/internal/auth"
 	"github.com/tinkerloft/fleetlift/internal/db"
 	"github.com/tinkerloft/fleetlift/internal/knowledge"
@@ -76,6 +77,12 @@ func main() {
 		log.Fatalf("invalid credential encryption key for system credentials: %v", err)
 	}
 
+	// Artifact storage (inline only unless ARTIFACT_STORE is set)
+	artifactStorage, err := artifact.FromEnv()
+	if err != nil {
+		log.Fatalf("artifact store: %v", err)
+	}
+
 	// Knowledge store
 	knowledgeStore := knowledge.NewDBStore(database)
 
@@ -100,10 +107,14 @@ func main() {
 		Auth:              handlers.NewAuthHandler(database, ghProvider, jwtSecret),
 		Workflows:         handlers.NewWorkflowsHandler(registry),
 		Runs:              handlers.NewRunsHandler(database, temporalClient, registry, nl),
+		Schedules:         handlers.NewSchedulesHandler(database, temporalClient.ScheduleClient(), registry),
+		Triggers:          handlers.NewTriggersHandler(database, temporalClient, registry, encKey),
 		Inbox:             handlers.NewInboxHandler(database, temporalClient),
-		Reports:           handlers.NewReportsHandler(database),
+		Reports:           handlers.NewReportsHandler(database, artifactStorage),
 		Credentials:       credHandler,
+		APIKeys:           handlers.NewAPIKeysHandler(database),
 		SystemCredentials: sysCredHandler,
+		Admin:             handlers.NewAdminHandler(temporalClient),
 		Knowledge:         handlers.NewKnowledgeHandler(knowledgeStore),
 		MCP:               mcpHandler,
 		DB:                database,
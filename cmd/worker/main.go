@@ -2,17 +2,33 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
 
 	"github.com/tinkerloft/fleetlift/internal/activity"
 	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/artifact"
 	"github.com/tinkerloft/fleetlift/internal/db"
+	"github.com/tinkerloft/fleetlift/internal/gitcache"
+	"github.com/tinkerloft/fleetlift/internal/knowledge"
+	"github.com/tinkerloft/fleetlift/internal/metrics"
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/docker"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/kubernetes"
 	"github.com/tinkerloft/fleetlift/internal/sandbox/opensandbox"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/pool"
+	"github.com/tinkerloft/fleetlift/internal/template"
 	"github.com/tinkerloft/fleetlift/internal/workflow"
 )
 
@@ -43,10 +59,52 @@ func main() {
 	}
 
 	// Create sandbox client
-	sbClient := opensandbox.New(
-		os.Getenv("OPENSANDBOX_DOMAIN"),
-		os.Getenv("OPENSANDBOX_API_KEY"),
-	)
+	sbClient, err := newSandboxClient()
+	if err != nil {
+		log.Fatalf("sandbox client: %v", err)
+	}
+
+	// Warm sandbox pool (optional — only if SANDBOX_POOL is set)
+	var sbPool *pool.Pool
+	if spec := os.Getenv("SANDBOX_POOL"); spec != "" {
+		sizes, err := pool.ParseSizes(spec)
+		if err != nil {
+			log.Fatalf("SANDBOX_POOL: %v", err)
+		}
+		sbPool = pool.New(sbClient, sizes)
+		sbPool.Start()
+		defer sbPool.Close(context.Background())
+		sbClient = sbPool
+	}
+
+	// Repository cache (optional — only if GIT_CACHE_DIR is set)
+	var repoCache *gitcache.Cache
+	if dir := os.Getenv("GIT_CACHE_DIR"); dir != "" {
+		var maxBytes int64
+		if v := os.Getenv("GIT_CACHE_MAX_BYTES"); v != "" {
+			maxBytes, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || maxBytes <= 0 {
+				log.Fatalf("GIT_CACHE_MAX_BYTES must be a positive integer, got %q", v)
+			}
+		}
+		repoCache, err = gitcache.New(dir, maxBytes)
+		if err != nil {
+			log.Fatalf("git cache: %v", err)
+		}
+	}
+
+	// Artifact storage (inline only unless ARTIFACT_STORE is set)
+	artifactStorage, err := artifact.FromEnv()
+	if err != nil {
+		log.Fatalf("artifact store: %v", err)
+	}
+
+	// Template registry, used to resolve the workflows that schedules start
+	builtinProvider, err := template.NewBuiltinProvider()
+	if err != nil {
+		log.Fatalf("load builtin templates: %v", err)
+	}
+	templates := template.NewRegistry(builtinProvider, template.NewDBProvider(database))
 
 	// Create credential store (optional — only if encryption key is set)
 	var credStore activity.CredentialStore
@@ -65,9 +123,21 @@ func main() {
 		CredStore: credStore,
 		AgentRunners: map[string]agent.Runner{
 			"claude-code": agent.NewClaudeCodeRunner(sbClient),
+			"codex":       agent.NewCodexRunner(sbClient),
+			"gemini":      agent.NewGeminiRunner(sbClient),
 			"shell":       agent.NewShellRunner(sbClient),
 		},
-		ProfileStore: &activity.DBProfileStore{DB: database},
+		ProfileStore:   &activity.DBProfileStore{DB: database},
+		KnowledgeStore: knowledge.NewDBStore(database),
+		Artifacts:      artifactStorage,
+		Templates:      templates,
+	}
+
+	if sbPool != nil {
+		acts.Pool = sbPool
+	}
+	if repoCache != nil {
+		acts.RepoCache = repoCache
 	}
 
 	// Create and configure worker
@@ -75,11 +145,22 @@ func main() {
 	if taskQueue == "" {
 		taskQueue = "fleetlift"
 	}
-	w := worker.New(c, taskQueue, worker.Options{})
+	// Prometheus metrics for activity executions, served on METRICS_ADDR
+	m := metrics.New()
+	if err := metrics.RegisterWith(prometheus.DefaultRegisterer, m); err != nil {
+		log.Fatalf("register metrics: %v", err)
+	}
+	go serveMetrics(envOr("METRICS_ADDR", ":9090"))
+
+	w := worker.New(c, taskQueue, worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{metrics.NewInterceptor(m)},
+	})
 
 	// Register workflows
 	w.RegisterWorkflow(workflow.DAGWorkflow)
 	w.RegisterWorkflow(workflow.StepWorkflow)
+	w.RegisterWorkflow(workflow.ScheduledRunWorkflow)
+	w.RegisterWorkflow(workflow.SandboxReaperWorkflow)
 
 	// Register activities
 	w.RegisterActivity(acts)
@@ -88,8 +169,66 @@ func main() {
 	w.RegisterActivity(activity.NewSlackActivities())
 	w.RegisterActivity(activity.NewGitHubActivities())
 
+	if err := startSandboxReaper(c, taskQueue, envOr("SANDBOX_REAPER_CRON", "*/15 * * * *")); err != nil {
+		log.Fatalf("start sandbox reaper: %v", err)
+	}
+
 	slog.Info("starting fleetlift worker", "task_queue", taskQueue, "temporal", temporalAddr)
 	if err := w.Run(worker.InterruptCh()); err != nil {
 		log.Fatalf("worker run: %v", err)
 	}
 }
+
+// startSandboxReaper starts the sandbox reaper as a Temporal cron workflow, unless
+// cron is "off". Every worker calls this; the fixed workflow ID means only the
+// first start takes effect, so changing the schedule requires terminating the
+// running reaper workflow first.
+func startSandboxReaper(c client.Client, taskQueue, cron string) error {
+	if cron == "off" {
+		slog.Info("sandbox reaper disabled")
+		return nil
+	}
+	_, err := c.ExecuteWorkflow(context.Background(), client.StartWorkflowOptions{
+		ID:           workflow.SandboxReaperWorkflowID,
+		TaskQueue:    taskQueue,
+		CronSchedule: cron,
+	}, workflow.SandboxReaperWorkflow, workflow.ReapInput{})
+	return err
+}
+
+// serveMetrics exposes the Prometheus registry on addr until the process exits.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	slog.Info("serving metrics", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil {
+		slog.Error("metrics server stopped", "error", err)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// newSandboxClient selects the sandbox provider from SANDBOX_PROVIDER:
+// "opensandbox" (the default), "docker" for a local Docker Engine, or
+// "kubernetes" for Pods in the cluster the worker runs in.
+func newSandboxClient() (sandbox.Client, error) {
+	switch provider := os.Getenv("SANDBOX_PROVIDER"); provider {
+	case "", "opensandbox":
+		return opensandbox.New(
+			os.Getenv("OPENSANDBOX_DOMAIN"),
+			os.Getenv("OPENSANDBOX_API_KEY"),
+		), nil
+	case "docker":
+		return docker.New(os.Getenv("DOCKER_HOST"))
+	case "kubernetes":
+		return kubernetes.NewInCluster(os.Getenv("SANDBOX_NAMESPACE"))
+	default:
+		return nil, fmt.Errorf("unknown SANDBOX_PROVIDER %q (want opensandbox, docker or kubernetes)", provider)
+	}
+}
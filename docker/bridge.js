@@ -35,6 +35,9 @@ function validateRequest(req) {
   if (req.model !== undefined && typeof req.model !== "string") {
     throw new Error("request.model must be a string");
   }
+  if (req.resume_session !== undefined && typeof req.resume_session !== "string") {
+    throw new Error("request.resume_session must be a string");
+  }
 }
 
 function toText(content) {
@@ -80,6 +83,30 @@ function emitToolResultChunks(callID, stream, content) {
   }
 }
 
+// emitUsage reports a message's token usage once; claude repeats the message,
+// usage included, for every content block.
+function emitUsage(message, state) {
+  const usage = message?.usage;
+  if (!usage || typeof usage !== "object") {
+    return;
+  }
+  const id = typeof message.id === "string" ? message.id : "";
+  if (id !== "") {
+    if (state.usageSeen.has(id)) {
+      return;
+    }
+    state.usageSeen.add(id);
+  }
+  emit({
+    type: "usage",
+    model: typeof message.model === "string" ? message.model : "",
+    input_tokens: Number(usage.input_tokens) || 0,
+    cache_creation_input_tokens: Number(usage.cache_creation_input_tokens) || 0,
+    cache_read_input_tokens: Number(usage.cache_read_input_tokens) || 0,
+    output_tokens: Number(usage.output_tokens) || 0,
+  });
+}
+
 function mapClaudeEvent(raw, state) {
   if (!raw || typeof raw !== "object") {
     return;
@@ -111,6 +138,7 @@ function mapClaudeEvent(raw, state) {
   }
 
   if (raw.type === "assistant") {
+    emitUsage(raw.message, state);
     const blocks = raw?.message?.content;
     if (!Array.isArray(blocks)) {
       return;
@@ -230,6 +258,10 @@ async function main() {
     args.push("--model", request.model);
   }
 
+  if (typeof request.resume_session === "string" && request.resume_session !== "") {
+    args.push("--resume", request.resume_session);
+  }
+
   if (Array.isArray(request.plugin_dirs)) {
     for (const pluginDir of request.plugin_dirs) {
       if (typeof pluginDir === "string" && pluginDir !== "") {
@@ -247,7 +279,7 @@ async function main() {
     stdio: ["ignore", "pipe", "pipe"],
   });
 
-  const state = { sawComplete: false };
+  const state = { sawComplete: false, usageSeen: new Set() };
 
   streamLines(child.stdout, (line) => {
     try {
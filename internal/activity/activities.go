@@ -6,7 +6,10 @@ import (
 	"github.com/google/go-github/v62/github"
 	"github.com/jmoiron/sqlx"
 	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/artifact"
+	"github.com/tinkerloft/fleetlift/internal/knowledge"
 	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/template"
 )
 
 // CredentialStore resolves team-scoped credentials by name.
@@ -17,12 +20,35 @@ type CredentialStore interface {
 	GetBatch(ctx context.Context, teamID string, names []string) (map[string]string, error)
 }
 
+// SandboxPool hands out pre-created sandboxes. Claim returns false when none is
+// ready for image, in which case the caller creates one.
+type SandboxPool interface {
+	Claim(ctx context.Context, image string, env map[string]string) (id string, ok bool)
+}
+
+// RepoCache serves git bundles from worker-side mirrors, keyed by team and repo
+// URL. Bundle returns the bundled branch, resolving "" to the default branch,
+// and a release func to call once data has been written to the sandbox.
+type RepoCache interface {
+	Bundle(ctx context.Context, teamID, url, token, branch string) (data []byte, resolvedBranch string, release func(), err error)
+}
+
 // Activities holds all Temporal activity implementations and their shared dependencies.
 type Activities struct {
-	Sandbox      sandbox.Client
+	Sandbox sandbox.Client
+	// Pool supplies warm sandboxes to ProvisionSandbox; nil creates every sandbox.
+	Pool SandboxPool
+	// RepoCache seeds ExecuteStep's clones from bundles; nil clones from the remote.
+	RepoCache    RepoCache
 	DB           *sqlx.DB
 	CredStore    CredentialStore
 	AgentRunners map[string]agent.Runner
 	ProfileStore ProfileStore
 	GitHubClient *github.Client // if nil, constructed from GITHUB_TOKEN env var at call time
+	// KnowledgeStore backs knowledge.enrich and knowledge.capture; both are skipped when nil.
+	KnowledgeStore knowledge.Store
+	// Artifacts decides where collected artifact bytes are stored; nil keeps everything inline.
+	Artifacts *artifact.Storage
+	// Templates resolves workflow templates for runs started by schedules.
+	Templates *template.Registry
 }
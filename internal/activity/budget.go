@@ -0,0 +1,199 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// budgetSoftLimit is the share of a step budget at which an operator is asked
+// to extend the budget or stop the agent.
+const budgetSoftLimit = 0.8
+
+// Answers to a budget_exceeded inbox item.
+const (
+	budgetAnswerExtend = "extend"
+	budgetAnswerStop   = "stop"
+)
+
+// budgetPollInterval throttles the database round trips made while an agent
+// runs: polling for the operator's answer and syncing run-level spend.
+var budgetPollInterval = 15 * time.Second
+
+// budgetTracker enforces a step's budget and its run's budget against the
+// agent's streamed usage.
+type budgetTracker struct {
+	a     *Activities
+	input workflow.StepInput
+	base  model.BudgetDef // the step budget as declared; each extension adds it again
+	limit model.BudgetDef // the step budget including extensions
+	run   *model.BudgetDef
+	used  agent.Usage
+
+	prompted bool   // the operator was asked at the current limit
+	promptID string // unanswered budget_exceeded inbox item
+	lastPoll time.Time
+	unpriced bool // the model has no known price, which was logged once
+	log      func(stream, content string)
+}
+
+// newBudgetTracker returns nil when neither the step nor its run has a budget.
+func newBudgetTracker(a *Activities, input workflow.StepInput, log func(stream, content string)) *budgetTracker {
+	b := &budgetTracker{a: a, input: input, run: input.RunBudget, log: log}
+	if input.StepDef.Budget != nil {
+		b.base = *input.StepDef.Budget
+		b.limit = b.base
+	}
+	if b.limit == (model.BudgetDef{}) && (b.run == nil || *b.run == (model.BudgetDef{})) {
+		return nil
+	}
+	return b
+}
+
+// add records usage and returns why the agent must be stopped, or "" to let it
+// continue.
+func (b *budgetTracker) add(ctx context.Context, u agent.Usage) string {
+	b.used.InputTokens += u.InputTokens
+	b.used.CachedInputTokens += u.CachedInputTokens
+	b.used.OutputTokens += u.OutputTokens
+	b.used.CostUSD += u.CostUSD
+	if u.Unpriced && !b.unpriced && (b.limit.MaxUSD > 0 || (b.run != nil && b.run.MaxUSD > 0)) {
+		b.unpriced = true
+		b.log("stderr", "No price is known for this agent's model, so budget.max_usd cannot be enforced; only max_tokens limits apply")
+		activity.GetLogger(ctx).Warn("max_usd cannot be enforced for an unpriced model", "step_id", b.input.StepDef.ID)
+	}
+
+	poll := time.Since(b.lastPoll) >= budgetPollInterval
+	if poll {
+		b.lastPoll = time.Now()
+	}
+	// Check for an answer early at the hard limit: the operator may already
+	// have extended the budget.
+	if b.promptID != "" && (poll || over(b.used, b.limit, 1)) {
+		switch b.answer(ctx) {
+		case budgetAnswerStop:
+			return "stopped by an operator at " + b.describe(b.limit)
+		case budgetAnswerExtend:
+			b.promptID, b.prompted = "", false
+			b.limit.MaxUSD += b.base.MaxUSD
+			b.limit.MaxTokens += b.base.MaxTokens
+			b.log("stdout", "Budget extended to "+b.describe(b.limit))
+		}
+	}
+	if over(b.used, b.limit, 1) {
+		return "step budget exhausted: " + b.describe(b.limit)
+	}
+	if !b.prompted && over(b.used, b.limit, budgetSoftLimit) {
+		b.prompted = true
+		b.prompt(ctx)
+	}
+	if poll && b.run != nil {
+		if runUsed, ok := b.syncRun(ctx); ok && over(runUsed, *b.run, 1) {
+			return "run budget exhausted: " + describeUsage(runUsed, *b.run)
+		}
+	}
+	return ""
+}
+
+// finish records the step's final token usage for run-level accounting.
+func (b *budgetTracker) finish(ctx context.Context) {
+	if b.run != nil {
+		b.syncRun(ctx)
+	}
+}
+
+// over reports whether used has reached fraction of any limit set in budget.
+func over(used agent.Usage, budget model.BudgetDef, fraction float64) bool {
+	return (budget.MaxUSD > 0 && used.CostUSD >= budget.MaxUSD*fraction) ||
+		(budget.MaxTokens > 0 && float64(used.Tokens()) >= float64(budget.MaxTokens)*fraction)
+}
+
+func (b *budgetTracker) describe(budget model.BudgetDef) string {
+	if b.unpriced {
+		budget.MaxUSD = 0 // the cost is unknown, not $0
+	}
+	return describeUsage(b.used, budget)
+}
+
+// describeUsage renders usage against the limits set in budget, e.g.
+// "$1.62 of $2.00, 120000 of 150000 tokens".
+func describeUsage(used agent.Usage, budget model.BudgetDef) string {
+	var parts []string
+	if budget.MaxUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f of $%.2f", used.CostUSD, budget.MaxUSD))
+	}
+	if budget.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d tokens", used.Tokens(), budget.MaxTokens))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// prompt raises a budget_exceeded inbox item asking whether to extend the
+// budget or stop the agent. The step keeps running until the hard limit.
+func (b *budgetTracker) prompt(ctx context.Context) {
+	question := fmt.Sprintf("Step %s has used %s of its budget. Extend the budget or stop the agent?",
+		b.input.StepDef.ID, b.describe(b.limit))
+	b.log("stderr", "Budget: "+question)
+	if b.a.DB == nil {
+		return
+	}
+	id := uuid.New().String()
+	if _, err := b.a.DB.ExecContext(ctx, `
+		INSERT INTO inbox_items
+			(id, team_id, run_id, step_run_id, step_id, kind, title, summary, question, options, urgency, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, ''), 'budget_exceeded', $6, $7, $8, $9, 'high', now())`,
+		id, b.input.TeamID, b.input.RunID, b.input.StepRunID, b.input.StepDef.ID,
+		fmt.Sprintf("Budget nearly spent: %s", b.input.StepDef.ID),
+		b.describe(b.limit), question,
+		pq.StringArray{budgetAnswerExtend, budgetAnswerStop},
+	); err != nil {
+		activity.GetLogger(ctx).Warn("failed to create budget inbox item", "step_id", b.input.StepDef.ID, "error", err)
+		return
+	}
+	b.promptID = id
+}
+
+// answer returns the operator's answer to the budget prompt, or "".
+func (b *budgetTracker) answer(ctx context.Context) string {
+	var answer string
+	if err := b.a.DB.QueryRowContext(ctx,
+		`SELECT COALESCE(answer, '') FROM inbox_items WHERE id = $1`, b.promptID,
+	).Scan(&answer); err != nil {
+		return ""
+	}
+	return answer
+}
+
+// syncRun publishes the step's running spend and returns the run's total
+// across all of its step runs, including concurrent fan-out children.
+func (b *budgetTracker) syncRun(ctx context.Context) (agent.Usage, bool) {
+	if b.a.DB == nil || b.input.StepRunID == "" {
+		return agent.Usage{}, false
+	}
+	if _, err := b.a.DB.ExecContext(ctx,
+		`UPDATE step_runs SET cost_usd = NULLIF($2::numeric, 0), tokens_used = $3 WHERE id = $1`,
+		b.input.StepRunID, b.used.CostUSD, b.used.Tokens(),
+	); err != nil {
+		activity.GetLogger(ctx).Warn("failed to record step usage", "step_run_id", b.input.StepRunID, "error", err)
+		return agent.Usage{}, false
+	}
+	var cost float64
+	var tokens int64
+	if err := b.a.DB.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(cost_usd), 0), COALESCE(SUM(tokens_used), 0) FROM step_runs WHERE run_id = $1`,
+		b.input.RunID,
+	).Scan(&cost, &tokens); err != nil {
+		activity.GetLogger(ctx).Warn("failed to read run usage", "run_id", b.input.RunID, "error", err)
+		return agent.Usage{}, false
+	}
+	return agent.Usage{InputTokens: tokens, CostUSD: cost}, true
+}
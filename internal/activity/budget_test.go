@@ -0,0 +1,153 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// usageRunner reports the given costs as usage events, then completes. With
+// final set, the usage is reported as the agent finishes, as Codex and Gemini do.
+type usageRunner struct {
+	costs       []float64
+	final       bool
+	interrupted bool
+}
+
+func (r *usageRunner) Name() string { return "usage" }
+
+func (r *usageRunner) Run(context.Context, string, agent.RunOpts) (<-chan agent.Event, error) {
+	ch := make(chan agent.Event, len(r.costs)+1)
+	for _, c := range r.costs {
+		ch <- agent.Event{Type: "usage", Final: r.final, Usage: &agent.Usage{InputTokens: 1000, OutputTokens: 100, CostUSD: c}}
+	}
+	ch <- agent.Event{Type: "complete", Output: map[string]any{"result": "done", "total_cost_usd": 9.99}}
+	close(ch)
+	return ch, nil
+}
+
+func (r *usageRunner) Interrupt(context.Context, string) error {
+	r.interrupted = true
+	return nil
+}
+
+func runBudgetStep(t *testing.T, runner *usageRunner, input workflow.StepInput, expect func(sqlmock.Sqlmock)) *model.StepOutput {
+	t.Helper()
+	old := budgetPollInterval
+	budgetPollInterval = time.Hour // answers are only checked at the hard limit
+	defer func() { budgetPollInterval = old }()
+
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.MatchExpectationsInOrder(false)
+	expect(dbMock)
+
+	a := &Activities{
+		Sandbox:      &noopSandbox{},
+		DB:           sqlx.NewDb(db, "sqlmock"),
+		AgentRunners: map[string]agent.Runner{"usage": runner},
+	}
+	input.StepRunID = "sr-1"
+	input.RunID = "run-1"
+	input.StepDef.ID = "fix"
+	input.ResolvedOpts.Agent = "usage"
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	val, err := env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{StepInput: input, SandboxID: "sb-1", Prompt: "Fix it"})
+	require.NoError(t, err)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	var out model.StepOutput
+	require.NoError(t, val.Get(&out))
+	return &out
+}
+
+func TestExecuteStep_StopsAgentAtStepBudget(t *testing.T) {
+	runner := &usageRunner{costs: []float64{0.5, 0.6, 0.5}}
+	out := runBudgetStep(t, runner, workflow.StepInput{
+		StepDef: model.StepDef{Budget: &model.BudgetDef{MaxUSD: 1}},
+	}, func(sqlmock.Sqlmock) {})
+
+	assert.True(t, runner.interrupted)
+	assert.Equal(t, model.StepStatusFailed, out.Status)
+	assert.Contains(t, out.Error, "step budget exhausted: $1.10 of $1.00")
+	assert.InDelta(t, 1.1, out.CostUSD, 1e-9, "cost is the running estimate, not a later result")
+}
+
+func TestExecuteStep_KeepsWorkWhenFinalUsageIsOverBudget(t *testing.T) {
+	runner := &usageRunner{costs: []float64{1.5}, final: true}
+	out := runBudgetStep(t, runner, workflow.StepInput{
+		StepDef: model.StepDef{Budget: &model.BudgetDef{MaxUSD: 1}},
+	}, func(sqlmock.Sqlmock) {})
+
+	assert.False(t, runner.interrupted, "an agent that has finished is not interrupted")
+	assert.Equal(t, model.StepStatusComplete, out.Status)
+	assert.Equal(t, "done", out.Output["result"])
+}
+
+func TestExecuteStep_OperatorExtendsBudget(t *testing.T) {
+	runner := &usageRunner{costs: []float64{0.85, 0.3}}
+	out := runBudgetStep(t, runner, workflow.StepInput{
+		TeamID:  "team-1",
+		StepDef: model.StepDef{Budget: &model.BudgetDef{MaxUSD: 1}},
+	}, func(m sqlmock.Sqlmock) {
+		m.ExpectExec(`INSERT INTO inbox_items`).
+			WithArgs(sqlmock.AnyArg(), "team-1", "run-1", "sr-1", "fix", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		m.ExpectQuery(`SELECT COALESCE\(answer, ''\) FROM inbox_items`).
+			WillReturnRows(sqlmock.NewRows([]string{"answer"}).AddRow("extend"))
+	})
+
+	assert.False(t, runner.interrupted)
+	assert.Equal(t, model.StepStatusComplete, out.Status)
+}
+
+func TestExecuteStep_StopsAgentAtRunBudget(t *testing.T) {
+	runner := &usageRunner{costs: []float64{0.5}}
+	out := runBudgetStep(t, runner, workflow.StepInput{
+		RunBudget: &model.BudgetDef{MaxUSD: 5},
+	}, func(m sqlmock.Sqlmock) {
+		m.ExpectExec(`UPDATE step_runs SET cost_usd`).WithArgs("sr-1", 0.5, int64(1100)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// Fan-out siblings have already spent the rest of the run's budget.
+		m.ExpectQuery(`SELECT COALESCE\(SUM\(cost_usd\), 0\)`).WithArgs("run-1").
+			WillReturnRows(sqlmock.NewRows([]string{"cost", "tokens"}).AddRow(5.2, 52000))
+	})
+
+	assert.True(t, runner.interrupted)
+	assert.Contains(t, out.Error, "run budget exhausted: $5.20 of $5.00")
+}
+
+func TestBudgetTracker_UnpricedModelFallsBackToTokens(t *testing.T) {
+	var logs []string
+	b := newBudgetTracker(&Activities{}, workflow.StepInput{
+		StepDef: model.StepDef{ID: "fix", Budget: &model.BudgetDef{MaxUSD: 1, MaxTokens: 2000}},
+	}, func(_, content string) { logs = append(logs, content) })
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	var stops []string
+	env.RegisterActivityWithOptions(func(ctx context.Context) error {
+		for i := 0; i < 2; i++ {
+			stops = append(stops, b.add(ctx, agent.Usage{InputTokens: 1000, OutputTokens: 100, Unpriced: true}))
+		}
+		return nil
+	}, activity.RegisterOptions{Name: "usage"})
+	_, err := env.ExecuteActivity("usage")
+	require.NoError(t, err)
+
+	require.Len(t, logs, 1, "the missing price is reported once")
+	assert.Contains(t, logs[0], "max_usd cannot be enforced")
+	assert.Equal(t, []string{"", "step budget exhausted: 2200 of 2000 tokens"}, stops)
+}
@@ -5,22 +5,39 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/uuid"
 	"go.temporal.io/sdk/activity"
 
+	"github.com/tinkerloft/fleetlift/internal/artifact"
 	"github.com/tinkerloft/fleetlift/internal/model"
 )
 
 // artifactRow holds a single artifact's data ready for batch INSERT.
 type artifactRow struct {
+	id          string
 	name        string
 	path        string
 	sizeBytes   int
 	contentType string
 	storage     string
 	data        []byte
+	objectKey   *string
 }
 
-// CollectArtifacts reads artifact files from a sandbox and stores them in the DB.
+// artifactObjectKey is the object store key for an artifact's bytes.
+func artifactObjectKey(stepRunID, artifactID string) string {
+	return "artifacts/" + stepRunID + "/" + artifactID
+}
+
+// artifactID derives an artifact's ID from its step run and path. A retried
+// activity then overwrites the object an earlier attempt uploaded, and its INSERT
+// conflicts with the row instead of adding a second one, so nothing is orphaned.
+func artifactID(stepRunID, path string) string {
+	return uuid.NewSHA1(uuid.Nil, []byte(stepRunID+"\x00"+path)).String()
+}
+
+// CollectArtifacts reads artifact files from a sandbox and stores them, inline in
+// the DB or in the artifact object store when they exceed the inline threshold.
 func (a *Activities) CollectArtifacts(ctx context.Context, sandboxID, stepRunID string, artifacts []model.ArtifactRef) error {
 	rows := make([]artifactRow, 0, len(artifacts))
 
@@ -35,13 +52,22 @@ func (a *Activities) CollectArtifacts(ctx context.Context, sandboxID, stepRunID
 			return fmt.Errorf("read artifact %s at %s: %w", art.Name, art.Path, err)
 		}
 
+		const contentType = "text/plain"
+		id := artifactID(stepRunID, art.Path)
+		loc, err := a.Artifacts.Save(ctx, artifactObjectKey(stepRunID, id), data, contentType)
+		if err != nil {
+			return fmt.Errorf("store artifact %s: %w", art.Name, err)
+		}
+
 		rows = append(rows, artifactRow{
+			id:          id,
 			name:        art.Name,
 			path:        art.Path,
 			sizeBytes:   len(data),
-			contentType: "text/plain",
-			storage:     "inline",
-			data:        data,
+			contentType: contentType,
+			storage:     loc.Storage,
+			data:        loc.Data,
+			objectKey:   loc.ObjectKey,
 		})
 	}
 
@@ -50,17 +76,21 @@ func (a *Activities) CollectArtifacts(ctx context.Context, sandboxID, stepRunID
 	}
 
 	placeholders := make([]string, 0, len(rows))
-	args := make([]any, 0, len(rows)*7)
+	args := make([]any, 0, len(rows)*9)
 	for i, row := range rows {
-		base := i * 7
+		base := i * 9
 		placeholders = append(placeholders, fmt.Sprintf(
-			"($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
-			base+1, base+2, base+3, base+4, base+5, base+6, base+7,
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9,
 		))
-		args = append(args, stepRunID, row.name, row.path, row.sizeBytes, row.contentType, row.storage, row.data)
+		var data any // NULL, not an empty BYTEA, for object_store rows
+		if row.storage == artifact.StorageInline {
+			data = row.data
+		}
+		args = append(args, row.id, stepRunID, row.name, row.path, row.sizeBytes, row.contentType, row.storage, data, row.objectKey)
 	}
-	query := `INSERT INTO artifacts (step_run_id, name, path, size_bytes, content_type, storage, data) VALUES ` +
-		strings.Join(placeholders, ", ")
+	query := `INSERT INTO artifacts (id, step_run_id, name, path, size_bytes, content_type, storage, data, object_key) VALUES ` +
+		strings.Join(placeholders, ", ") + ` ON CONFLICT (id) DO NOTHING`
 	if _, err := a.DB.ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("store artifacts: %w", err)
 	}
@@ -2,12 +2,20 @@ package activity
 
 import (
 	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
 
+	"github.com/tinkerloft/fleetlift/internal/artifact"
 	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
 )
 
 func TestCollectArtifacts_RejectsPathOutsideWorkspace(t *testing.T) {
@@ -86,3 +94,78 @@ func TestCollectArtifacts_AcceptsValidWorkspacePath(t *testing.T) {
 		{Name: "output", Path: "/workspace/output.txt"},
 	})
 }
+
+func TestCollectArtifacts_OffloadsLargeArtifacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	root := t.TempDir()
+	fs, err := artifact.NewFSStore(root)
+	require.NoError(t, err)
+	a := &Activities{
+		Sandbox: sandbox.NewMemoryClient(map[string][]byte{
+			"/workspace/small.txt": []byte("tiny"),
+			"/workspace/big.tar":   []byte("0123456789"),
+		}),
+		DB:        sqlx.NewDb(db, "sqlmock"),
+		Artifacts: artifact.New(fs, 5),
+	}
+
+	mock.ExpectExec(`INSERT INTO artifacts \(id, step_run_id, name, path, size_bytes, content_type, storage, data, object_key\)`).
+		WithArgs(
+			sqlmock.AnyArg(), "sr-1", "small", "/workspace/small.txt", 4, "text/plain", "inline", []byte("tiny"), nil,
+			sqlmock.AnyArg(), "sr-1", "big", "/workspace/big.tar", 10, "text/plain", "object_store", nil, sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.CollectArtifacts)
+	_, err = env.ExecuteActivity(a.CollectArtifacts, "sb-1", "sr-1", []model.ArtifactRef{
+		{Name: "small", Path: "/workspace/small.txt"},
+		{Name: "big", Path: "/workspace/big.tar"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	entries, err := os.ReadDir(filepath.Join(root, "artifacts", "sr-1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "only the artifact above the threshold goes to the object store")
+}
+
+func TestCollectArtifacts_RetryReusesObjectKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	root := t.TempDir()
+	fs, err := artifact.NewFSStore(root)
+	require.NoError(t, err)
+	a := &Activities{
+		Sandbox:   sandbox.NewMemoryClient(map[string][]byte{"/workspace/big.tar": []byte("0123456789")}),
+		DB:        sqlx.NewDb(db, "sqlmock"),
+		Artifacts: artifact.New(fs, 5),
+	}
+	id := artifactID("sr-1", "/workspace/big.tar")
+	key := artifactObjectKey("sr-1", id)
+	args := []driver.Value{id, "sr-1", "big", "/workspace/big.tar", 10, "text/plain", "object_store", nil, key}
+
+	// The first attempt's INSERT fails; the retry must reuse its object, not add another.
+	mock.ExpectExec(`INSERT INTO artifacts .* ON CONFLICT \(id\) DO NOTHING`).WithArgs(args...).WillReturnError(assert.AnError)
+	mock.ExpectExec(`INSERT INTO artifacts .* ON CONFLICT \(id\) DO NOTHING`).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.CollectArtifacts)
+	refs := []model.ArtifactRef{{Name: "big", Path: "/workspace/big.tar"}}
+	_, err = env.ExecuteActivity(a.CollectArtifacts, "sb-1", "sr-1", refs)
+	require.Error(t, err)
+	_, err = env.ExecuteActivity(a.CollectArtifacts, "sb-1", "sr-1", refs)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	entries, err := os.ReadDir(filepath.Join(root, "artifacts", "sr-1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -14,6 +14,7 @@ const (
 	ActivityProvisionSandbox = "ProvisionSandbox"
 	ActivityRunVerifiers     = "RunVerifiers"
 	ActivityCleanupSandbox   = "CleanupSandbox"
+	ActivityReapSandboxes    = "ReapSandboxes"
 
 	// GitHub activities
 	ActivityCreatePullRequest = "CreatePullRequest"
@@ -38,6 +39,7 @@ const (
 	// E3: MCP interactive tools
 	ActivityCleanupCheckpointBranch   = "CleanupCheckpointBranch"
 	ActivityCreateContinuationStepRun = "CreateContinuationStepRun"
+	ActivityCanResumeSession          = "CanResumeSession"
 
 	// Artifact lookup
 	ActivityGetPrimaryRunArtifactID = "GetPrimaryRunArtifactID"
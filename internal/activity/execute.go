@@ -74,6 +74,11 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 	if attempt := activity.GetInfo(ctx).Attempt; attempt > 1 {
 		logLine("stderr", fmt.Sprintf("--- retry attempt %d ---", attempt))
 	}
+	checkpoint := executeCheckpoint{Phase: phaseClone, SandboxID: input.SandboxID}
+	if stepInput.SandboxID != "" {
+		// A sandbox group's sandbox is provisioned without a step run.
+		a.recordStepSandbox(ctx, stepInput.StepRunID, stepInput.SandboxID)
+	}
 
 	// 1. Clone repos
 	for _, repo := range stepInput.ResolvedOpts.Repos {
@@ -92,16 +97,30 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 			cloneCmd += fmt.Sprintf(" --branch %s", shellquote.Quote(repo.Branch))
 		}
 		cloneCmd += fmt.Sprintf(" %s %s", shellquote.Quote(repo.URL), shellquote.Quote(repoDir))
-		activity.RecordHeartbeat(ctx, "cloning "+repoName(repo))
+		activity.RecordHeartbeat(ctx, checkpoint)
 		a.updateStepStatus(ctx, stepInput.StepRunID, model.StepStatusCloning)
-		logLine("stdout", "Cloning "+repo.URL+"…")
+
+		// Seed from the worker's repository cache when it can serve the repo. The
+		// clone's origin is pointed back at the remote so later fetches and pushes
+		// behave exactly as after a direct clone.
+		if bundle, branch, ok := a.stageRepoBundle(ctx, input.SandboxID, stepInput, repo); ok {
+			cloneCmd = fmt.Sprintf("git clone --quiet --branch %s %s %s && git -C %s remote set-url origin %s; rc=$?; rm -f %s; exit $rc",
+				shellquote.Quote(branch), shellquote.Quote(bundle), shellquote.Quote(repoDir),
+				shellquote.Quote(repoDir), shellquote.Quote(repo.URL), shellquote.Quote(bundle))
+			logLine("stdout", "Cloning "+repo.URL+" from the repository cache…")
+		} else {
+			logLine("stdout", "Cloning "+repo.URL+"…")
+		}
 
 		// Remove any leftover directory from a previous attempt (e.g. sandbox reuse on retry).
 		if _, _, err := sb.Exec(ctx, input.SandboxID, "rm -rf "+shellquote.Quote(repoDir), "/"); err != nil {
 			return nil, fmt.Errorf("clean repo dir %s: %w", repoDir, err)
 		}
 
-		if _, stderr, err := sb.Exec(ctx, input.SandboxID, cloneCmd, "/"); err != nil {
+		if _, stderr, err := sb.Exec(ctx, input.SandboxID, cloneCmd, "/"); (err != nil || gitFailed(stderr)) && isDiskFull(stderr) {
+			logLine("stderr", "clone failed: "+strings.TrimSpace(stderr))
+			return nil, diskFullError("clone of " + repo.URL)
+		} else if err != nil {
 			msg := fmt.Sprintf("clone failed: %v", err)
 			logLine("stderr", msg)
 			return nil, fmt.Errorf("clone %s: %w", repo.URL, err)
@@ -127,7 +146,10 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 		if repo.Ref != "" {
 			logLine("stdout", "Fetching ref "+repo.Ref+"…")
 			fetchCmd := fmt.Sprintf("git -C %s fetch origin %s", shellquote.Quote(repoDir), shellquote.Quote(repo.Ref))
-			if _, stderr, err := sb.Exec(ctx, input.SandboxID, fetchCmd, "/"); err != nil {
+			if _, stderr, err := sb.Exec(ctx, input.SandboxID, fetchCmd, "/"); (err != nil || gitFailed(stderr)) && isDiskFull(stderr) {
+				logLine("stderr", "fetch failed: "+strings.TrimSpace(stderr))
+				return nil, diskFullError("fetch of " + repo.Ref)
+			} else if err != nil {
 				msg := fmt.Sprintf("fetch failed: %v", err)
 				logLine("stderr", msg)
 				return nil, fmt.Errorf("fetch ref %s: %w", repo.Ref, err)
@@ -148,22 +170,25 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 			}
 			logLine("stdout", "Checked out ref "+repo.Ref)
 		}
+	}
 
-		// Create a working branch if configured (e.g. quick-run).
-		// The branch is created after clone + ref checkout so it forks from the right base.
-		if repo.CreateBranch != "" {
-			branchName := fmt.Sprintf("%s/%s", repo.CreateBranch, stepInput.RunID[:8])
-			createBranchCmd := fmt.Sprintf("git -C %s checkout -b %s",
-				shellquote.Quote(repoDir), shellquote.Quote(branchName))
-			if _, stderr, err := sb.Exec(ctx, input.SandboxID, createBranchCmd, "/"); err != nil {
+	// Check out the step's working branch. -B makes this idempotent across
+	// retries, and a clone left by an earlier step in the sandbox group
+	// carries its work over onto this step's branch.
+	if branch := stepInput.ResolvedOpts.Branch; branch != "" {
+		for _, repo := range stepInput.ResolvedOpts.Repos {
+			repoDir := "/workspace/" + repoName(repo)
+			branchCmd := fmt.Sprintf("git -C %s checkout -B %s", shellquote.Quote(repoDir), shellquote.Quote(branch))
+			if _, stderr, err := sb.Exec(ctx, input.SandboxID, branchCmd, "/"); err != nil {
 				logLine("stderr", fmt.Sprintf("create branch failed: %v", err))
-				return nil, fmt.Errorf("create branch %s: %w", branchName, err)
+				return nil, fmt.Errorf("create branch %s: %w", branch, err)
 			} else if gitFailed(stderr) {
 				logLine("stderr", "create branch failed: "+strings.TrimSpace(stderr))
-				return nil, fmt.Errorf("create branch %s: %s", branchName, strings.TrimSpace(stderr))
+				return nil, fmt.Errorf("create branch %s: %s", branch, strings.TrimSpace(stderr))
 			}
-			logLine("stdout", "Created branch "+branchName)
+			logLine("stdout", "Working on branch "+branch)
 		}
+		a.recordStepBranch(ctx, stepInput.StepRunID, branch)
 	}
 
 	// After cloning, checkout checkpoint branch if this is a continuation step
@@ -186,7 +211,8 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 	}
 
 	// 2. Run agent with streaming output
-	activity.RecordHeartbeat(ctx, "running agent")
+	checkpoint.Phase = phaseAgent
+	activity.RecordHeartbeat(ctx, checkpoint)
 	a.updateStepStatus(ctx, stepInput.StepRunID, model.StepStatusRunning)
 
 	runner, ok := a.AgentRunners[stepInput.ResolvedOpts.Agent]
@@ -194,14 +220,39 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 		return nil, fmt.Errorf("unknown agent: %s", stepInput.ResolvedOpts.Agent)
 	}
 
-	// Apply continuation context to prompt if present
-	if input.ContinuationContext != nil {
-		input.Prompt = buildContinuationPrompt(input.Prompt, input.ContinuationContext)
-	}
+	// Resume the agent's previous session when the runner supports it: the
+	// steering text or human answer becomes the next turn of the conversation,
+	// which keeps the agent's tool context. Otherwise fall back to a fresh run
+	// with the history stuffed into the prompt.
+	resumer, _ := runner.(agent.Resumer)
+	resuming := input.ResumeSession != "" && resumer != nil &&
+		resumer.CanResume(ctx, input.SandboxID, input.ResumeSession)
 
-	prompt := input.Prompt
-	if input.ConversationHistory != "" {
-		prompt = input.ConversationHistory + "\n\n" + prompt
+	var prompt string
+	if resuming {
+		prompt = input.ResumePrompt
+		logLine("stdout", "Resuming agent session "+input.ResumeSession)
+	} else {
+		// Apply continuation context to prompt if present
+		if input.ContinuationContext != nil {
+			input.Prompt = buildContinuationPrompt(input.Prompt, input.ContinuationContext)
+		}
+
+		prompt = input.Prompt
+		if input.ConversationHistory != "" {
+			prompt = input.ConversationHistory + "\n\n" + prompt
+		}
+
+		// Prepend approved knowledge items when the step opts into enrichment.
+		// Enrichment is best-effort: a lookup failure must not fail the step.
+		if enrichment, err := a.buildKnowledgeEnrichment(ctx, stepInput.TeamID, stepInput.WorkflowTemplateID, stepInput.StepDef.Knowledge); err != nil {
+			activity.GetLogger(ctx).Warn("knowledge enrichment failed", "step_id", stepInput.StepDef.ID, "error", err)
+		} else if enrichment != "" {
+			prompt = enrichment + "\n" + prompt
+		}
+		if stepInput.StepDef.Knowledge != nil && stepInput.StepDef.Knowledge.Capture {
+			prompt += learningsInstructions(stepInput.StepRunID)
+		}
 	}
 
 	// Append schema output instructions if step declares an output schema.
@@ -215,16 +266,51 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 		workDir = "/workspace/" + repoName(stepInput.ResolvedOpts.Repos[0])
 	}
 
-	events, err := runner.Run(ctx, input.SandboxID, agent.RunOpts{
+	runOpts := agent.RunOpts{
 		Prompt:         prompt,
 		WorkDir:        workDir,
 		MaxTurns:       stepInput.ResolvedOpts.MaxTurns,
 		Model:          stepInput.ModelOverride,
 		EvalPluginDirs: input.EvalPluginDirs,
-	})
+	}
+	if resuming {
+		runOpts.ResumeSession = input.ResumeSession
+	}
+
+	// Steps that opt into retry_on_worker_restart run the agent detached, so it
+	// outlives this worker. A log left behind means an earlier attempt died
+	// before handling its run: reattach to it, whether still going or finished,
+	// instead of starting the agent over. The replayed output reuses the original
+	// log sequence numbers, which makes re-inserting it a no-op.
+	if stepInput.StepDef.RetryOnWorkerRestart {
+		checkpoint.SessionID = stepInput.StepRunID
+		if checkpoint.SessionID == "" {
+			checkpoint.SessionID = input.SandboxID
+		}
+		checkpoint.LogPath = agentLogPath(checkpoint.SessionID)
+		checkpoint.Seq = seq
+		runOpts.LogPath = checkpoint.LogPath
+		if a.sandboxFileExists(ctx, input.SandboxID, checkpoint.LogPath) {
+			runOpts.Reattach = true
+			if prev, ok := previousCheckpoint(ctx); ok && prev.Phase == phaseAgent && prev.LogPath == checkpoint.LogPath {
+				checkpoint.Seq = prev.Seq
+				seq = prev.Seq
+			}
+			activity.GetLogger(ctx).Info("reattaching to agent after worker restart", "session_id", checkpoint.SessionID)
+		}
+		activity.RecordHeartbeat(ctx, checkpoint)
+		defer a.releaseAgentLog(ctx, input.SandboxID, checkpoint.LogPath)
+	}
+
+	// runCtx is cancelled when the agent is stopped for its budget.
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	events, err := runner.Run(runCtx, input.SandboxID, runOpts)
 	if err != nil {
 		return nil, fmt.Errorf("start agent: %w", err)
 	}
+	budget := newBudgetTracker(a, stepInput, logLine)
+	var budgetStop string
 
 	// Background heartbeat ticker: agent "thinking" phases produce no output
 	// events, which would starve the heartbeat and trigger Temporal's
@@ -236,7 +322,7 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 		for {
 			select {
 			case <-ticker.C:
-				activity.RecordHeartbeat(ctx, "agent running (heartbeat)")
+				activity.RecordHeartbeat(ctx, checkpoint)
 			case <-hbDone:
 				return
 			}
@@ -245,11 +331,39 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 
 	var lastOutput map[string]any
 	var gotComplete bool
+	// lastLine is the agent's final output before it failed: a CLI that dies
+	// on a full disk says so last. Earlier lines may be tool output, such as
+	// a test the agent ran that filled a temp directory, and are ignored.
+	var lastLine string
 	for event := range events {
 		if event.Type == "" && event.Content == "" {
 			continue // skip empty events (filtered noise)
 		}
-		activity.RecordHeartbeat(ctx, "agent running: "+event.Type)
+		if budgetStop != "" {
+			continue // drain the interrupted run
+		}
+		if event.Usage != nil {
+			if budget != nil {
+				stop := budget.add(ctx, *event.Usage)
+				switch {
+				case stop == "":
+				case event.Final:
+					// The agent has already finished: the money is spent, so keep
+					// the work and record the overrun instead of failing the step.
+					logLine("stderr", "Budget exceeded as the agent finished: "+stop)
+					activity.GetLogger(ctx).Warn("agent finished over budget", "step_id", stepInput.StepDef.ID, "budget", stop)
+				default:
+					budgetStop = stop
+					logLine("stderr", "Stopping agent: "+budgetStop)
+					if err := runner.Interrupt(ctx, input.SandboxID); err != nil {
+						activity.GetLogger(ctx).Warn("failed to interrupt agent", "error", err)
+					}
+					cancelRun()
+				}
+			}
+			continue
+		}
+		activity.RecordHeartbeat(ctx, checkpoint)
 		buf.add(ctx, seq, event.Content)
 		seq++
 		if event.Type == "complete" {
@@ -259,12 +373,28 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 		if event.Type == "error" {
 			close(hbDone)
 			buf.flush(ctx)
+			if isDiskFull(event.Content) || isDiskFull(lastLine) {
+				return nil, diskFullError("agent execution")
+			}
 			return nil, fmt.Errorf("agent error: %s", event.Content)
 		}
+		lastLine = event.Content
 	}
 	close(hbDone)
 	buf.flush(ctx)
 
+	if budget != nil {
+		budget.finish(ctx)
+	}
+	if budgetStop != "" {
+		return &model.StepOutput{
+			StepID:  stepInput.StepDef.ID,
+			Status:  model.StepStatusFailed,
+			Error:   "budget: " + budgetStop,
+			CostUSD: budget.used.CostUSD,
+		}, nil
+	}
+
 	// Check if MCP handler set status to awaiting_input during this execution
 	if a.DB != nil {
 		var dbStatus string
@@ -294,11 +424,15 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 				Question:         question,
 				CheckpointBranch: checkpointBranch,
 				StateArtifactID:  stateArtifactID,
+				SessionID:        sessionID(resumer, lastOutput),
 			}, nil
 		}
 	}
 
 	// If the agent never emitted a completion event, the command failed.
+	if !gotComplete && isDiskFull(lastLine) {
+		return nil, diskFullError("agent execution")
+	}
 	if !gotComplete {
 		return &model.StepOutput{
 			StepID: stepInput.StepDef.ID,
@@ -371,11 +505,13 @@ func (a *Activities) ExecuteStep(ctx context.Context, input workflow.ExecuteStep
 	}
 
 	return &model.StepOutput{
-		StepID:  stepInput.StepDef.ID,
-		Status:  model.StepStatusComplete,
-		Output:  structured,
-		Diff:    diff,
-		CostUSD: extractCostUSD(lastOutput),
+		StepID:     stepInput.StepDef.ID,
+		Status:     model.StepStatusComplete,
+		Output:     structured,
+		Diff:       diff,
+		BranchName: stepInput.ResolvedOpts.Branch,
+		CostUSD:    extractCostUSD(lastOutput),
+		SessionID:  sessionID(resumer, lastOutput),
 	}, nil
 }
 
@@ -2,11 +2,17 @@ package activity
 
 import (
 	"context"
+	"errors"
 	"sort"
+	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
 
 	"github.com/tinkerloft/fleetlift/internal/agent"
 	"github.com/tinkerloft/fleetlift/internal/model"
@@ -36,6 +42,7 @@ func (n *noopSandbox) ReadBytes(_ context.Context, _, _ string) ([]byte, error)
 }
 func (n *noopSandbox) Kill(_ context.Context, _ string) error            { return nil }
 func (n *noopSandbox) RenewExpiration(_ context.Context, _ string) error { return nil }
+func (n *noopSandbox) List(_ context.Context) ([]sandbox.Info, error)    { return nil, nil }
 
 func TestExecuteStep_RejectsNonHTTPS(t *testing.T) {
 	a := &Activities{
@@ -439,3 +446,184 @@ func TestExtractStructuredOutput_PlainText_AbsentOptionalFields(t *testing.T) {
 	_, hasExitCode := got["exit_code"]
 	assert.False(t, hasExitCode, "exit_code should be absent when not in raw")
 }
+
+// diskFullSandbox fails every git clone as if the workspace disk were full.
+type diskFullSandbox struct{ noopSandbox }
+
+func (s *diskFullSandbox) Exec(_ context.Context, _, cmd, _ string) (string, string, error) {
+	if strings.HasPrefix(cmd, "git clone") {
+		return "", "fatal: write error: No space left on device", errors.New("exit status 128")
+	}
+	return "", "", nil
+}
+
+func TestExecuteStep_DiskFullCloneIsNonRetryable(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.MatchExpectationsInOrder(false)
+	dbMock.ExpectExec(`UPDATE step_runs SET status`).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(`INSERT INTO step_run_logs`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := &Activities{Sandbox: &diskFullSandbox{}, DB: sqlx.NewDb(db, "sqlmock")}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	_, err = env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{
+			StepRunID: "sr-1",
+			ResolvedOpts: workflow.ResolvedStepOpts{
+				Repos: []model.RepoRef{{URL: "https://github.com/org/repo.git"}},
+				Agent: "claude-code",
+			},
+		},
+		SandboxID: "sb-test",
+	})
+
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrTypeDiskFull, appErr.Type())
+	assert.True(t, appErr.NonRetryable())
+	assert.Contains(t, appErr.Error(), "workspace_size")
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestExecuteStep_RecordsSharedSandboxOnStepRun(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.MatchExpectationsInOrder(false)
+	dbMock.ExpectExec(`UPDATE step_runs SET sandbox_id`).WithArgs("sb-group", "sr-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := &Activities{
+		Sandbox:      &noopSandbox{},
+		DB:           sqlx.NewDb(db, "sqlmock"),
+		AgentRunners: map[string]agent.Runner{"usage": &usageRunner{}},
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	_, err = env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{
+			StepRunID:    "sr-1",
+			StepDef:      model.StepDef{ID: "fix", SandboxGroup: "repo-work"},
+			ResolvedOpts: workflow.ResolvedStepOpts{Agent: "usage"},
+			SandboxID:    "sb-group",
+		},
+		SandboxID: "sb-group",
+		Prompt:    "Fix it",
+	})
+	require.NoError(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestExecuteStep_ChecksOutAndRecordsWorkingBranch(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.MatchExpectationsInOrder(false)
+	dbMock.ExpectExec(`UPDATE step_runs SET branch_name`).WithArgs("fix/run-1/api", "sr-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// An earlier step in the sandbox group already cloned the repo.
+	sb := &scriptingSandbox{responses: []sandboxResponse{{match: ".git/HEAD", stdout: "ref: refs/heads/main\n"}}}
+	a := &Activities{
+		Sandbox:      sb,
+		DB:           sqlx.NewDb(db, "sqlmock"),
+		AgentRunners: map[string]agent.Runner{"usage": &usageRunner{}},
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	val, err := env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{
+			StepRunID: "sr-1",
+			StepDef:   model.StepDef{ID: "fix"},
+			ResolvedOpts: workflow.ResolvedStepOpts{
+				Agent:  "usage",
+				Repos:  []model.RepoRef{{URL: "https://github.com/acme/api"}},
+				Branch: "fix/run-1/api",
+			},
+		},
+		SandboxID: "sb-1",
+		Prompt:    "Fix it",
+	})
+	require.NoError(t, err)
+	var out model.StepOutput
+	require.NoError(t, val.Get(&out))
+
+	assert.Contains(t, sb.cmds, "git -C '/workspace/api' checkout -B 'fix/run-1/api'")
+	assert.Equal(t, "fix/run-1/api", out.BranchName)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// eventsRunner emits the given events, then ends the run.
+type eventsRunner struct{ events []agent.Event }
+
+func (r *eventsRunner) Name() string { return "events" }
+
+func (r *eventsRunner) Run(context.Context, string, agent.RunOpts) (<-chan agent.Event, error) {
+	ch := make(chan agent.Event, len(r.events))
+	for _, e := range r.events {
+		ch <- e
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (r *eventsRunner) Interrupt(context.Context, string) error { return nil }
+
+func runEventsStep(t *testing.T, events ...agent.Event) (*model.StepOutput, error) {
+	t.Helper()
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	a := &Activities{
+		Sandbox:      &noopSandbox{},
+		DB:           sqlx.NewDb(db, "sqlmock"),
+		AgentRunners: map[string]agent.Runner{"events": &eventsRunner{events: events}},
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	val, err := env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{StepRunID: "sr-1", ResolvedOpts: workflow.ResolvedStepOpts{Agent: "events"}},
+		SandboxID: "sb-1",
+		Prompt:    "Fix it",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out model.StepOutput
+	require.NoError(t, val.Get(&out))
+	return &out, nil
+}
+
+func TestExecuteStep_DiskFullOnlyFromTheFailure(t *testing.T) {
+	// A tool the agent ran filled a temp directory, but the agent carried on.
+	out, err := runEventsStep(t,
+		agent.Event{Type: "stderr", Content: "write /tmp/cache: No space left on device"},
+		agent.Event{Type: "stdout", Content: "Cleaned up the cache and retried"},
+		agent.Event{Type: "error", Content: "agent bridge error"},
+	)
+	require.Error(t, err)
+	assert.Nil(t, out)
+	var appErr *temporal.ApplicationError
+	assert.False(t, errors.As(err, &appErr) && appErr.Type() == ErrTypeDiskFull, err.Error())
+
+	out, err = runEventsStep(t,
+		agent.Event{Type: "stderr", Content: "write /tmp/cache: No space left on device"},
+		agent.Event{Type: "stdout", Content: "Cleaned up the cache and retried"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, model.StepStatusFailed, out.Status)
+
+	_, err = runEventsStep(t,
+		agent.Event{Type: "stdout", Content: "Editing main.go"},
+		agent.Event{Type: "stderr", Content: "Error: ENOSPC: no space left on device, write"},
+	)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrTypeDiskFull, appErr.Type())
+}
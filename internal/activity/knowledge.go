@@ -0,0 +1,182 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tinkerloft/fleetlift/internal/knowledge"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+// KnowledgeLearningsPath is where the agent of a step run writes learnings when
+// knowledge.capture is enabled. Each step run gets its own file, so later steps in
+// a shared sandbox never re-capture an earlier step's learnings.
+func KnowledgeLearningsPath(stepRunID string) string {
+	if stepRunID == "" {
+		return "/workspace/.fleetlift/learnings.json"
+	}
+	return "/workspace/.fleetlift/learnings-" + stepRunID + ".json"
+}
+
+// maxCapturedLearnings caps how many items a single step can submit for review.
+const maxCapturedLearnings = 20
+
+// defaultEnrichItems is used when knowledge.max_items is unset.
+const defaultEnrichItems = 10
+
+// enrichTagPool is how many approved items are fetched before tag filtering,
+// so a tag-scoped step is not starved by higher-confidence items with other tags.
+const enrichTagPool = 100
+
+// learningsInstructions is appended to the prompt of steps with knowledge.capture enabled.
+func learningsInstructions(stepRunID string) string {
+	return "\n\nBefore you finish, record anything a future run of this task should know " +
+		"(conventions, pitfalls, corrections, useful context) as a JSON array in " + KnowledgeLearningsPath(stepRunID) +
+		`, e.g. [{"type": "gotcha", "summary": "one line", "details": "optional", "confidence": 0.8, "tags": ["go"]}]. ` +
+		"Valid types are pattern, correction, gotcha and context. Skip the file if there is nothing worth recording."
+}
+
+// capturedLearning is one entry of the learnings file written by the agent.
+type capturedLearning struct {
+	Type       string   `json:"type"`
+	Summary    string   `json:"summary"`
+	Details    string   `json:"details"`
+	Confidence *float64 `json:"confidence"`
+	Tags       []string `json:"tags"`
+}
+
+// templateIDForKnowledge returns id if it refers to a workflow_templates row.
+// Builtin templates use their slug as ID, which cannot be stored in the UUID column;
+// knowledge for those runs is scoped to no template.
+func templateIDForKnowledge(id string) string {
+	if _, err := uuid.Parse(id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// buildKnowledgeEnrichment returns the approved-knowledge block to prepend to a step prompt,
+// or "" if the step does not enable knowledge.enrich or nothing is approved.
+func (a *Activities) buildKnowledgeEnrichment(ctx context.Context, teamID, workflowTemplateID string, def *model.KnowledgeDef) (string, error) {
+	if def == nil || !def.Enrich || a.KnowledgeStore == nil {
+		return "", nil
+	}
+	templateID := templateIDForKnowledge(workflowTemplateID)
+	if templateID == "" {
+		// uuid.Nil never matches a template, so only template-agnostic items are returned.
+		templateID = uuid.Nil.String()
+	}
+	limit := def.MaxItems
+	if limit <= 0 {
+		limit = defaultEnrichItems
+	}
+	fetch := limit
+	if len(def.Tags) > 0 {
+		fetch = max(limit, enrichTagPool)
+	}
+	items, err := a.KnowledgeStore.ListApprovedByWorkflow(ctx, teamID, templateID, fetch)
+	if err != nil {
+		return "", fmt.Errorf("list approved knowledge: %w", err)
+	}
+	if len(def.Tags) > 0 {
+		items = slices.DeleteFunc(items, func(item model.KnowledgeItem) bool {
+			return !slices.ContainsFunc(item.Tags, func(tag string) bool { return slices.Contains(def.Tags, tag) })
+		})
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return knowledge.FormatEnrichmentBlock(items), nil
+}
+
+// CaptureKnowledge reads the learnings file written by the agent and stores its
+// entries as pending knowledge items for the team to review, then removes the file
+// so a retry does not save them twice. A missing file is not an error — the agent
+// may have had nothing to record. Returns the number of items saved.
+func (a *Activities) CaptureKnowledge(ctx context.Context, input model.CaptureKnowledgeInput) (int, error) {
+	if a.KnowledgeStore == nil {
+		return 0, nil
+	}
+	activity.RecordHeartbeat(ctx, "capturing knowledge")
+
+	path := KnowledgeLearningsPath(input.StepRunID)
+	data, err := a.Sandbox.ReadBytes(ctx, input.SandboxID, path)
+	if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+		activity.GetLogger(ctx).Info("no learnings file captured", "sandbox_id", input.SandboxID)
+		return 0, nil
+	}
+
+	items, err := parseLearnings(data, input)
+	if err != nil {
+		return 0, err
+	}
+	if err := a.KnowledgeStore.BatchSave(ctx, items); err != nil {
+		return 0, fmt.Errorf("save captured knowledge: %w", err)
+	}
+	if _, stderr, err := a.Sandbox.Exec(ctx, input.SandboxID, "rm -f "+shellquote.Quote(path), "/"); err != nil {
+		activity.GetLogger(ctx).Warn("failed to remove captured learnings file", "path", path, "error", err, "stderr", stderr)
+	}
+	return len(items), nil
+}
+
+// parseLearnings converts a learnings file into pending knowledge items, dropping
+// entries with an unknown type or an empty summary.
+func parseLearnings(data []byte, input model.CaptureKnowledgeInput) ([]model.KnowledgeItem, error) {
+	var entries []capturedLearning
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", KnowledgeLearningsPath(input.StepRunID), err)
+	}
+
+	var templateID, stepRunID *string
+	if id := templateIDForKnowledge(input.WorkflowTemplateID); id != "" {
+		templateID = &id
+	}
+	if input.StepRunID != "" {
+		stepRunID = &input.StepRunID
+	}
+
+	items := make([]model.KnowledgeItem, 0, len(entries))
+	for _, e := range entries {
+		if len(items) == maxCapturedLearnings {
+			break
+		}
+		typ := model.KnowledgeType(e.Type)
+		switch typ {
+		case model.KnowledgeTypePattern, model.KnowledgeTypeCorrection,
+			model.KnowledgeTypeGotcha, model.KnowledgeTypeContext:
+		default:
+			continue
+		}
+		summary := strings.TrimSpace(e.Summary)
+		if summary == "" {
+			continue
+		}
+		confidence := 1.0
+		if e.Confidence != nil {
+			confidence = min(max(*e.Confidence, 0), 1)
+		}
+		tags := append(append([]string{}, input.Tags...), e.Tags...)
+		slices.Sort(tags)
+		items = append(items, model.KnowledgeItem{
+			TeamID:             input.TeamID,
+			WorkflowTemplateID: templateID,
+			StepRunID:          stepRunID,
+			Type:               typ,
+			Summary:            summary,
+			Details:            strings.TrimSpace(e.Details),
+			Source:             model.KnowledgeSourceAutoCaptured,
+			Tags:               pq.StringArray(slices.Compact(tags)),
+			Confidence:         confidence,
+			Status:             model.KnowledgeStatusPending,
+		})
+	}
+	return items, nil
+}
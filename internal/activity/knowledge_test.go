@@ -0,0 +1,126 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/knowledge"
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+func TestParseLearnings(t *testing.T) {
+	data := []byte(`[
+		{"type": "gotcha", "summary": "  run make generate first ", "confidence": 1.7, "tags": ["go", "build"]},
+		{"type": "opinion", "summary": "unknown type is dropped"},
+		{"type": "pattern", "summary": "   "},
+		{"type": "context", "summary": "defaults confidence"}
+	]`)
+	input := model.CaptureKnowledgeInput{
+		TeamID:             "team-1",
+		WorkflowTemplateID: "builtin-slug",
+		StepRunID:          "sr-1",
+		Tags:               []string{"go"},
+	}
+
+	items, err := parseLearnings(data, input)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	assert.Equal(t, model.KnowledgeTypeGotcha, items[0].Type)
+	assert.Equal(t, "run make generate first", items[0].Summary)
+	assert.Equal(t, 1.0, items[0].Confidence)
+	assert.Equal(t, pq.StringArray{"build", "go"}, items[0].Tags)
+	assert.Equal(t, model.KnowledgeSourceAutoCaptured, items[0].Source)
+	assert.Equal(t, model.KnowledgeStatusPending, items[0].Status)
+	assert.Nil(t, items[0].WorkflowTemplateID, "non-UUID template IDs are not stored")
+	require.NotNil(t, items[0].StepRunID)
+	assert.Equal(t, "sr-1", *items[0].StepRunID)
+
+	assert.Equal(t, model.KnowledgeTypeContext, items[1].Type)
+	assert.Equal(t, 1.0, items[1].Confidence)
+	assert.Equal(t, pq.StringArray{"go"}, items[1].Tags)
+}
+
+func TestParseLearnings_KeepsUUIDTemplate(t *testing.T) {
+	id := "5b0c4c9e-3f3b-4c8e-9a55-0f7f2d1a6b11"
+	items, err := parseLearnings([]byte(`[{"type": "pattern", "summary": "s"}]`),
+		model.CaptureKnowledgeInput{TeamID: "team-1", WorkflowTemplateID: id})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.NotNil(t, items[0].WorkflowTemplateID)
+	assert.Equal(t, id, *items[0].WorkflowTemplateID)
+	assert.Nil(t, items[0].StepRunID)
+}
+
+func TestParseLearnings_InvalidJSON(t *testing.T) {
+	_, err := parseLearnings([]byte(`{"type": "pattern"}`), model.CaptureKnowledgeInput{})
+	require.Error(t, err)
+}
+
+func TestBuildKnowledgeEnrichment(t *testing.T) {
+	ctx := context.Background()
+	store := knowledge.NewMemoryStore()
+	for _, item := range []model.KnowledgeItem{
+		{TeamID: "team-1", Summary: "go item", Type: model.KnowledgeTypePattern, Tags: pq.StringArray{"go"}, Confidence: 0.9, Status: model.KnowledgeStatusApproved},
+		{TeamID: "team-1", Summary: "python item", Type: model.KnowledgeTypePattern, Tags: pq.StringArray{"python"}, Confidence: 0.95, Status: model.KnowledgeStatusApproved},
+		{TeamID: "team-1", Summary: "pending item", Type: model.KnowledgeTypePattern, Tags: pq.StringArray{"go"}, Confidence: 1, Status: model.KnowledgeStatusPending},
+	} {
+		_, err := store.Save(ctx, item)
+		require.NoError(t, err)
+	}
+	a := &Activities{KnowledgeStore: store}
+
+	block, err := a.buildKnowledgeEnrichment(ctx, "team-1", "builtin-slug", &model.KnowledgeDef{Enrich: true, Tags: []string{"go"}})
+	require.NoError(t, err)
+	assert.Contains(t, block, "go item")
+	assert.NotContains(t, block, "python item")
+	assert.NotContains(t, block, "pending item")
+
+	block, err = a.buildKnowledgeEnrichment(ctx, "team-1", "builtin-slug", &model.KnowledgeDef{Enrich: true, MaxItems: 1})
+	require.NoError(t, err)
+	assert.Contains(t, block, "python item")
+	assert.NotContains(t, block, "go item")
+
+	block, err = a.buildKnowledgeEnrichment(ctx, "team-1", "builtin-slug", &model.KnowledgeDef{Capture: true})
+	require.NoError(t, err)
+	assert.Empty(t, block, "enrich disabled")
+}
+
+// learningsSandbox serves one learnings file and records commands.
+type learningsSandbox struct {
+	scriptingSandbox
+	files map[string][]byte
+}
+
+func (s *learningsSandbox) ReadBytes(_ context.Context, _, path string) ([]byte, error) {
+	data, ok := s.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	return data, nil
+}
+
+func TestCaptureKnowledge_ReadsStepRunFileAndRemovesIt(t *testing.T) {
+	store := knowledge.NewMemoryStore()
+	sb := &learningsSandbox{files: map[string][]byte{
+		"/workspace/.fleetlift/learnings.json":      []byte(`[{"type": "gotcha", "summary": "an earlier step's"}]`),
+		"/workspace/.fleetlift/learnings-sr-2.json": []byte(`[{"type": "gotcha", "summary": "use make test"}]`),
+	}}
+	a := &Activities{Sandbox: sb, KnowledgeStore: store}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.CaptureKnowledge)
+
+	val, err := env.ExecuteActivity(a.CaptureKnowledge, model.CaptureKnowledgeInput{SandboxID: "sb-1", TeamID: "team-1", StepRunID: "sr-2"})
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, val.Get(&n))
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"rm -f '/workspace/.fleetlift/learnings-sr-2.json'"}, sb.cmds)
+}
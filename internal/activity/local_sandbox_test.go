@@ -0,0 +1,194 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/go-github/v62/github"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/artifact"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/local"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// gitHost runs git on the host for test setup, isolated from the user's config.
+func gitHost(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = []string{"HOME=" + dir, "GIT_CONFIG_NOSYSTEM=1", "PATH=/usr/local/bin:/usr/bin:/bin"}
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+	return strings.TrimSpace(string(out))
+}
+
+// newBareRemote creates a bare repository with one commit on main and returns its path.
+func newBareRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	seed := filepath.Join(dir, "seed")
+	gitHost(t, dir, "init", "--bare", "-b", "main", remote)
+	gitHost(t, dir, "init", "-b", "main", seed)
+	gitHost(t, seed, "-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "--allow-empty", "-m", "initial")
+	gitHost(t, seed, "push", remote, "main")
+	return remote
+}
+
+// TestLocalSandbox_ShellVerifyCollectAndPR runs a step's sandbox-side pipeline for
+// real: clone, shell agent edit, verifiers, artifact collection and PR push to a
+// local bare remote standing in for GitHub.
+func TestLocalSandbox_ShellVerifyCollectAndPR(t *testing.T) {
+	remote := newBareRemote(t)
+	ctx := context.Background()
+
+	sb := local.New(t.TempDir())
+	sandboxID, err := sb.Create(ctx, sandbox.CreateOpts{TimeoutMins: 5})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sb.Kill(ctx, sandboxID) })
+
+	// Point the GitHub URL the workflow uses at the bare remote.
+	const repoURL = "https://github.com/acme/repo"
+	require.NoError(t, sb.WriteFile(ctx, sandboxID, "/home/.gitconfig",
+		"[url \"file://"+remote+"\"]\n\tinsteadOf = "+repoURL+"\n"))
+	_, stderr, err := sb.Exec(ctx, sandboxID, "git clone "+repoURL+" /workspace/repo", "/")
+	require.NoError(t, err, stderr)
+
+	// Shell agent: edit the clone and report through the exit sentinel.
+	events, err := agent.NewShellRunner(sb).Run(ctx, sandboxID, agent.RunOpts{
+		Prompt:  "echo 'hello' > README.md\nmkdir -p /workspace/out && echo report > /workspace/out/report.txt\necho done",
+		WorkDir: "/workspace/repo",
+	})
+	require.NoError(t, err)
+	var last agent.Event
+	for ev := range events {
+		last = ev
+	}
+	require.Equal(t, "complete", last.Type, last.Content)
+	assert.Equal(t, "done\n", last.Output["stdout"])
+
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	fs, err := artifact.NewFSStore(t.TempDir())
+	require.NoError(t, err)
+
+	var pushedHead string
+	ghClient := newTestGitHubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req github.NewPullRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		pushedHead = req.GetHead()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(github.PullRequest{
+			Number:  github.Int(7),
+			HTMLURL: github.String("https://github.com/acme/repo/pull/7"),
+		})
+	}))
+
+	a := &Activities{
+		Sandbox:      sb,
+		DB:           sqlx.NewDb(db, "sqlmock"),
+		Artifacts:    artifact.New(fs, 1024),
+		GitHubClient: ghClient,
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.VerifyStep)
+	env.RegisterActivity(a.CollectArtifacts)
+	env.RegisterActivity(a.CreatePullRequest)
+
+	// Verifiers run in /workspace and fail on a non-zero exit.
+	dbMock.ExpectExec(`UPDATE step_runs SET status`).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = env.ExecuteActivity(a.VerifyStep, sandboxID, "sr-1", []any{"grep -q hello repo/README.md"})
+	require.NoError(t, err)
+
+	dbMock.ExpectExec(`UPDATE step_runs SET status`).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = env.ExecuteActivity(a.VerifyStep, sandboxID, "sr-1", []any{"grep -q goodbye repo/README.md"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "verification failed")
+
+	dbMock.ExpectExec(`INSERT INTO artifacts`).
+		WithArgs(sqlmock.AnyArg(), "sr-1", "report", "/workspace/out/report.txt", 7, "text/plain", "inline", []byte("report\n"), nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = env.ExecuteActivity(a.CollectArtifacts, sandboxID, "sr-1", []model.ArtifactRef{
+		{Name: "report", Path: "/workspace/out/report.txt"},
+	})
+	require.NoError(t, err)
+
+	dbMock.ExpectExec(`UPDATE step_runs SET pr_url`).
+		WithArgs("https://github.com/acme/repo/pull/7", "agent/run-abc", "created", "steprun-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	val, err := env.ExecuteActivity(a.CreatePullRequest, sandboxID, makePRTestInput(repoURL))
+	require.NoError(t, err)
+	var prURL string
+	require.NoError(t, val.Get(&prURL))
+	assert.Equal(t, "https://github.com/acme/repo/pull/7", prURL)
+	assert.Equal(t, "agent/run-abc", pushedHead)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+
+	// The branch really reached the remote with the agent's change.
+	assert.Equal(t, "hello", gitHost(t, remote, "show", "agent/run-abc:README.md"))
+	assert.Equal(t, "fix: test PR", gitHost(t, remote, "log", "-1", "--format=%s", "agent/run-abc"))
+}
+
+// TestLocalSandbox_ExecuteStepReattachesAfterWorkerRestart runs a
+// retry_on_worker_restart step whose detached agent was started by an attempt
+// that died: the retry collects that run's output instead of running it again.
+func TestLocalSandbox_ExecuteStepReattachesAfterWorkerRestart(t *testing.T) {
+	ctx := context.Background()
+	sb := local.New(t.TempDir())
+	sandboxID, err := sb.Create(ctx, sandbox.CreateOpts{TimeoutMins: 5})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sb.Kill(ctx, sandboxID) })
+
+	const prompt = "echo run >> /workspace/runs\necho hello"
+	logPath := agentLogPath("sr-1")
+	runner := agent.NewShellRunner(sb)
+
+	// The first attempt started the agent, then its worker went away.
+	events, err := runner.Run(ctx, sandboxID, agent.RunOpts{Prompt: prompt, WorkDir: "/workspace", LogPath: logPath})
+	require.NoError(t, err)
+	for range events {
+	}
+
+	a := &Activities{Sandbox: sb, AgentRunners: map[string]agent.Runner{"shell": runner}}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	env.SetHeartbeatDetails(executeCheckpoint{Phase: phaseAgent, SandboxID: sandboxID, SessionID: "sr-1", LogPath: logPath, Seq: 3})
+	val, err := env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{
+			StepRunID:    "sr-1",
+			StepDef:      model.StepDef{ID: "build", RetryOnWorkerRestart: true},
+			ResolvedOpts: workflow.ResolvedStepOpts{Agent: "shell"},
+		},
+		SandboxID: sandboxID,
+		Prompt:    prompt,
+	})
+	require.NoError(t, err)
+	var out model.StepOutput
+	require.NoError(t, val.Get(&out))
+	assert.Equal(t, model.StepStatusComplete, out.Status, out.Error)
+
+	runs, err := sb.ReadFile(ctx, sandboxID, "/workspace/runs")
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", runs, "the agent ran once")
+	assert.False(t, a.sandboxFileExists(ctx, sandboxID, logPath), "the handled run's log is released")
+}
@@ -10,6 +10,8 @@ import (
 	"go.temporal.io/sdk/activity"
 	"golang.org/x/oauth2"
 
+	"github.com/tinkerloft/fleetlift/internal/artifact"
+	"github.com/tinkerloft/fleetlift/internal/model"
 	"github.com/tinkerloft/fleetlift/internal/shellquote"
 	"github.com/tinkerloft/fleetlift/internal/workflow"
 )
@@ -31,7 +33,12 @@ func (a *Activities) CreatePullRequest(ctx context.Context, sandboxID string, in
 		return "", fmt.Errorf("no repos configured for PR creation")
 	}
 
-	branchName := fmt.Sprintf("%s/%s", prDef.BranchPrefix, input.RunID)
+	// Push the working branch ExecuteStep created. Inputs resolved without one
+	// fall back to the prefix naming it would have used.
+	branchName := input.ResolvedOpts.Branch
+	if branchName == "" {
+		branchName = fmt.Sprintf("%s/%s", prDef.BranchPrefix, input.RunID)
+	}
 	repoDir := "/workspace/" + repoName(input.ResolvedOpts.Repos[0])
 
 	execGit := func(cmd string) error {
@@ -60,6 +67,51 @@ func (a *Activities) CreatePullRequest(ctx context.Context, sandboxID string, in
 	// Record heartbeat now that we know there is real work to do.
 	activity.RecordHeartbeat(ctx, "creating PR")
 
+	repoURL := input.ResolvedOpts.Repos[0].URL
+	owner, repo := extractOwnerRepo(repoURL)
+	if owner == "" || repo == "" {
+		return "", fmt.Errorf("could not parse owner/repo from %s", repoURL)
+	}
+
+	ghClient := a.GitHubClient
+	if ghClient == nil {
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			return "", fmt.Errorf("GITHUB_TOKEN not set")
+		}
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		tc := oauth2.NewClient(ctx, ts)
+		ghClient = github.NewClient(tc)
+	}
+
+	baseBranch := DefaultBranch
+	if input.ResolvedOpts.Repos[0].Branch != "" {
+		baseBranch = input.ResolvedOpts.Repos[0].Branch
+	}
+
+	onExisting := prDef.OnExisting
+	if onExisting == "" {
+		onExisting = model.PROnExistingNew
+	}
+	marker := prMarker(input)
+	body := prDef.Body
+	if marker != "" {
+		body = strings.TrimRight(body, "\n") + "\n\n" + marker
+	}
+
+	var existing *github.PullRequest
+	if onExisting != model.PROnExistingNew {
+		var err error
+		if existing, err = findOpenPR(ctx, ghClient, owner, repo, baseBranch, branchName, marker); err != nil {
+			return "", err
+		}
+	}
+	if existing != nil && onExisting == model.PROnExistingSkip {
+		activity.GetLogger(ctx).Info("leaving existing PR alone", "pr_url", existing.GetHTMLURL())
+		a.recordPR(ctx, input.StepRunID, existing.GetHTMLURL(), existing.GetHead().GetRef(), prActionSkipped)
+		return existing.GetHTMLURL(), nil
+	}
+
 	if err := execGit(fmt.Sprintf("git -C %s add -A", shellquote.Quote(repoDir))); err != nil {
 		return "", err
 	}
@@ -82,60 +134,161 @@ func (a *Activities) CreatePullRequest(ctx context.Context, sandboxID string, in
 	if err := execGit(commitCmd); err != nil {
 		return "", err
 	}
-	if err := execGit(fmt.Sprintf("git -C %s push origin %s", shellquote.Quote(repoDir), shellquote.Quote(branchName))); err != nil {
+
+	// In update and skip modes a re-run replaces whatever an earlier run left
+	// on the branch, so the push is forced. An open PR found by its marker may
+	// live on a differently named branch; push this run's work there.
+	pushCmd := "git -C " + shellquote.Quote(repoDir) + " push"
+	if onExisting != model.PROnExistingNew {
+		pushCmd += " --force"
+	}
+	headBranch := branchName
+	if existing != nil {
+		headBranch = existing.GetHead().GetRef()
+	}
+	refspec := branchName
+	if headBranch != branchName {
+		refspec = branchName + ":" + headBranch
+	}
+	if err := execGit(pushCmd + " origin " + shellquote.Quote(refspec)); err != nil {
 		return "", err
 	}
 
-	// Create GitHub PR
-	repoURL := input.ResolvedOpts.Repos[0].URL
-	owner, repo := extractOwnerRepo(repoURL)
-	if owner == "" || repo == "" {
-		return "", fmt.Errorf("could not parse owner/repo from %s", repoURL)
+	var pr *github.PullRequest
+	action := prActionCreated
+	if existing != nil {
+		var err error
+		pr, _, err = ghClient.PullRequests.Edit(ctx, owner, repo, existing.GetNumber(), &github.PullRequest{
+			Title: github.String(prDef.Title),
+			Body:  github.String(body),
+		})
+		if err != nil {
+			return "", fmt.Errorf("update PR #%d: %w", existing.GetNumber(), err)
+		}
+		action = prActionUpdated
+	} else {
+		var err error
+		pr, _, err = ghClient.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+			Title: github.String(prDef.Title),
+			Body:  github.String(body),
+			Head:  github.String(branchName),
+			Base:  github.String(baseBranch),
+			Draft: github.Bool(prDef.Draft),
+		})
+		if err != nil {
+			return "", fmt.Errorf("create PR: %w", err)
+		}
 	}
 
-	ghClient := a.GitHubClient
-	if ghClient == nil {
-		token := os.Getenv("GITHUB_TOKEN")
-		if token == "" {
-			return "", fmt.Errorf("GITHUB_TOKEN not set")
+	// Add labels if configured
+	if len(prDef.Labels) > 0 {
+		_, _, err := ghClient.Issues.AddLabelsToIssue(ctx, owner, repo, pr.GetNumber(), prDef.Labels)
+		if err != nil {
+			activity.GetLogger(ctx).Warn("failed to add labels to PR", "error", err)
 		}
-		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-		tc := oauth2.NewClient(ctx, ts)
-		ghClient = github.NewClient(tc)
 	}
 
-	baseBranch := DefaultBranch
-	if input.ResolvedOpts.Repos[0].Branch != "" {
-		baseBranch = input.ResolvedOpts.Repos[0].Branch
+	a.recordPR(ctx, input.StepRunID, pr.GetHTMLURL(), headBranch, action)
+	return pr.GetHTMLURL(), nil
+}
+
+// What CreatePullRequest did, recorded in step_runs.pr_action.
+const (
+	prActionCreated = "created"
+	prActionUpdated = "updated"
+	prActionSkipped = "skipped"
+)
+
+// prMarker is a hidden line in the PR body that identifies the job that opened
+// it: the team, the workflow step and the run's parameters. A later run of the
+// same job finds the PR even under another branch name, while runs of the same
+// template with other parameters, or by another team, do not. It is empty for
+// steps run outside a workflow template.
+func prMarker(input workflow.StepInput) string {
+	if input.WorkflowTemplateID == "" {
+		return ""
 	}
+	return fmt.Sprintf("<!-- fleetlift:pr %s/%s/%s/%s -->",
+		input.TeamID, input.WorkflowTemplateID, input.StepDef.ID, input.ParamsHash)
+}
 
-	pr, _, err := ghClient.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
-		Title: github.String(prDef.Title),
-		Body:  github.String(prDef.Body),
-		Head:  github.String(branchName),
-		Base:  github.String(baseBranch),
-		Draft: github.Bool(prDef.Draft),
+// findOpenPR returns the open PR against base that comes from head or carries
+// marker in its body, or nil when there is none.
+func findOpenPR(ctx context.Context, gh *github.Client, owner, repo, base, head, marker string) (*github.PullRequest, error) {
+	prs, _, err := gh.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  owner + ":" + head,
+		Base:  base,
 	})
 	if err != nil {
-		return "", fmt.Errorf("create PR: %w", err)
+		return nil, fmt.Errorf("list PRs from %s: %w", head, err)
 	}
-
-	// Add labels if configured
-	if len(prDef.Labels) > 0 {
-		_, _, err = ghClient.Issues.AddLabelsToIssue(ctx, owner, repo, pr.GetNumber(), prDef.Labels)
+	if len(prs) > 0 {
+		return prs[0], nil
+	}
+	if marker == "" {
+		return nil, nil
+	}
+	opts := &github.PullRequestListOptions{State: "open", Base: base, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		prs, resp, err := gh.PullRequests.List(ctx, owner, repo, opts)
 		if err != nil {
-			activity.GetLogger(ctx).Warn("failed to add labels to PR", "error", err)
+			return nil, fmt.Errorf("list open PRs: %w", err)
+		}
+		for _, pr := range prs {
+			// The PR's branch must be in this repo for the push to reach it.
+			if pr.GetHead().GetRepo().GetFullName() == pr.GetBase().GetRepo().GetFullName() &&
+				strings.Contains(pr.GetBody(), marker) {
+				return pr, nil
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return nil, nil
 		}
+		opts.Page = resp.NextPage
 	}
+}
+
+// recordPR stores the PR URL, its branch and what was done on the step run.
+func (a *Activities) recordPR(ctx context.Context, stepRunID, url, branch, action string) {
+	if a.DB == nil {
+		return
+	}
+	if _, err := a.DB.ExecContext(ctx,
+		`UPDATE step_runs SET pr_url = $1, branch_name = $2, pr_action = $3 WHERE id = $4`,
+		url, branch, action, stepRunID); err != nil {
+		activity.GetLogger(ctx).Warn("failed to record PR URL in step_run", "pr_url", url, "error", err)
+	}
+}
 
-	// Update step run with PR URL and branch
-	if a.DB != nil {
+// NotifyPRSkipped raises a notify inbox item for a transform step whose agent
+// declared its changes not PR-ready, so a human can decide what to do with
+// them. The step's diff is attached as an artifact.
+func (a *Activities) NotifyPRSkipped(ctx context.Context, input workflow.PRSkippedInput) error {
+	const diffPath = "/pr-skipped.diff"
+	var diffArtifactID string
+	if input.Diff != "" {
+		const contentType = "text/x-diff"
+		id := artifactID(input.StepRunID, diffPath)
+		data := []byte(input.Diff)
+		loc, err := a.Artifacts.Save(ctx, artifactObjectKey(input.StepRunID, id), data, contentType)
+		if err != nil {
+			return fmt.Errorf("store diff: %w", err)
+		}
+		var inline any // NULL, not an empty BYTEA, for object_store rows
+		if loc.Storage == artifact.StorageInline {
+			inline = loc.Data
+		}
 		if _, err := a.DB.ExecContext(ctx,
-			`UPDATE step_runs SET pr_url = $1, branch_name = $2 WHERE id = $3`,
-			pr.GetHTMLURL(), branchName, input.StepRunID); err != nil {
-			activity.GetLogger(ctx).Warn("failed to record PR URL in step_run", "pr_url", pr.GetHTMLURL(), "error", err)
+			`INSERT INTO artifacts (id, step_run_id, name, path, size_bytes, content_type, storage, data, object_key)
+			 VALUES ($1, $2, 'pr-skipped-diff', $3, $4, $5, $6, $7, $8)
+			 ON CONFLICT (id) DO NOTHING`,
+			id, input.StepRunID, diffPath, len(data), contentType, loc.Storage, inline, loc.ObjectKey,
+		); err != nil {
+			return fmt.Errorf("store diff: %w", err)
 		}
+		diffArtifactID = id
 	}
-
-	return pr.GetHTMLURL(), nil
+	return a.CreateInboxItem(ctx, input.TeamID, input.RunID, input.StepRunID, "notify",
+		fmt.Sprintf("Pull request skipped: %s", input.StepID), input.Reason, diffArtifactID, input.StepID)
 }
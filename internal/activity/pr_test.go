@@ -9,7 +9,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/go-github/v62/github"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.temporal.io/sdk/testsuite"
@@ -23,6 +25,7 @@ import (
 type scriptingSandbox struct {
 	noopSandbox
 	responses []sandboxResponse
+	cmds      []string
 }
 
 type sandboxResponse struct {
@@ -33,6 +36,7 @@ type sandboxResponse struct {
 }
 
 func (s *scriptingSandbox) Exec(_ context.Context, _, cmd, _ string) (string, string, error) {
+	s.cmds = append(s.cmds, cmd)
 	for _, r := range s.responses {
 		if r.match == "" || strings.Contains(cmd, r.match) {
 			return r.stdout, r.stderr, r.err
@@ -145,3 +149,167 @@ func TestCreatePullRequest_DirtyTree(t *testing.T) {
 	assert.Equal(t, "https://github.com/acme/repo/pull/42", prURL)
 	assert.True(t, prCreated)
 }
+
+// TestCreatePullRequest_PushesResolvedBranch verifies that the PR is opened
+// from the working branch ExecuteStep created, not a name derived again from
+// branch_prefix.
+func TestCreatePullRequest_PushesResolvedBranch(t *testing.T) {
+	var head string
+	ghClient := newTestGitHubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost && r.URL.Path == "/repos/acme/repo/pulls" {
+			var req github.NewPullRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			head = req.GetHead()
+			_ = json.NewEncoder(w).Encode(github.PullRequest{Number: github.Int(7), HTMLURL: github.String("https://github.com/acme/repo/pull/7")})
+			return
+		}
+		_, _ = fmt.Fprint(w, "[]")
+	}))
+	sb := &scriptingSandbox{responses: []sandboxResponse{{match: "status --porcelain", stdout: "M  main.go\n"}}}
+	a := &Activities{Sandbox: sb, GitHubClient: ghClient}
+
+	input := makePRTestInput("https://github.com/acme/repo")
+	input.ResolvedOpts.Branch = "agent/quick-run/run-abc"
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.CreatePullRequest)
+	_, err := env.ExecuteActivity(a.CreatePullRequest, "sb-1", input)
+	require.NoError(t, err)
+
+	assert.Equal(t, "agent/quick-run/run-abc", head)
+	assert.Contains(t, sb.cmds, "git -C '/workspace/repo' push origin 'agent/quick-run/run-abc'")
+}
+
+func TestNotifyPRSkipped_AttachesDiff(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.ExpectExec(`INSERT INTO artifacts`).
+		WithArgs(sqlmock.AnyArg(), "sr-1", "/pr-skipped.diff", len("some diff"), "text/x-diff", "inline", []byte("some diff"), nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(`INSERT INTO inbox_items`).
+		WithArgs("team-1", "run-1", "sr-1", "notify", "Pull request skipped: fix", "tests still fail", sqlmock.AnyArg(), "fix").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := &Activities{DB: sqlx.NewDb(db, "sqlmock")}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.NotifyPRSkipped)
+	_, err = env.ExecuteActivity(a.NotifyPRSkipped, workflow.PRSkippedInput{
+		TeamID: "team-1", RunID: "run-1", StepRunID: "sr-1", StepID: "fix",
+		Reason: "tests still fail", Diff: "some diff",
+	})
+	require.NoError(t, err)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// TestCreatePullRequest_UpdatesExistingPR verifies that on_existing: update
+// force-pushes to the open PR's branch and refreshes it instead of opening a
+// second PR.
+func TestCreatePullRequest_UpdatesExistingPR(t *testing.T) {
+	var edited github.PullRequest
+	created := false
+	ghClient := newTestGitHubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/repo/pulls":
+			assert.Equal(t, "acme:agent/run-abc", r.URL.Query().Get("head"))
+			_ = json.NewEncoder(w).Encode([]github.PullRequest{{
+				Number:  github.Int(7),
+				HTMLURL: github.String("https://github.com/acme/repo/pull/7"),
+				Head:    &github.PullRequestBranch{Ref: github.String("agent/run-abc")},
+			}})
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/acme/repo/pulls/7":
+			_ = json.NewDecoder(r.Body).Decode(&edited)
+			_ = json.NewEncoder(w).Encode(github.PullRequest{Number: github.Int(7), HTMLURL: github.String("https://github.com/acme/repo/pull/7")})
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/repo/pulls":
+			created = true
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		default:
+			_, _ = fmt.Fprint(w, "[]")
+		}
+	}))
+	sb := &scriptingSandbox{responses: []sandboxResponse{{match: "status --porcelain", stdout: "M  main.go\n"}}}
+	a := &Activities{Sandbox: sb, GitHubClient: ghClient}
+
+	input := makePRTestInput("https://github.com/acme/repo")
+	input.WorkflowTemplateID = "bump-deps"
+	input.TeamID = "team-1"
+	input.ParamsHash = "0123456789ab"
+	input.StepDef.PullRequest.OnExisting = model.PROnExistingUpdate
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.CreatePullRequest)
+	val, err := env.ExecuteActivity(a.CreatePullRequest, "sb-1", input)
+	require.NoError(t, err)
+
+	var prURL string
+	require.NoError(t, val.Get(&prURL))
+	assert.Equal(t, "https://github.com/acme/repo/pull/7", prURL)
+	assert.False(t, created, "no second PR is opened")
+	assert.Contains(t, sb.cmds, "git -C '/workspace/repo' push --force origin 'agent/run-abc'")
+	assert.Equal(t, "fix: test PR", edited.GetTitle())
+	assert.Equal(t, "automated\n\n<!-- fleetlift:pr team-1/bump-deps/execute/0123456789ab -->", edited.GetBody())
+}
+
+// TestCreatePullRequest_SkipsExistingPRFoundByMarker verifies that
+// on_existing: skip finds an open PR on another branch by its body marker and
+// leaves it alone, recording it on the step run.
+func TestCreatePullRequest_SkipsExistingPRFoundByMarker(t *testing.T) {
+	ghClient := newTestGitHubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet && r.URL.Path == "/repos/acme/repo/pulls" {
+			if r.URL.Query().Get("head") != "" {
+				_, _ = fmt.Fprint(w, "[]")
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]github.PullRequest{
+				{Number: github.Int(3), Body: github.String("unrelated")},
+				{
+					// Same template step, other parameters: a different job.
+					Number: github.Int(4),
+					Body:   github.String("automated\n\n<!-- fleetlift:pr team-1/bump-deps/execute/ffffffffffff -->"),
+					Head:   &github.PullRequestBranch{Ref: github.String("agent/run-other")},
+				},
+				{
+					Number:  github.Int(5),
+					HTMLURL: github.String("https://github.com/acme/repo/pull/5"),
+					Body:    github.String("automated\n\n<!-- fleetlift:pr team-1/bump-deps/execute/0123456789ab -->"),
+					Head:    &github.PullRequestBranch{Ref: github.String("agent/run-old")},
+				},
+			})
+			return
+		}
+		t.Errorf("unexpected GitHub call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.ExpectExec(`UPDATE step_runs SET pr_url`).
+		WithArgs("https://github.com/acme/repo/pull/5", "agent/run-old", "skipped", "steprun-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sb := &scriptingSandbox{responses: []sandboxResponse{{match: "status --porcelain", stdout: "M  main.go\n"}}}
+	a := &Activities{Sandbox: sb, GitHubClient: ghClient, DB: sqlx.NewDb(db, "sqlmock")}
+
+	input := makePRTestInput("https://github.com/acme/repo")
+	input.WorkflowTemplateID = "bump-deps"
+	input.TeamID = "team-1"
+	input.ParamsHash = "0123456789ab"
+	input.StepDef.PullRequest.OnExisting = model.PROnExistingSkip
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.CreatePullRequest)
+	val, err := env.ExecuteActivity(a.CreatePullRequest, "sb-1", input)
+	require.NoError(t, err)
+
+	var prURL string
+	require.NoError(t, val.Get(&prURL))
+	assert.Equal(t, "https://github.com/acme/repo/pull/5", prURL)
+	for _, cmd := range sb.cmds {
+		assert.NotContains(t, cmd, " push ", "nothing is pushed to a skipped PR")
+	}
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
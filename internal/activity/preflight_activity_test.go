@@ -43,6 +43,9 @@ func (s *preflightRecordingSandbox) ReadBytes(_ context.Context, _, _ string) ([
 }
 func (s *preflightRecordingSandbox) Kill(_ context.Context, _ string) error            { return nil }
 func (s *preflightRecordingSandbox) RenewExpiration(_ context.Context, _ string) error { return nil }
+func (s *preflightRecordingSandbox) List(_ context.Context) ([]sandbox.Info, error) {
+	return nil, nil
+}
 
 func TestRunPreflight_ExecutesScriptInSandbox(t *testing.T) {
 	sb := &preflightRecordingSandbox{}
@@ -33,6 +33,7 @@ func (s *capturingSandbox) ReadFile(_ context.Context, _, _ string) (string, err
 func (s *capturingSandbox) ReadBytes(_ context.Context, _, _ string) ([]byte, error)  { return nil, nil }
 func (s *capturingSandbox) Kill(_ context.Context, _ string) error                    { return nil }
 func (s *capturingSandbox) RenewExpiration(_ context.Context, _ string) error         { return nil }
+func (s *capturingSandbox) List(_ context.Context) ([]sandbox.Info, error)            { return nil, nil }
 
 // stubCredStore resolves credentials from an in-memory map.
 type stubCredStore struct {
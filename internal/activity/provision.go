@@ -67,6 +67,7 @@ func (a *Activities) ProvisionSandbox(ctx context.Context, input workflow.StepIn
 	// claude-code step, so we inject auth unconditionally. If no credential
 	// exists, resolveClaudeAuth is a no-op.
 	a.resolveClaudeAuth(ctx, input.TeamID, env)
+	a.resolveAgentAuth(ctx, input.ResolvedOpts.Agent, input.TeamID, env)
 
 	// Resolve git identity: prefer the triggering user's GitHub identity, fall back to worker env.
 	gitName := os.Getenv("GIT_USER_NAME")
@@ -102,6 +103,11 @@ func (a *Activities) ProvisionSandbox(ctx context.Context, input workflow.StepIn
 		Image:       image,
 		Env:         env,
 		TimeoutMins: 120,
+		Labels: map[string]string{
+			sandbox.LabelRunID:  input.RunID,
+			sandbox.LabelStepID: input.StepDef.ID,
+			sandbox.LabelTeamID: input.TeamID,
+		},
 	}
 
 	// Apply per-step sandbox spec (resources, egress policy) if defined.
@@ -122,6 +128,7 @@ func (a *Activities) ProvisionSandbox(ctx context.Context, input workflow.StepIn
 			}
 			createOpts.NetworkPolicy = np
 		}
+		createOpts.Storage = spec.WorkspaceSize
 		if spec.Timeout != "" {
 			d, err := time.ParseDuration(spec.Timeout)
 			if err != nil {
@@ -135,9 +142,24 @@ func (a *Activities) ProvisionSandbox(ctx context.Context, input workflow.StepIn
 		}
 	}
 
-	sandboxID, err := a.Sandbox.Create(ctx, createOpts)
-	if err != nil {
-		return "", fmt.Errorf("create sandbox: %w", err)
+	// Warm sandboxes have provider-default resources and disk, open egress and the
+	// default timeout, so steps that customise any of these always get a fresh one.
+	var sandboxID string
+	if a.Pool != nil && poolable(input.ResolvedOpts.SandboxSpec) {
+		if id, ok := a.Pool.Claim(ctx, image, env); ok {
+			sandboxID = id
+			recordPoolOutcome(ctx, PoolHit)
+			// Record the claim at once: until then the reaper sees an unowned
+			// pool sandbox and kills it once it is older than pool.MaxIdle.
+			a.recordStepSandbox(ctx, input.StepRunID, sandboxID)
+		}
+	}
+	if sandboxID == "" {
+		id, err := a.Sandbox.Create(ctx, createOpts)
+		if err != nil {
+			return "", fmt.Errorf("create sandbox: %w", err)
+		}
+		sandboxID = id
 	}
 
 	// Ensure /workspace exists — the execd fails with a misleading bash error when
@@ -300,9 +322,54 @@ func (a *Activities) ProvisionSandbox(ctx context.Context, input workflow.StepIn
 		}
 	}
 
+	// Record the sandbox on the step run so the reaper can tie it back to its run.
+	// Best-effort: the run-id label set above serves the same purpose.
+	a.recordStepSandbox(ctx, input.StepRunID, sandboxID)
+
 	return sandboxID, nil
 }
 
+// recordStepSandbox sets the sandbox a step run executes in. Step runs that
+// share a sandbox group's sandbox all record it.
+func (a *Activities) recordStepSandbox(ctx context.Context, stepRunID, sandboxID string) {
+	if a.DB == nil || stepRunID == "" {
+		return
+	}
+	if _, err := a.DB.ExecContext(ctx,
+		`UPDATE step_runs SET sandbox_id = $1 WHERE id = $2`, sandboxID, stepRunID,
+	); err != nil {
+		activity.GetLogger(ctx).Warn("failed to record sandbox on step run",
+			"step_run_id", stepRunID, "sandbox_id", sandboxID, "error", err)
+	}
+}
+
+// poolable reports whether a sandbox with spec can be claimed from the warm pool.
+func poolable(spec *model.SandboxSpec) bool {
+	return spec == nil || (spec.Resources == model.SandboxResources{} &&
+		!spec.Egress.DenyAllByDefault && spec.Timeout == "" && spec.WorkspaceSize == "")
+}
+
+// Pool outcomes reported by ProvisionSandbox for the provision-duration metric.
+const (
+	PoolHit  = "hit"
+	PoolMiss = "miss"
+)
+
+type poolOutcomeKey struct{}
+
+// WithPoolOutcome returns a context through which ProvisionSandbox reports whether
+// it claimed a warm sandbox, and a func that reads the outcome once it returns.
+func WithPoolOutcome(ctx context.Context) (context.Context, func() string) {
+	outcome := PoolMiss
+	return context.WithValue(ctx, poolOutcomeKey{}, &outcome), func() string { return outcome }
+}
+
+func recordPoolOutcome(ctx context.Context, outcome string) {
+	if p, ok := ctx.Value(poolOutcomeKey{}).(*string); ok {
+		*p = outcome
+	}
+}
+
 // CleanupCheckpointBranch deletes a fleetlift checkpoint branch from the remote.
 // Returns nil if the branch does not exist (idempotent).
 func (a *Activities) CleanupCheckpointBranch(ctx context.Context, input model.CleanupCheckpointInput) error {
@@ -362,6 +429,33 @@ func (a *Activities) resolveClaudeAuth(ctx context.Context, teamID string, env m
 	}
 }
 
+// agentAuthCredentials lists, per non-Claude agent, the credential names that
+// authenticate its CLI, in order of preference.
+var agentAuthCredentials = map[string][]string{
+	"codex":  {"OPENAI_API_KEY", "CODEX_API_KEY"},
+	"gemini": {"GEMINI_API_KEY", "GOOGLE_API_KEY"},
+}
+
+// resolveAgentAuth fetches the first available auth credential for agentName from
+// the team's credential store. Best-effort, like resolveClaudeAuth; unlike Claude
+// auth it is only injected into sandboxes for that agent.
+func (a *Activities) resolveAgentAuth(ctx context.Context, agentName, teamID string, env map[string]string) {
+	names := agentAuthCredentials[agentName]
+	if a.CredStore == nil || len(names) == 0 {
+		return
+	}
+	for _, name := range names {
+		if _, ok := env[name]; ok {
+			return // already injected via the step's credentials list
+		}
+		val, err := a.CredStore.Get(ctx, teamID, name)
+		if err == nil && val != "" {
+			env[name] = val
+			return
+		}
+	}
+}
+
 func agentImage(agentName string) string {
 	switch agentName {
 	case "codex":
@@ -369,6 +463,11 @@ func agentImage(agentName string) string {
 			return img
 		}
 		return "codex:latest"
+	case "gemini":
+		if img := os.Getenv("GEMINI_IMAGE"); img != "" {
+			return img
+		}
+		return "gemini:latest"
 	case "shell":
 		if img := os.Getenv("SHELL_IMAGE"); img != "" {
 			return img
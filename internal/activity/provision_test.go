@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.temporal.io/sdk/temporal"
@@ -156,6 +158,13 @@ func TestAgentImage_ClaudeCodeDefaultsToSandboxImage(t *testing.T) {
 	assert.Equal(t, "claude-code-sandbox:latest", agentImage("claude-code"))
 }
 
+func TestAgentImage_Gemini(t *testing.T) {
+	t.Setenv("GEMINI_IMAGE", "")
+	assert.Equal(t, "gemini:latest", agentImage("gemini"))
+	t.Setenv("GEMINI_IMAGE", "registry.example.com/gemini:1.2")
+	assert.Equal(t, "registry.example.com/gemini:1.2", agentImage("gemini"))
+}
+
 // mcpSandbox records exec calls and returns "ok" for health check requests.
 type mcpSandbox struct {
 	noopSandbox
@@ -501,6 +510,134 @@ func TestProvisionSandbox_SandboxSpecResources(t *testing.T) {
 	assert.Equal(t, "4Gi", sb.capturedOpts.Resources.Memory)
 }
 
+func TestProvisionSandbox_LabelsSandboxWithOwner(t *testing.T) {
+	sb := &createOptsRecordingSandbox{}
+	a := &Activities{Sandbox: sb}
+
+	_, err := a.ProvisionSandbox(context.Background(), workflow.StepInput{
+		RunID:        "run-1",
+		TeamID:       "team-1",
+		StepDef:      model.StepDef{ID: "fix"},
+		ResolvedOpts: workflow.ResolvedStepOpts{Agent: "shell"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		sandbox.LabelRunID:  "run-1",
+		sandbox.LabelStepID: "fix",
+		sandbox.LabelTeamID: "team-1",
+	}, sb.capturedOpts.Labels)
+}
+
+func TestProvisionSandbox_RecordsSandboxOnStepRun(t *testing.T) {
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.ExpectExec(`UPDATE step_runs SET sandbox_id`).
+		WithArgs("sb-spec", "sr-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	a := &Activities{Sandbox: &createOptsRecordingSandbox{}, DB: sqlx.NewDb(db, "sqlmock")}
+	id, err := a.ProvisionSandbox(context.Background(), workflow.StepInput{
+		RunID:        "run-1",
+		StepRunID:    "sr-1",
+		TeamID:       "team-1",
+		ResolvedOpts: workflow.ResolvedStepOpts{Agent: "shell"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sb-spec", id)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+// stubPool hands out one warm sandbox and records the env it was claimed with.
+type stubPool struct {
+	image string
+	env   map[string]string
+}
+
+func (p *stubPool) Claim(_ context.Context, image string, env map[string]string) (string, bool) {
+	if image != p.image {
+		return "", false
+	}
+	p.env = env
+	return "sb-warm", true
+}
+
+func TestProvisionSandbox_ClaimsWarmSandbox(t *testing.T) {
+	t.Setenv("SHELL_IMAGE", "")
+	sb := &createOptsRecordingSandbox{}
+	pool := &stubPool{image: "ubuntu:22.04"}
+	a := &Activities{Sandbox: sb, Pool: pool, CredStore: &stubCredStore{val: "secret"}}
+
+	ctx, outcome := WithPoolOutcome(context.Background())
+	id, err := a.ProvisionSandbox(ctx, workflow.StepInput{
+		TeamID:       "team-1",
+		ResolvedOpts: workflow.ResolvedStepOpts{Agent: "shell", Credentials: []string{"API_KEY"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sb-warm", id)
+	assert.Equal(t, PoolHit, outcome())
+	assert.Equal(t, "secret", pool.env["API_KEY"], "credentials are injected at claim time")
+	assert.Empty(t, sb.capturedOpts.Image, "no sandbox is created on a pool hit")
+}
+
+func TestProvisionSandbox_CustomSandboxSpecBypassesPool(t *testing.T) {
+	t.Setenv("SHELL_IMAGE", "")
+	sb := &createOptsRecordingSandbox{}
+	a := &Activities{Sandbox: sb, Pool: &stubPool{image: "ubuntu:22.04"}}
+
+	ctx, outcome := WithPoolOutcome(context.Background())
+	id, err := a.ProvisionSandbox(ctx, workflow.StepInput{
+		TeamID: "team-1",
+		ResolvedOpts: workflow.ResolvedStepOpts{
+			Agent:       "shell",
+			SandboxSpec: &model.SandboxSpec{Resources: model.SandboxResources{CPU: "4"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sb-spec", id)
+	assert.Equal(t, PoolMiss, outcome())
+}
+
+func TestProvisionSandbox_InjectsCodexAuthForCodexSteps(t *testing.T) {
+	t.Setenv("CODEX_IMAGE", "")
+	creds := &mockCredStore{data: map[string]string{
+		"ANTHROPIC_API_KEY": "sk-ant",
+		"OPENAI_API_KEY":    "sk-openai",
+	}}
+
+	sb := &createOptsRecordingSandbox{}
+	a := &Activities{Sandbox: sb, CredStore: creds}
+	_, err := a.ProvisionSandbox(context.Background(), workflow.StepInput{
+		TeamID:       "team-1",
+		ResolvedOpts: workflow.ResolvedStepOpts{Agent: "codex"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sk-openai", sb.capturedOpts.Env["OPENAI_API_KEY"])
+	assert.Equal(t, "codex:latest", sb.capturedOpts.Image)
+
+	sb = &createOptsRecordingSandbox{}
+	a = &Activities{Sandbox: sb, CredStore: creds}
+	_, err = a.ProvisionSandbox(context.Background(), workflow.StepInput{
+		TeamID:       "team-1",
+		ResolvedOpts: workflow.ResolvedStepOpts{Agent: "claude-code"},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, sb.capturedOpts.Env, "OPENAI_API_KEY", "OpenAI key is only injected for codex steps")
+}
+
+func TestProvisionSandbox_InjectsGeminiAuthForGeminiSteps(t *testing.T) {
+	t.Setenv("GEMINI_IMAGE", "")
+	sb := &createOptsRecordingSandbox{}
+	a := &Activities{Sandbox: sb, CredStore: &mockCredStore{data: map[string]string{"GOOGLE_API_KEY": "g-key"}}}
+	_, err := a.ProvisionSandbox(context.Background(), workflow.StepInput{
+		TeamID:       "team-1",
+		ResolvedOpts: workflow.ResolvedStepOpts{Agent: "gemini"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "g-key", sb.capturedOpts.Env["GOOGLE_API_KEY"], "falls back to GOOGLE_API_KEY")
+	assert.Equal(t, "gemini:latest", sb.capturedOpts.Image)
+}
+
 func TestProvisionSandbox_SandboxSpecEgressPolicy(t *testing.T) {
 	sb := &createOptsRecordingSandbox{}
 	a := &Activities{Sandbox: sb}
@@ -0,0 +1,122 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/pool"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// ReapSandboxes kills live sandboxes whose run has finished or no longer exists,
+// and deletes exited sandboxes whatever their run. A sandbox is tied to its run
+// by the run-id label set at provisioning, falling back to step_runs.sandbox_id.
+// Sandboxes that match no run are reported as unowned and left for the
+// provider's timeout, except warm pool sandboxes: those are skipped while idle
+// and reaped once older than pool.MaxIdle, since a live pool would have replaced
+// them. Without a creation time they are always skipped. With DryRun set nothing
+// is killed.
+func (a *Activities) ReapSandboxes(ctx context.Context, input workflow.ReapInput) (*workflow.ReapReport, error) {
+	live, err := a.Sandbox.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list sandboxes: %w", err)
+	}
+	report := &workflow.ReapReport{DryRun: input.DryRun, Live: len(live), Reaped: []workflow.ReapedSandbox{}}
+	if len(live) == 0 {
+		return report, nil
+	}
+
+	runBySandbox := make(map[string]string, len(live))
+	var unlabelled []string
+	for _, sb := range live {
+		if runID := sb.Labels[sandbox.LabelRunID]; runID != "" {
+			runBySandbox[sb.ID] = runID
+		} else {
+			unlabelled = append(unlabelled, sb.ID)
+		}
+	}
+	if len(unlabelled) > 0 {
+		var rows []struct {
+			SandboxID string `db:"sandbox_id"`
+			RunID     string `db:"run_id"`
+		}
+		if err := a.DB.SelectContext(ctx, &rows,
+			`SELECT DISTINCT sandbox_id, run_id FROM step_runs WHERE sandbox_id = ANY($1)`,
+			pq.Array(unlabelled),
+		); err != nil {
+			return nil, fmt.Errorf("look up sandbox step runs: %w", err)
+		}
+		for _, r := range rows {
+			runBySandbox[r.SandboxID] = r.RunID
+		}
+	}
+
+	// Look up the status of every referenced run. Labels are free-form on some
+	// providers, so drop anything that is not a UUID before querying.
+	var runIDs []string
+	seen := make(map[string]bool)
+	for _, runID := range runBySandbox {
+		if _, err := uuid.Parse(runID); err == nil && !seen[runID] {
+			seen[runID] = true
+			runIDs = append(runIDs, runID)
+		}
+	}
+	statuses := make(map[string]model.RunStatus, len(runIDs))
+	if len(runIDs) > 0 {
+		var rows []struct {
+			ID     string          `db:"id"`
+			Status model.RunStatus `db:"status"`
+		}
+		if err := a.DB.SelectContext(ctx, &rows,
+			`SELECT id, status FROM runs WHERE id = ANY($1::uuid[])`,
+			pq.Array(runIDs),
+		); err != nil {
+			return nil, fmt.Errorf("look up run statuses: %w", err)
+		}
+		for _, r := range rows {
+			statuses[r.ID] = r.Status
+		}
+	}
+
+	logger := activity.GetLogger(ctx)
+	for _, sb := range live {
+		runID, ok := runBySandbox[sb.ID]
+		status, exists := statuses[runID]
+		switch {
+		case sb.Exited:
+			// Nothing runs in it any more; only its resources are left to delete.
+		case !ok && sb.Labels[sandbox.LabelPool] != "":
+			if sb.CreatedAt.IsZero() || time.Since(sb.CreatedAt) < pool.MaxIdle {
+				continue // idle in a worker's warm pool
+			}
+			// Left behind by a worker that exited without closing its pool.
+		case !ok:
+			report.Unowned = append(report.Unowned, sb.ID)
+			continue
+		case exists && !isRunTerminal(status):
+			continue
+		}
+		if !input.DryRun {
+			if err := a.Sandbox.Kill(ctx, sb.ID); err != nil {
+				logger.Warn("failed to reap sandbox", "sandbox_id", sb.ID, "run_id", runID, "error", err)
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", sb.ID, err))
+				continue
+			}
+			logger.Info("reaped sandbox", "sandbox_id", sb.ID, "run_id", runID, "run_status", status)
+		}
+		report.Reaped = append(report.Reaped, workflow.ReapedSandbox{
+			SandboxID: sb.ID,
+			RunID:     runID,
+			RunStatus: string(status),
+			CreatedAt: sb.CreatedAt,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,104 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// listingSandbox lists a fixed set of sandboxes and records kills.
+type listingSandbox struct {
+	noopSandbox
+	live   []sandbox.Info
+	killed []string
+}
+
+func (s *listingSandbox) List(context.Context) ([]sandbox.Info, error) { return s.live, nil }
+func (s *listingSandbox) Kill(_ context.Context, id string) error {
+	s.killed = append(s.killed, id)
+	return nil
+}
+
+const (
+	runDone    = "11111111-1111-1111-1111-111111111111"
+	runActive  = "22222222-2222-2222-2222-222222222222"
+	runDeleted = "33333333-3333-3333-3333-333333333333"
+)
+
+func runReap(t *testing.T, sb *listingSandbox, dryRun bool) *workflow.ReapReport {
+	t.Helper()
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	dbMock.ExpectQuery(`SELECT DISTINCT sandbox_id, run_id FROM step_runs`).
+		WillReturnRows(sqlmock.NewRows([]string{"sandbox_id", "run_id"}).AddRow("sb-old", runDone))
+	dbMock.ExpectQuery(`SELECT id, status FROM runs`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).
+			AddRow(runDone, "complete").
+			AddRow(runActive, "running"))
+
+	a := &Activities{Sandbox: sb, DB: sqlx.NewDb(db, "sqlmock")}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ReapSandboxes)
+	val, err := env.ExecuteActivity(a.ReapSandboxes, workflow.ReapInput{DryRun: dryRun})
+	require.NoError(t, err)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+
+	var report workflow.ReapReport
+	require.NoError(t, val.Get(&report))
+	return &report
+}
+
+func newListingSandbox() *listingSandbox {
+	return &listingSandbox{live: []sandbox.Info{
+		{ID: "sb-done", Labels: map[string]string{sandbox.LabelRunID: runDone}},
+		{ID: "sb-active", Labels: map[string]string{sandbox.LabelRunID: runActive}},
+		// Its run is still going, but the sandbox is past its deadline and stopped.
+		{ID: "sb-exited", Labels: map[string]string{sandbox.LabelRunID: runActive}, Exited: true},
+		{ID: "sb-deleted", Labels: map[string]string{sandbox.LabelRunID: runDeleted}},
+		{ID: "sb-old"}, // no labels; matched through step_runs.sandbox_id
+		{ID: "sb-stray"},
+		{ID: "sb-warm", Labels: map[string]string{sandbox.LabelPool: "ubuntu:22.04"}, CreatedAt: time.Now()},
+		// Outlived the pool's idle age: its worker exited without closing the pool.
+		{ID: "sb-warm-orphan", Labels: map[string]string{sandbox.LabelPool: "ubuntu:22.04"}, CreatedAt: time.Now().Add(-2 * time.Hour)},
+	}}
+}
+
+func TestReapSandboxes_KillsSandboxesOfFinishedRuns(t *testing.T) {
+	sb := newListingSandbox()
+	report := runReap(t, sb, false)
+
+	assert.ElementsMatch(t, []string{"sb-done", "sb-exited", "sb-deleted", "sb-old", "sb-warm-orphan"}, sb.killed)
+	assert.Equal(t, 8, report.Live)
+	require.Len(t, report.Reaped, 5)
+	byID := map[string]workflow.ReapedSandbox{}
+	for _, r := range report.Reaped {
+		byID[r.SandboxID] = r
+	}
+	assert.Equal(t, "complete", byID["sb-done"].RunStatus)
+	assert.Equal(t, "running", byID["sb-exited"].RunStatus)
+	assert.Equal(t, "", byID["sb-deleted"].RunStatus, "deleted run has no status")
+	assert.Equal(t, runDone, byID["sb-old"].RunID)
+	assert.Equal(t, "", byID["sb-warm-orphan"].RunID)
+	assert.Equal(t, []string{"sb-stray"}, report.Unowned, "warm pool sandboxes are neither reaped nor unowned")
+}
+
+func TestReapSandboxes_DryRunKillsNothing(t *testing.T) {
+	sb := newListingSandbox()
+	report := runReap(t, sb, true)
+
+	assert.Empty(t, sb.killed)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Reaped, 5)
+}
@@ -0,0 +1,46 @@
+package activity
+
+import (
+	"context"
+	"slices"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// stageRepoBundle writes a bundle of repo from the worker's repository cache
+// into the sandbox and returns its path and branch. ok is false when the cache
+// is disabled or cannot serve the repo; the sandbox then clones from the remote.
+func (a *Activities) stageRepoBundle(ctx context.Context, sandboxID string, input workflow.StepInput, repo model.RepoRef) (path, branch string, ok bool) {
+	if a.RepoCache == nil {
+		return "", "", false
+	}
+	logger := activity.GetLogger(ctx)
+
+	// Fetch with the team's own token, the one the sandbox would clone with,
+	// so the cache never reads a repository the step itself could not.
+	var token string
+	if a.CredStore != nil && slices.Contains(input.ResolvedOpts.Credentials, "GITHUB_TOKEN") {
+		vals, err := a.CredStore.GetBatch(ctx, input.TeamID, []string{"GITHUB_TOKEN"})
+		if err != nil {
+			logger.Warn("repository cache: resolve GITHUB_TOKEN", "error", err)
+			return "", "", false
+		}
+		token = vals["GITHUB_TOKEN"]
+	}
+
+	data, branch, release, err := a.RepoCache.Bundle(ctx, input.TeamID, repo.URL, token, repo.Branch)
+	if err != nil {
+		logger.Warn("repository cache unavailable, cloning from remote", "repo", repo.URL, "error", err)
+		return "", "", false
+	}
+	defer release()
+	path = "/tmp/fleetlift-" + repoName(repo) + ".bundle"
+	if err := a.Sandbox.WriteBytes(ctx, sandboxID, path, data); err != nil {
+		logger.Warn("repository cache: write bundle to sandbox", "repo", repo.URL, "error", err)
+		return "", "", false
+	}
+	return path, branch, true
+}
@@ -0,0 +1,108 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+type stubRepoCache struct {
+	err      error
+	teamID   string
+	token    string
+	released bool
+}
+
+func (c *stubRepoCache) Bundle(_ context.Context, teamID, _, token, _ string) ([]byte, string, func(), error) {
+	c.teamID, c.token = teamID, token
+	if c.err != nil {
+		return nil, "", nil, c.err
+	}
+	return []byte("bundle"), "main", func() { c.released = true }, nil
+}
+
+// cloneRecordingSandbox records staged files and fails the clone so ExecuteStep stops there.
+type cloneRecordingSandbox struct {
+	noopSandbox
+	written map[string][]byte
+	clone   string
+}
+
+func (s *cloneRecordingSandbox) WriteBytes(_ context.Context, _, path string, data []byte) error {
+	s.written[path] = data
+	return nil
+}
+
+func (s *cloneRecordingSandbox) Exec(_ context.Context, _, cmd, _ string) (string, string, error) {
+	if strings.HasPrefix(cmd, "git clone") {
+		s.clone = cmd
+		return "", "", errors.New("exit status 128")
+	}
+	return "", "", nil
+}
+
+func runCachedClone(t *testing.T, cache *stubRepoCache) *cloneRecordingSandbox {
+	t.Helper()
+	db, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	dbMock.MatchExpectationsInOrder(false)
+	dbMock.ExpectExec(`UPDATE step_runs SET status`).WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(`INSERT INTO step_run_logs`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sb := &cloneRecordingSandbox{written: map[string][]byte{}}
+	a := &Activities{
+		Sandbox:   sb,
+		DB:        sqlx.NewDb(db, "sqlmock"),
+		CredStore: &stubCredStore{val: "team-token"},
+		RepoCache: cache,
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	_, err = env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{
+			TeamID:    "team-1",
+			StepRunID: "sr-1",
+			ResolvedOpts: workflow.ResolvedStepOpts{
+				Repos:       []model.RepoRef{{URL: "https://github.com/org/repo.git"}},
+				Agent:       "claude-code",
+				Credentials: []string{"GITHUB_TOKEN"},
+			},
+		},
+		SandboxID: "sb-test",
+	})
+	require.Error(t, err)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+	return sb
+}
+
+func TestExecuteStep_ClonesFromRepoCache(t *testing.T) {
+	cache := &stubRepoCache{}
+	sb := runCachedClone(t, cache)
+
+	assert.Equal(t, "team-1", cache.teamID)
+	assert.Equal(t, "team-token", cache.token, "cache fetches with the team's own token")
+	assert.Equal(t, []byte("bundle"), sb.written["/tmp/fleetlift-repo.bundle"])
+	assert.True(t, cache.released, "the bundle is released once written")
+	assert.Contains(t, sb.clone, "git clone --quiet --branch 'main' '/tmp/fleetlift-repo.bundle' '/workspace/repo'")
+	assert.Contains(t, sb.clone, "remote set-url origin 'https://github.com/org/repo.git'")
+}
+
+func TestExecuteStep_RepoCacheErrorFallsBackToRemote(t *testing.T) {
+	sb := runCachedClone(t, &stubRepoCache{err: errors.New("fetch failed")})
+
+	assert.Empty(t, sb.written)
+	assert.Contains(t, sb.clone, "git clone --depth 50")
+	assert.Contains(t, sb.clone, "'https://github.com/org/repo.git'")
+}
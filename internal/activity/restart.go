@@ -0,0 +1,61 @@
+package activity
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+// ExecuteStep phases recorded in its heartbeat details.
+const (
+	phaseClone = "clone"
+	phaseAgent = "agent"
+)
+
+// executeCheckpoint is ExecuteStep's heartbeat details. A retry after a worker
+// restart reads it to find the agent run the previous attempt started.
+type executeCheckpoint struct {
+	Phase     string `json:"phase"`
+	SandboxID string `json:"sandbox_id"`
+	SessionID string `json:"session_id,omitempty"` // detached agent run, for retry_on_worker_restart steps
+	LogPath   string `json:"log_path,omitempty"`   // the detached run's output file in the sandbox
+	Seq       int64  `json:"seq,omitempty"`        // log sequence number of the agent's first output line
+}
+
+// previousCheckpoint returns the last checkpoint of an earlier attempt, if any.
+func previousCheckpoint(ctx context.Context) (executeCheckpoint, bool) {
+	var cp executeCheckpoint
+	if !activity.HasHeartbeatDetails(ctx) {
+		return cp, false
+	}
+	if err := activity.GetHeartbeatDetails(ctx, &cp); err != nil {
+		return cp, false // details from another heartbeat, e.g. a failed status update
+	}
+	return cp, true
+}
+
+// agentLogPath is where a step run's detached agent writes its output. It is
+// derived from the step run so a retry finds the run even when the previous
+// attempt's last heartbeat never reached Temporal.
+func agentLogPath(sessionID string) string {
+	return "/tmp/fleetlift-agent-" + sessionID + ".log"
+}
+
+// sandboxFileExists reports whether path exists in the sandbox.
+func (a *Activities) sandboxFileExists(ctx context.Context, sandboxID, path string) bool {
+	out, _, err := a.Sandbox.Exec(ctx, sandboxID, "test -f "+shellquote.Quote(path)+" && echo yes", "/")
+	return err == nil && out != ""
+}
+
+// releaseAgentLog removes a detached run's output once this attempt has handled
+// it, so a later retry re-runs the agent instead of replaying a finished run.
+// The log is kept when ctx is done: the worker is going away and the retry
+// should pick the run up.
+func (a *Activities) releaseAgentLog(ctx context.Context, sandboxID, path string) {
+	if ctx.Err() != nil {
+		return
+	}
+	_, _, _ = a.Sandbox.Exec(ctx, sandboxID, "rm -f "+shellquote.Quote(path)+" "+shellquote.Quote(path+".exit"), "/")
+}
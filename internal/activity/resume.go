@@ -0,0 +1,32 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// CanResumeSession reports whether the agent session can be resumed in the
+// sandbox. It is false for agents whose runner cannot resume sessions.
+func (a *Activities) CanResumeSession(ctx context.Context, input workflow.ResumeSessionInput) (bool, error) {
+	runner, ok := a.AgentRunners[input.Agent]
+	if !ok {
+		return false, fmt.Errorf("unknown agent: %s", input.Agent)
+	}
+	resumer, ok := runner.(agent.Resumer)
+	if !ok || input.SessionID == "" {
+		return false, nil
+	}
+	return resumer.CanResume(ctx, input.SandboxID, input.SessionID), nil
+}
+
+// sessionID returns the session a finished agent run can be resumed from, or
+// "" when the runner cannot resume sessions.
+func sessionID(resumer agent.Resumer, output map[string]any) string {
+	if resumer == nil || output == nil {
+		return ""
+	}
+	return resumer.SessionID(output)
+}
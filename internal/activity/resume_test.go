@@ -0,0 +1,93 @@
+package activity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/agent"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// resumingRunner records its RunOpts and reports every run as session "sess-2".
+type resumingRunner struct {
+	live map[string]bool // sessions still in the sandbox
+	opts agent.RunOpts
+}
+
+func (r *resumingRunner) Name() string { return "resuming" }
+
+func (r *resumingRunner) Run(_ context.Context, _ string, opts agent.RunOpts) (<-chan agent.Event, error) {
+	r.opts = opts
+	ch := make(chan agent.Event, 1)
+	ch <- agent.Event{Type: "complete", Content: "done", Output: map[string]any{"result": "done", "session_id": "sess-2"}}
+	close(ch)
+	return ch, nil
+}
+
+func (r *resumingRunner) Interrupt(context.Context, string) error { return nil }
+
+func (r *resumingRunner) SessionID(output map[string]any) string {
+	id, _ := output["session_id"].(string)
+	return id
+}
+
+func (r *resumingRunner) CanResume(_ context.Context, _, sessionID string) bool {
+	return r.live[sessionID]
+}
+
+func runResumeStep(t *testing.T, runner *resumingRunner) *model.StepOutput {
+	t.Helper()
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	a := &Activities{
+		Sandbox:      &noopSandbox{},
+		DB:           sqlx.NewDb(db, "sqlmock"),
+		AgentRunners: map[string]agent.Runner{"resuming": runner},
+	}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ExecuteStep)
+	val, err := env.ExecuteActivity(a.ExecuteStep, workflow.ExecuteStepInput{
+		StepInput: workflow.StepInput{
+			StepRunID:    "sr-1",
+			StepDef:      model.StepDef{ID: "fix"},
+			ResolvedOpts: workflow.ResolvedStepOpts{Agent: "resuming"},
+		},
+		SandboxID:           "sb-1",
+		Prompt:              "Fix the bug",
+		ConversationHistory: "Previous attempt output:\n...",
+		ResumeSession:       "sess-1",
+		ResumePrompt:        "Also update the docs",
+	})
+	require.NoError(t, err)
+	var out model.StepOutput
+	require.NoError(t, val.Get(&out))
+	return &out
+}
+
+func TestExecuteStep_ResumesAgentSession(t *testing.T) {
+	runner := &resumingRunner{live: map[string]bool{"sess-1": true}}
+	out := runResumeStep(t, runner)
+
+	assert.Equal(t, "sess-1", runner.opts.ResumeSession)
+	assert.Equal(t, "Also update the docs", runner.opts.Prompt, "the steering text is the next turn, without the stuffed history")
+	assert.Equal(t, "sess-2", out.SessionID)
+}
+
+func TestExecuteStep_FallsBackWhenSessionIsGone(t *testing.T) {
+	runner := &resumingRunner{live: map[string]bool{}}
+	out := runResumeStep(t, runner)
+
+	assert.Empty(t, runner.opts.ResumeSession)
+	assert.Equal(t, "Previous attempt output:\n...\n\nFix the bug", runner.opts.Prompt)
+	assert.Equal(t, model.StepStatusComplete, out.Status)
+}
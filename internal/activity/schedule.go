@@ -0,0 +1,72 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/tinkerloft/fleetlift/internal/launch"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// StartScheduledRun records a run for a firing schedule using the schedule's current
+// workflow, parameters and model. The run is triggered by the schedule's owner.
+// Returns nil when the schedule was disabled or deleted after Temporal fired it.
+//
+// The run ID is the Temporal run ID of the calling ScheduledRunWorkflow, so retries
+// of this activity record the same run instead of creating duplicates.
+func (a *Activities) StartScheduledRun(ctx context.Context, input workflow.ScheduledRunInput) (*workflow.ScheduledRun, error) {
+	var sched model.Schedule
+	err := a.DB.GetContext(ctx, &sched, `SELECT * FROM schedules WHERE id = $1`, input.ScheduleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load schedule %s: %w", input.ScheduleID, err)
+	}
+	if !sched.Enabled {
+		return nil, nil
+	}
+	if a.Templates == nil {
+		return nil, temporal.NewNonRetryableApplicationError("worker has no template registry", "ConfigError", nil)
+	}
+	if sched.CreatedBy == nil {
+		// Runs are attributed to the schedule's owner; without one the run cannot be recorded.
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("schedule %s has no owner; recreate it to resume runs", sched.ID), "InvalidSchedule", nil)
+	}
+
+	req := launch.Request{
+		RunID:       activity.GetInfo(ctx).WorkflowExecution.RunID,
+		TeamID:      sched.TeamID,
+		WorkflowID:  sched.WorkflowID,
+		Parameters:  sched.Parameters,
+		ScheduleID:  sched.ID,
+		TriggeredBy: *sched.CreatedBy,
+	}
+	if sched.Model != nil {
+		req.Model = *sched.Model
+	}
+
+	run, err := launch.Prepare(ctx, a.Templates, req)
+	if err != nil {
+		var verr *launch.ValidationError
+		if errors.Is(err, launch.ErrWorkflowNotFound) || errors.Is(err, launch.ErrInvalidDefinition) || errors.As(err, &verr) {
+			// The template changed since the schedule was saved; retrying will not help.
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("schedule %s: %v", sched.ID, err), "InvalidSchedule", err)
+		}
+		return nil, err
+	}
+	run.Input.DefaultMaxParallel = launch.DefaultMaxParallel(ctx, a.DB, sched.TeamID)
+	if err := launch.Insert(ctx, a.DB, run); err != nil {
+		return nil, fmt.Errorf("record scheduled run: %w", err)
+	}
+
+	return &workflow.ScheduledRun{RunID: run.ID, TemporalID: run.TemporalID, Input: run.Input}, nil
+}
@@ -0,0 +1,115 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// fixedTemplateProvider serves a single template regardless of team.
+type fixedTemplateProvider struct{ tmpl *model.WorkflowTemplate }
+
+func (p *fixedTemplateProvider) Name() string   { return "fixed" }
+func (p *fixedTemplateProvider) Writable() bool { return false }
+func (p *fixedTemplateProvider) List(context.Context, string) ([]*model.WorkflowTemplate, error) {
+	return []*model.WorkflowTemplate{p.tmpl}, nil
+}
+func (p *fixedTemplateProvider) Get(_ context.Context, _, slug string) (*model.WorkflowTemplate, error) {
+	if slug == p.tmpl.Slug {
+		return p.tmpl, nil
+	}
+	return nil, template.ErrNotFound
+}
+func (p *fixedTemplateProvider) Save(context.Context, string, *model.WorkflowTemplate) error {
+	return nil
+}
+func (p *fixedTemplateProvider) Delete(context.Context, string, string) error { return nil }
+
+var scheduleCols = []string{"id", "team_id", "name", "workflow_id", "cron", "timezone", "parameters", "model", "enabled", "created_by", "created_at", "updated_at"}
+
+func TestStartScheduledRun_RecordsRunFromSchedule(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	a := &Activities{
+		DB: sqlx.NewDb(db, "sqlmock"),
+		Templates: template.NewRegistry(&fixedTemplateProvider{tmpl: &model.WorkflowTemplate{
+			ID:    "wf-1",
+			Slug:  "nightly-audit",
+			Title: "Nightly Audit",
+			YAMLBody: `
+version: 1
+id: nightly-audit
+parameters:
+  - name: repo
+    type: string
+    required: true
+steps:
+  - id: audit
+    execution:
+      agent: claude-code
+      prompt: audit {{ .Params.repo }}
+`,
+		}}),
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT \* FROM schedules WHERE id = \$1`).
+		WithArgs("sched-1").
+		WillReturnRows(sqlmock.NewRows(scheduleCols).AddRow(
+			"sched-1", "team-1", "nightly", "nightly-audit", "0 2 * * *", "UTC",
+			[]byte(`{"repo":"acme/api"}`), "claude-sonnet-4-6", true, "user-1", now, now))
+	mock.ExpectQuery(`SELECT max_parallel FROM teams`).
+		WillReturnRows(sqlmock.NewRows([]string{"max_parallel"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO runs`).
+		WithArgs(sqlmock.AnyArg(), "team-1", "nightly-audit", "Nightly Audit", sqlmock.AnyArg(),
+			"claude-sonnet-4-6", "pending", sqlmock.AnyArg(), "user-1", "sched-1", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.StartScheduledRun)
+	val, err := env.ExecuteActivity(a.StartScheduledRun, workflow.ScheduledRunInput{ScheduleID: "sched-1"})
+	require.NoError(t, err)
+
+	var run *workflow.ScheduledRun
+	require.NoError(t, val.Get(&run))
+	require.NotNil(t, run)
+	assert.Equal(t, run.RunID, run.Input.RunID)
+	assert.Equal(t, "acme/api", run.Input.Parameters["repo"])
+	assert.Equal(t, "claude-sonnet-4-6", run.Input.ModelOverride)
+	assert.Equal(t, "user-1", run.Input.TriggeredBy)
+	assert.Equal(t, 3, run.Input.DefaultMaxParallel)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStartScheduledRun_DisabledScheduleIsSkipped(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	a := &Activities{DB: sqlx.NewDb(db, "sqlmock")}
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT \* FROM schedules WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(scheduleCols).AddRow(
+			"sched-1", "team-1", "nightly", "nightly-audit", "0 2 * * *", "UTC",
+			[]byte(`{}`), nil, false, nil, now, now))
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.StartScheduledRun)
+	val, err := env.ExecuteActivity(a.StartScheduledRun, workflow.ScheduledRunInput{ScheduleID: "sched-1"})
+	require.NoError(t, err)
+	assert.False(t, val.HasValue(), "a skipped schedule returns no run")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -198,6 +198,20 @@ func (a *Activities) updateStepStatus(ctx context.Context, stepRunID string, sta
 	}
 }
 
+// recordStepBranch sets the working branch of a step run. CreatePullRequest
+// pushes the same branch and records it again alongside the PR URL.
+func (a *Activities) recordStepBranch(ctx context.Context, stepRunID, branch string) {
+	if a.DB == nil || stepRunID == "" {
+		return
+	}
+	if _, err := a.DB.ExecContext(ctx,
+		`UPDATE step_runs SET branch_name = $1 WHERE id = $2`, branch, stepRunID,
+	); err != nil {
+		activity.GetLogger(ctx).Warn("failed to record branch on step run",
+			"step_run_id", stepRunID, "branch", branch, "error", err)
+	}
+}
+
 // logLine holds a single buffered log entry.
 type logLine struct {
 	Seq     int64
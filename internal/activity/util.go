@@ -1,7 +1,31 @@
 // Package activity contains Temporal activity implementations.
 package activity
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// ErrTypeDiskFull is the application error type of steps that ran out of sandbox disk.
+const ErrTypeDiskFull = "DiskFull"
+
+var diskFullRe = regexp.MustCompile(`(?i)no space left on device|disk quota exceeded|\bENOSPC\b`)
+
+// isDiskFull reports whether command output shows the sandbox disk filled up.
+func isDiskFull(output string) bool {
+	return diskFullRe.MatchString(output)
+}
+
+// diskFullError reports a disk-full failure during phase. It is non-retryable:
+// a retry in a sandbox of the same size fails the same way.
+func diskFullError(phase string) error {
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("sandbox ran out of disk space during %s; increase sandbox.workspace_size", phase),
+		ErrTypeDiskFull, nil)
+}
 
 // gitFailed reports whether stderr from a git command indicates a fatal error.
 // ExecStream does not propagate exit codes, so we check stderr instead.
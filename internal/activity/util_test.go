@@ -1 +1,20 @@
 package activity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDiskFull(t *testing.T) {
+	for _, out := range []string{
+		"fatal: write error: No space left on device",
+		"error: unable to write file: Disk quota exceeded",
+		"npm ERR! code ENOSPC",
+	} {
+		assert.True(t, isDiskFull(out), out)
+	}
+	for _, out := range []string{"", "fatal: repository not found", "ENOSPCX"} {
+		assert.False(t, isDiskFull(out), out)
+	}
+}
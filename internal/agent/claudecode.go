@@ -46,8 +46,9 @@ func (r *ClaudeCodeRunner) Run(ctx context.Context, sandboxID string, opts RunOp
 			Enabled:    true,
 			ConfigPath: "/workspace/.mcp.json",
 		},
-		PluginDirs: opts.EvalPluginDirs,
-		Env:        opts.Environment,
+		PluginDirs:    opts.EvalPluginDirs,
+		Env:           opts.Environment,
+		ResumeSession: opts.ResumeSession,
 	}
 	reqBytes, err := json.Marshal(req)
 	if err != nil {
@@ -64,7 +65,7 @@ func (r *ClaudeCodeRunner) Run(ctx context.Context, sandboxID string, opts RunOp
 		`printf '{"mcpServers":{"fleetlift":{"type":"sse","url":"http://localhost:%s/sse"}}}' "$FLEETLIFT_MCP_PORT" > /workspace/.mcp.json; ` +
 		`fi`
 
-	cmd := fmt.Sprintf("%s && node /agent/bridge.js %s", mcpSetup, shellquote.Quote(requestPath))
+	cmd := detached(fmt.Sprintf("%s && node /agent/bridge.js %s", mcpSetup, shellquote.Quote(requestPath)), opts)
 
 	ch := make(chan Event, 64)
 	go func() {
@@ -142,6 +143,8 @@ type bridgeRequest struct {
 	MCP        bridgeMCPRequest  `json:"mcp,omitempty"`
 	PluginDirs []string          `json:"plugin_dirs,omitempty"`
 	Env        map[string]string `json:"env,omitempty"`
+	// ResumeSession is passed to claude --resume.
+	ResumeSession string `json:"resume_session,omitempty"`
 }
 
 type bridgeMCPRequest struct {
@@ -158,6 +161,26 @@ func effectiveMaxTurns(configured int) int {
 	return configured
 }
 
+// SessionID returns the Claude session ID reported in the bridge's complete event.
+func (r *ClaudeCodeRunner) SessionID(output map[string]any) string {
+	id, _ := output["session_id"].(string)
+	if _, err := uuid.Parse(id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// CanResume reports whether the sandbox still holds the session's transcript,
+// which claude keeps under ~/.claude/projects.
+func (r *ClaudeCodeRunner) CanResume(ctx context.Context, sandboxID, sessionID string) bool {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return false
+	}
+	out, _, err := r.sandbox.Exec(ctx, sandboxID,
+		`ls "$HOME"/.claude/projects/*/`+shellquote.Quote(sessionID+".jsonl")+` 2>/dev/null`, "/")
+	return err == nil && strings.TrimSpace(out) != ""
+}
+
 func (r *ClaudeCodeRunner) Interrupt(ctx context.Context, sandboxID string) error {
 	_, _, err := r.sandbox.Exec(ctx, sandboxID, "pkill -INT -f 'claude'", "/")
 	return err
@@ -223,6 +246,8 @@ func parseClaudeEvent(line string) Event {
 		return Event{Type: "stdout", Content: content}
 	case "complete":
 		return Event{Type: "complete", Output: raw}
+	case "usage":
+		return parseClaudeUsage(raw)
 	case "error":
 		content, _ := raw["content"].(string)
 		if content == "" {
@@ -239,6 +264,37 @@ func parseClaudeEvent(line string) Event {
 	}
 }
 
+// parseClaudeUsage converts the bridge's per-message usage event. Claude bills
+// cache writes at a premium, approximated here at the uncached input rate.
+func parseClaudeUsage(raw map[string]any) Event {
+	tokens := func(key string) int64 {
+		v, _ := raw[key].(float64)
+		return int64(v)
+	}
+	cached := tokens("cache_read_input_tokens")
+	u := &Usage{
+		InputTokens:       tokens("input_tokens") + tokens("cache_creation_input_tokens") + cached,
+		CachedInputTokens: cached,
+		OutputTokens:      tokens("output_tokens"),
+	}
+	model, _ := raw["model"].(string)
+	if price, ok := lookupPrice(claudePrices, model); ok {
+		u.CostUSD = price.cost(u.InputTokens, u.CachedInputTokens, u.OutputTokens)
+	}
+	return Event{Type: "usage", Usage: u}
+}
+
+// claudePrices lists published Anthropic API prices. They only estimate the
+// running cost for budgets; the step's cost comes from the result event.
+var claudePrices = map[string]tokenPrice{
+	"claude-opus-4":     {15, 1.5, 75},
+	"claude-opus-4-5":   {5, 0.5, 25},
+	"claude-sonnet-4":   {3, 0.3, 15},
+	"claude-3-7-sonnet": {3, 0.3, 15},
+	"claude-haiku-4-5":  {1, 0.1, 5},
+	"claude-3-5-haiku":  {0.8, 0.08, 4},
+}
+
 func parseNormalizedToolCall(raw map[string]any) Event {
 	name, _ := raw["name"].(string)
 	desc, _ := raw["description"].(string)
@@ -18,6 +18,7 @@ type runnerSandbox struct {
 	err         error
 	execCmd     string
 	execWorkDir string
+	execOut     string // returned by Exec
 	writes      map[string]string
 }
 
@@ -31,7 +32,7 @@ func (s *runnerSandbox) ExecStream(_ context.Context, _, cmd, workDir string, on
 }
 
 func (s *runnerSandbox) Exec(_ context.Context, _, _, _ string) (string, string, error) {
-	return "", "", nil
+	return s.execOut, "", nil
 }
 
 func (s *runnerSandbox) WriteFile(_ context.Context, _, path, content string) error {
@@ -48,6 +49,7 @@ func (s *runnerSandbox) ReadFile(context.Context, string, string) (string, error
 func (s *runnerSandbox) ReadBytes(context.Context, string, string) ([]byte, error)  { return nil, nil }
 func (s *runnerSandbox) Kill(context.Context, string) error                         { return nil }
 func (s *runnerSandbox) RenewExpiration(context.Context, string) error              { return nil }
+func (s *runnerSandbox) List(context.Context) ([]sandbox.Info, error)               { return nil, nil }
 
 func wrapped(stream string, event map[string]any) string {
 	inner, _ := json.Marshal(event)
@@ -108,6 +110,35 @@ func TestClaudeCodeRunner_RunUsesBridgeRequestFile(t *testing.T) {
 	assert.Equal(t, "/workspace/.mcp.json", mcp["config_path"])
 }
 
+func TestClaudeCodeRunner_ResumesSession(t *testing.T) {
+	const session = "5f0c6a36-3f5e-4d7a-9b8e-2a1c7d9e0f12"
+	sb := &runnerSandbox{
+		lines: []string{wrapped("stdout", map[string]any{"type": "complete", "result": "ok", "session_id": session})},
+	}
+	r := NewClaudeCodeRunner(sb)
+	var _ Resumer = r
+
+	ch, err := r.Run(context.Background(), "sb-1", RunOpts{Prompt: "also fix the tests", ResumeSession: session})
+	require.NoError(t, err)
+	events := collectEvents(ch, 2*time.Second)
+	require.NotEmpty(t, events)
+	assert.Equal(t, session, r.SessionID(events[len(events)-1].Output))
+
+	for path, content := range sb.writes {
+		if strings.HasPrefix(path, "/tmp/fleetlift-request-") {
+			var req map[string]any
+			require.NoError(t, json.Unmarshal([]byte(content), &req))
+			assert.Equal(t, session, req["resume_session"])
+		}
+	}
+
+	assert.False(t, r.CanResume(context.Background(), "sb-1", session), "no transcript in the sandbox")
+	sb.execOut = "/root/.claude/projects/-workspace-repo/" + session + ".jsonl\n"
+	assert.True(t, r.CanResume(context.Background(), "sb-1", session))
+	assert.False(t, r.CanResume(context.Background(), "sb-1", "$(reboot)"), "session IDs are UUIDs")
+	assert.Empty(t, r.SessionID(map[string]any{"session_id": "not-a-uuid"}))
+}
+
 func TestParseClaudeEvent_AssistantText(t *testing.T) {
 	ev := parseClaudeEvent(`{"type":"assistant_text","content":"Working on it..."}`)
 	assert.Equal(t, "stdout", ev.Type)
@@ -151,6 +182,16 @@ func TestParseClaudeEvent_Complete(t *testing.T) {
 	assert.Equal(t, false, ev.Output["is_error"])
 }
 
+func TestParseClaudeEvent_Usage(t *testing.T) {
+	ev := parseClaudeEvent(`{"type":"usage","model":"claude-sonnet-4-5-20250929","input_tokens":1000,"cache_creation_input_tokens":0,"cache_read_input_tokens":1000000,"output_tokens":10000}`)
+	assert.Equal(t, "usage", ev.Type)
+	require.NotNil(t, ev.Usage)
+	assert.Equal(t, int64(1_001_000), ev.Usage.InputTokens)
+	assert.Equal(t, int64(1_000_000), ev.Usage.CachedInputTokens)
+	// 1k uncached * 3 + 1M cached * 0.30 + 10k output * 15, per million.
+	assert.InDelta(t, 0.453, ev.Usage.CostUSD, 1e-9)
+}
+
 func TestParseClaudeEvent_Error(t *testing.T) {
 	ev := parseClaudeEvent(`{"type":"error","content":"API rate limit"}`)
 	assert.Equal(t, "error", ev.Type)
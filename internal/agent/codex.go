@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+// CodexRunner implements Runner by invoking the OpenAI Codex CLI (codex exec --json)
+// inside a sandbox.
+type CodexRunner struct {
+	sandbox sandbox.Client
+}
+
+// NewCodexRunner creates a new CodexRunner backed by the given sandbox client.
+func NewCodexRunner(sb sandbox.Client) *CodexRunner {
+	return &CodexRunner{sandbox: sb}
+}
+
+func (r *CodexRunner) Name() string { return "codex" }
+
+func (r *CodexRunner) Run(ctx context.Context, sandboxID string, opts RunOpts) (<-chan Event, error) {
+	promptPath := fmt.Sprintf("/tmp/fleetlift-prompt-%s.txt", uuid.NewString())
+	if err := r.sandbox.WriteFile(ctx, sandboxID, promptPath, opts.Prompt); err != nil {
+		return nil, fmt.Errorf("write prompt file: %w", err)
+	}
+
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = "/workspace"
+	}
+	cmd := detached(codexCommand(promptPath, workDir, opts), opts)
+	stream := newCodexStream(opts.Model, effectiveMaxTurns(opts.MaxTurns))
+	return streamTurnLimited(ctx, r.sandbox, sandboxID, cmd, workDir, stream, r.Interrupt), nil
+}
+
+// codexCommand builds the shell command that runs codex exec non-interactively.
+// The prompt is read from a file on stdin so it never appears in the process list.
+func codexCommand(promptPath, workDir string, opts RunOpts) string {
+	var b strings.Builder
+	b.WriteString(envExports(opts.Environment))
+	b.WriteString("codex exec --json --skip-git-repo-check --dangerously-bypass-approvals-and-sandbox")
+	b.WriteString(" --cd " + shellquote.Quote(workDir))
+	if opts.Model != "" {
+		b.WriteString(" --model " + shellquote.Quote(opts.Model))
+	}
+	b.WriteString(" - < " + shellquote.Quote(promptPath))
+	return b.String()
+}
+
+func (r *CodexRunner) Interrupt(ctx context.Context, sandboxID string) error {
+	_, _, err := r.sandbox.Exec(ctx, sandboxID, "pkill -INT -f 'codex exec'", "/")
+	return err
+}
+
+// codexUsage is the token usage reported on turn.completed.
+type codexUsage struct {
+	InputTokens       int64 `json:"input_tokens"`
+	CachedInputTokens int64 `json:"cached_input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+}
+
+type codexItem struct {
+	ID               string `json:"id"`
+	Type             string `json:"type"`
+	Text             string `json:"text"`
+	Command          string `json:"command"`
+	AggregatedOutput string `json:"aggregated_output"`
+	ExitCode         *int   `json:"exit_code"`
+	Server           string `json:"server"`
+	Tool             string `json:"tool"`
+	Query            string `json:"query"`
+	Message          string `json:"message"`
+	Changes          []struct {
+		Path string `json:"path"`
+		Kind string `json:"kind"`
+	} `json:"changes"`
+}
+
+type codexEvent struct {
+	Type     string     `json:"type"`
+	ThreadID string     `json:"thread_id"`
+	Item     *codexItem `json:"item"`
+	Usage    codexUsage `json:"usage"`
+	Message  string     `json:"message"`
+	Error    *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// codexStream turns codex exec --json output into Events and accumulates what is
+// needed for the final "complete" event: the last agent message, token usage, and
+// the number of tool calls made.
+type codexStream struct {
+	mu          sync.Mutex
+	model       string
+	maxTurns    int
+	threadID    string
+	lastMessage string
+	usage       codexUsage
+	turns       int // tool calls started; codex has no native max_turns
+	completed   bool
+	failed      string
+}
+
+func newCodexStream(model string, maxTurns int) *codexStream {
+	return &codexStream{model: model, maxTurns: maxTurns}
+}
+
+// handle parses one ExecStream line. Lines arrive in the normalized format
+// {"stream":"stdout","content":"..."} where content may hold several JSONL events.
+func (s *codexStream) handle(line string) []Event {
+	stream, parts := splitStreamLine(line)
+	var events []Event
+	for _, part := range parts {
+		if event := s.handleEvent(stream, part); event.Type != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (s *codexStream) handleEvent(stream, raw string) Event {
+	var ev codexEvent
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil || ev.Type == "" {
+		// Not a codex event — plain output, e.g. CLI diagnostics on stderr.
+		if stream == "stderr" {
+			return Event{Type: "stderr", Content: raw}
+		}
+		return Event{Type: "stdout", Content: raw}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch ev.Type {
+	case "thread.started":
+		s.threadID = ev.ThreadID
+	case "turn.completed":
+		s.usage.InputTokens += ev.Usage.InputTokens
+		s.usage.CachedInputTokens += ev.Usage.CachedInputTokens
+		s.usage.OutputTokens += ev.Usage.OutputTokens
+		s.completed = true
+		// codex exec runs a single turn, so its usage arrives with the result.
+		cost, priced := codexCostUSD(s.model, ev.Usage)
+		return Event{Type: "usage", Final: true, Usage: &Usage{
+			InputTokens:       ev.Usage.InputTokens,
+			CachedInputTokens: ev.Usage.CachedInputTokens,
+			OutputTokens:      ev.Usage.OutputTokens,
+			CostUSD:           cost,
+			Unpriced:          !priced,
+		}}
+	case "turn.failed":
+		s.failed = "codex turn failed"
+		if ev.Error != nil && ev.Error.Message != "" {
+			s.failed = ev.Error.Message
+		}
+		return Event{Type: "error", Content: s.failed}
+	case "error":
+		// Transient errors (e.g. reconnects) are reported here; fatal ones are
+		// followed by turn.failed.
+		if ev.Message != "" {
+			return Event{Type: "stderr", Content: ev.Message}
+		}
+	case "item.started":
+		if ev.Item != nil && isCodexToolItem(ev.Item.Type) {
+			s.turns++
+			return codexToolEvent(ev.Item)
+		}
+	case "item.completed":
+		if ev.Item != nil {
+			return s.completedItem(ev.Item)
+		}
+	}
+	return Event{}
+}
+
+func (s *codexStream) completedItem(item *codexItem) Event {
+	switch item.Type {
+	case "agent_message":
+		if item.Text == "" {
+			return Event{}
+		}
+		s.lastMessage = item.Text
+		return Event{Type: "stdout", Content: item.Text}
+	case "command_execution":
+		if item.ExitCode != nil && *item.ExitCode != 0 && item.AggregatedOutput != "" {
+			out := item.AggregatedOutput
+			if len(out) > 200 {
+				out = out[:200] + "…"
+			}
+			return Event{Type: "stderr", Content: out}
+		}
+	case "file_change":
+		// Patches are applied without a separate item.started event.
+		s.turns++
+		paths := make([]string, 0, len(item.Changes))
+		for _, c := range item.Changes {
+			paths = append(paths, c.Path)
+		}
+		if len(paths) == 0 {
+			return Event{Type: "stdout", Content: "[tool] edit"}
+		}
+		return Event{Type: "stdout", Content: "[tool] edit: " + strings.Join(paths, ", ")}
+	case "error":
+		if item.Message != "" {
+			return Event{Type: "stderr", Content: item.Message}
+		}
+	}
+	// Reasoning, todo lists, and successful tool results are noise in logs.
+	return Event{}
+}
+
+func isCodexToolItem(typ string) bool {
+	switch typ {
+	case "command_execution", "mcp_tool_call", "web_search":
+		return true
+	}
+	return false
+}
+
+func codexToolEvent(item *codexItem) Event {
+	switch item.Type {
+	case "command_execution":
+		cmd := item.Command
+		if len(cmd) > 120 {
+			cmd = cmd[:120] + "…"
+		}
+		return Event{Type: "stdout", Content: "[tool] shell: " + cmd}
+	case "mcp_tool_call":
+		return Event{Type: "stdout", Content: fmt.Sprintf("[tool] %s.%s", item.Server, item.Tool)}
+	case "web_search":
+		return Event{Type: "stdout", Content: "[tool] web_search: " + item.Query}
+	}
+	return Event{}
+}
+
+func (s *codexStream) exceededMaxTurns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxTurns > 0 && s.turns > s.maxTurns
+}
+
+// result returns the "complete" event once codex has finished its turn. The
+// output mirrors the Claude Code result event so downstream extraction of
+// result, is_error, and total_cost_usd works for either agent.
+func (s *codexStream) result() (Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.completed || s.failed != "" {
+		return Event{}, false
+	}
+	return Event{Type: "complete", Output: s.output(s.lastMessage, false)}, true
+}
+
+// maxTurnsResult reports a run stopped for exceeding max_turns as an agent error,
+// matching Claude Code's error_max_turns result.
+func (s *codexStream) maxTurnsResult() Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.output(fmt.Sprintf("codex exceeded max_turns (%d)", s.maxTurns), true)
+	out["subtype"] = "error_max_turns"
+	return Event{Type: "complete", Output: out}
+}
+
+func (s *codexStream) output(result string, isError bool) map[string]any {
+	out := map[string]any{
+		"type":       "result",
+		"result":     result,
+		"is_error":   isError,
+		"session_id": s.threadID,
+		"num_turns":  s.turns,
+		"usage": map[string]any{
+			"input_tokens":        s.usage.InputTokens,
+			"cached_input_tokens": s.usage.CachedInputTokens,
+			"output_tokens":       s.usage.OutputTokens,
+		},
+	}
+	if cost, ok := codexCostUSD(s.model, s.usage); ok {
+		out["total_cost_usd"] = cost
+	}
+	return out
+}
+
+// codexPrices lists published API prices for models commonly used with Codex.
+var codexPrices = map[string]tokenPrice{
+	"gpt-5-codex": {1.25, 0.125, 10},
+	"gpt-5-mini":  {0.25, 0.025, 2},
+	"gpt-5-nano":  {0.05, 0.005, 0.4},
+	"gpt-5":       {1.25, 0.125, 10},
+	"gpt-4.1":     {2, 0.5, 8},
+	"o4-mini":     {1.1, 0.275, 4.4},
+	"o3":          {2, 0.5, 8},
+}
+
+// defaultCodexModel is the model codex uses when none is configured.
+const defaultCodexModel = "gpt-5-codex"
+
+// codexCostUSD estimates the run cost from token usage, since codex does not
+// report a dollar amount. It reports false for a model with no known price
+// rather than guessing.
+func codexCostUSD(model string, u codexUsage) (float64, bool) {
+	if model == "" {
+		model = defaultCodexModel
+	}
+	price, ok := lookupPrice(codexPrices, model)
+	if !ok {
+		return 0, false
+	}
+	return price.cost(u.InputTokens, u.CachedInputTokens, u.OutputTokens), true
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func codexLines(events ...string) []string {
+	lines := make([]string, 0, len(events))
+	for _, e := range events {
+		lines = append(lines, wrappedContent("stdout", e))
+	}
+	return lines
+}
+
+func TestCodexRunner_RunProducesCompleteEvent(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"thread.started","thread_id":"th-1"}`,
+		`{"type":"turn.started"}`,
+		`{"type":"item.completed","item":{"id":"item_0","type":"reasoning","text":"thinking"}}`,
+		`{"type":"item.started","item":{"id":"item_1","type":"command_execution","command":"bash -lc ls","status":"in_progress"}}`,
+		`{"type":"item.completed","item":{"id":"item_1","type":"command_execution","command":"bash -lc ls","aggregated_output":"main.go\n","exit_code":0,"status":"completed"}}`,
+		`{"type":"item.completed","item":{"id":"item_2","type":"file_change","changes":[{"path":"main.go","kind":"update"}],"status":"completed"}}`,
+		"{\"type\":\"item.completed\",\"item\":{\"id\":\"item_3\",\"type\":\"agent_message\",\"text\":\"Done.\\n```json\\n{\\\"ok\\\": true}\\n```\"}}",
+		`{"type":"turn.completed","usage":{"input_tokens":1000000,"cached_input_tokens":200000,"output_tokens":100000}}`,
+	)}
+	r := NewCodexRunner(sb)
+
+	ch, err := r.Run(context.Background(), "sb-1", RunOpts{
+		Prompt:      "fix the bug",
+		WorkDir:     "/workspace/repo",
+		Model:       "gpt-5-codex",
+		Environment: map[string]string{"FOO": "bar baz", "bad key": "x"},
+	})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.Len(t, got, 5)
+	assert.Equal(t, Event{Type: "stdout", Content: "[tool] shell: bash -lc ls"}, got[0])
+	assert.Equal(t, Event{Type: "stdout", Content: "[tool] edit: main.go"}, got[1])
+	assert.Equal(t, "stdout", got[2].Type)
+	assert.Contains(t, got[2].Content, "Done.")
+
+	assert.Equal(t, "usage", got[3].Type)
+	assert.Equal(t, int64(1_100_000), got[3].Usage.Tokens())
+
+	complete := got[4]
+	assert.Equal(t, "complete", complete.Type)
+	assert.Contains(t, complete.Output["result"], `{"ok": true}`)
+	assert.Equal(t, false, complete.Output["is_error"])
+	assert.Equal(t, "th-1", complete.Output["session_id"])
+	assert.Equal(t, 2, complete.Output["num_turns"])
+	// 800k uncached * 1.25 + 200k cached * 0.125 + 100k output * 10, per million.
+	assert.InDelta(t, 2.025, complete.Output["total_cost_usd"], 1e-9)
+
+	assert.Contains(t, sb.execCmd, "codex exec --json")
+	assert.Contains(t, sb.execCmd, "--model 'gpt-5-codex'")
+	assert.Contains(t, sb.execCmd, "--cd '/workspace/repo'")
+	assert.Contains(t, sb.execCmd, "export FOO='bar baz';")
+	assert.NotContains(t, sb.execCmd, "bad key")
+	assert.NotContains(t, sb.execCmd, "fix the bug", "prompt must be passed via file")
+	assert.Equal(t, "/workspace/repo", sb.execWorkDir)
+
+	var promptPath string
+	for path := range sb.writes {
+		if strings.HasPrefix(path, "/tmp/fleetlift-prompt-") {
+			promptPath = path
+		}
+	}
+	require.NotEmpty(t, promptPath)
+	assert.Equal(t, "fix the bug", sb.writes[promptPath])
+	assert.Contains(t, sb.execCmd, "- < '"+promptPath+"'")
+}
+
+func TestCodexRunner_TurnFailedEmitsErrorWithoutComplete(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"thread.started","thread_id":"th-1"}`,
+		`{"type":"error","message":"stream disconnected; retrying"}`,
+		`{"type":"turn.failed","error":{"message":"quota exceeded"}}`,
+	)}
+	ch, err := NewCodexRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x"})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, Event{Type: "stderr", Content: "stream disconnected; retrying"}, got[0])
+	assert.Equal(t, Event{Type: "error", Content: "quota exceeded"}, got[1])
+}
+
+func TestCodexRunner_MaxTurnsStopsRun(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"item.started","item":{"type":"command_execution","command":"ls"}}`,
+		`{"type":"item.started","item":{"type":"command_execution","command":"pwd"}}`,
+		`{"type":"item.started","item":{"type":"command_execution","command":"whoami"}}`,
+		`{"type":"turn.completed","usage":{"input_tokens":10,"output_tokens":5}}`,
+	)}
+	ch, err := NewCodexRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x", MaxTurns: 2})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.NotEmpty(t, got)
+	last := got[len(got)-1]
+	assert.Equal(t, "complete", last.Type)
+	assert.Equal(t, true, last.Output["is_error"])
+	assert.Equal(t, "error_max_turns", last.Output["subtype"])
+	assert.Equal(t, "codex exceeded max_turns (2)", last.Output["result"])
+}
+
+func TestCodexRunner_NoTurnCompletedMeansNoResult(t *testing.T) {
+	sb := &runnerSandbox{lines: []string{wrappedContent("stderr", "codex: command not found")}}
+	ch, err := NewCodexRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x"})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.Len(t, got, 1)
+	assert.Equal(t, Event{Type: "stderr", Content: "codex: command not found"}, got[0])
+}
+
+func TestCodexStream_SplitsCombinedChunk(t *testing.T) {
+	s := newCodexStream("", 0)
+	events := s.handle(wrappedContent("stdout", strings.Join([]string{
+		`{"type":"item.started","item":{"type":"mcp_tool_call","server":"fleetlift","tool":"request_input"}}`,
+		`{"type":"item.completed","item":{"type":"command_execution","command":"go test","aggregated_output":"FAIL","exit_code":1}}`,
+	}, "\n")))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, Event{Type: "stdout", Content: "[tool] fleetlift.request_input"}, events[0])
+	assert.Equal(t, Event{Type: "stderr", Content: "FAIL"}, events[1])
+}
+
+func TestCodexCostUSD(t *testing.T) {
+	u := codexUsage{InputTokens: 1_000_000, OutputTokens: 1_000_000}
+	cost := func(model string) float64 {
+		c, ok := codexCostUSD(model, u)
+		require.True(t, ok, model)
+		return c
+	}
+	assert.InDelta(t, 11.25, cost(""), 1e-9, "empty model uses the codex default")
+	assert.InDelta(t, 2.25, cost("gpt-5-mini-2025-08-07"), 1e-9, "dated snapshot matches family")
+	assert.InDelta(t, 5.5, cost("o4-mini"), 1e-9)
+	_, ok := codexCostUSD("some-local-model", u)
+	assert.False(t, ok, "an unknown model has no price")
+}
+
+func TestCodexStream_UnpricedModel(t *testing.T) {
+	s := newCodexStream("some-local-model", 0)
+	ev := s.handleEvent("stdout", `{"type":"turn.completed","usage":{"input_tokens":10,"output_tokens":5}}`)
+	require.NotNil(t, ev.Usage)
+	assert.True(t, ev.Usage.Unpriced)
+	assert.NotContains(t, s.output("done", false), "total_cost_usd", "no made-up $0 cost")
+}
@@ -0,0 +1,301 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+// GeminiRunner implements Runner by invoking the Gemini CLI
+// (gemini --output-format stream-json) inside a sandbox.
+type GeminiRunner struct {
+	sandbox sandbox.Client
+}
+
+// NewGeminiRunner creates a new GeminiRunner backed by the given sandbox client.
+func NewGeminiRunner(sb sandbox.Client) *GeminiRunner {
+	return &GeminiRunner{sandbox: sb}
+}
+
+func (r *GeminiRunner) Name() string { return "gemini" }
+
+func (r *GeminiRunner) Run(ctx context.Context, sandboxID string, opts RunOpts) (<-chan Event, error) {
+	promptPath := fmt.Sprintf("/tmp/fleetlift-prompt-%s.txt", uuid.NewString())
+	if err := r.sandbox.WriteFile(ctx, sandboxID, promptPath, opts.Prompt); err != nil {
+		return nil, fmt.Errorf("write prompt file: %w", err)
+	}
+
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = "/workspace"
+	}
+	cmd := detached(geminiCommand(promptPath, opts), opts)
+	stream := newGeminiStream(opts.Model, effectiveMaxTurns(opts.MaxTurns))
+	return streamTurnLimited(ctx, r.sandbox, sandboxID, cmd, workDir, stream, r.Interrupt), nil
+}
+
+// geminiCommand builds the shell command that runs gemini non-interactively. The
+// prompt is piped on stdin, which puts the CLI in headless mode.
+func geminiCommand(promptPath string, opts RunOpts) string {
+	// If the MCP sidecar is available, register it in the user-level Gemini settings
+	// (not the workspace, so the repo diff stays clean).
+	mcpSetup := `. /tmp/fleetlift-mcp-env.sh 2>/dev/null; ` +
+		`if [ -n "$FLEETLIFT_MCP_PORT" ]; then ` +
+		`mkdir -p "$HOME/.gemini" && ` +
+		`printf '{"mcpServers":{"fleetlift":{"url":"http://localhost:%s/sse"}}}' "$FLEETLIFT_MCP_PORT" > "$HOME/.gemini/settings.json"; ` +
+		`fi; `
+
+	var b strings.Builder
+	b.WriteString(mcpSetup)
+	b.WriteString(envExports(opts.Environment))
+	b.WriteString("gemini --output-format stream-json --yolo")
+	if opts.Model != "" {
+		b.WriteString(" --model " + shellquote.Quote(opts.Model))
+	}
+	b.WriteString(" < " + shellquote.Quote(promptPath))
+	return b.String()
+}
+
+func (r *GeminiRunner) Interrupt(ctx context.Context, sandboxID string) error {
+	_, _, err := r.sandbox.Exec(ctx, sandboxID, "pkill -INT -f 'gemini --output-format'", "/")
+	return err
+}
+
+type geminiStats struct {
+	TotalTokens  int64 `json:"total_tokens"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	DurationMS   int64 `json:"duration_ms"`
+	ToolCalls    int   `json:"tool_calls"`
+}
+
+type geminiEvent struct {
+	Type       string         `json:"type"`
+	SessionID  string         `json:"session_id"`
+	Model      string         `json:"model"`
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	Delta      bool           `json:"delta"`
+	ToolName   string         `json:"tool_name"`
+	Parameters map[string]any `json:"parameters"`
+	Status     string         `json:"status"`
+	Output     string         `json:"output"`
+	Severity   string         `json:"severity"`
+	Message    string         `json:"message"`
+	Error      *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Stats *geminiStats `json:"stats"`
+}
+
+// geminiStream turns Gemini CLI stream-json output into Events. Assistant text
+// arrives as deltas, so it is buffered and emitted as one event per message.
+type geminiStream struct {
+	mu        sync.Mutex
+	model     string
+	maxTurns  int
+	sessionID string
+	message   strings.Builder // assistant text since the last tool call
+	lastText  string          // most recent complete assistant message
+	stats     geminiStats
+	turns     int // tool calls made; counted here because headless gemini has no max_turns flag
+	completed bool
+}
+
+func newGeminiStream(model string, maxTurns int) *geminiStream {
+	return &geminiStream{model: model, maxTurns: maxTurns}
+}
+
+func (s *geminiStream) handle(line string) []Event {
+	stream, parts := splitStreamLine(line)
+	var events []Event
+	for _, part := range parts {
+		events = append(events, s.handleEvent(stream, part)...)
+	}
+	return events
+}
+
+func (s *geminiStream) handleEvent(stream, raw string) []Event {
+	var ev geminiEvent
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil || ev.Type == "" {
+		if stream == "stderr" {
+			return []Event{{Type: "stderr", Content: raw}}
+		}
+		return []Event{{Type: "stdout", Content: raw}}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch ev.Type {
+	case "init":
+		s.sessionID = ev.SessionID
+		if s.model == "" {
+			s.model = ev.Model
+		}
+		return nil
+	case "message":
+		if ev.Role != "assistant" {
+			return nil
+		}
+		if !ev.Delta {
+			s.message.Reset()
+		}
+		s.message.WriteString(ev.Content)
+		return nil
+	case "tool_use":
+		events := s.flushMessage()
+		s.turns++
+		return append(events, geminiToolEvent(ev))
+	case "tool_result":
+		if ev.Status != "error" {
+			return nil // successful tool output is noise in logs
+		}
+		msg := ev.Output
+		if ev.Error != nil && ev.Error.Message != "" {
+			msg = ev.Error.Message
+		}
+		if msg == "" {
+			return nil
+		}
+		if len(msg) > 200 {
+			msg = msg[:200] + "…"
+		}
+		return []Event{{Type: "stderr", Content: msg}}
+	case "error":
+		// Non-fatal errors and warnings; a fatal error is also reported by the result event.
+		if ev.Message == "" {
+			return nil
+		}
+		return []Event{{Type: "stderr", Content: ev.Message}}
+	case "result":
+		events := s.flushMessage()
+		if ev.Stats != nil {
+			s.stats = *ev.Stats
+			cost, priced := geminiCostUSD(s.model, s.stats)
+			events = append(events, Event{Type: "usage", Final: true, Usage: &Usage{
+				InputTokens:  s.stats.InputTokens,
+				OutputTokens: s.stats.OutputTokens,
+				CostUSD:      cost,
+				Unpriced:     !priced,
+			}})
+		}
+		if ev.Status == "error" {
+			msg := "gemini run failed"
+			if ev.Error != nil && ev.Error.Message != "" {
+				msg = ev.Error.Message
+			}
+			return append(events, Event{Type: "error", Content: msg})
+		}
+		s.completed = true
+		return events
+	}
+	return nil
+}
+
+// flushMessage emits buffered assistant text, if any. Callers hold s.mu.
+func (s *geminiStream) flushMessage() []Event {
+	text := strings.TrimSpace(s.message.String())
+	s.message.Reset()
+	if text == "" {
+		return nil
+	}
+	s.lastText = text
+	return []Event{{Type: "stdout", Content: text}}
+}
+
+// geminiToolEvent renders a tool call. Calls to the fleetlift request_input MCP tool
+// surface as needs_input so the question is visible in the step log.
+func geminiToolEvent(ev geminiEvent) Event {
+	if strings.HasSuffix(ev.ToolName, "request_input") {
+		if q, _ := ev.Parameters["question"].(string); q != "" {
+			return Event{Type: "needs_input", Content: q}
+		}
+	}
+	if desc, _ := ev.Parameters["description"].(string); desc != "" {
+		return Event{Type: "stdout", Content: fmt.Sprintf("[tool] %s: %s", ev.ToolName, desc)}
+	}
+	if cmd, _ := ev.Parameters["command"].(string); cmd != "" {
+		if len(cmd) > 120 {
+			cmd = cmd[:120] + "…"
+		}
+		return Event{Type: "stdout", Content: fmt.Sprintf("[tool] %s: %s", ev.ToolName, cmd)}
+	}
+	if path, _ := ev.Parameters["file_path"].(string); path != "" {
+		return Event{Type: "stdout", Content: fmt.Sprintf("[tool] %s: %s", ev.ToolName, path)}
+	}
+	return Event{Type: "stdout", Content: "[tool] " + ev.ToolName}
+}
+
+func (s *geminiStream) exceededMaxTurns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxTurns > 0 && s.turns > s.maxTurns
+}
+
+// result mirrors the Claude Code result event so result, is_error, and
+// total_cost_usd are extracted the same way for every agent.
+func (s *geminiStream) result() (Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.completed {
+		return Event{}, false
+	}
+	return Event{Type: "complete", Output: s.output(s.lastText, false)}, true
+}
+
+func (s *geminiStream) maxTurnsResult() Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.output(fmt.Sprintf("gemini exceeded max_turns (%d)", s.maxTurns), true)
+	out["subtype"] = "error_max_turns"
+	return Event{Type: "complete", Output: out}
+}
+
+func (s *geminiStream) output(result string, isError bool) map[string]any {
+	out := map[string]any{
+		"type":       "result",
+		"result":     result,
+		"is_error":   isError,
+		"session_id": s.sessionID,
+		"num_turns":  s.turns,
+		"usage": map[string]any{
+			"input_tokens":  s.stats.InputTokens,
+			"output_tokens": s.stats.OutputTokens,
+		},
+	}
+	if cost, ok := geminiCostUSD(s.model, s.stats); ok {
+		out["total_cost_usd"] = cost
+	}
+	return out
+}
+
+// geminiPrices lists published Gemini API prices (prompts up to 200k tokens).
+var geminiPrices = map[string]tokenPrice{
+	"gemini-2.5-pro":        {1.25, 0.31, 10},
+	"gemini-2.5-flash":      {0.3, 0.075, 2.5},
+	"gemini-2.5-flash-lite": {0.1, 0.025, 0.4},
+	"gemini-2.0-flash":      {0.1, 0.025, 0.4},
+}
+
+// defaultGeminiModel is the model the Gemini CLI uses when none is configured.
+const defaultGeminiModel = "gemini-2.5-pro"
+
+// geminiCostUSD estimates the run cost from token usage. It reports false for a
+// model with no known price.
+func geminiCostUSD(model string, st geminiStats) (float64, bool) {
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	price, ok := lookupPrice(geminiPrices, model)
+	if !ok {
+		return 0, false
+	}
+	return price.cost(st.InputTokens, 0, st.OutputTokens), true
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiRunner_RunProducesCompleteEvent(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"init","session_id":"sess-1","model":"gemini-2.5-flash"}`,
+		`{"type":"message","role":"user","content":"fix the bug"}`,
+		`{"type":"message","role":"assistant","content":"Let me look","delta":true}`,
+		`{"type":"message","role":"assistant","content":" at the code.","delta":true}`,
+		`{"type":"tool_use","tool_name":"run_shell_command","tool_id":"t1","parameters":{"command":"go test ./..."}}`,
+		`{"type":"tool_result","tool_id":"t1","status":"error","output":"FAIL"}`,
+		`{"type":"message","role":"assistant","content":"Fixed. {\"ok\": true}","delta":true}`,
+		`{"type":"result","status":"success","stats":{"total_tokens":3000000,"input_tokens":2000000,"output_tokens":1000000,"tool_calls":1}}`,
+	)}
+	r := NewGeminiRunner(sb)
+
+	ch, err := r.Run(context.Background(), "sb-1", RunOpts{
+		Prompt:  "fix the bug",
+		WorkDir: "/workspace/repo",
+	})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.Len(t, got, 6)
+	assert.Equal(t, Event{Type: "stdout", Content: "Let me look at the code."}, got[0])
+	assert.Equal(t, Event{Type: "stdout", Content: "[tool] run_shell_command: go test ./..."}, got[1])
+	assert.Equal(t, Event{Type: "stderr", Content: "FAIL"}, got[2])
+	assert.Equal(t, Event{Type: "stdout", Content: `Fixed. {"ok": true}`}, got[3])
+
+	assert.Equal(t, Event{Type: "usage", Final: true, Usage: &Usage{InputTokens: 2_000_000, OutputTokens: 1_000_000, CostUSD: 3.1}}, got[4])
+
+	complete := got[5]
+	assert.Equal(t, "complete", complete.Type)
+	assert.Equal(t, `Fixed. {"ok": true}`, complete.Output["result"])
+	assert.Equal(t, false, complete.Output["is_error"])
+	assert.Equal(t, "sess-1", complete.Output["session_id"])
+	assert.Equal(t, 1, complete.Output["num_turns"])
+	// Model comes from the init event: 2M input * 0.30 + 1M output * 2.50, per million.
+	assert.InDelta(t, 3.1, complete.Output["total_cost_usd"], 1e-9)
+
+	assert.Contains(t, sb.execCmd, "gemini --output-format stream-json --yolo")
+	assert.NotContains(t, sb.execCmd, "--model")
+	assert.Contains(t, sb.execCmd, `"$HOME/.gemini/settings.json"`)
+	assert.Equal(t, "/workspace/repo", sb.execWorkDir)
+	for path, content := range sb.writes {
+		if strings.HasPrefix(path, "/tmp/fleetlift-prompt-") {
+			assert.Equal(t, "fix the bug", content)
+			assert.Contains(t, sb.execCmd, "< '"+path+"'")
+		}
+	}
+}
+
+func TestGeminiRunner_PassesModel(t *testing.T) {
+	sb := &runnerSandbox{}
+	ch, err := NewGeminiRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x", Model: "gemini-2.5-pro"})
+	require.NoError(t, err)
+	_ = collectEvents(ch, 2*time.Second)
+	assert.Contains(t, sb.execCmd, "--model 'gemini-2.5-pro'")
+}
+
+func TestGeminiRunner_ResultErrorEmitsError(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"error","severity":"warning","message":"Loop detected"}`,
+		`{"type":"result","status":"error","error":{"type":"FatalAuthenticationError","message":"GEMINI_API_KEY not set"}}`,
+	)}
+	ch, err := NewGeminiRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x"})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, Event{Type: "stderr", Content: "Loop detected"}, got[0])
+	assert.Equal(t, Event{Type: "error", Content: "GEMINI_API_KEY not set"}, got[1])
+}
+
+func TestGeminiRunner_RequestInputSurfacesAsNeedsInput(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"tool_use","tool_name":"inbox.request_input","parameters":{"question":"Which branch?"}}`,
+	)}
+	ch, err := NewGeminiRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x"})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.Len(t, got, 1)
+	assert.Equal(t, Event{Type: "needs_input", Content: "Which branch?"}, got[0])
+}
+
+func TestGeminiRunner_MaxTurnsStopsRun(t *testing.T) {
+	sb := &runnerSandbox{lines: codexLines(
+		`{"type":"tool_use","tool_name":"read_file","parameters":{"file_path":"a.go"}}`,
+		`{"type":"tool_use","tool_name":"read_file","parameters":{"file_path":"b.go"}}`,
+		`{"type":"result","status":"success","stats":{}}`,
+	)}
+	ch, err := NewGeminiRunner(sb).Run(context.Background(), "sb-1", RunOpts{Prompt: "x", MaxTurns: 1})
+	require.NoError(t, err)
+	got := collectEvents(ch, 2*time.Second)
+
+	require.NotEmpty(t, got)
+	last := got[len(got)-1]
+	assert.Equal(t, "complete", last.Type)
+	assert.Equal(t, true, last.Output["is_error"])
+	assert.Equal(t, "gemini exceeded max_turns (1)", last.Output["result"])
+}
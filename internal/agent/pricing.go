@@ -0,0 +1,33 @@
+package agent
+
+import "strings"
+
+// tokenPrice is USD per million tokens.
+type tokenPrice struct {
+	input, cachedInput, output float64
+}
+
+// lookupPrice finds the price for model in table. Keys are model-name prefixes and
+// the longest match wins, so dated snapshots inherit their family's price.
+func lookupPrice(table map[string]tokenPrice, model string) (tokenPrice, bool) {
+	var price tokenPrice
+	best := -1
+	for prefix, p := range table {
+		if strings.HasPrefix(model, prefix) && len(prefix) > best {
+			price, best = p, len(prefix)
+		}
+	}
+	return price, best >= 0
+}
+
+// cost returns the USD cost of a run. input includes cached tokens, which are
+// billed at the cached rate.
+func (p tokenPrice) cost(input, cached, output int64) float64 {
+	uncached := input - cached
+	if uncached < 0 {
+		uncached = 0
+	}
+	return (float64(uncached)*p.input +
+		float64(cached)*p.cachedInput +
+		float64(output)*p.output) / 1e6
+}
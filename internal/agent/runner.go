@@ -4,11 +4,25 @@ import "context"
 
 // Event represents a streaming event from an agent runner.
 type Event struct {
-	Type    string // "stdout" | "stderr" | "complete" | "error" | "needs_input"
+	Type    string // "stdout" | "stderr" | "complete" | "error" | "needs_input" | "usage"
 	Content string
 	Output  map[string]any // on "complete": structured output parsed from agent
+	Usage   *Usage         // on "usage": tokens used since the previous usage event
+	Final   bool           // on "usage": reported once the agent has finished, so there is nothing left to stop
 }
 
+// Usage is the token usage and estimated cost of part of an agent run.
+type Usage struct {
+	InputTokens       int64 // includes CachedInputTokens
+	CachedInputTokens int64
+	OutputTokens      int64
+	CostUSD           float64
+	Unpriced          bool // the model has no known price, so CostUSD is not set
+}
+
+// Tokens returns the input and output tokens used.
+func (u Usage) Tokens() int64 { return u.InputTokens + u.OutputTokens }
+
 // RunOpts configures an agent run.
 type RunOpts struct {
 	Prompt         string
@@ -17,6 +31,14 @@ type RunOpts struct {
 	Model          string
 	Environment    map[string]string
 	EvalPluginDirs []string // local sandbox paths for --plugin-dir flags
+	// LogPath, when set, runs the agent detached from the exec stream with its
+	// output written to this sandbox file, so the run outlives a dropped stream.
+	LogPath string
+	// Reattach follows the run already writing to LogPath instead of starting one.
+	Reattach bool
+	// ResumeSession continues this earlier session with Prompt as the next turn.
+	// Only runners implementing Resumer honour it.
+	ResumeSession string
 }
 
 // Runner is the interface for pluggable agent runners.
@@ -28,3 +50,13 @@ type Runner interface {
 	// Interrupt kills a running agent.
 	Interrupt(ctx context.Context, sandboxID string) error
 }
+
+// Resumer is implemented by runners that can continue an earlier session in the
+// same sandbox, keeping its full tool context, instead of starting over.
+type Resumer interface {
+	// SessionID returns the session a run can be resumed from, given the Output
+	// of its "complete" event, or "" if there is none.
+	SessionID(output map[string]any) string
+	// CanResume reports whether the session is still available in the sandbox.
+	CanResume(ctx context.Context, sandboxID, sessionID string) bool
+}
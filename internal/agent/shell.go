@@ -28,7 +28,7 @@ func (r *ShellRunner) Name() string { return "shell" }
 func (r *ShellRunner) Run(ctx context.Context, sandboxID string, opts RunOpts) (<-chan Event, error) {
 	// Append exit code sentinel. OpenSandbox's execd already runs commands via
 	// bash -c, so we pass the prompt directly — no extra /bin/sh -c wrapper needed.
-	cmd := opts.Prompt + "\necho " + exitCodeSentinel + "$?"
+	cmd := detached(opts.Prompt+"\necho "+exitCodeSentinel+"$?", opts)
 
 	ch := make(chan Event, 64)
 	go func() {
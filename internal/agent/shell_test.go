@@ -8,7 +8,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/local"
 )
 
 // sseJSON marshals a {"stream": stream, "content": content} JSON line.
@@ -238,3 +242,42 @@ func TestShellRunner_NonJSONLine(t *testing.T) {
 		t.Errorf("expected complete, got %+v", events[1])
 	}
 }
+
+func TestShellRunner_DetachedRunSurvivesDroppedStream(t *testing.T) {
+	sb := local.New(t.TempDir())
+	ctx := context.Background()
+	id, err := sb.Create(ctx, sandbox.CreateOpts{TimeoutMins: 5})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sb.Kill(ctx, id) })
+
+	r := NewShellRunner(sb)
+	opts := RunOpts{
+		Prompt:  "echo started >> /workspace/runs\necho running\nsleep 2\necho done",
+		WorkDir: "/workspace",
+		LogPath: "/tmp/agent.log",
+	}
+
+	// Drop the stream as soon as the agent is running, as a worker restart would.
+	streamCtx, cancel := context.WithCancel(ctx)
+	events, err := r.Run(streamCtx, id, opts)
+	require.NoError(t, err)
+	ev := <-events
+	assert.Equal(t, "running", strings.TrimSpace(ev.Content))
+	cancel()
+	for range events {
+	}
+
+	opts.Reattach = true
+	events, err = r.Run(ctx, id, opts)
+	require.NoError(t, err)
+	var last Event
+	for ev := range events {
+		last = ev
+	}
+	require.Equal(t, "complete", last.Type, last.Content)
+	assert.Equal(t, "running\ndone\n", last.Output["stdout"], "the log is replayed from the start")
+
+	runs, err := sb.ReadFile(ctx, id, "/workspace/runs")
+	require.NoError(t, err)
+	assert.Equal(t, "started\n", runs, "reattaching does not start the agent again")
+}
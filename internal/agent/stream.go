@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+// turnLimitedStream parses a CLI agent's JSON event stream. Agents without a native
+// max-turns option count tool calls themselves and report when the limit is exceeded.
+type turnLimitedStream interface {
+	// handle converts one ExecStream line into zero or more events.
+	handle(line string) []Event
+	exceededMaxTurns() bool
+	// maxTurnsResult is the "complete" event sent when the run is stopped for max_turns.
+	maxTurnsResult() Event
+	// result is the final "complete" event; ok is false if the agent never finished.
+	result() (event Event, ok bool)
+}
+
+// streamTurnLimited runs cmd in the sandbox, feeding output through s. When s reports
+// that max_turns was exceeded the stream is cancelled and the agent interrupted.
+func streamTurnLimited(ctx context.Context, sb sandbox.Client, sandboxID, cmd, workDir string,
+	s turnLimitedStream, interrupt func(context.Context, string) error) <-chan Event {
+	// runCtx is cancelled when the run exceeds max_turns, which stops the stream.
+	runCtx, cancel := context.WithCancel(ctx)
+
+	ch := make(chan Event, 64)
+	go func() {
+		defer close(ch)
+		defer cancel()
+		send := func(event Event) bool {
+			select {
+			case ch <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		err := sb.ExecStream(runCtx, sandboxID, cmd, workDir, func(line string) {
+			for _, event := range s.handle(line) {
+				if !send(event) {
+					return
+				}
+			}
+			if s.exceededMaxTurns() {
+				cancel()
+			}
+		})
+
+		if s.exceededMaxTurns() {
+			// Cancelling the stream does not necessarily stop the remote process.
+			_ = interrupt(ctx, sandboxID)
+			send(s.maxTurnsResult())
+			return
+		}
+		if err != nil {
+			send(Event{Type: "error", Content: err.Error()})
+			return
+		}
+		if event, ok := s.result(); ok {
+			send(event)
+		}
+	}()
+	return ch
+}
+
+// detached wraps cmd for opts.LogPath. Unless reattaching, cmd is started in
+// its own session with output going to LogPath and its exit status to
+// LogPath.exit. Either way the wrapper then streams LogPath from the start
+// until the exit file appears, and exits with the agent's status. stdout and
+// stderr share the log, so everything arrives on stdout.
+func detached(cmd string, opts RunOpts) string {
+	if opts.LogPath == "" {
+		return cmd
+	}
+	log := shellquote.Quote(opts.LogPath)
+	exit := shellquote.Quote(opts.LogPath + ".exit")
+	var b strings.Builder
+	if !opts.Reattach {
+		fmt.Fprintf(&b, "rm -f %s; : > %s\n", exit, log)
+		b.WriteString("d=; command -v setsid >/dev/null 2>&1 && d=setsid\n")
+		fmt.Fprintf(&b, "$d nohup sh -c '\"$1\" -c \"$2\"; echo $? > \"$3\"' fleetlift \"${BASH:-sh}\" %s %s >> %s 2>&1 < /dev/null &\n",
+			shellquote.Quote(cmd), exit, log)
+	}
+	fmt.Fprintf(&b, "tail -n +1 -f %s & t=$!\n", log)
+	fmt.Fprintf(&b, "while [ ! -f %s ]; do sleep 1; done\n", exit)
+	b.WriteString("sleep 1; kill $t 2>/dev/null\n")
+	fmt.Fprintf(&b, "exit \"$(cat %s)\"", exit)
+	return b.String()
+}
+
+var envKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envExports returns shell export statements for env in sorted order. Keys that are
+// not valid shell identifiers are dropped.
+func envExports(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		if envKeyRe.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "export %s=%s; ", k, shellquote.Quote(env[k]))
+	}
+	return b.String()
+}
+
+// splitStreamLine unwraps the normalized ExecStream format {"stream":"...","content":"..."}
+// and splits content into its non-empty lines, since one chunk may hold several JSONL events.
+// Lines that are not wrapped are treated as stdout.
+func splitStreamLine(line string) (stream string, parts []string) {
+	stream, content, ok := parseSSELine(line)
+	if !ok {
+		stream, content = "stdout", line
+	}
+	for _, part := range strings.Split(content, "\n") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return stream, parts
+}
@@ -0,0 +1,146 @@
+// Package artifact decides where artifact bytes live and reads them back.
+// Small artifacts stay inline in the artifacts.data column; larger ones go to
+// an object store (S3-compatible, or a local directory) and the row keeps only the key.
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+// Values of artifacts.storage.
+const (
+	StorageInline      = "inline"
+	StorageObjectStore = "object_store"
+)
+
+// DefaultInlineThreshold is the largest artifact kept inline when an object store is configured.
+const DefaultInlineThreshold = 1 << 20 // 1 MiB
+
+var (
+	// ErrNotFound is returned by ObjectStore.Open when the key does not exist.
+	ErrNotFound = errors.New("artifact object not found")
+	// ErrNoObjectStore is returned when reading an object_store artifact without a configured store.
+	ErrNoObjectStore = errors.New("no artifact object store configured")
+)
+
+// ObjectStore stores artifact bytes outside Postgres.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Location is where a saved artifact's bytes ended up, ready to be written to
+// the artifacts row: Data for inline storage, ObjectKey for object_store.
+type Location struct {
+	Storage   string
+	Data      []byte
+	ObjectKey *string
+}
+
+// Storage chooses between inline and object storage by size.
+// A nil *Storage or one without Objects stores everything inline.
+type Storage struct {
+	Objects         ObjectStore
+	InlineThreshold int64 // artifacts larger than this go to Objects
+}
+
+// New returns a Storage that offloads artifacts larger than threshold to objects.
+// A threshold <= 0 uses DefaultInlineThreshold.
+func New(objects ObjectStore, threshold int64) *Storage {
+	if threshold <= 0 {
+		threshold = DefaultInlineThreshold
+	}
+	return &Storage{Objects: objects, InlineThreshold: threshold}
+}
+
+// Save stores data under key, inline or in the object store depending on its size.
+func (s *Storage) Save(ctx context.Context, key string, data []byte, contentType string) (Location, error) {
+	if s == nil || s.Objects == nil || int64(len(data)) <= s.InlineThreshold {
+		return Location{Storage: StorageInline, Data: data}, nil
+	}
+	if err := s.Objects.Put(ctx, key, data, contentType); err != nil {
+		return Location{}, fmt.Errorf("put artifact object %s: %w", key, err)
+	}
+	return Location{Storage: StorageObjectStore, ObjectKey: &key}, nil
+}
+
+// Open returns a reader for the artifact's bytes, wherever they are stored.
+func (s *Storage) Open(ctx context.Context, a *model.Artifact) (io.ReadCloser, error) {
+	switch a.Storage {
+	case StorageInline, "":
+		return io.NopCloser(bytes.NewReader(a.Data)), nil
+	case StorageObjectStore:
+		if s == nil || s.Objects == nil {
+			return nil, ErrNoObjectStore
+		}
+		if a.ObjectKey == nil || *a.ObjectKey == "" {
+			return nil, fmt.Errorf("artifact %s has no object key", a.ID)
+		}
+		return s.Objects.Open(ctx, *a.ObjectKey)
+	default:
+		return nil, fmt.Errorf("unknown artifact storage %q", a.Storage)
+	}
+}
+
+// FromEnv builds the artifact Storage configured by environment variables:
+//
+//	ARTIFACT_STORE                "" or "inline" (default), "s3", or "fs"
+//	ARTIFACT_INLINE_MAX_BYTES     inline threshold in bytes (default 1 MiB)
+//	ARTIFACT_FS_ROOT              directory for the "fs" store
+//	ARTIFACT_S3_ENDPOINT          host[:port] of the S3-compatible endpoint
+//	ARTIFACT_S3_BUCKET            bucket name
+//	ARTIFACT_S3_ACCESS_KEY_ID     access key
+//	ARTIFACT_S3_SECRET_ACCESS_KEY secret key
+//	ARTIFACT_S3_REGION            optional region
+//	ARTIFACT_S3_INSECURE          "true" to use plain HTTP (e.g. local MinIO)
+//
+// Returns nil when everything should stay inline.
+func FromEnv() (*Storage, error) {
+	var threshold int64
+	if v := os.Getenv("ARTIFACT_INLINE_MAX_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("ARTIFACT_INLINE_MAX_BYTES must be a non-negative integer, got %q", v)
+		}
+		threshold = n
+	}
+
+	switch kind := os.Getenv("ARTIFACT_STORE"); kind {
+	case "", StorageInline:
+		return nil, nil
+	case "fs":
+		root := os.Getenv("ARTIFACT_FS_ROOT")
+		if root == "" {
+			return nil, fmt.Errorf("ARTIFACT_FS_ROOT is required when ARTIFACT_STORE=fs")
+		}
+		fs, err := NewFSStore(root)
+		if err != nil {
+			return nil, err
+		}
+		return New(fs, threshold), nil
+	case "s3":
+		s3, err := NewS3Store(S3Config{
+			Endpoint:        os.Getenv("ARTIFACT_S3_ENDPOINT"),
+			Bucket:          os.Getenv("ARTIFACT_S3_BUCKET"),
+			AccessKeyID:     os.Getenv("ARTIFACT_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("ARTIFACT_S3_SECRET_ACCESS_KEY"),
+			Region:          os.Getenv("ARTIFACT_S3_REGION"),
+			Insecure:        os.Getenv("ARTIFACT_S3_INSECURE") == "true",
+		})
+		if err != nil {
+			return nil, err
+		}
+		return New(s3, threshold), nil
+	default:
+		return nil, fmt.Errorf("unknown ARTIFACT_STORE %q (want inline, s3 or fs)", kind)
+	}
+}
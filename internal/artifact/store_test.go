@@ -0,0 +1,121 @@
+package artifact
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+func readAll(t *testing.T, rc io.ReadCloser) string {
+	t.Helper()
+	defer func() { _ = rc.Close() }()
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestStorage_SaveByThreshold(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFSStore(t.TempDir())
+	require.NoError(t, err)
+	s := New(fs, 4)
+
+	small, err := s.Save(ctx, "k/small", []byte("abcd"), "text/plain")
+	require.NoError(t, err)
+	assert.Equal(t, StorageInline, small.Storage)
+	assert.Equal(t, []byte("abcd"), small.Data)
+	assert.Nil(t, small.ObjectKey)
+
+	large, err := s.Save(ctx, "k/large", []byte("abcde"), "text/plain")
+	require.NoError(t, err)
+	assert.Equal(t, StorageObjectStore, large.Storage)
+	assert.Nil(t, large.Data)
+	require.NotNil(t, large.ObjectKey)
+	assert.Equal(t, "k/large", *large.ObjectKey)
+
+	rc, err := s.Open(ctx, &model.Artifact{Storage: large.Storage, ObjectKey: large.ObjectKey})
+	require.NoError(t, err)
+	assert.Equal(t, "abcde", readAll(t, rc))
+}
+
+func TestStorage_NilKeepsEverythingInline(t *testing.T) {
+	var s *Storage
+	loc, err := s.Save(context.Background(), "k", []byte(strings.Repeat("x", 10<<20)), "")
+	require.NoError(t, err)
+	assert.Equal(t, StorageInline, loc.Storage)
+
+	rc, err := s.Open(context.Background(), &model.Artifact{Storage: StorageInline, Data: []byte("hi")})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", readAll(t, rc))
+
+	key := "k"
+	_, err = s.Open(context.Background(), &model.Artifact{Storage: StorageObjectStore, ObjectKey: &key})
+	assert.ErrorIs(t, err, ErrNoObjectStore)
+}
+
+func TestNew_DefaultThreshold(t *testing.T) {
+	assert.Equal(t, int64(DefaultInlineThreshold), New(nil, 0).InlineThreshold)
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Run("default is inline", func(t *testing.T) {
+		t.Setenv("ARTIFACT_STORE", "")
+		s, err := FromEnv()
+		require.NoError(t, err)
+		assert.Nil(t, s)
+	})
+	t.Run("fs", func(t *testing.T) {
+		t.Setenv("ARTIFACT_STORE", "fs")
+		t.Setenv("ARTIFACT_FS_ROOT", t.TempDir())
+		t.Setenv("ARTIFACT_INLINE_MAX_BYTES", "512")
+		s, err := FromEnv()
+		require.NoError(t, err)
+		require.NotNil(t, s)
+		assert.Equal(t, int64(512), s.InlineThreshold)
+		assert.IsType(t, &FSStore{}, s.Objects)
+	})
+	t.Run("s3 requires bucket", func(t *testing.T) {
+		t.Setenv("ARTIFACT_STORE", "s3")
+		t.Setenv("ARTIFACT_S3_ENDPOINT", "localhost:9000")
+		t.Setenv("ARTIFACT_S3_BUCKET", "")
+		_, err := FromEnv()
+		assert.Error(t, err)
+	})
+	t.Run("unknown store", func(t *testing.T) {
+		t.Setenv("ARTIFACT_STORE", "gcs")
+		_, err := FromEnv()
+		assert.ErrorContains(t, err, "unknown ARTIFACT_STORE")
+	})
+	t.Run("bad threshold", func(t *testing.T) {
+		t.Setenv("ARTIFACT_STORE", "")
+		t.Setenv("ARTIFACT_INLINE_MAX_BYTES", "1MB")
+		_, err := FromEnv()
+		assert.ErrorContains(t, err, "ARTIFACT_INLINE_MAX_BYTES")
+	})
+}
+
+func TestFSStore(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFSStore(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, fs.Put(ctx, "artifacts/sr-1/a-1", []byte("payload"), "text/plain"))
+	rc, err := fs.Open(ctx, "artifacts/sr-1/a-1")
+	require.NoError(t, err)
+	assert.Equal(t, "payload", readAll(t, rc))
+
+	require.NoError(t, fs.Delete(ctx, "artifacts/sr-1/a-1"))
+	_, err = fs.Open(ctx, "artifacts/sr-1/a-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, fs.Delete(ctx, "artifacts/sr-1/a-1"), "deleting a missing object is not an error")
+
+	for _, key := range []string{"", "../escape", "/abs/path"} {
+		assert.Error(t, fs.Put(ctx, key, []byte("x"), ""), "key %q", key)
+	}
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+)
+
+// APIKeyPrefix marks a bearer token as a team API key rather than a JWT.
+const APIKeyPrefix = "fl_"
+
+var (
+	ErrAPIKeyInvalid = errors.New("invalid API key")
+	ErrAPIKeyExpired = errors.New("API key expired")
+)
+
+// APIKeyResolver resolves a raw API key to the claims it authenticates as.
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, raw string) (*Claims, error)
+}
+
+// IsAPIKey reports whether token looks like a Fleetlift API key.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// GenerateAPIKey returns a new random API key and the hash to store for it.
+func GenerateAPIKey() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashAPIKey(raw), nil
+}
+
+// HashAPIKey returns the value stored in api_keys.key_hash for raw.
+func HashAPIKey(raw string) string {
+	return sha256hex(raw)
+}
+
+// DBAPIKeyStore resolves API keys against the api_keys table.
+type DBAPIKeyStore struct {
+	DB *sqlx.DB
+}
+
+type apiKeyRecord struct {
+	ID        string     `db:"id"`
+	TeamID    string     `db:"team_id"`
+	Role      string     `db:"role"`
+	CreatedBy *string    `db:"created_by"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// ResolveAPIKey looks up raw by hash, rejects revoked and expired keys, and records
+// the key as used. The returned claims grant the key's role on its team only and act
+// on behalf of the user who created the key.
+func (s *DBAPIKeyStore) ResolveAPIKey(ctx context.Context, raw string) (*Claims, error) {
+	if !IsAPIKey(raw) {
+		return nil, ErrAPIKeyInvalid
+	}
+	var rec apiKeyRecord
+	err := s.DB.GetContext(ctx, &rec,
+		`SELECT id, team_id, role, created_by, expires_at, revoked_at FROM api_keys WHERE key_hash = $1`,
+		HashAPIKey(raw))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, fmt.Errorf("load API key: %w", err)
+	}
+	if rec.RevokedAt != nil {
+		return nil, ErrAPIKeyInvalid
+	}
+	if rec.ExpiresAt != nil && time.Now().After(*rec.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	// Usage tracking is best-effort; a failed write must not reject a valid key.
+	// Writes are throttled to one a minute so busy CI keys don't hammer the row.
+	if _, err := s.DB.ExecContext(ctx,
+		`UPDATE api_keys SET last_used_at = now()
+		 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`,
+		rec.ID); err != nil {
+		slog.Warn("failed to record API key usage", "error", err, "api_key_id", rec.ID)
+	}
+
+	claims := &Claims{
+		TeamRoles: map[string]string{rec.TeamID: rec.Role},
+		APIKeyID:  rec.ID,
+	}
+	if rec.CreatedBy != nil {
+		claims.UserID = *rec.CreatedBy
+	}
+	if rec.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*rec.ExpiresAt)
+	}
+	return claims, nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	raw, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, APIKeyPrefix))
+	assert.True(t, IsAPIKey(raw))
+	assert.Equal(t, HashAPIKey(raw), hash)
+	assert.NotContains(t, hash, raw)
+
+	raw2, _, err := GenerateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, raw, raw2)
+}
+
+func newAPIKeyStore(t *testing.T) (*DBAPIKeyStore, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return &DBAPIKeyStore{DB: sqlx.NewDb(sqlDB, "sqlmock")}, mock
+}
+
+var apiKeyCols = []string{"id", "team_id", "role", "created_by", "expires_at", "revoked_at"}
+
+func TestResolveAPIKey_Valid(t *testing.T) {
+	store, mock := newAPIKeyStore(t)
+	raw := "fl_valid"
+	expires := time.Now().Add(time.Hour)
+	mock.ExpectQuery(`SELECT id, team_id, role, created_by, expires_at, revoked_at FROM api_keys WHERE key_hash = \$1`).
+		WithArgs(HashAPIKey(raw)).
+		WillReturnRows(sqlmock.NewRows(apiKeyCols).AddRow("key-1", "team-1", "admin", "user-1", expires, nil))
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = now\(\)`).
+		WithArgs("key-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	claims, err := store.ResolveAPIKey(context.Background(), raw)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+	assert.Equal(t, map[string]string{"team-1": "admin"}, claims.TeamRoles)
+	assert.Equal(t, "key-1", claims.APIKeyID)
+	assert.False(t, claims.PlatformAdmin)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveAPIKey_Expired(t *testing.T) {
+	store, mock := newAPIKeyStore(t)
+	mock.ExpectQuery(`SELECT .* FROM api_keys`).
+		WillReturnRows(sqlmock.NewRows(apiKeyCols).AddRow("key-1", "team-1", "member", nil, time.Now().Add(-time.Minute), nil))
+
+	_, err := store.ResolveAPIKey(context.Background(), "fl_expired")
+	assert.ErrorIs(t, err, ErrAPIKeyExpired)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveAPIKey_Revoked(t *testing.T) {
+	store, mock := newAPIKeyStore(t)
+	mock.ExpectQuery(`SELECT .* FROM api_keys`).
+		WillReturnRows(sqlmock.NewRows(apiKeyCols).AddRow("key-1", "team-1", "member", nil, nil, time.Now()))
+
+	_, err := store.ResolveAPIKey(context.Background(), "fl_revoked")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+}
+
+func TestResolveAPIKey_Unknown(t *testing.T) {
+	store, mock := newAPIKeyStore(t)
+	mock.ExpectQuery(`SELECT .* FROM api_keys`).
+		WillReturnRows(sqlmock.NewRows(apiKeyCols))
+
+	_, err := store.ResolveAPIKey(context.Background(), "fl_unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyInvalid)
+}
+
+type stubAPIKeyResolver struct {
+	claims *Claims
+	err    error
+	got    string
+}
+
+func (s *stubAPIKeyResolver) ResolveAPIKey(_ context.Context, raw string) (*Claims, error) {
+	s.got = raw
+	return s.claims, s.err
+}
+
+func TestMiddleware_AllowsAPIKey(t *testing.T) {
+	resolver := &stubAPIKeyResolver{claims: &Claims{
+		UserID:    "user-1",
+		TeamRoles: map[string]string{"team-1": "member"},
+		APIKeyID:  "key-1",
+	}}
+	var got *Claims
+	h := Middleware([]byte("secret"), resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClaimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Authorization", "Bearer fl_abc")
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "fl_abc", resolver.got)
+	require.NotNil(t, got)
+	assert.Equal(t, "key-1", got.APIKeyID)
+}
+
+func TestMiddleware_RejectsExpiredAPIKey(t *testing.T) {
+	resolver := &stubAPIKeyResolver{err: ErrAPIKeyExpired}
+	h := Middleware([]byte("secret"), resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer fl_abc")
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestMiddleware_JWTStillAcceptedWithAPIKeys(t *testing.T) {
+	secret := []byte("test-secret")
+	tokenStr, err := IssueToken(secret, "user-1", map[string]string{"team-1": "member"}, false)
+	require.NoError(t, err)
+
+	resolver := &stubAPIKeyResolver{}
+	h := Middleware(secret, resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenStr)
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, resolver.got, "JWTs must not be sent to the API key resolver")
+}
@@ -20,6 +20,7 @@ type Claims struct {
 	UserID        string            `json:"user_id"`
 	TeamRoles     map[string]string `json:"team_roles"`
 	PlatformAdmin bool              `json:"platform_admin"`
+	APIKeyID      string            `json:"api_key_id,omitempty"` // set when authenticated with a team API key
 	jwt.RegisteredClaims
 }
 
@@ -11,8 +11,9 @@ type contextKey string
 const claimsKey contextKey = "claims"
 
 // Middleware returns an HTTP middleware that validates JWT tokens from the
-// Authorization header (Bearer) or fl_token cookie.
-func Middleware(secret []byte) func(http.Handler) http.Handler {
+// Authorization header (Bearer) or fl_token cookie. When apiKeys is non-nil,
+// bearer tokens with the fl_ prefix are resolved as team API keys instead.
+func Middleware(secret []byte, apiKeys APIKeyResolver) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := extractToken(r)
@@ -20,7 +21,15 @@ func Middleware(secret []byte) func(http.Handler) http.Handler {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-			claims, err := ValidateToken(secret, token)
+			var (
+				claims *Claims
+				err    error
+			)
+			if apiKeys != nil && IsAPIKey(token) {
+				claims, err = apiKeys.ResolveAPIKey(r.Context(), token)
+			} else {
+				claims, err = ValidateToken(secret, token)
+			}
 			if err != nil {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
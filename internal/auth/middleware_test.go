@@ -10,7 +10,7 @@ import (
 )
 
 func TestMiddleware_BlocksNoToken(t *testing.T) {
-	h := Middleware([]byte("secret"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := Middleware([]byte("secret"), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 	rr := httptest.NewRecorder()
@@ -19,7 +19,7 @@ func TestMiddleware_BlocksNoToken(t *testing.T) {
 }
 
 func TestMiddleware_BlocksInvalidToken(t *testing.T) {
-	h := Middleware([]byte("secret"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := Middleware([]byte("secret"), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 	rr := httptest.NewRecorder()
@@ -35,7 +35,7 @@ func TestMiddleware_AllowsValidBearerToken(t *testing.T) {
 	require.NoError(t, err)
 
 	var gotUserID string
-	h := Middleware(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := Middleware(secret, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c := ClaimsFromContext(r.Context())
 		if c != nil {
 			gotUserID = c.UserID
@@ -55,7 +55,7 @@ func TestMiddleware_AllowsValidCookieToken(t *testing.T) {
 	tokenStr, err := IssueToken(secret, "user-2", map[string]string{}, false)
 	require.NoError(t, err)
 
-	h := Middleware(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := Middleware(secret, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 	rr := httptest.NewRecorder()
@@ -70,7 +70,7 @@ func TestMiddleware_BlocksCookieOnMutatingMethod(t *testing.T) {
 	tokenStr, err := IssueToken(secret, "user-3", map[string]string{}, false)
 	require.NoError(t, err)
 
-	h := Middleware(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := Middleware(secret, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 	rr := httptest.NewRecorder()
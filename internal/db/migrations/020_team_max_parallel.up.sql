@@ -0,0 +1,2 @@
+-- Team-level default for fan-out concurrency (steps without max_parallel).
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS max_parallel INT;
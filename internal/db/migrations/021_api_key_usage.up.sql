@@ -0,0 +1,4 @@
+-- Track API key usage and support revocation without losing the audit trail.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_api_keys_team ON api_keys (team_id, created_at DESC);
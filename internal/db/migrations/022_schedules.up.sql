@@ -0,0 +1,20 @@
+-- Cron schedules that start workflow runs via Temporal Schedules.
+CREATE TABLE IF NOT EXISTS schedules (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id     UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    workflow_id TEXT NOT NULL,   -- slug; may reference builtin
+    cron        TEXT NOT NULL,
+    timezone    TEXT NOT NULL DEFAULT 'UTC',
+    parameters  JSONB NOT NULL DEFAULT '{}',
+    model       TEXT,
+    enabled     BOOLEAN NOT NULL DEFAULT true,
+    created_by  UUID REFERENCES users(id) ON DELETE SET NULL, -- owner; runs are triggered as this user
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS schedules_team ON schedules(team_id, created_at DESC);
+
+ALTER TABLE runs
+    ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES schedules(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS runs_schedule ON runs(schedule_id, created_at DESC) WHERE schedule_id IS NOT NULL;
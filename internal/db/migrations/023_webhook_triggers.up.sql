@@ -0,0 +1,29 @@
+-- GitHub webhook triggers: start a workflow run when a matching event is delivered.
+CREATE TABLE IF NOT EXISTS webhook_triggers (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id     UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    workflow_id TEXT NOT NULL,             -- slug; may reference builtin
+    event       TEXT NOT NULL,             -- X-GitHub-Event, e.g. pull_request
+    repo        TEXT NOT NULL DEFAULT '',  -- owner/name glob; empty matches any repo
+    actions     TEXT[] NOT NULL DEFAULT '{}', -- payload "action" values; empty matches any
+    parameters  JSONB NOT NULL DEFAULT '{}',  -- param name -> Go template over the payload
+    model       TEXT,
+    enabled     BOOLEAN NOT NULL DEFAULT true,
+    secret      BYTEA NOT NULL,            -- HMAC secret, AES-GCM encrypted
+    created_by  UUID REFERENCES users(id) ON DELETE SET NULL, -- runs are triggered as this user
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS webhook_triggers_team ON webhook_triggers(team_id, created_at DESC);
+
+-- One row per accepted delivery, so redelivered webhooks do not start duplicate runs.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    trigger_id  UUID NOT NULL REFERENCES webhook_triggers(id) ON DELETE CASCADE,
+    delivery_id TEXT NOT NULL,             -- X-GitHub-Delivery
+    event       TEXT NOT NULL,
+    run_id      UUID REFERENCES runs(id) ON DELETE SET NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (trigger_id, delivery_id)
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_received ON webhook_deliveries(trigger_id, received_at DESC);
@@ -0,0 +1,4 @@
+-- Retried runs link back to the run they were retried from.
+ALTER TABLE runs
+    ADD COLUMN IF NOT EXISTS parent_run_id UUID REFERENCES runs(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS runs_parent ON runs(parent_run_id) WHERE parent_run_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Token usage of a step run, kept current while the agent runs so run-level
+-- budgets can be enforced across concurrent steps.
+ALTER TABLE step_runs ADD COLUMN IF NOT EXISTS tokens_used BIGINT;
+
+-- Add budget_exceeded to inbox_items kind constraint.
+DO $$ BEGIN
+  ALTER TABLE inbox_items DROP CONSTRAINT IF EXISTS inbox_items_kind_check;
+  ALTER TABLE inbox_items ADD CONSTRAINT inbox_items_kind_check
+    CHECK (kind IN ('awaiting_input','output_ready','notify','request_input','fan_out_partial_failure','step_failed','budget_exceeded'));
+EXCEPTION WHEN others THEN NULL;
+END $$;
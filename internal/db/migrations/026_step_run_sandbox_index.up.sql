@@ -0,0 +1,4 @@
+-- Step runs in a sandbox group record the shared sandbox, so one sandbox can
+-- map to many step runs. Index it for the reaper and for finding the steps
+-- that shared a sandbox.
+CREATE INDEX IF NOT EXISTS step_runs_sandbox ON step_runs(sandbox_id) WHERE sandbox_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- What CreatePullRequest did for a step: created a PR, or updated or skipped
+-- an open one (pull_request.on_existing).
+ALTER TABLE step_runs ADD COLUMN IF NOT EXISTS pr_action TEXT;
@@ -0,0 +1,3 @@
+-- The workflow definition a run was started with, so a retry replays the same
+-- steps even after the template has changed.
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS workflow_yaml TEXT;
@@ -0,0 +1,262 @@
+// Package gitcache keeps worker-side git mirrors so sandboxes can be seeded
+// without cloning from the remote every time.
+package gitcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// DefaultMaxBytes caps the total size of a cache's mirrors.
+const DefaultMaxBytes int64 = 20 << 30
+
+// MaxBundleBytes caps a single bundle; larger repositories are cloned directly.
+const MaxBundleBytes int64 = 256 << 20
+
+// MaxStagedBytes caps the bundles held in memory at once. Bundle waits for room,
+// so a wide fan-out cloning large repositories cannot exhaust the worker's heap.
+const MaxStagedBytes int64 = 1 << 30
+
+// Cache holds one bare mirror per team and repository URL under dir. Mirrors
+// are fetched incrementally on every use and evicted least recently used first
+// once their total size exceeds maxBytes. Teams never share a mirror, so a
+// private repository fetched with one team's token is never served to another.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // mirror path -> lock held while fetching or bundling
+
+	staged *semaphore.Weighted // bytes of bundles returned but not yet released
+}
+
+// New returns a cache rooted at dir, creating it if needed.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create git cache dir: %w", err)
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		locks:    make(map[string]*sync.Mutex),
+		staged:   semaphore.NewWeighted(MaxStagedBytes),
+	}, nil
+}
+
+// Bundle brings the team's mirror of url up to date and returns a git bundle
+// of branch, or of the default branch when branch is empty, along with the
+// branch name. token, if set, authenticates the fetch as a GitHub token. The
+// caller must call release once it is done with data; until then the bundle
+// counts against MaxStagedBytes.
+func (c *Cache) Bundle(ctx context.Context, teamID, url, token, branch string) (data []byte, resolvedBranch string, release func(), err error) {
+	mirror := c.mirrorPath(teamID, url)
+	lock := c.lock(mirror)
+	lock.Lock()
+	path, branch, err := c.bundle(ctx, mirror, url, token, branch)
+	lock.Unlock()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer func() { _ = os.Remove(path) }()
+	c.evict()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	size := fi.Size()
+	if size > MaxBundleBytes {
+		return nil, "", nil, fmt.Errorf("bundle of %s is %d bytes, over the %d byte limit", url, size, MaxBundleBytes)
+	}
+	if err := c.staged.Acquire(ctx, size); err != nil {
+		return nil, "", nil, fmt.Errorf("wait to stage bundle: %w", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		c.staged.Release(size)
+		return nil, "", nil, fmt.Errorf("read bundle: %w", err)
+	}
+	var once sync.Once
+	return data, branch, func() { once.Do(func() { c.staged.Release(size) }) }, nil
+}
+
+// bundle writes a bundle of branch to a temporary file under the cache dir and
+// returns its path, which the caller removes.
+func (c *Cache) bundle(ctx context.Context, mirror, url, token, branch string) (string, string, error) {
+	if err := c.sync(ctx, mirror, url, token); err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	_ = os.Chtimes(mirror, now, now) // mtime orders eviction
+
+	if branch == "" {
+		head, err := git(ctx, nil, "--git-dir="+mirror, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return "", "", fmt.Errorf("resolve default branch: %w", err)
+		}
+		branch = head
+	}
+	ref := "refs/heads/" + branch
+	if _, err := git(ctx, nil, "--git-dir="+mirror, "rev-parse", "--verify", "--quiet", ref); err != nil {
+		return "", "", fmt.Errorf("branch %q not found in %s", branch, url)
+	}
+
+	f, err := os.CreateTemp(c.dir, "bundle-*")
+	if err != nil {
+		return "", "", fmt.Errorf("create bundle file: %w", err)
+	}
+	path := f.Name()
+	_ = f.Close()
+	if _, err := git(ctx, nil, "--git-dir="+mirror, "bundle", "create", path, ref); err != nil {
+		_ = os.Remove(path)
+		return "", "", err
+	}
+	return path, branch, nil
+}
+
+// sync creates the mirror on first use and fetches branches and tags into it after that.
+func (c *Cache) sync(ctx context.Context, mirror, url, token string) error {
+	auth := authConfig(token)
+	if _, err := os.Stat(mirror); err == nil {
+		_, err := git(ctx, auth, "--git-dir="+mirror, "fetch", "--quiet", "--prune", "origin",
+			"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(mirror), 0o700); err != nil {
+		return fmt.Errorf("create team cache dir: %w", err)
+	}
+	tmp := mirror + ".tmp"
+	_ = os.RemoveAll(tmp)
+	if _, err := git(ctx, auth, "clone", "--bare", "--quiet", url, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, mirror); err != nil {
+		_ = os.RemoveAll(tmp)
+		return fmt.Errorf("install mirror: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) mirrorPath(teamID, url string) string {
+	return filepath.Join(c.dir, hash(teamID), hash(url)+".git")
+}
+
+func (c *Cache) lock(mirror string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.locks[mirror]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[mirror] = l
+	}
+	return l
+}
+
+type mirrorUsage struct {
+	path     string
+	size     int64
+	lastUsed time.Time
+}
+
+// evict removes least recently used mirrors until the cache fits in maxBytes.
+// Mirrors in use are skipped.
+func (c *Cache) evict() {
+	paths, _ := filepath.Glob(filepath.Join(c.dir, "*", "*.git"))
+	var mirrors []mirrorUsage
+	var total int64
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		size := dirSize(p)
+		total += size
+		mirrors = append(mirrors, mirrorUsage{path: p, size: size, lastUsed: fi.ModTime()})
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].lastUsed.Before(mirrors[j].lastUsed) })
+	for _, m := range mirrors {
+		if total <= c.maxBytes {
+			return
+		}
+		lock := c.lock(m.path)
+		if !lock.TryLock() {
+			continue
+		}
+		if err := os.RemoveAll(m.path); err != nil {
+			slog.Warn("failed to evict git mirror", "path", m.path, "error", err)
+		} else {
+			total -= m.size
+		}
+		lock.Unlock()
+	}
+}
+
+func dirSize(root string) int64 {
+	var size int64
+	_ = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// authConfig passes token as an HTTP header through git's GIT_CONFIG_*
+// environment, keeping it out of the mirror's config and the process list.
+func authConfig(token string) []string {
+	if token == "" {
+		return nil
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic " + basic}
+}
+
+func git(ctx context.Context, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", gitSubcommand(args), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitSubcommand names the command in args for error messages, skipping global options.
+func gitSubcommand(args []string) string {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return a
+		}
+	}
+	return ""
+}
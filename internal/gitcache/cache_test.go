@@ -0,0 +1,154 @@
+package gitcache
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+// gitHost runs git for test setup, isolated from the user's config.
+func gitHost(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = []string{"HOME=" + dir, "GIT_CONFIG_NOSYSTEM=1", "PATH=/usr/local/bin:/usr/bin:/bin"}
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+	return strings.TrimSpace(string(out))
+}
+
+// newRemote creates a bare repository with one commit on main and returns a
+// file:// URL for it and the seed working copy used to push more commits.
+func newRemote(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	seed := filepath.Join(dir, "seed")
+	gitHost(t, dir, "init", "--bare", "-b", "main", remote)
+	gitHost(t, dir, "init", "-b", "main", seed)
+	commit(t, seed, "initial")
+	gitHost(t, seed, "remote", "add", "origin", remote)
+	gitHost(t, seed, "push", "origin", "main")
+	return "file://" + remote, seed
+}
+
+func commit(t *testing.T, dir, msg string) {
+	t.Helper()
+	gitHost(t, dir, "-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "--allow-empty", "-m", msg)
+}
+
+// cloneBundle clones data on branch and returns the subject of its head commit.
+func cloneBundle(t *testing.T, data []byte, branch string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "repo.bundle")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	gitHost(t, dir, "clone", "--quiet", "--branch", branch, path, "repo")
+	return gitHost(t, filepath.Join(dir, "repo"), "log", "-1", "--format=%s")
+}
+
+func TestBundle_FetchesIncrementally(t *testing.T) {
+	url, seed := newRemote(t)
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	data, branch, release, err := c.Bundle(ctx, "team-1", url, "", "")
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, "main", branch, "default branch is resolved from the remote")
+	assert.Equal(t, "initial", cloneBundle(t, data, branch))
+
+	commit(t, seed, "second")
+	gitHost(t, seed, "push", "origin", "main")
+	data, _, release, err = c.Bundle(ctx, "team-1", url, "", "main")
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, "second", cloneBundle(t, data, "main"))
+
+	_, _, _, err = c.Bundle(ctx, "team-1", url, "", "no-such-branch")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestBundle_IsolatesTeams(t *testing.T) {
+	url, _ := newRemote(t)
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, team := range []string{"team-1", "team-2"} {
+		_, _, release, err := c.Bundle(ctx, team, url, "", "")
+		require.NoError(t, err)
+		release()
+	}
+	assert.NotEqual(t, c.mirrorPath("team-1", url), c.mirrorPath("team-2", url))
+	assert.DirExists(t, c.mirrorPath("team-1", url))
+	assert.DirExists(t, c.mirrorPath("team-2", url))
+}
+
+func TestBundle_EvictsLeastRecentlyUsed(t *testing.T) {
+	urlA, _ := newRemote(t)
+	urlB, _ := newRemote(t)
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, _, release, err := c.Bundle(ctx, "team-1", urlA, "", "")
+	require.NoError(t, err)
+	release()
+	mirrorA := c.mirrorPath("team-1", urlA)
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(mirrorA, past, past))
+
+	// Room for roughly one mirror: using B pushes out A.
+	c.maxBytes = dirSize(mirrorA) * 3 / 2
+	_, _, release, err = c.Bundle(ctx, "team-1", urlB, "", "")
+	require.NoError(t, err)
+	release()
+	assert.NoDirExists(t, mirrorA)
+	assert.DirExists(t, c.mirrorPath("team-1", urlB))
+}
+
+func TestBundle_BoundsStagedBytes(t *testing.T) {
+	url, _ := newRemote(t)
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	data, _, release, err := c.Bundle(ctx, "team-1", url, "", "")
+	require.NoError(t, err)
+	release()
+	// Room for exactly one bundle of this repository.
+	c.staged = semaphore.NewWeighted(int64(len(data)))
+
+	_, _, release, err = c.Bundle(ctx, "team-1", url, "", "")
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, _, _, err = c.Bundle(waitCtx, "team-1", url, "", "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "a second bundle waits for the first to be released")
+
+	release()
+	release() // releasing twice is harmless
+	_, _, release, err = c.Bundle(ctx, "team-1", url, "", "")
+	require.NoError(t, err)
+	release()
+}
+
+func TestAuthConfig_EncodesToken(t *testing.T) {
+	env := authConfig("s3cret")
+	require.Len(t, env, 3)
+	assert.NotContains(t, strings.Join(env, " "), "s3cret", "token is base64-encoded in the header")
+	assert.Nil(t, authConfig(""))
+}
@@ -0,0 +1,172 @@
+// Package launch prepares and records workflow runs. The runs API and schedules share
+// it so every run is resolved, validated and recorded the same way regardless of
+// what triggered it.
+package launch
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// TaskQueue is the Temporal task queue that workers poll for DAG workflows.
+const TaskQueue = "fleetlift"
+
+var (
+	// ErrWorkflowNotFound is returned when the requested template does not exist for the team.
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	// ErrInvalidDefinition is returned when the template's YAML cannot be parsed.
+	ErrInvalidDefinition = errors.New("invalid workflow definition")
+)
+
+// ValidationError reports that a workflow failed validation with the supplied parameters.
+type ValidationError struct {
+	Errors []workflow.ValidationError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, ve := range e.Errors {
+		msgs = append(msgs, ve.Error())
+	}
+	return "workflow validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Request describes a run to start.
+type Request struct {
+	RunID       string // optional; generated when empty
+	TeamID      string
+	WorkflowID  string // template slug
+	Parameters  map[string]any
+	Model       string
+	TriggeredBy string // user ID
+	ScheduleID  string // set when a schedule started the run
+	ParentRunID string // set when the run retries an earlier run
+	// WorkflowYAML replaces the template's current definition; retries set it to
+	// the definition the earlier run started with.
+	WorkflowYAML string
+}
+
+// Run is a prepared run: its identifiers and the input for DAGWorkflow.
+type Run struct {
+	ID            string
+	WorkflowID    string // template slug
+	TemporalID    string
+	WorkflowTitle string
+	Input         workflow.DAGInput
+	scheduleID    string
+	parentRunID   string
+	workflowYAML  string
+}
+
+// Prepare loads the workflow template, applies parameter defaults and validates the
+// workflow. It does not touch the database; the caller fills in
+// Input.DefaultMaxParallel and records the run with Insert.
+func Prepare(ctx context.Context, registry *template.Registry, req Request) (*Run, error) {
+	t, err := registry.Get(ctx, req.TeamID, req.WorkflowID)
+	if err != nil {
+		if errors.Is(err, template.ErrNotFound) {
+			return nil, ErrWorkflowNotFound
+		}
+		return nil, fmt.Errorf("load workflow %q: %w", req.WorkflowID, err)
+	}
+
+	body := t.YAMLBody
+	if req.WorkflowYAML != "" {
+		body = req.WorkflowYAML
+	}
+	var def model.WorkflowDef
+	if err := model.ParseWorkflowYAML([]byte(body), &def); err != nil {
+		return nil, ErrInvalidDefinition
+	}
+
+	// Apply defaults for optional parameters not supplied by the caller.
+	params := make(map[string]any, len(req.Parameters))
+	for k, v := range req.Parameters {
+		params[k] = v
+	}
+	for _, p := range def.Parameters {
+		if _, ok := params[p.Name]; !ok && p.Default != nil {
+			params[p.Name] = p.Default
+		}
+	}
+
+	if errs := workflow.ValidateWorkflow(def, params); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	runID := req.RunID
+	if runID == "" {
+		runID = uuid.New().String()
+	}
+	return &Run{
+		ID:            runID,
+		WorkflowID:    req.WorkflowID,
+		TemporalID:    fmt.Sprintf("fl-%s-%s", req.WorkflowID, runID[:8]),
+		WorkflowTitle: t.Title,
+		scheduleID:    req.ScheduleID,
+		parentRunID:   req.ParentRunID,
+		workflowYAML:  body,
+		Input: workflow.DAGInput{
+			RunID:              runID,
+			TeamID:             req.TeamID,
+			WorkflowTemplateID: t.ID,
+			WorkflowDef:        def,
+			Parameters:         params,
+			ModelOverride:      req.Model,
+			TriggeredBy:        req.TriggeredBy,
+		},
+	}, nil
+}
+
+// Insert records the run as pending. Inserting a run whose ID already exists is a
+// no-op, so callers retrying with a fixed RunID do not create duplicates. db may be
+// a transaction.
+func Insert(ctx context.Context, db sqlx.ExecerContext, run *Run) error {
+	params, err := json.Marshal(run.Input.Parameters)
+	if err != nil {
+		return fmt.Errorf("marshal parameters: %w", err)
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO runs (id, team_id, workflow_id, workflow_title, parameters, model, status, temporal_id, triggered_by, schedule_id, parent_run_id, workflow_yaml)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, NULLIF($10, '')::uuid, NULLIF($11, '')::uuid, $12)
+		 ON CONFLICT (id) DO NOTHING`,
+		run.ID, run.Input.TeamID, run.WorkflowID, run.WorkflowTitle,
+		params, run.Input.ModelOverride, string(model.RunStatusPending),
+		run.TemporalID, run.Input.TriggeredBy, run.scheduleID, run.parentRunID, run.workflowYAML)
+	return err
+}
+
+// DefaultMaxParallel resolves the fan-out concurrency applied to steps that do
+// not set max_parallel: the team's max_parallel setting, then the
+// FLEETLIFT_DEFAULT_MAX_PARALLEL env var, then workflow.DefaultFanOutMaxParallel.
+func DefaultMaxParallel(ctx context.Context, db *sqlx.DB, teamID string) int {
+	var teamMax sql.NullInt64
+	if err := db.GetContext(ctx, &teamMax, `SELECT max_parallel FROM teams WHERE id = $1`, teamID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("failed to look up team max_parallel", "team_id", teamID, "error", err)
+		}
+	} else if teamMax.Valid && teamMax.Int64 > 0 {
+		return int(teamMax.Int64)
+	}
+	if v := os.Getenv("FLEETLIFT_DEFAULT_MAX_PARALLEL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("ignoring invalid FLEETLIFT_DEFAULT_MAX_PARALLEL", "value", v)
+	}
+	return workflow.DefaultFanOutMaxParallel
+}
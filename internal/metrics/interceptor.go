@@ -7,6 +7,7 @@ import (
 	"go.temporal.io/sdk/interceptor"
 
 	internalactivity "github.com/tinkerloft/fleetlift/internal/activity"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
 )
 
 // Interceptor is a Temporal WorkerInterceptor that records Prometheus metrics for every activity.
@@ -46,6 +47,10 @@ func (a *activityInterceptor) ExecuteActivity(ctx context.Context, in *intercept
 		info := a.outbound.GetInfo(ctx)
 		name = info.ActivityType.Name
 	}
+	poolOutcome := func() string { return internalactivity.PoolMiss }
+	if name == internalactivity.ActivityProvisionSandbox {
+		ctx, poolOutcome = internalactivity.WithPoolOutcome(ctx)
+	}
 	start := time.Now()
 
 	result, err := a.ActivityInboundInterceptorBase.ExecuteActivity(ctx, in)
@@ -63,9 +68,13 @@ func (a *activityInterceptor) ExecuteActivity(ctx context.Context, in *intercept
 	if err == nil {
 		switch name {
 		case internalactivity.ActivityProvisionSandbox:
-			a.m.SandboxProvisionDuration.Observe(duration)
+			a.m.SandboxProvisionDuration.WithLabelValues(poolOutcome()).Observe(duration)
 		case internalactivity.ActivityCreatePullRequest:
 			a.m.PRsCreatedTotal.Inc()
+		case internalactivity.ActivityReapSandboxes:
+			if report, ok := result.(*workflow.ReapReport); ok && report != nil && !report.DryRun {
+				a.m.SandboxesReapedTotal.Add(float64(len(report.Reaped)))
+			}
 		}
 	}
 
@@ -8,7 +8,8 @@ type Metrics struct {
 	ActivityDuration         *prometheus.HistogramVec
 	ActivityTotal            *prometheus.CounterVec
 	PRsCreatedTotal          prometheus.Counter
-	SandboxProvisionDuration prometheus.Histogram
+	SandboxProvisionDuration *prometheus.HistogramVec
+	SandboxesReapedTotal     prometheus.Counter
 }
 
 // Register registers all metrics with the given registry and returns the Metrics instance.
@@ -19,6 +20,7 @@ func Register(reg prometheus.Registerer) error {
 		m.ActivityTotal,
 		m.PRsCreatedTotal,
 		m.SandboxProvisionDuration,
+		m.SandboxesReapedTotal,
 	}
 	for _, c := range collectors {
 		if err := reg.Register(c); err != nil {
@@ -35,6 +37,7 @@ func RegisterWith(reg prometheus.Registerer, m *Metrics) error {
 		m.ActivityTotal,
 		m.PRsCreatedTotal,
 		m.SandboxProvisionDuration,
+		m.SandboxesReapedTotal,
 	}
 	for _, c := range collectors {
 		if err := reg.Register(c); err != nil {
@@ -66,10 +69,17 @@ func New() *Metrics {
 			Name: "fleetlift_prs_created_total",
 			Help: "Total number of pull requests successfully created.",
 		}),
-		SandboxProvisionDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
-			Name:    "agentbox_sandbox_provision_seconds",
-			Help:    "Duration of sandbox provisioning in seconds.",
-			Buckets: []float64{1, 5, 10, 30, 60, 120, 300},
+		SandboxProvisionDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "agentbox_sandbox_provision_seconds",
+				Help:    "Duration of sandbox provisioning in seconds, by whether a warm pool sandbox was claimed.",
+				Buckets: []float64{1, 5, 10, 30, 60, 120, 300},
+			},
+			[]string{"pool"},
+		),
+		SandboxesReapedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "fleetlift_sandboxes_reaped_total",
+			Help: "Total number of orphaned sandboxes killed by the reaper.",
 		}),
 	}
 }
@@ -14,6 +14,7 @@ import (
 	"go.temporal.io/sdk/interceptor"
 
 	"github.com/tinkerloft/fleetlift/internal/metrics"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
 )
 
 func TestRegister(t *testing.T) {
@@ -24,6 +25,7 @@ func TestRegister(t *testing.T) {
 	// Seed vec metrics so they appear in Gather()
 	m.ActivityDuration.WithLabelValues("seed", "success").Observe(0)
 	m.ActivityTotal.WithLabelValues("seed", "success").Add(0)
+	m.SandboxProvisionDuration.WithLabelValues("miss").Observe(0)
 
 	mfs, err := reg.Gather()
 	require.NoError(t, err)
@@ -36,6 +38,7 @@ func TestRegister(t *testing.T) {
 	assert.True(t, names["agentbox_activity_total"])
 	assert.True(t, names["fleetlift_prs_created_total"])
 	assert.True(t, names["agentbox_sandbox_provision_seconds"])
+	assert.True(t, names["fleetlift_sandboxes_reaped_total"])
 }
 
 func TestInterceptor_RecordsSuccessMetrics(t *testing.T) {
@@ -92,6 +95,65 @@ func TestInterceptor_RecordsFailureMetrics(t *testing.T) {
 	assert.Equal(t, float64(1), total)
 }
 
+func TestInterceptor_LabelsProvisionDurationByPoolOutcome(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New()
+	require.NoError(t, metrics.RegisterWith(reg, m))
+
+	i := metrics.NewInterceptor(m)
+	fakeNext := &fakeActivityInterceptor{
+		fn: func(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+			return "sb-1", nil
+		},
+	}
+	actInterceptor := i.InterceptActivity(context.Background(), fakeNext)
+	require.NoError(t, actInterceptor.Init(&fakeActivityOutbound{activityName: "ProvisionSandbox"}))
+	_, err := actInterceptor.ExecuteActivity(context.Background(), &interceptor.ExecuteActivityInput{})
+	require.NoError(t, err)
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	var count uint64
+	for _, mf := range mfs {
+		if mf.GetName() != "agentbox_sandbox_provision_seconds" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			if matchLabels(metric, []string{"pool", "miss"}) {
+				count = metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	assert.Equal(t, uint64(1), count, "provisions without a pool claim are misses")
+}
+
+func TestInterceptor_CountsReapedSandboxes(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New()
+	require.NoError(t, metrics.RegisterWith(reg, m))
+
+	i := metrics.NewInterceptor(m)
+	for _, dryRun := range []bool{false, true} {
+		report := &workflow.ReapReport{
+			DryRun: dryRun,
+			Reaped: []workflow.ReapedSandbox{{SandboxID: "sb-1"}, {SandboxID: "sb-2"}},
+		}
+		fakeNext := &fakeActivityInterceptor{
+			fn: func(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+				return report, nil
+			},
+		}
+		actInterceptor := i.InterceptActivity(context.Background(), fakeNext)
+		require.NoError(t, actInterceptor.Init(&fakeActivityOutbound{activityName: "ReapSandboxes"}))
+		_, err := actInterceptor.ExecuteActivity(context.Background(), &interceptor.ExecuteActivityInput{})
+		require.NoError(t, err)
+	}
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), findCounter(mfs, "fleetlift_sandboxes_reaped_total"), "dry runs are not counted")
+}
+
 // --- helpers ---
 
 type fakeActivityInterceptor struct {
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// APIKey is a team-scoped service account key. Only the SHA-256 hash of the
+// key is stored; the raw value is returned once, at creation time.
+type APIKey struct {
+	ID         string     `db:"id" json:"id"`
+	TeamID     string     `db:"team_id" json:"team_id"`
+	Name       string     `db:"name" json:"name"`
+	KeyHash    string     `db:"key_hash" json:"-"`
+	Role       string     `db:"role" json:"role"` // "member" | "admin"
+	CreatedBy  *string    `db:"created_by" json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
@@ -59,8 +59,9 @@ type KnowledgeDef struct {
 
 // CaptureKnowledgeInput is the input for the CaptureKnowledge Temporal activity.
 type CaptureKnowledgeInput struct {
-	SandboxID          string `json:"sandbox_id"`
-	TeamID             string `json:"team_id"`
-	WorkflowTemplateID string `json:"workflow_template_id,omitempty"`
-	StepRunID          string `json:"step_run_id,omitempty"`
+	SandboxID          string   `json:"sandbox_id"`
+	TeamID             string   `json:"team_id"`
+	WorkflowTemplateID string   `json:"workflow_template_id,omitempty"`
+	StepRunID          string   `json:"step_run_id,omitempty"`
+	Tags               []string `json:"tags,omitempty"` // applied to every captured item
 }
@@ -27,5 +27,8 @@ type Run struct {
 	CompletedAt   *time.Time `db:"completed_at" json:"completed_at,omitempty"`
 	ErrorMessage  *string    `db:"error_message" json:"error_message,omitempty"`
 	TotalCostUSD  *float64   `db:"total_cost_usd" json:"total_cost_usd,omitempty"`
+	ScheduleID    *string    `db:"schedule_id" json:"schedule_id,omitempty"`
+	ParentRunID   *string    `db:"parent_run_id" json:"parent_run_id,omitempty"`
+	WorkflowYAML  *string    `db:"workflow_yaml" json:"-"` // definition the run started with
 	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
 }
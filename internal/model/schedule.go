@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Schedule starts runs of a workflow template on a cron schedule. Each schedule is
+// mirrored by a Temporal Schedule; parameters and model are read when it fires, so
+// edits apply to the next run without touching Temporal.
+type Schedule struct {
+	ID         string    `db:"id" json:"id"`
+	TeamID     string    `db:"team_id" json:"team_id"`
+	Name       string    `db:"name" json:"name"`
+	WorkflowID string    `db:"workflow_id" json:"workflow_id"`
+	Cron       string    `db:"cron" json:"cron"`
+	Timezone   string    `db:"timezone" json:"timezone"`
+	Parameters JSONMap   `db:"parameters" json:"parameters"`
+	Model      *string   `db:"model" json:"model,omitempty"`
+	Enabled    bool      `db:"enabled" json:"enabled"`
+	CreatedBy  *string   `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
@@ -26,6 +26,7 @@ type StepRun struct {
 	Output               JSONMap    `db:"output" json:"output,omitempty"`
 	Diff                 *string    `db:"diff" json:"diff,omitempty"`
 	PRUrl                *string    `db:"pr_url" json:"pr_url,omitempty"`
+	PRAction             *string    `db:"pr_action" json:"pr_action,omitempty"`
 	BranchName           *string    `db:"branch_name" json:"branch_name,omitempty"`
 	ErrorMessage         *string    `db:"error_message" json:"error_message,omitempty"`
 	TemporalWorkflowID   *string    `db:"temporal_workflow_id" json:"temporal_workflow_id,omitempty"`
@@ -35,6 +36,7 @@ type StepRun struct {
 	StartedAt            *time.Time `db:"started_at" json:"started_at,omitempty"`
 	CompletedAt          *time.Time `db:"completed_at" json:"completed_at,omitempty"`
 	CostUSD              *float64   `db:"cost_usd" json:"cost_usd,omitempty"`
+	TokensUsed           *int64     `db:"tokens_used" json:"tokens_used,omitempty"`
 	Input                JSONMap    `db:"input" json:"input,omitempty"`
 	CreatedAt            time.Time  `db:"created_at" json:"created_at"`
 }
@@ -56,9 +58,13 @@ type StepOutput struct {
 	Diff       string         `json:"diff,omitempty"`
 	PRUrl      string         `json:"pr_url,omitempty"`
 	BranchName string         `json:"branch_name,omitempty"`
-	Outputs    []StepOutput   `json:"outputs,omitempty"` // fan-out: per-repo results
+	Outputs    []StepOutput   `json:"outputs,omitempty"`     // fan-out: per-repo results
+	RepoURL    string         `json:"repo_url,omitempty"`    // fan-out: the repo this result is for
+	RepoBranch string         `json:"repo_branch,omitempty"` // fan-out: the repo entry's branch, when set
+	RepoRef    string         `json:"repo_ref,omitempty"`    // fan-out: the repo entry's ref, when set
 	Error      string         `json:"error,omitempty"`
 	CostUSD    float64        `json:"cost_usd,omitempty"`
+	SessionID  string         `json:"session_id,omitempty"` // agent session a steer or continuation can resume
 	// Fields used when Status == "awaiting_input"
 	InboxItemID      string `json:"inbox_item_id,omitempty"`
 	Question         string `json:"question,omitempty"`
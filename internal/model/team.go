@@ -3,10 +3,11 @@ package model
 import "time"
 
 type Team struct {
-	ID        string    `db:"id" json:"id"`
-	Name      string    `db:"name" json:"name"`
-	Slug      string    `db:"slug" json:"slug"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID          string    `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Slug        string    `db:"slug" json:"slug"`
+	MaxParallel *int      `db:"max_parallel" json:"max_parallel,omitempty"` // default fan-out concurrency for steps without max_parallel
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 }
 
 type TeamMember struct {
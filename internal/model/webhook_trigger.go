@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookTrigger starts a workflow run when a matching GitHub webhook event is
+// delivered to /webhooks/github/{id}. Parameters map workflow parameter names to Go
+// templates rendered against the event payload.
+type WebhookTrigger struct {
+	ID         string         `db:"id" json:"id"`
+	TeamID     string         `db:"team_id" json:"team_id"`
+	Name       string         `db:"name" json:"name"`
+	WorkflowID string         `db:"workflow_id" json:"workflow_id"`
+	Event      string         `db:"event" json:"event"`
+	Repo       string         `db:"repo" json:"repo"`
+	Actions    pq.StringArray `db:"actions" json:"actions"`
+	Parameters JSONMap        `db:"parameters" json:"parameters"`
+	Model      *string        `db:"model" json:"model,omitempty"`
+	Enabled    bool           `db:"enabled" json:"enabled"`
+	Secret     []byte         `db:"secret" json:"-"` // encrypted HMAC secret
+	CreatedBy  *string        `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// WebhookDelivery records a delivery accepted by a trigger.
+type WebhookDelivery struct {
+	TriggerID  string    `db:"trigger_id" json:"trigger_id"`
+	DeliveryID string    `db:"delivery_id" json:"delivery_id"`
+	Event      string    `db:"event" json:"event"`
+	RunID      *string   `db:"run_id" json:"run_id,omitempty"`
+	ReceivedAt time.Time `db:"received_at" json:"received_at"`
+}
@@ -46,6 +46,7 @@ type WorkflowDef struct {
 	Steps         []StepDef                  `yaml:"steps"`
 	AgentProfile  string                     `yaml:"agent_profile,omitempty" json:"agent_profile,omitempty"`
 	SandboxGroups map[string]SandboxGroupDef `yaml:"sandbox_groups,omitempty" json:"sandbox_groups,omitempty"`
+	Budget        *BudgetDef                 `yaml:"budget,omitempty" json:"budget,omitempty"` // caps the sum across all steps of a run
 }
 
 type ParameterDef struct {
@@ -64,11 +65,14 @@ type StepDef struct {
 	Mode              string          `yaml:"mode,omitempty"` // report | transform
 	Repositories      any             `yaml:"repositories,omitempty"`
 	MaxParallel       int             `yaml:"max_parallel,omitempty"`
+	FailureThreshold  string          `yaml:"failure_threshold,omitempty"` // absolute count ("2") or percentage ("10%") of fan-out repos
 	Execution         *ExecutionDef   `yaml:"execution,omitempty"`
 	ApprovalPolicy    string          `yaml:"approval_policy,omitempty"` // always|never|agent|on_changes
 	AllowMidExecPause bool            `yaml:"allow_mid_execution_pause,omitempty"`
 	PullRequest       *PRDef          `yaml:"pull_request,omitempty"`
+	Branch            string          `yaml:"branch,omitempty"` // working branch name template, rendered per repo
 	Condition         string          `yaml:"condition,omitempty"`
+	Filter            string          `yaml:"filter,omitempty"` // per-repo condition for fan-out steps
 	Optional          bool            `yaml:"optional,omitempty"`
 	Outputs           *StepOutputsDef `yaml:"outputs,omitempty"`
 	Inputs            *StepInputsDef  `yaml:"inputs,omitempty"`
@@ -76,6 +80,16 @@ type StepDef struct {
 	Sandbox           *SandboxSpec    `yaml:"sandbox,omitempty"`
 	Knowledge         *KnowledgeDef   `yaml:"knowledge,omitempty"`
 	Timeout           string          `yaml:"timeout,omitempty"`
+	Budget            *BudgetDef      `yaml:"budget,omitempty"`
+	// RetryOnWorkerRestart runs the agent detached so a retry after a worker
+	// restart reattaches to it instead of starting over.
+	RetryOnWorkerRestart bool `yaml:"retry_on_worker_restart,omitempty"`
+}
+
+// BudgetDef caps the agent spend of a step or a run. Zero fields are unlimited.
+type BudgetDef struct {
+	MaxUSD    float64 `yaml:"max_usd,omitempty" json:"max_usd,omitempty"`
+	MaxTokens int64   `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"` // input (including cached) plus output tokens
 }
 
 // SandboxSpec declares the infrastructure requirements for a step's sandbox.
@@ -98,7 +112,7 @@ type EgressPolicy struct {
 }
 
 type ExecutionDef struct {
-	Agent       string           `yaml:"agent"` // "claude-code" | "codex" | "shell"
+	Agent       string           `yaml:"agent"` // "claude-code" | "codex" | "gemini" | "shell"
 	Prompt      string           `yaml:"prompt"`
 	Verifiers   any              `yaml:"verifiers,omitempty"`
 	Credentials []string         `yaml:"credentials,omitempty"`
@@ -117,8 +131,17 @@ type PRDef struct {
 	Body         string   `yaml:"body,omitempty"`
 	Labels       []string `yaml:"labels,omitempty"`
 	Draft        bool     `yaml:"draft,omitempty"`
+	NotifyOnSkip bool     `yaml:"notify_on_skip,omitempty"` // raise an inbox notify item when the agent skips the PR
+	OnExisting   string   `yaml:"on_existing,omitempty"`    // update | skip | new; default: new
 }
 
+// What CreatePullRequest does when the step already has an open PR.
+const (
+	PROnExistingUpdate = "update" // push to the open PR's branch and refresh its title and body
+	PROnExistingSkip   = "skip"   // leave the open PR alone and record its URL
+	PROnExistingNew    = "new"    // always open a new PR
+)
+
 type ActionDef struct {
 	Type        string         `yaml:"type"`
 	Config      map[string]any `yaml:"config"`
@@ -148,7 +171,7 @@ type RepoRef struct {
 	Branch       string `yaml:"branch,omitempty"         json:"branch,omitempty"`
 	Ref          string `yaml:"ref,omitempty"             json:"ref,omitempty"` // git ref to fetch after clone (e.g. "pull/19/head")
 	Name         string `yaml:"name,omitempty"            json:"name,omitempty"`
-	CreateBranch string `yaml:"create_branch,omitempty"   json:"create_branch,omitempty"` // branch prefix — creates <prefix>/<runID> after clone
+	CreateBranch string `yaml:"create_branch,omitempty"   json:"create_branch,omitempty"` // branch prefix — the working branch is <prefix>/<runID>
 }
 
 func ParseWorkflowYAML(data []byte, def *WorkflowDef) error {
@@ -1,6 +1,9 @@
 package sandbox
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Client is the interface for sandbox operations (create, exec, file I/O, lifecycle).
 type Client interface {
@@ -13,6 +16,15 @@ type Client interface {
 	ReadBytes(ctx context.Context, id, path string) ([]byte, error)
 	Kill(ctx context.Context, id string) error
 	RenewExpiration(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Info, error) // live sandboxes created by FleetLift
+}
+
+// Info describes a live sandbox returned by List.
+type Info struct {
+	ID        string
+	Labels    map[string]string // CreateOpts.Labels; empty for sandboxes created before labelling
+	CreatedAt time.Time         // zero when the provider does not report it
+	Exited    bool              // stopped for good, but its resources are not yet deleted
 }
 
 // CreateOpts configures sandbox creation.
@@ -22,8 +34,21 @@ type CreateOpts struct {
 	TimeoutMins   int
 	Resources     *ResourceLimits // nil = provider defaults
 	NetworkPolicy *NetworkPolicy  // nil = no egress restrictions
+	Storage       string          // workspace disk size, Kubernetes-style ("20Gi"); "" = provider default
+	// Labels identify the sandbox's owner (run, step, team). Providers attach them
+	// where they can, for operators and garbage collection; they carry no behaviour.
+	Labels map[string]string
 }
 
+// Label keys set by ProvisionSandbox and the warm pool.
+const (
+	LabelRunID  = "run-id"
+	LabelStepID = "step-id"
+	LabelTeamID = "team-id"
+	// LabelPool marks sandboxes created by the warm pool; its value is the image.
+	LabelPool = "pool"
+)
+
 // ResourceLimits specifies CPU and memory for a sandbox container.
 type ResourceLimits struct {
 	CPU    string // Kubernetes-style, e.g. "1000m", "2"
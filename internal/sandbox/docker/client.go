@@ -0,0 +1,575 @@
+// Package docker implements sandbox.Client against a local Docker Engine, so
+// FleetLift can run on a laptop or in CI without an OpenSandbox deployment.
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+const (
+	// apiVersion is the Engine API version requested; 1.41 is Docker 20.10.
+	apiVersion = "v1.41"
+
+	// DefaultHost is used when neither the caller nor DOCKER_HOST names an engine.
+	DefaultHost = "unix:///var/run/docker.sock"
+
+	// LabelSandbox marks containers created by this client.
+	LabelSandbox = "io.fleetlift.sandbox"
+
+	// deadlineFile holds the Unix time at which the container's keep-alive loop exits.
+	// RenewExpiration rewrites it.
+	deadlineFile = "/tmp/.fleetlift-deadline"
+
+	// renewPeriod matches the two-hour extension OpenSandbox applies on renew.
+	renewPeriod = 2 * time.Hour
+)
+
+// keepAlive is the container's PID 1 (under docker-init). It exits once the deadline
+// passes, and AutoRemove then deletes the container, so sandboxes expire even if the
+// worker that created them is gone. $1 is the initial timeout in seconds.
+const keepAlive = `d=$(( $(date +%s) + $1 ))
+while :; do
+  [ -f ` + deadlineFile + ` ] && d=$(cat ` + deadlineFile + `)
+  [ "$(date +%s)" -lt "$d" ] || exit 0
+  sleep 10
+done`
+
+// Client implements sandbox.Client using the Docker Engine API. Each sandbox is a
+// container; Exec runs through exec instances and file I/O through archive copies.
+//
+// A command that exits non-zero makes Exec and ExecStream return an error after
+// its output has been delivered, as OpenSandbox does; verifiers and "test -x"
+// style probes rely on this.
+type Client struct {
+	base       string // http://docker for a unix socket, else http://host:port
+	http       *http.Client
+	streamHTTP *http.Client
+}
+
+// New creates a client for the engine at host, which may be unix:///path,
+// tcp://host:port or http://host:port. An empty host uses DOCKER_HOST, then
+// DefaultHost. TLS-protected engines are not supported.
+func New(host string) (*Client, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("docker: parse host %q: %w", host, err)
+	}
+
+	transport := &http.Transport{}
+	var base string
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		base = "http://docker"
+	case "tcp", "http":
+		base = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("docker: unsupported host scheme %q (want unix, tcp or http)", u.Scheme)
+	}
+
+	return &Client{
+		base:       base,
+		http:       &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		streamHTTP: &http.Client{Transport: transport}, // context-controlled for streaming and pulls
+	}, nil
+}
+
+// do sends a request to the Engine API. Responses outside ok are turned into errors
+// carrying the engine's message; the returned error wraps errNotFound on a 404.
+func (c *Client) do(ctx context.Context, hc *http.Client, method, p string, query url.Values, body io.Reader, contentType string, ok ...int) (*http.Response, error) {
+	u := c.base + "/" + apiVersion + p
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	var msg struct {
+		Message string `json:"message"`
+	}
+	detail := strings.TrimSpace(string(b))
+	if json.Unmarshal(b, &msg) == nil && msg.Message != "" {
+		detail = msg.Message
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", errNotFound, detail)
+	}
+	return nil, fmt.Errorf("returned %d: %s", resp.StatusCode, detail)
+}
+
+var errNotFound = errors.New("not found")
+
+// doJSON sends in as JSON and decodes the response into out (when non-nil).
+func (c *Client) doJSON(ctx context.Context, method, p string, query url.Values, in, out any, ok ...int) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, err := c.do(ctx, c.http, method, p, query, body, "application/json", ok...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) Create(ctx context.Context, opts sandbox.CreateOpts) (string, error) {
+	timeoutSecs := max(opts.TimeoutMins*60, 60)
+
+	env := make([]string, 0, len(opts.Env))
+	for k, v := range opts.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+
+	hostConfig := map[string]any{
+		"Init":       true, // reap zombies left by agents and git
+		"AutoRemove": true,
+	}
+	if opts.Resources != nil {
+		if opts.Resources.CPU != "" {
+			nano, err := parseCPU(opts.Resources.CPU)
+			if err != nil {
+				return "", fmt.Errorf("docker: %w", err)
+			}
+			hostConfig["NanoCpus"] = nano
+		}
+		if opts.Resources.Memory != "" {
+			mem, err := parseMemory(opts.Resources.Memory)
+			if err != nil {
+				return "", fmt.Errorf("docker: %w", err)
+			}
+			hostConfig["Memory"] = mem
+		}
+	}
+	if opts.Storage != "" {
+		// Docker's per-container size limit needs overlay2 on xfs with pquota,
+		// which local engines rarely have.
+		slog.Warn("docker sandbox: storage limit is not enforced", "image", opts.Image, "storage", opts.Storage)
+	}
+	if np := opts.NetworkPolicy; np != nil {
+		if denyAll(np) {
+			hostConfig["NetworkMode"] = "none"
+		} else {
+			// Docker has no per-destination egress filtering. Local use only, so
+			// run unrestricted rather than refuse the step.
+			slog.Warn("docker sandbox: egress allow-list is not enforced; network is unrestricted",
+				"image", opts.Image, "rules", len(np.Egress))
+		}
+	}
+
+	labels := map[string]string{LabelSandbox: "true"}
+	for k, v := range opts.Labels {
+		labels["io.fleetlift."+k] = v
+	}
+
+	body := map[string]any{
+		"Image":      opts.Image,
+		"Env":        env,
+		"Entrypoint": []string{"/bin/sh", "-c", keepAlive, "fleetlift-sandbox", strconv.Itoa(timeoutSecs)},
+		"Cmd":        []string{},
+		"Labels":     labels,
+		"HostConfig": hostConfig,
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err := c.doJSON(ctx, http.MethodPost, "/containers/create", nil, body, &created, http.StatusCreated)
+	if errors.Is(err, errNotFound) {
+		// The only 404 from create is a missing image.
+		if err := c.pull(ctx, opts.Image); err != nil {
+			return "", err
+		}
+		err = c.doJSON(ctx, http.MethodPost, "/containers/create", nil, body, &created, http.StatusCreated)
+	}
+	if err != nil {
+		return "", fmt.Errorf("docker: create: %w", err)
+	}
+	if created.ID == "" {
+		return "", fmt.Errorf("docker: create response missing container ID")
+	}
+
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil, nil,
+		http.StatusNoContent, http.StatusNotModified); err != nil {
+		_ = c.Kill(context.WithoutCancel(ctx), created.ID)
+		return "", fmt.Errorf("docker: start: %w", err)
+	}
+	return created.ID, nil
+}
+
+// denyAll reports whether the policy blocks all egress, which is the one policy
+// Docker can enforce (network mode "none").
+func denyAll(np *sandbox.NetworkPolicy) bool {
+	if np.DefaultAction != "deny" {
+		return false
+	}
+	for _, r := range np.Egress {
+		if r.Action == "allow" {
+			return false
+		}
+	}
+	return true
+}
+
+// pull fetches image from its registry. The engine streams JSON progress messages;
+// a failure arrives as a message with an "error" field, not as a status code.
+func (c *Client) pull(ctx context.Context, image string) error {
+	name, tag := splitImageRef(image)
+	q := url.Values{"fromImage": {name}, "tag": {tag}}
+	resp, err := c.do(ctx, c.streamHTTP, http.MethodPost, "/images/create", q, nil, "", http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("docker: pull %s: %w", image, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("docker: pull %s: %w", image, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("docker: pull %s: %s", image, msg.Error)
+		}
+	}
+}
+
+// splitImageRef splits an image reference into the fromImage and tag pull
+// parameters. A digest is passed as the tag; a missing tag means "latest".
+func splitImageRef(image string) (name, tag string) {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+func (c *Client) ExecStream(ctx context.Context, id, cmd, workDir string, onLine func(string)) error {
+	execBody := map[string]any{
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Tty":          false,
+		"Cmd":          []string{"/bin/sh", "-c", cmd},
+	}
+	if workDir != "" {
+		execBody["WorkingDir"] = workDir
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/"+id+"/exec", nil, execBody, &created, http.StatusCreated); err != nil {
+		return fmt.Errorf("docker: exec: %w", err)
+	}
+
+	start, _ := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	resp, err := c.do(ctx, c.streamHTTP, http.MethodPost, "/exec/"+created.ID+"/start", nil,
+		bytes.NewReader(start), "application/json", http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("docker: exec start: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := demux(resp.Body, onLine); err != nil {
+		return fmt.Errorf("docker: exec stream: %w", err)
+	}
+
+	var inspect struct {
+		ExitCode int  `json:"ExitCode"`
+		Running  bool `json:"Running"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/exec/"+created.ID+"/json", nil, nil, &inspect, http.StatusOK); err != nil {
+		return fmt.Errorf("docker: exec inspect: %w", err)
+	}
+	if !inspect.Running && inspect.ExitCode != 0 {
+		return fmt.Errorf("docker: command exited with code %d", inspect.ExitCode)
+	}
+	return nil
+}
+
+// demux reads the engine's multiplexed exec stream — frames of an 8-byte header
+// (stream type, 3 padding bytes, big-endian payload size) followed by the payload —
+// and emits one normalized {"stream":"...","content":"..."} message per output line,
+// without its trailing newline.
+func demux(r io.Reader, onLine func(string)) error {
+	pending := map[string]*bytes.Buffer{"stdout": {}, "stderr": {}}
+	emit := func(stream, content string) {
+		msg, _ := json.Marshal(map[string]string{"stream": stream, "content": content})
+		onLine(string(msg))
+	}
+
+	br := bufio.NewReader(r)
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return err
+		}
+		stream := "stdout"
+		if header[0] == 2 {
+			stream = "stderr"
+		}
+		buf := pending[stream]
+		if _, err := io.CopyN(buf, br, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return err
+		}
+		for {
+			i := bytes.IndexByte(buf.Bytes(), '\n')
+			if i < 0 {
+				break
+			}
+			emit(stream, string(buf.Next(i + 1)[:i]))
+		}
+	}
+	for _, stream := range []string{"stdout", "stderr"} {
+		if buf := pending[stream]; buf.Len() > 0 {
+			emit(stream, buf.String())
+		}
+	}
+	return nil
+}
+
+func (c *Client) Exec(ctx context.Context, id, cmd, workDir string) (string, string, error) {
+	var stdout, stderr strings.Builder
+	err := c.ExecStream(ctx, id, cmd, workDir, func(line string) {
+		var msg struct {
+			Stream  string `json:"stream"`
+			Content string `json:"content"`
+		}
+		_ = json.Unmarshal([]byte(line), &msg)
+		w := &stdout
+		if msg.Stream == "stderr" {
+			w = &stderr
+		}
+		w.WriteString(msg.Content)
+		w.WriteByte('\n')
+	})
+	return stdout.String(), stderr.String(), err
+}
+
+func (c *Client) WriteFile(ctx context.Context, id, p, content string) error {
+	return c.WriteBytes(ctx, id, p, []byte(content))
+}
+
+// WriteBytes copies data into the container as a single-file tar archive,
+// creating the parent directory first.
+func (c *Client) WriteBytes(ctx context.Context, id, p string, data []byte) error {
+	dir, name := path.Split(path.Clean(p))
+	if dir == "" {
+		dir = "/"
+	}
+	if _, stderr, err := c.Exec(ctx, id, "mkdir -p "+shellquote.Quote(dir), "/"); err != nil {
+		return err
+	} else if stderr != "" {
+		return fmt.Errorf("docker: create %s: %s", dir, strings.TrimSpace(stderr))
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("docker: write tar header: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("docker: write tar: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("docker: finalise tar: %w", err)
+	}
+
+	// copyUIDGID makes the file owned by the container's user rather than root.
+	q := url.Values{"path": {dir}, "copyUIDGID": {"1"}}
+	resp, err := c.do(ctx, c.http, http.MethodPut, "/containers/"+id+"/archive", q, &buf, "application/x-tar", http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("docker: write %s: %w", p, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (c *Client) ReadFile(ctx context.Context, id, p string) (string, error) {
+	b, err := c.ReadBytes(ctx, id, p)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ReadBytes copies a single file out of the container.
+func (c *Client) ReadBytes(ctx context.Context, id, p string) ([]byte, error) {
+	resp, err := c.do(ctx, c.http, http.MethodGet, "/containers/"+id+"/archive", url.Values{"path": {p}}, nil, "", http.StatusOK)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, fmt.Errorf("docker: file not found: %s", p)
+		}
+		return nil, fmt.Errorf("docker: read %s: %w", p, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	tr := tar.NewReader(resp.Body)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("docker: read %s: %w", p, err)
+	}
+	if hdr.Typeflag == tar.TypeDir {
+		return nil, fmt.Errorf("docker: read %s: is a directory", p)
+	}
+	return io.ReadAll(tr)
+}
+
+// Kill force-removes the container. A container that is already gone (for example
+// one that expired and was auto-removed) is not an error.
+func (c *Client) Kill(ctx context.Context, id string) error {
+	err := c.doJSON(ctx, http.MethodDelete, "/containers/"+id, url.Values{"force": {"true"}}, nil, nil,
+		http.StatusNoContent, http.StatusOK)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("docker: kill: %w", err)
+	}
+	return nil
+}
+
+// List returns the containers labelled as FleetLift sandboxes, including stopped
+// ones that have not been removed yet.
+func (c *Client) List(ctx context.Context) ([]sandbox.Info, error) {
+	filters, _ := json.Marshal(map[string][]string{"label": {LabelSandbox + "=true"}})
+	var containers []struct {
+		ID      string            `json:"Id"`
+		Created int64             `json:"Created"`
+		Labels  map[string]string `json:"Labels"`
+	}
+	q := url.Values{"all": {"true"}, "filters": {string(filters)}}
+	if err := c.doJSON(ctx, http.MethodGet, "/containers/json", q, nil, &containers, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("docker: list: %w", err)
+	}
+	out := make([]sandbox.Info, 0, len(containers))
+	for _, ctr := range containers {
+		labels := map[string]string{}
+		for k, v := range ctr.Labels {
+			if key, ok := strings.CutPrefix(k, "io.fleetlift."); ok && k != LabelSandbox {
+				labels[key] = v
+			}
+		}
+		out = append(out, sandbox.Info{ID: ctr.ID, Labels: labels, CreatedAt: time.Unix(ctr.Created, 0)})
+	}
+	return out, nil
+}
+
+// RenewExpiration moves the container's deadline two hours out.
+func (c *Client) RenewExpiration(ctx context.Context, id string) error {
+	deadline := time.Now().Add(renewPeriod).Unix()
+	cmd := fmt.Sprintf("echo %d > %s", deadline, deadlineFile)
+	if _, stderr, err := c.Exec(ctx, id, cmd, "/"); err != nil {
+		return fmt.Errorf("docker: renew: %w", err)
+	} else if stderr != "" {
+		return fmt.Errorf("docker: renew: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+// parseCPU converts a Kubernetes-style CPU quantity ("500m", "2", "1.5") to
+// Docker NanoCpus.
+func parseCPU(s string) (int64, error) {
+	if milli, ok := strings.CutSuffix(s, "m"); ok {
+		n, err := strconv.ParseInt(milli, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid cpu %q", s)
+		}
+		return n * 1_000_000, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("invalid cpu %q", s)
+	}
+	return int64(f * 1e9), nil
+}
+
+var memorySuffixes = []struct {
+	suffix string
+	mult   int64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+}
+
+// parseMemory converts a Kubernetes-style memory quantity ("512Mi", "2Gi", "1G",
+// "1048576") to bytes.
+func parseMemory(s string) (int64, error) {
+	mult := int64(1)
+	num := s
+	for _, m := range memorySuffixes {
+		if n, ok := strings.CutSuffix(s, m.suffix); ok {
+			num, mult = n, m.mult
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("invalid memory %q", s)
+	}
+	return int64(f * float64(mult)), nil
+}
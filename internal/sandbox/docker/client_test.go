@@ -0,0 +1,337 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+)
+
+// fakeEngine is a minimal Docker Engine API for exercising the client without a daemon.
+type fakeEngine struct {
+	mu          sync.Mutex
+	created     []map[string]any
+	pulls       []string
+	imageReady  bool
+	execCmds    [][]string
+	execOutput  []byte // multiplexed stream returned by every exec start
+	exitCode    int
+	archive     map[string][]byte
+	deleted     []string
+	listFilters string
+}
+
+func newFakeEngine(t *testing.T) (*fakeEngine, *Client) {
+	t.Helper()
+	e := &fakeEngine{imageReady: true, archive: map[string][]byte{}}
+	ts := httptest.NewServer(e)
+	t.Cleanup(ts.Close)
+	c, err := New(strings.Replace(ts.URL, "http://", "tcp://", 1))
+	require.NoError(t, err)
+	return e, c
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p := strings.TrimPrefix(r.URL.Path, "/"+apiVersion)
+	switch {
+	case r.Method == http.MethodPost && p == "/containers/create":
+		if !e.imageReady {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No such image"}`))
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		e.created = append(e.created, body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"c1"}`))
+	case r.Method == http.MethodPost && p == "/images/create":
+		e.pulls = append(e.pulls, r.URL.Query().Get("fromImage")+":"+r.URL.Query().Get("tag"))
+		e.imageReady = true
+		_, _ = w.Write([]byte(`{"status":"Pulling"}` + "\n" + `{"status":"Done"}`))
+	case r.Method == http.MethodPost && p == "/containers/c1/start":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && p == "/containers/c1/exec":
+		var body struct{ Cmd []string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		e.execCmds = append(e.execCmds, body.Cmd)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"e1"}`))
+	case r.Method == http.MethodPost && p == "/exec/e1/start":
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+		_, _ = w.Write(e.execOutput)
+	case r.Method == http.MethodGet && p == "/exec/e1/json":
+		_, _ = fmt.Fprintf(w, `{"ExitCode":%d,"Running":false}`, e.exitCode)
+	case r.Method == http.MethodPut && p == "/containers/c1/archive":
+		tr := tar.NewReader(r.Body)
+		hdr, err := tr.Next()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(tr)
+		e.archive[strings.TrimSuffix(r.URL.Query().Get("path"), "/")+"/"+hdr.Name] = data
+	case r.Method == http.MethodGet && p == "/containers/c1/archive":
+		data, ok := e.archive[r.URL.Query().Get("path")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Could not find the file"}`))
+			return
+		}
+		tw := tar.NewWriter(w)
+		_ = tw.WriteHeader(&tar.Header{Name: "f", Mode: 0o644, Size: int64(len(data))})
+		_, _ = tw.Write(data)
+		_ = tw.Close()
+	case r.Method == http.MethodGet && p == "/containers/json":
+		e.listFilters = r.URL.Query().Get("filters")
+		_, _ = w.Write([]byte(`[{"Id":"c1","Created":1700000000,"Labels":{"io.fleetlift.sandbox":"true","io.fleetlift.run-id":"run-1","other":"x"}}]`))
+	case r.Method == http.MethodDelete && strings.HasPrefix(p, "/containers/"):
+		id := strings.TrimPrefix(p, "/containers/")
+		if id != "c1" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No such container"}`))
+			return
+		}
+		e.deleted = append(e.deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// frame builds one multiplexed stream frame (1 = stdout, 2 = stderr).
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestCreate_MapsOpts(t *testing.T) {
+	e, c := newFakeEngine(t)
+
+	id, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image:         "ubuntu:22.04",
+		Env:           map[string]string{"B": "2", "A": "1"},
+		TimeoutMins:   5,
+		Resources:     &sandbox.ResourceLimits{CPU: "1500m", Memory: "2Gi"},
+		NetworkPolicy: &sandbox.NetworkPolicy{DefaultAction: "deny"},
+		Labels:        map[string]string{sandbox.LabelRunID: "run-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", id)
+
+	require.Len(t, e.created, 1)
+	body := e.created[0]
+	assert.Equal(t, "ubuntu:22.04", body["Image"])
+	assert.Equal(t, []any{"A=1", "B=2"}, body["Env"])
+	assert.Equal(t, "300", body["Entrypoint"].([]any)[4], "timeout is passed to the keep-alive loop in seconds")
+	assert.Equal(t, "true", body["Labels"].(map[string]any)[LabelSandbox])
+	assert.Equal(t, "run-1", body["Labels"].(map[string]any)["io.fleetlift.run-id"])
+
+	hc := body["HostConfig"].(map[string]any)
+	assert.Equal(t, float64(1_500_000_000), hc["NanoCpus"])
+	assert.Equal(t, float64(2<<30), hc["Memory"])
+	assert.Equal(t, "none", hc["NetworkMode"])
+	assert.Equal(t, true, hc["AutoRemove"])
+}
+
+func TestCreate_AllowListLeavesNetworkOpen(t *testing.T) {
+	e, c := newFakeEngine(t)
+
+	_, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image: "ubuntu:22.04",
+		NetworkPolicy: &sandbox.NetworkPolicy{
+			DefaultAction: "deny",
+			Egress:        []sandbox.NetworkRule{{Action: "allow", Target: "api.github.com"}},
+		},
+	})
+	require.NoError(t, err)
+	_, hasMode := e.created[0]["HostConfig"].(map[string]any)["NetworkMode"]
+	assert.False(t, hasMode)
+}
+
+func TestCreate_PullsMissingImage(t *testing.T) {
+	e, c := newFakeEngine(t)
+	e.imageReady = false
+
+	_, err := c.Create(context.Background(), sandbox.CreateOpts{Image: "ghcr.io/acme/agent"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ghcr.io/acme/agent:latest"}, e.pulls)
+	assert.Len(t, e.created, 1)
+}
+
+func TestCreate_RejectsBadResources(t *testing.T) {
+	_, c := newFakeEngine(t)
+	_, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image:     "ubuntu:22.04",
+		Resources: &sandbox.ResourceLimits{Memory: "lots"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid memory")
+}
+
+func TestExec_DemuxesStreams(t *testing.T) {
+	e, c := newFakeEngine(t)
+	var out []byte
+	out = append(out, frame(1, "hel")...)
+	out = append(out, frame(1, "lo\nwor")...)
+	out = append(out, frame(2, "oops\n")...)
+	out = append(out, frame(1, "ld")...)
+	e.execOutput = out
+
+	var lines []string
+	err := c.ExecStream(context.Background(), "c1", "echo hello", "/workspace", func(line string) {
+		lines = append(lines, line)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"content":"hello","stream":"stdout"}`,
+		`{"content":"oops","stream":"stderr"}`,
+		`{"content":"world","stream":"stdout"}`,
+	}, lines)
+	assert.Equal(t, []string{"/bin/sh", "-c", "echo hello"}, e.execCmds[0])
+
+	stdout, stderr, err := c.Exec(context.Background(), "c1", "echo hello", "")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\nworld\n", stdout)
+	assert.Equal(t, "oops\n", stderr)
+}
+
+func TestExec_NonZeroExitIsError(t *testing.T) {
+	e, c := newFakeEngine(t)
+	e.execOutput = frame(2, "sh: nope: not found\n")
+	e.exitCode = 127
+
+	_, stderr, err := c.Exec(context.Background(), "c1", "nope", "/")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited with code 127")
+	assert.Equal(t, "sh: nope: not found\n", stderr)
+}
+
+func TestWriteReadBytes(t *testing.T) {
+	e, c := newFakeEngine(t)
+	ctx := context.Background()
+
+	require.NoError(t, c.WriteBytes(ctx, "c1", "/workspace/out/result.json", []byte(`{"ok":true}`)))
+	assert.Equal(t, []string{"/bin/sh", "-c", "mkdir -p '/workspace/out/'"}, e.execCmds[0])
+
+	got, err := c.ReadBytes(ctx, "c1", "/workspace/out/result.json")
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"ok":true}`), got)
+
+	_, err = c.ReadFile(ctx, "c1", "/missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestKill_IgnoresMissingContainer(t *testing.T) {
+	e, c := newFakeEngine(t)
+	require.NoError(t, c.Kill(context.Background(), "c1"))
+	require.NoError(t, c.Kill(context.Background(), "gone"))
+	assert.Equal(t, []string{"c1"}, e.deleted)
+}
+
+func TestNew_Hosts(t *testing.T) {
+	for _, host := range []string{"unix:///var/run/docker.sock", "tcp://127.0.0.1:2375", "http://localhost:2375"} {
+		_, err := New(host)
+		assert.NoError(t, err, host)
+	}
+	_, err := New("ssh://user@host")
+	assert.Error(t, err)
+}
+
+func TestParseQuantities(t *testing.T) {
+	cpu := map[string]int64{"500m": 500_000_000, "2": 2_000_000_000, "0.5": 500_000_000}
+	for in, want := range cpu {
+		got, err := parseCPU(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	mem := map[string]int64{"512Mi": 512 << 20, "2Gi": 2 << 30, "1G": 1_000_000_000, "1048576": 1 << 20}
+	for in, want := range mem {
+		got, err := parseMemory(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, bad := range []string{"", "abc", "-1", "0"} {
+		_, err := parseCPU(bad)
+		assert.Error(t, err, bad)
+		_, err = parseMemory(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	cases := map[string][2]string{
+		"ubuntu":                       {"ubuntu", "latest"},
+		"ubuntu:22.04":                 {"ubuntu", "22.04"},
+		"localhost:5000/agent":         {"localhost:5000/agent", "latest"},
+		"localhost:5000/agent:v1":      {"localhost:5000/agent", "v1"},
+		"ghcr.io/acme/agent@sha256:ab": {"ghcr.io/acme/agent", "sha256:ab"},
+	}
+	for in, want := range cases {
+		name, tag := splitImageRef(in)
+		assert.Equal(t, want, [2]string{name, tag}, in)
+	}
+}
+
+// TestClientRoundTrip runs against a real engine when FLEETLIFT_TEST_DOCKER is set.
+func TestClientRoundTrip(t *testing.T) {
+	if os.Getenv("FLEETLIFT_TEST_DOCKER") == "" {
+		t.Skip("FLEETLIFT_TEST_DOCKER not set")
+	}
+	ctx := context.Background()
+	c, err := New("")
+	require.NoError(t, err)
+
+	id, err := c.Create(ctx, sandbox.CreateOpts{Image: "alpine:3.20", Env: map[string]string{"TEST": "1"}, TimeoutMins: 5})
+	require.NoError(t, err)
+	defer c.Kill(ctx, id) //nolint:errcheck
+
+	stdout, stderr, err := c.Exec(ctx, id, `echo "$TEST" && echo err >&2`, "/")
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", stdout)
+	assert.Equal(t, "err\n", stderr)
+
+	_, _, err = c.Exec(ctx, id, "exit 3", "/")
+	assert.ErrorContains(t, err, "exited with code 3")
+
+	require.NoError(t, c.WriteFile(ctx, id, "/workspace/a/b.txt", "content"))
+	got, err := c.ReadFile(ctx, id, "/workspace/a/b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "content", got)
+
+	require.NoError(t, c.RenewExpiration(ctx, id))
+	deadline, err := c.ReadFile(ctx, id, deadlineFile)
+	require.NoError(t, err)
+	assert.NotEmpty(t, bytes.TrimSpace([]byte(deadline)))
+}
+
+func TestList_ReturnsLabelledContainers(t *testing.T) {
+	e, c := newFakeEngine(t)
+
+	got, err := c.List(context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label":["io.fleetlift.sandbox=true"]}`, e.listFilters)
+	require.Len(t, got, 1)
+	assert.Equal(t, "c1", got[0].ID)
+	assert.Equal(t, map[string]string{"run-id": "run-1"}, got[0].Labels)
+	assert.Equal(t, int64(1700000000), got[0].CreatedAt.Unix())
+}
@@ -0,0 +1,564 @@
+// Package kubernetes implements sandbox.Client with one Pod per sandbox, so a
+// FleetLift worker running in a cluster can provision sandboxes without OpenSandbox.
+//
+// It uses client-go: typed clients for pods and network policies, and
+// remotecommand for the pods/exec subresource. The worker's service account
+// needs create, get, list and delete on pods, create on pods/exec, and create and
+// delete on networkpolicies in the sandbox namespace.
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+const (
+	// LabelManagedBy marks pods and network policies created by this client.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	managedByValue = "fleetlift"
+
+	// LabelSandbox holds the sandbox (pod) name; the sandbox's NetworkPolicy selects on it.
+	LabelSandbox = "fleetlift.io/sandbox"
+
+	// labelPrefix namespaces CreateOpts.Labels, e.g. fleetlift.io/run-id.
+	labelPrefix = "fleetlift.io/"
+
+	containerName = "sandbox"
+
+	// deadlineFile holds the Unix time at which the pod's keep-alive loop exits.
+	// RenewExpiration rewrites it.
+	deadlineFile = "/tmp/.fleetlift-deadline"
+
+	// renewPeriod matches the two-hour extension OpenSandbox applies on renew.
+	renewPeriod = 2 * time.Hour
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// keepAlive is the container's command. It exits once the deadline passes; the pod
+// then completes and stops consuming resources even if the worker that created it
+// is gone. The completed pod and its network policy remain until the sandbox
+// reaper deletes them. $1 is the initial timeout in seconds.
+const keepAlive = `d=$(( $(date +%s) + $1 ))
+while :; do
+  [ -f ` + deadlineFile + ` ] && d=$(cat ` + deadlineFile + `)
+  [ "$(date +%s)" -lt "$d" ] || exit 0
+  sleep 10
+done`
+
+// execFunc runs argv in the sandbox container of pod id, streaming its output.
+// A command that exits non-zero returns a utilexec.ExitError.
+type execFunc func(ctx context.Context, id string, argv []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+// Client implements sandbox.Client with Pods. The sandbox ID is the pod name.
+//
+// A command that exits non-zero makes Exec and ExecStream return an error after
+// its output has been delivered, as OpenSandbox does; verifiers and "test -x"
+// style probes rely on this.
+type Client struct {
+	clientset k8s.Interface
+	namespace string
+	exec      execFunc
+
+	// pollInterval is how often Create checks whether the pod is running.
+	pollInterval time.Duration
+	// startTimeout bounds the wait for a pod to start, including the image pull.
+	startTimeout time.Duration
+}
+
+// NewInCluster creates a client from the service account of the pod the worker
+// runs in. namespace overrides the service account's own namespace when non-empty.
+func NewInCluster(namespace string) (*Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: %w", err)
+	}
+	if namespace == "" {
+		ns, err := os.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: read service account namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+	clientset, err := k8s.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: %w", err)
+	}
+	return New(clientset, config, namespace)
+}
+
+// New creates a client that manages sandboxes in namespace. config is used to
+// open pods/exec streams; it is the config clientset was built from.
+func New(clientset k8s.Interface, config *rest.Config, namespace string) (*Client, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("kubernetes: namespace is required")
+	}
+	c := newClient(clientset, namespace)
+	c.exec = func(ctx context.Context, id string, argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		req := clientset.CoreV1().RESTClient().Post().
+			Namespace(namespace).Resource("pods").Name(id).SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: containerName,
+				Command:   argv,
+				Stdin:     stdin != nil,
+				Stdout:    true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+		// WebSocket first, SPDY for API servers that predate it, as kubectl does.
+		wsExec, err := remotecommand.NewWebSocketExecutor(config, "GET", req.URL().String())
+		if err != nil {
+			return err
+		}
+		spdyExec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+		if err != nil {
+			return err
+		}
+		executor, err := remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+			return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+		})
+		if err != nil {
+			return err
+		}
+		return executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+	}
+	return c, nil
+}
+
+func newClient(clientset k8s.Interface, namespace string) *Client {
+	return &Client{
+		clientset:    clientset,
+		namespace:    namespace,
+		pollInterval: time.Second,
+		startTimeout: 5 * time.Minute,
+	}
+}
+
+func (c *Client) Create(ctx context.Context, opts sandbox.CreateOpts) (string, error) {
+	timeoutSecs := max(opts.TimeoutMins*60, 60)
+	name := "fleetlift-sb-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	labels := podLabels(name, opts.Labels)
+
+	pod, err := podSpec(name, labels, opts, timeoutSecs)
+	if err != nil {
+		return "", err
+	}
+	np, err := networkPolicy(name, labels, opts.NetworkPolicy)
+	if err != nil {
+		return "", err
+	}
+	if np != nil {
+		// Created before the pod so the pod never runs unrestricted.
+		if _, err := c.clientset.NetworkingV1().NetworkPolicies(c.namespace).Create(ctx, np, metav1.CreateOptions{}); err != nil {
+			return "", fmt.Errorf("kubernetes: create network policy: %w", err)
+		}
+	}
+
+	if _, err := c.clientset.CoreV1().Pods(c.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		_ = c.Kill(context.WithoutCancel(ctx), name)
+		return "", fmt.Errorf("kubernetes: create pod: %w", err)
+	}
+
+	if err := c.waitRunning(ctx, name); err != nil {
+		_ = c.Kill(context.WithoutCancel(ctx), name)
+		return "", err
+	}
+	return name, nil
+}
+
+func podSpec(name string, labels map[string]string, opts sandbox.CreateOpts, timeoutSecs int) (*corev1.Pod, error) {
+	keys := make([]string, 0, len(opts.Env))
+	for k := range opts.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]corev1.EnvVar, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, corev1.EnvVar{Name: k, Value: opts.Env[k]})
+	}
+
+	container := corev1.Container{
+		Name:    containerName,
+		Image:   opts.Image,
+		Command: []string{"/bin/sh", "-c", keepAlive, "fleetlift-sandbox", strconv.Itoa(timeoutSecs)},
+		Env:     env,
+	}
+	// Requests equal limits, so the scheduler reserves what the step asked for.
+	q := corev1.ResourceList{}
+	add := func(res corev1.ResourceName, value string) error {
+		if value == "" {
+			return nil
+		}
+		qty, err := resource.ParseQuantity(value)
+		if err != nil {
+			return fmt.Errorf("kubernetes: invalid %s %q: %w", res, value, err)
+		}
+		q[res] = qty
+		return nil
+	}
+	if r := opts.Resources; r != nil {
+		if err := add(corev1.ResourceCPU, r.CPU); err != nil {
+			return nil, err
+		}
+		if err := add(corev1.ResourceMemory, r.Memory); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(corev1.ResourceEphemeralStorage, opts.Storage); err != nil {
+		return nil, err
+	}
+	if len(q) > 0 {
+		container.Resources = corev1.ResourceRequirements{Requests: q, Limits: q}
+	}
+
+	noToken, noLinks, grace := false, false, int64(5)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			// Sandboxes run untrusted agent code; keep the API server out of reach.
+			AutomountServiceAccountToken:  &noToken,
+			EnableServiceLinks:            &noLinks,
+			TerminationGracePeriodSeconds: &grace,
+			Containers:                    []corev1.Container{container},
+		},
+	}, nil
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// podLabels builds the pod's labels, coercing CreateOpts.Labels values to the
+// Kubernetes label value syntax (63 characters, alphanumeric at both ends).
+func podLabels(name string, extra map[string]string) map[string]string {
+	labels := map[string]string{LabelManagedBy: managedByValue, LabelSandbox: name}
+	for k, v := range extra {
+		v = invalidLabelChars.ReplaceAllString(v, "-")
+		if len(v) > 63 {
+			v = v[:63]
+		}
+		v = strings.Trim(v, "-_.")
+		if v != "" {
+			labels[labelPrefix+k] = v
+		}
+	}
+	return labels
+}
+
+// networkPolicy translates a sandbox egress policy into a Kubernetes NetworkPolicy
+// selecting only this sandbox's pod, or returns nil when no restriction applies.
+//
+// NetworkPolicy matches IP blocks, not hostnames, and the addresses behind a
+// hostname (GitHub, CDNs) change while a sandbox runs. Rather than freeze them at
+// creation, egress on this provider is CIDR-only: a hostname or wildcard target
+// fails the sandbox. With a deny default, DNS stays open. With an allow default,
+// deny rules become exceptions to an allow-all rule.
+func networkPolicy(name string, labels map[string]string, np *sandbox.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	if np == nil {
+		return nil, nil
+	}
+
+	action := "allow"
+	if np.DefaultAction != "deny" {
+		action = "deny"
+	}
+	var cidrs, hosts []string
+	for _, r := range np.Egress {
+		if r.Action != action {
+			continue
+		}
+		if cidr, ok := toCIDR(r.Target); ok {
+			cidrs = append(cidrs, cidr)
+		} else {
+			hosts = append(hosts, r.Target)
+		}
+	}
+	if len(hosts) > 0 {
+		return nil, fmt.Errorf("kubernetes: egress %s rules must be IP addresses or CIDR blocks, not hostnames: %s",
+			action, strings.Join(hosts, ", "))
+	}
+
+	var egress []networkingv1.NetworkPolicyEgressRule
+	if np.DefaultAction == "deny" {
+		udp, tcp := corev1.ProtocolUDP, corev1.ProtocolTCP
+		dns := intstr.FromInt32(53)
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}, {Protocol: &tcp, Port: &dns}},
+		})
+		if len(cidrs) > 0 {
+			to := make([]networkingv1.NetworkPolicyPeer, 0, len(cidrs))
+			for _, cidr := range cidrs {
+				to = append(to, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+			}
+			egress = append(egress, networkingv1.NetworkPolicyEgressRule{To: to})
+		}
+	} else {
+		if len(cidrs) == 0 {
+			return nil, nil // allow-all: no policy needed
+		}
+		v4 := &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}
+		v6 := &networkingv1.IPBlock{CIDR: "::/0"}
+		for _, cidr := range cidrs {
+			block := v4
+			if strings.Contains(cidr, ":") {
+				block = v6
+			}
+			block.Except = append(block.Except, cidr)
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{{IPBlock: v4}, {IPBlock: v6}},
+		})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{LabelSandbox: name}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}, nil
+}
+
+// toCIDR returns target as a CIDR if it is an IP address or CIDR block.
+func toCIDR(target string) (string, bool) {
+	if _, n, err := net.ParseCIDR(target); err == nil {
+		return n.String(), true
+	}
+	if ip := net.ParseIP(target); ip != nil {
+		if ip.To4() != nil {
+			return ip.String() + "/32", true
+		}
+		return ip.String() + "/128", true
+	}
+	return "", false
+}
+
+// waitRunning polls the pod until it is running, failing fast when it can never
+// start (terminated, unpullable image) and giving up after startTimeout.
+func (c *Client) waitRunning(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.startTimeout)
+	defer cancel()
+	for {
+		pod, err := c.clientset.CoreV1().Pods(c.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("kubernetes: get pod %s: %w", name, err)
+		}
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			return nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return fmt.Errorf("kubernetes: pod %s exited before it could be used (phase %s)", name, pod.Status.Phase)
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if w := cs.State.Waiting; w != nil {
+				switch w.Reason {
+				case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError", "CreateContainerError":
+					return fmt.Errorf("kubernetes: pod %s cannot start: %s: %s", name, w.Reason, w.Message)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("kubernetes: pod %s did not start: %w", name, ctx.Err())
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// run executes a shell command in the sandbox, in workDir when set.
+func (c *Client) run(ctx context.Context, id, cmd, workDir string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if workDir != "" {
+		// A separate line, so lists in cmd ("a; b", "a || b") all run in workDir.
+		cmd = "cd " + shellquote.Quote(workDir) + " || exit 1\n" + cmd
+	}
+	err := c.exec(ctx, id, []string{"/bin/sh", "-c", cmd}, stdin, stdout, stderr)
+	if err == nil {
+		return nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("kubernetes: command exited with code %d", exitErr.ExitStatus())
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("kubernetes: exec: %w", ctx.Err())
+	}
+	return fmt.Errorf("kubernetes: exec: %w", err)
+}
+
+// lineWriter turns one output stream of an exec into the JSON lines ExecStream
+// delivers. stdout and stderr are written from different goroutines, so the
+// writers of one exec share mu.
+type lineWriter struct {
+	mu     *sync.Mutex
+	stream string
+	onLine func(string)
+	buf    bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.buf.Next(i + 1)[:i]))
+	}
+	return len(p), nil
+}
+
+// flush delivers a final line that has no trailing newline.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.emit(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *lineWriter) emit(content string) {
+	msg, _ := json.Marshal(map[string]string{"stream": w.stream, "content": content})
+	w.onLine(string(msg))
+}
+
+func (c *Client) ExecStream(ctx context.Context, id, cmd, workDir string, onLine func(string)) error {
+	var mu sync.Mutex
+	stdout := &lineWriter{mu: &mu, stream: "stdout", onLine: onLine}
+	stderr := &lineWriter{mu: &mu, stream: "stderr", onLine: onLine}
+	err := c.run(ctx, id, cmd, workDir, nil, stdout, stderr)
+	stdout.flush()
+	stderr.flush()
+	return err
+}
+
+func (c *Client) Exec(ctx context.Context, id, cmd, workDir string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	err := c.run(ctx, id, cmd, workDir, nil, &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func (c *Client) WriteFile(ctx context.Context, id, p, content string) error {
+	return c.WriteBytes(ctx, id, p, []byte(content))
+}
+
+// WriteBytes streams data to the file over the exec's stdin.
+func (c *Client) WriteBytes(ctx context.Context, id, p string, data []byte) error {
+	cmd := "mkdir -p " + shellquote.Quote(path.Dir(path.Clean(p))) + " && cat > " + shellquote.Quote(p)
+	var stderr bytes.Buffer
+	if err := c.run(ctx, id, cmd, "", bytes.NewReader(data), io.Discard, &stderr); err != nil {
+		return fmt.Errorf("kubernetes: write %s: %w (%s)", p, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (c *Client) ReadFile(ctx context.Context, id, p string) (string, error) {
+	b, err := c.ReadBytes(ctx, id, p)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// errFileNotFound is the exit code ReadBytes uses for a missing file.
+const errFileNotFound = 44
+
+func (c *Client) ReadBytes(ctx context.Context, id, p string) ([]byte, error) {
+	q := shellquote.Quote(p)
+	var stdout, stderr bytes.Buffer
+	err := c.run(ctx, id, "test -f "+q+" || exit "+strconv.Itoa(errFileNotFound)+"; cat "+q, "", nil, &stdout, &stderr)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "exited with code "+strconv.Itoa(errFileNotFound)) {
+			return nil, fmt.Errorf("kubernetes: file not found: %s", p)
+		}
+		return nil, fmt.Errorf("kubernetes: read %s: %w (%s)", p, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Kill deletes the sandbox's pod and network policy. Objects that are already gone
+// are not an error.
+func (c *Client) Kill(ctx context.Context, id string) error {
+	grace := int64(0)
+	err := c.clientset.CoreV1().Pods(c.namespace).Delete(ctx, id, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("kubernetes: kill: %w", err)
+	}
+	err = c.clientset.NetworkingV1().NetworkPolicies(c.namespace).Delete(ctx, id, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("kubernetes: kill: delete network policy: %w", err)
+	}
+	return nil
+}
+
+// List returns the pods created by this client in the namespace, including pods
+// whose keep-alive loop has exited but which have not been deleted. Those are
+// marked Exited so the reaper deletes them and their network policies.
+func (c *Client) List(ctx context.Context) ([]sandbox.Info, error) {
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: LabelManagedBy + "=" + managedByValue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: list: %w", err)
+	}
+	out := make([]sandbox.Info, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		labels := map[string]string{}
+		for k, v := range pod.Labels {
+			if key, ok := strings.CutPrefix(k, labelPrefix); ok && k != LabelSandbox {
+				labels[key] = v
+			}
+		}
+		out = append(out, sandbox.Info{
+			ID:        pod.Name,
+			Labels:    labels,
+			CreatedAt: pod.CreationTimestamp.Time,
+			Exited:    pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed,
+		})
+	}
+	return out, nil
+}
+
+// RenewExpiration moves the pod's deadline two hours out.
+func (c *Client) RenewExpiration(ctx context.Context, id string) error {
+	deadline := time.Now().Add(renewPeriod).Unix()
+	cmd := fmt.Sprintf("echo %d > %s", deadline, deadlineFile)
+	if _, stderr, err := c.Exec(ctx, id, cmd, ""); err != nil {
+		return fmt.Errorf("kubernetes: renew: %w", err)
+	} else if stderr != "" {
+		return fmt.Errorf("kubernetes: renew: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	utilexec "k8s.io/client-go/util/exec"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+)
+
+var _ sandbox.Client = (*Client)(nil)
+
+// execResult is what the fake exec returns for one command.
+type execResult struct {
+	stdout, stderr string
+	exitCode       int
+}
+
+// fakeCluster is a client backed by the client-go fake clientset in namespace
+// "sandboxes", with pods/exec replaced by execHandler.
+type fakeCluster struct {
+	clientset *fake.Clientset
+
+	mu          sync.Mutex
+	phase       corev1.PodPhase // phase given to created pods
+	commands    []string
+	stdin       [][]byte
+	execHandler func(cmd string) execResult
+}
+
+func newFakeCluster(t *testing.T) (*fakeCluster, *Client) {
+	t.Helper()
+	f := &fakeCluster{
+		clientset:   fake.NewSimpleClientset(),
+		phase:       corev1.PodRunning,
+		execHandler: func(string) execResult { return execResult{} },
+	}
+	// The fake stores pods as created; let them start as the test asks.
+	f.clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Phase = f.phase
+		return false, nil, nil
+	})
+
+	c := newClient(f.clientset, "sandboxes")
+	c.pollInterval = 0
+	c.exec = func(_ context.Context, _ string, argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		require.Equal(t, []string{"/bin/sh", "-c"}, argv[:2])
+		var in []byte
+		if stdin != nil {
+			in, _ = io.ReadAll(stdin)
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, argv[2])
+		f.stdin = append(f.stdin, in)
+		handler := f.execHandler
+		f.mu.Unlock()
+
+		res := handler(argv[2])
+		_, _ = io.WriteString(stdout, res.stdout)
+		_, _ = io.WriteString(stderr, res.stderr)
+		if res.exitCode != 0 {
+			return utilexec.CodeExitError{Err: errors.New("command terminated with non-zero exit code"), Code: res.exitCode}
+		}
+		return nil
+	}
+	return f, c
+}
+
+func (f *fakeCluster) pod(t *testing.T, name string) *corev1.Pod {
+	t.Helper()
+	pod, err := f.clientset.CoreV1().Pods("sandboxes").Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return pod
+}
+
+func (f *fakeCluster) policy(t *testing.T, name string) *networkingv1.NetworkPolicy {
+	t.Helper()
+	np, err := f.clientset.NetworkingV1().NetworkPolicies("sandboxes").Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return np
+}
+
+func (f *fakeCluster) policies(t *testing.T) []networkingv1.NetworkPolicy {
+	t.Helper()
+	list, err := f.clientset.NetworkingV1().NetworkPolicies("sandboxes").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	return list.Items
+}
+
+func TestCreate_PodSpec(t *testing.T) {
+	f, c := newFakeCluster(t)
+
+	id, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image:       "ubuntu:22.04",
+		Env:         map[string]string{"B": "2", "A": "1"},
+		TimeoutMins: 5,
+		Resources:   &sandbox.ResourceLimits{CPU: "1500m", Memory: "2Gi"},
+		Storage:     "20Gi",
+		Labels:      map[string]string{sandbox.LabelRunID: "run-1", sandbox.LabelStepID: "fix tests!"},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(id, "fleetlift-sb-"))
+	assert.Empty(t, f.policies(t), "no network policy without egress rules")
+
+	pod := f.pod(t, id)
+	assert.Equal(t, "fleetlift", pod.Labels[LabelManagedBy])
+	assert.Equal(t, id, pod.Labels[LabelSandbox])
+	assert.Equal(t, "run-1", pod.Labels["fleetlift.io/run-id"])
+	assert.Equal(t, "fix-tests", pod.Labels["fleetlift.io/step-id"], "label values are coerced to the label syntax")
+
+	assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	require.NotNil(t, pod.Spec.AutomountServiceAccountToken)
+	assert.False(t, *pod.Spec.AutomountServiceAccountToken)
+	ctr := pod.Spec.Containers[0]
+	assert.Equal(t, "ubuntu:22.04", ctr.Image)
+	assert.Equal(t, "300", ctr.Command[4], "timeout is passed to the keep-alive loop in seconds")
+	assert.Equal(t, []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}, ctr.Env)
+	want := corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("1500m"),
+		corev1.ResourceMemory:           resource.MustParse("2Gi"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+	}
+	assert.Equal(t, want, ctr.Resources.Limits)
+	assert.Equal(t, want, ctr.Resources.Requests)
+}
+
+func TestCreate_InvalidQuantity(t *testing.T) {
+	f, c := newFakeCluster(t)
+
+	_, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image:     "ubuntu:22.04",
+		Resources: &sandbox.ResourceLimits{Memory: "two gigs"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid memory "two gigs"`)
+	assert.Empty(t, f.clientset.Actions(), "nothing is created")
+}
+
+func TestCreate_DenyDefaultNetworkPolicy(t *testing.T) {
+	f, c := newFakeCluster(t)
+
+	id, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image: "ubuntu:22.04",
+		NetworkPolicy: &sandbox.NetworkPolicy{
+			DefaultAction: "deny",
+			Egress: []sandbox.NetworkRule{
+				{Action: "allow", Target: "10.0.0.0/8"},
+				{Action: "allow", Target: "140.82.112.3"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	np := f.policy(t, id)
+	assert.Equal(t, map[string]string{LabelSandbox: id}, np.Spec.PodSelector.MatchLabels)
+	assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, np.Spec.PolicyTypes)
+
+	require.Len(t, np.Spec.Egress, 2)
+	assert.Len(t, np.Spec.Egress[0].Ports, 2, "DNS stays reachable")
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "140.82.112.3/32"}},
+	}, np.Spec.Egress[1].To)
+}
+
+func TestCreate_HostnameEgressIsRejected(t *testing.T) {
+	f, c := newFakeCluster(t)
+
+	_, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image: "ubuntu:22.04",
+		NetworkPolicy: &sandbox.NetworkPolicy{
+			DefaultAction: "deny",
+			Egress: []sandbox.NetworkRule{
+				{Action: "allow", Target: "10.0.0.0/8"},
+				{Action: "allow", Target: "github.com"},
+				{Action: "allow", Target: "*.example.com"},
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not hostnames: github.com, *.example.com")
+	assert.Empty(t, f.clientset.Actions(), "nothing is created")
+}
+
+func TestCreate_AllowDefaultDenyRulesBecomeExceptions(t *testing.T) {
+	f, c := newFakeCluster(t)
+
+	id, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image: "ubuntu:22.04",
+		NetworkPolicy: &sandbox.NetworkPolicy{
+			DefaultAction: "allow",
+			Egress:        []sandbox.NetworkRule{{Action: "deny", Target: "169.254.169.254"}},
+		},
+	})
+	require.NoError(t, err)
+
+	egress := f.policy(t, id).Spec.Egress
+	require.Len(t, egress, 1)
+	assert.Equal(t, []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: []string{"169.254.169.254/32"}}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+	}, egress[0].To)
+}
+
+func TestCreate_PodThatCannotStartIsCleanedUp(t *testing.T) {
+	f, c := newFakeCluster(t)
+	f.phase = corev1.PodFailed
+
+	_, err := c.Create(context.Background(), sandbox.CreateOpts{
+		Image:         "ubuntu:22.04",
+		NetworkPolicy: &sandbox.NetworkPolicy{DefaultAction: "deny"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "phase Failed")
+
+	pods, err := f.clientset.CoreV1().Pods("sandboxes").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, pods.Items)
+	assert.Empty(t, f.policies(t))
+}
+
+func TestExecStream_LinesAndExitCode(t *testing.T) {
+	f, c := newFakeCluster(t)
+	f.execHandler = func(string) execResult {
+		return execResult{stdout: "line one\nline two\npartial", stderr: "warn\n", exitCode: 3}
+	}
+
+	var stdout, stderr []string
+	err := c.ExecStream(context.Background(), "fleetlift-sb-1", "make test", "/workspace/repo", func(line string) {
+		var msg map[string]string
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		if msg["stream"] == "stderr" {
+			stderr = append(stderr, msg["content"])
+		} else {
+			stdout = append(stdout, msg["content"])
+		}
+	})
+	require.Error(t, err)
+	assert.Equal(t, "kubernetes: command exited with code 3", err.Error())
+	assert.Equal(t, []string{"line one", "line two", "partial"}, stdout)
+	assert.Equal(t, []string{"warn"}, stderr)
+	assert.Equal(t, []string{"cd '/workspace/repo' || exit 1\nmake test"}, f.commands)
+}
+
+func TestExec_Success(t *testing.T) {
+	f, c := newFakeCluster(t)
+	f.execHandler = func(string) execResult { return execResult{stdout: "ok\n"} }
+
+	stdout, stderr, err := c.Exec(context.Background(), "fleetlift-sb-1", "echo ok", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", stdout)
+	assert.Empty(t, stderr)
+	assert.Equal(t, []string{"echo ok"}, f.commands)
+}
+
+func TestWriteAndReadBytes(t *testing.T) {
+	f, c := newFakeCluster(t)
+	ctx := context.Background()
+
+	data := []byte("hello\x00world\n")
+	require.NoError(t, c.WriteBytes(ctx, "fleetlift-sb-1", "/workspace/a/b.bin", data))
+	assert.Equal(t, []string{"mkdir -p '/workspace/a' && cat > '/workspace/a/b.bin'"}, f.commands)
+	assert.Equal(t, data, f.stdin[0], "content travels over stdin")
+
+	f.execHandler = func(cmd string) execResult {
+		if strings.Contains(cmd, "missing") {
+			return execResult{exitCode: errFileNotFound}
+		}
+		return execResult{stdout: "hello\x00world"}
+	}
+	got, err := c.ReadBytes(ctx, "fleetlift-sb-1", "/workspace/out.bin")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello\x00world"), got)
+
+	_, err = c.ReadFile(ctx, "fleetlift-sb-1", "/workspace/missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestKill_DeletesPodAndPolicy(t *testing.T) {
+	f, c := newFakeCluster(t)
+	ctx := context.Background()
+
+	id, err := c.Create(ctx, sandbox.CreateOpts{Image: "ubuntu:22.04", NetworkPolicy: &sandbox.NetworkPolicy{DefaultAction: "deny"}})
+	require.NoError(t, err)
+
+	require.NoError(t, c.Kill(ctx, id))
+	_, err = f.clientset.CoreV1().Pods("sandboxes").Get(ctx, id, metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.Empty(t, f.policies(t))
+	require.NoError(t, c.Kill(ctx, id), "killing a sandbox that is already gone is not an error")
+}
+
+func TestList_ReturnsManagedPods(t *testing.T) {
+	f, c := newFakeCluster(t)
+	ctx := context.Background()
+
+	id, err := c.Create(ctx, sandbox.CreateOpts{Image: "ubuntu:22.04", Labels: map[string]string{sandbox.LabelRunID: "run-1"}})
+	require.NoError(t, err)
+	// A pod FleetLift did not create.
+	_, err = f.clientset.CoreV1().Pods("sandboxes").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"app": "other"}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	got, err := c.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, id, got[0].ID)
+	assert.Equal(t, map[string]string{"run-id": "run-1"}, got[0].Labels)
+}
+
+func TestList_MarksCompletedPodsExited(t *testing.T) {
+	f, c := newFakeCluster(t)
+	ctx := context.Background()
+
+	running, err := c.Create(ctx, sandbox.CreateOpts{Image: "ubuntu:22.04"})
+	require.NoError(t, err)
+	// A pod whose keep-alive deadline passed.
+	done := f.pod(t, running).DeepCopy()
+	done.Name, done.ResourceVersion = "fleetlift-sb-done", ""
+	f.phase = corev1.PodSucceeded
+	_, err = f.clientset.CoreV1().Pods("sandboxes").Create(ctx, done, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	got, err := c.List(ctx)
+	require.NoError(t, err)
+	exited := map[string]bool{}
+	for _, sb := range got {
+		exited[sb.ID] = sb.Exited
+	}
+	assert.Equal(t, map[string]bool{running: false, "fleetlift-sb-done": true}, exited)
+}
+
+func TestRenewExpiration(t *testing.T) {
+	f, c := newFakeCluster(t)
+	require.NoError(t, c.RenewExpiration(context.Background(), "fleetlift-sb-1"))
+	require.Len(t, f.commands, 1)
+	assert.Contains(t, f.commands[0], "> "+deadlineFile)
+}
+
+func TestNew_RequiresNamespace(t *testing.T) {
+	_, err := New(fake.NewSimpleClientset(), nil, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,321 @@
+// Package local implements sandbox.Client on the host: each sandbox is a temporary
+// directory and commands run with os/exec. It lets integration tests run real
+// clones, shell steps, verifiers and artifact collection without a sandbox service.
+//
+// It is not an isolation boundary. Commands run as the calling user with the host's
+// tools; only paths are redirected into the sandbox directory.
+package local
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+)
+
+// renewPeriod matches the two-hour extension OpenSandbox applies on renew.
+const renewPeriod = 2 * time.Hour
+
+// mappedRoots are the absolute directories sandbox commands use. Occurrences in
+// command text are rewritten to the sandbox's copy, so "cd /workspace/repo" works
+// without a real chroot.
+var mappedRoots = []string{"/workspace", "/tmp"}
+
+var mappedRootRe = regexp.MustCompile(`(^|[\s'"=:;|&<>(])(` +
+	strings.Join(quoteAll(mappedRoots), "|") + `)\b`)
+
+func quoteAll(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = regexp.QuoteMeta(s)
+	}
+	return out
+}
+
+// Client implements sandbox.Client with a directory per sandbox.
+type Client struct {
+	baseDir string
+
+	mu    sync.Mutex
+	boxes map[string]*box
+}
+
+type box struct {
+	root    string
+	env     []string
+	labels  map[string]string
+	created time.Time
+	ctx     context.Context // cancelled by Kill; stops running commands
+	cancel  context.CancelFunc
+	expiry  *time.Timer
+}
+
+// New creates a client that places sandbox directories under baseDir (the system
+// temp directory when empty).
+func New(baseDir string) *Client {
+	return &Client{baseDir: baseDir, boxes: map[string]*box{}}
+}
+
+// Root returns the host directory backing sandbox id, for tests that inspect or
+// seed its files directly.
+func (c *Client) Root(id string) (string, error) {
+	b, err := c.get(id)
+	if err != nil {
+		return "", err
+	}
+	return b.root, nil
+}
+
+func (c *Client) get(id string) (*box, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.boxes[id]
+	if !ok {
+		return nil, fmt.Errorf("local: sandbox %s not found", id)
+	}
+	return b, nil
+}
+
+// Create makes the sandbox directory with workspace, tmp and home subdirectories.
+// Image, resources and network policy are ignored: commands use the host's tools.
+func (c *Client) Create(_ context.Context, opts sandbox.CreateOpts) (string, error) {
+	root, err := os.MkdirTemp(c.baseDir, "fleetlift-sandbox-")
+	if err != nil {
+		return "", fmt.Errorf("local: create: %w", err)
+	}
+	for _, dir := range []string{"workspace", "tmp", "home"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			_ = os.RemoveAll(root)
+			return "", fmt.Errorf("local: create: %w", err)
+		}
+	}
+
+	// A minimal environment: the host PATH so tools resolve, a private HOME so
+	// "git config --global" stays inside the sandbox, then the caller's env.
+	env := map[string]string{
+		"PATH":                  os.Getenv("PATH"),
+		"HOME":                  filepath.Join(root, "home"),
+		"TMPDIR":                filepath.Join(root, "tmp"),
+		"GIT_CONFIG_NOSYSTEM":   "1",
+		"FLEETLIFT_SANDBOX_DIR": root,
+	}
+	for k, v := range opts.Env {
+		env[k] = v
+	}
+	envList := make([]string, 0, len(env))
+	for k, v := range env {
+		envList = append(envList, k+"="+v)
+	}
+	sort.Strings(envList)
+
+	id := "local-" + uuid.NewString()
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &box{root: root, env: envList, labels: opts.Labels, created: time.Now(), ctx: ctx, cancel: cancel}
+	timeout := time.Duration(max(opts.TimeoutMins, 1)) * time.Minute
+	b.expiry = time.AfterFunc(timeout, func() { _ = c.Kill(context.Background(), id) })
+
+	c.mu.Lock()
+	c.boxes[id] = b
+	c.mu.Unlock()
+	return id, nil
+}
+
+// hostPath maps an absolute sandbox path to its location under root.
+func (b *box) hostPath(p string) string {
+	if p == "" {
+		p = "/"
+	}
+	return filepath.Join(b.root, filepath.FromSlash(filepath.Clean("/"+p)))
+}
+
+// rewrite redirects the mapped roots in command text into the sandbox directory.
+func (b *box) rewrite(cmd string) string {
+	return mappedRootRe.ReplaceAllStringFunc(cmd, func(m string) string {
+		sub := mappedRootRe.FindStringSubmatch(m)
+		return sub[1] + filepath.Join(b.root, sub[2])
+	})
+}
+
+func (c *Client) ExecStream(ctx context.Context, id, cmd, workDir string, onLine func(string)) error {
+	b, err := c.get(id)
+	if err != nil {
+		return err
+	}
+	// Stop the command when either the caller gives up or the sandbox is killed.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(b.ctx, cancel)
+	defer stop()
+
+	dir := b.hostPath(workDir)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("local: exec: working directory %s: %w", workDir, err)
+	}
+
+	proc := exec.CommandContext(ctx, "/bin/sh", "-c", b.rewrite(cmd))
+	proc.Dir = dir
+	proc.Env = b.env
+	// Run in its own process group so cancellation also stops background children,
+	// and don't wait forever on output pipes something else still holds open.
+	proc.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	proc.Cancel = func() error { return syscall.Kill(-proc.Process.Pid, syscall.SIGKILL) }
+	proc.WaitDelay = 5 * time.Second
+
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	proc.Stdout = stdoutW
+	proc.Stderr = stderrW
+	if err := proc.Start(); err != nil {
+		return fmt.Errorf("local: exec: %w", err)
+	}
+
+	var mu sync.Mutex // onLine is called from both readers
+	var wg sync.WaitGroup
+	pump := func(stream string, r io.Reader) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			msg, _ := json.Marshal(map[string]string{"stream": stream, "content": scanner.Text()})
+			mu.Lock()
+			onLine(string(msg))
+			mu.Unlock()
+		}
+		_, _ = io.Copy(io.Discard, r) // keep draining past an over-long line
+	}
+	wg.Add(2)
+	go pump("stdout", stdoutR)
+	go pump("stderr", stderrR)
+
+	waitErr := proc.Wait()
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	wg.Wait()
+
+	if err := waitErr; err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return fmt.Errorf("local: command exited with code %d", exitErr.ExitCode())
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("local: exec: %w", ctx.Err())
+		}
+		return fmt.Errorf("local: exec: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Exec(ctx context.Context, id, cmd, workDir string) (string, string, error) {
+	var stdout, stderr strings.Builder
+	err := c.ExecStream(ctx, id, cmd, workDir, func(line string) {
+		var msg struct {
+			Stream  string `json:"stream"`
+			Content string `json:"content"`
+		}
+		_ = json.Unmarshal([]byte(line), &msg)
+		w := &stdout
+		if msg.Stream == "stderr" {
+			w = &stderr
+		}
+		w.WriteString(msg.Content)
+		w.WriteByte('\n')
+	})
+	return stdout.String(), stderr.String(), err
+}
+
+func (c *Client) WriteFile(ctx context.Context, id, path, content string) error {
+	return c.WriteBytes(ctx, id, path, []byte(content))
+}
+
+func (c *Client) WriteBytes(_ context.Context, id, path string, data []byte) error {
+	b, err := c.get(id)
+	if err != nil {
+		return err
+	}
+	dst := b.hostPath(path)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("local: write %s: %w", path, err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		return fmt.Errorf("local: write %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) ReadFile(ctx context.Context, id, path string) (string, error) {
+	data, err := c.ReadBytes(ctx, id, path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (c *Client) ReadBytes(_ context.Context, id, path string) ([]byte, error) {
+	b, err := c.get(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(b.hostPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("local: file not found: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("local: read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Kill stops the sandbox's running commands and deletes its directory. Killing an
+// unknown or already-killed sandbox is not an error.
+func (c *Client) Kill(_ context.Context, id string) error {
+	c.mu.Lock()
+	b, ok := c.boxes[id]
+	delete(c.boxes, id)
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	b.expiry.Stop()
+	b.cancel()
+	if err := os.RemoveAll(b.root); err != nil {
+		return fmt.Errorf("local: kill: %w", err)
+	}
+	return nil
+}
+
+// RenewExpiration pushes the sandbox's automatic Kill two hours out.
+func (c *Client) RenewExpiration(_ context.Context, id string) error {
+	b, err := c.get(id)
+	if err != nil {
+		return err
+	}
+	b.expiry.Reset(renewPeriod)
+	return nil
+}
+
+// List returns the sandboxes this client has created and not yet killed.
+func (c *Client) List(_ context.Context) ([]sandbox.Info, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]sandbox.Info, 0, len(c.boxes))
+	for id, b := range c.boxes {
+		out = append(out, sandbox.Info{ID: id, Labels: b.labels, CreatedAt: b.created})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
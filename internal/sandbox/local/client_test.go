@@ -0,0 +1,165 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+)
+
+var _ sandbox.Client = (*Client)(nil)
+
+func newSandbox(t *testing.T, opts sandbox.CreateOpts) (*Client, string) {
+	t.Helper()
+	c := New(t.TempDir())
+	id, err := c.Create(context.Background(), opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Kill(context.Background(), id) })
+	return c, id
+}
+
+func TestExecStream_StreamsLinesWithoutNewlines(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{})
+
+	var lines []map[string]string
+	err := c.ExecStream(context.Background(), id, "echo one; echo two >&2; echo three", "/", func(line string) {
+		var msg map[string]string
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		lines = append(lines, msg)
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr []string
+	for _, l := range lines {
+		if l["stream"] == "stderr" {
+			stderr = append(stderr, l["content"])
+		} else {
+			stdout = append(stdout, l["content"])
+		}
+	}
+	assert.Equal(t, []string{"one", "three"}, stdout)
+	assert.Equal(t, []string{"two"}, stderr)
+}
+
+func TestExec_NonZeroExitIsError(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{})
+
+	stdout, _, err := c.Exec(context.Background(), id, "echo partial; exit 3", "/")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited with code 3")
+	assert.Equal(t, "partial\n", stdout)
+}
+
+func TestExec_MapsWorkspaceAndTmpIntoSandbox(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{Env: map[string]string{"GREETING": "hi"}})
+	ctx := context.Background()
+
+	_, _, err := c.Exec(ctx, id, `mkdir -p /workspace/repo && echo "$GREETING" > /workspace/repo/out.txt && echo tmp>/tmp/t.txt`, "/")
+	require.NoError(t, err)
+
+	got, err := c.ReadFile(ctx, id, "/workspace/repo/out.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", got)
+
+	root, err := c.Root(id)
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(root, "tmp", "t.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "tmp\n", string(data))
+
+	// workDir is mapped too, and relative paths resolve against it.
+	stdout, _, err := c.Exec(ctx, id, "cat out.txt", "/workspace/repo")
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", stdout)
+}
+
+func TestRewrite_LeavesOtherPathsAlone(t *testing.T) {
+	b := &box{root: "/sb"}
+	cases := map[string]string{
+		"cd /workspace/repo":               "cd /sb/workspace/repo",
+		"git -C '/workspace/repo' status":  "git -C '/sb/workspace/repo' status",
+		"X=/tmp/a cmd":                     "X=/sb/tmp/a cmd",
+		"cat /etc/hosts /tmpfile":          "cat /etc/hosts /tmpfile",
+		"git clone file:///tmp/remote.git": "git clone file:///tmp/remote.git",
+		"/usr/bin/env":                     "/usr/bin/env",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, b.rewrite(in), in)
+	}
+}
+
+func TestWriteReadBytes(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{})
+	ctx := context.Background()
+
+	require.NoError(t, c.WriteBytes(ctx, id, "/workspace/a/b/c.bin", []byte{0, 1, 2}))
+	data, err := c.ReadBytes(ctx, id, "/workspace/a/b/c.bin")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, data)
+
+	// Traversal stays inside the sandbox root.
+	require.NoError(t, c.WriteFile(ctx, id, "/../../escape.txt", "x"))
+	root, _ := c.Root(id)
+	_, err = os.Stat(filepath.Join(root, "escape.txt"))
+	require.NoError(t, err)
+
+	_, err = c.ReadFile(ctx, id, "/workspace/missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestKill_StopsCommandsAndRemovesRoot(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{})
+	ctx := context.Background()
+	root, err := c.Root(id)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.ExecStream(ctx, id, "sleep 30", "/", func(string) {})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, c.Kill(ctx, id))
+	select {
+	case err := <-done:
+		require.Error(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("running command was not stopped by Kill")
+	}
+
+	_, err = os.Stat(root)
+	assert.True(t, os.IsNotExist(err))
+	_, _, err = c.Exec(ctx, id, "true", "/")
+	require.Error(t, err)
+	require.NoError(t, c.Kill(ctx, id), "killing twice is not an error")
+}
+
+func TestList(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{Labels: map[string]string{sandbox.LabelRunID: "run-1"}})
+	ctx := context.Background()
+
+	got, err := c.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, id, got[0].ID)
+	assert.Equal(t, "run-1", got[0].Labels[sandbox.LabelRunID])
+
+	require.NoError(t, c.Kill(ctx, id))
+	got, err = c.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestRenewExpiration(t *testing.T) {
+	c, id := newSandbox(t, sandbox.CreateOpts{TimeoutMins: 1})
+	require.NoError(t, c.RenewExpiration(context.Background(), id))
+	require.Error(t, c.RenewExpiration(context.Background(), "unknown"))
+}
@@ -65,3 +65,7 @@ func (m *MemoryClient) Kill(_ context.Context, _ string) error {
 func (m *MemoryClient) RenewExpiration(_ context.Context, _ string) error {
 	return nil
 }
+
+func (m *MemoryClient) List(_ context.Context) ([]Info, error) {
+	return nil, nil
+}
@@ -10,6 +10,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +18,15 @@ import (
 	"github.com/tinkerloft/fleetlift/internal/sandbox"
 )
 
+const (
+	// metadataPrefix namespaces CreateOpts.Labels in sandbox metadata.
+	metadataPrefix    = "fleetlift.io/"
+	metadataManagedBy = metadataPrefix + "managed-by"
+	managedByValue    = "fleetlift"
+
+	listPageSize = 100
+)
+
 // Client implements sandbox.Client using the OpenSandbox REST API.
 type Client struct {
 	domain     string
@@ -99,6 +109,9 @@ func (c *Client) Create(ctx context.Context, opts sandbox.CreateOpts) (string, e
 			rl["memory"] = opts.Resources.Memory
 		}
 	}
+	if opts.Storage != "" {
+		rl["ephemeral-storage"] = opts.Storage
+	}
 
 	body := map[string]any{
 		"image":          map[string]string{"uri": opts.Image},
@@ -108,6 +121,13 @@ func (c *Client) Create(ctx context.Context, opts sandbox.CreateOpts) (string, e
 		"entrypoint":     []string{"sleep", "infinity"},
 	}
 
+	// Metadata marks the sandbox as ours and records its owner, so List can find it.
+	metadata := map[string]string{metadataManagedBy: managedByValue}
+	for k, v := range opts.Labels {
+		metadata[metadataPrefix+k] = v
+	}
+	body["metadata"] = metadata
+
 	// Network policy: pass through to OpenSandbox egress sidecar.
 	// Field name "networkPolicy" matches OpenSandbox server v0.1.9 REST API.
 	if opts.NetworkPolicy != nil {
@@ -475,3 +495,66 @@ func (c *Client) RenewExpiration(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+// List returns the live sandboxes whose metadata marks them as created by
+// FleetLift, following the API's pagination.
+func (c *Client) List(ctx context.Context) ([]sandbox.Info, error) {
+	var out []sandbox.Info
+	for page := 1; ; page++ {
+		q := url.Values{
+			"metadata": {metadataManagedBy + "=" + managedByValue},
+			"page":     {strconv.Itoa(page)},
+			"pageSize": {strconv.Itoa(listPageSize)},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL()+"/v1/sandboxes?"+q.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("opensandbox: list request: %w", err)
+		}
+		c.setAuth(req)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("opensandbox: list: %w", err)
+		}
+		var result struct {
+			Items []struct {
+				ID       string            `json:"id"`
+				Metadata map[string]string `json:"metadata"`
+				Status   struct {
+					State string `json:"state"`
+				} `json:"status"`
+				CreatedAt time.Time `json:"createdAt"`
+			} `json:"items"`
+			Pagination struct {
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pagination"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("opensandbox: list returned %d: %s", resp.StatusCode, string(b))
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("opensandbox: decode list response: %w", err)
+		}
+
+		for _, item := range result.Items {
+			// Older servers ignore the metadata filter; check it here as well.
+			if item.Metadata[metadataManagedBy] != managedByValue || item.Status.State == "Terminated" {
+				continue
+			}
+			labels := map[string]string{}
+			for k, v := range item.Metadata {
+				if key, ok := strings.CutPrefix(k, metadataPrefix); ok && k != metadataManagedBy {
+					labels[key] = v
+				}
+			}
+			out = append(out, sandbox.Info{ID: item.ID, Labels: labels, CreatedAt: item.CreatedAt})
+		}
+		if !result.Pagination.HasNextPage || len(result.Items) == 0 {
+			return out, nil
+		}
+	}
+}
@@ -248,6 +248,7 @@ func TestCreate_CustomResources(t *testing.T) {
 		Image:       "test:latest",
 		TimeoutMins: 5,
 		Resources:   &sandbox.ResourceLimits{CPU: "2000m", Memory: "4Gi"},
+		Storage:     "20Gi",
 	})
 	require.NoError(t, err)
 
@@ -255,6 +256,7 @@ func TestCreate_CustomResources(t *testing.T) {
 	require.True(t, ok, "resourceLimits should be present in request body")
 	assert.Equal(t, "2000m", rl["cpu"])
 	assert.Equal(t, "4Gi", rl["memory"])
+	assert.Equal(t, "20Gi", rl["ephemeral-storage"])
 }
 
 func TestCreate_DefaultResources(t *testing.T) {
@@ -282,6 +284,8 @@ func TestCreate_DefaultResources(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, "1000m", rl["cpu"])
 	assert.Equal(t, "2Gi", rl["memory"])
+	_, hasStorage := rl["ephemeral-storage"]
+	assert.False(t, hasStorage, "storage is left to the provider default when not specified")
 
 	// networkPolicy should NOT be present when not specified
 	_, hasNP := capturedBody["networkPolicy"]
@@ -307,3 +311,55 @@ func TestCreate_EmptySandboxIDError(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "missing sandbox ID")
 }
+
+func TestCreate_RecordsLabelsAsMetadata(t *testing.T) {
+	var capturedBody map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&capturedBody)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":       "sb-1",
+			"metadata": map[string]string{"opensandbox.io/embedding-proxy-port": "12345"},
+		})
+	}))
+	defer ts.Close()
+
+	client := opensandbox.New(ts.URL, "test-key")
+	_, err := client.Create(context.Background(), sandbox.CreateOpts{
+		Image:  "test:latest",
+		Labels: map[string]string{sandbox.LabelRunID: "run-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"fleetlift.io/managed-by": "fleetlift",
+		"fleetlift.io/run-id":     "run-1",
+	}, capturedBody["metadata"])
+}
+
+func TestList_FollowsPagesAndFiltersForeignSandboxes(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/sandboxes", r.URL.Path)
+		queries = append(queries, r.URL.Query().Get("metadata")+" page="+r.URL.Query().Get("page"))
+		if r.URL.Query().Get("page") == "1" {
+			_, _ = w.Write([]byte(`{"items":[
+				{"id":"sb-1","metadata":{"fleetlift.io/managed-by":"fleetlift","fleetlift.io/run-id":"run-1"},"status":{"state":"Running"},"createdAt":"2026-01-02T03:04:05Z"},
+				{"id":"sb-foreign","metadata":{},"status":{"state":"Running"}}
+			],"pagination":{"hasNextPage":true}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[
+			{"id":"sb-2","metadata":{"fleetlift.io/managed-by":"fleetlift"},"status":{"state":"Paused"}},
+			{"id":"sb-gone","metadata":{"fleetlift.io/managed-by":"fleetlift"},"status":{"state":"Terminated"}}
+		],"pagination":{"hasNextPage":false}}`))
+	}))
+	defer ts.Close()
+
+	got, err := opensandbox.New(ts.URL, "test-key").List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fleetlift.io/managed-by=fleetlift page=1", "fleetlift.io/managed-by=fleetlift page=2"}, queries)
+	require.Len(t, got, 2)
+	assert.Equal(t, "sb-1", got[0].ID)
+	assert.Equal(t, map[string]string{"run-id": "run-1"}, got[0].Labels)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), got[0].CreatedAt)
+	assert.Equal(t, "sb-2", got[1].ID)
+}
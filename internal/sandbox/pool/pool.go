@@ -0,0 +1,255 @@
+// Package pool keeps pre-created sandboxes warm so steps skip container start-up.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/shellquote"
+)
+
+// EnvFile is where Claim writes a claimed sandbox's environment. Pool-created
+// sandboxes start without credentials, so Exec and ExecStream source this file
+// before every command in place of the provider's create-time env.
+const EnvFile = "/tmp/fleetlift-env.sh"
+
+// MaxIdle is how long a warm sandbox may sit unclaimed before the pool
+// replaces it. Older unclaimed pool sandboxes were left by a worker that exited.
+const MaxIdle = 30 * time.Minute
+
+const (
+	defaultTimeoutMins = 120
+	defaultRetryDelay  = 30 * time.Second
+)
+
+// sourceEnv is prepended on its own line so lists in the command are unaffected.
+const sourceEnv = "if [ -f " + EnvFile + " ]; then . " + EnvFile + "; fi\n"
+
+type warm struct {
+	id      string
+	created time.Time
+}
+
+// Pool wraps a sandbox.Client, keeping a configured number of idle sandboxes
+// per image. Call Start to begin filling it and Close on shutdown.
+type Pool struct {
+	sandbox.Client
+
+	sizes      map[string]int
+	maxIdle    time.Duration // idle sandboxes older than this are replaced
+	retryDelay time.Duration // wait after a failed create before trying again
+
+	mu   sync.Mutex
+	idle map[string][]warm    // image -> idle sandboxes, oldest first
+	wake map[string]chan bool // image -> refill signal
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a pool over inner holding sizes[image] idle sandboxes per image.
+func New(inner sandbox.Client, sizes map[string]int) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		Client:     inner,
+		sizes:      sizes,
+		maxIdle:    MaxIdle,
+		retryDelay: defaultRetryDelay,
+		idle:       make(map[string][]warm),
+		wake:       make(map[string]chan bool),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	for image := range sizes {
+		p.wake[image] = make(chan bool, 1)
+	}
+	return p
+}
+
+// ParseSizes parses a pool spec such as "claude-code-sandbox:latest=3,ubuntu:22.04=1".
+func ParseSizes(spec string) (map[string]int, error) {
+	sizes := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		image, n, ok := strings.Cut(entry, "=")
+		size, err := strconv.Atoi(strings.TrimSpace(n))
+		if !ok || strings.TrimSpace(image) == "" || err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid pool entry %q: want image=size", entry)
+		}
+		sizes[strings.TrimSpace(image)] = size
+	}
+	return sizes, nil
+}
+
+// Start fills the pool in the background, one goroutine per image.
+func (p *Pool) Start() {
+	for image, size := range p.sizes {
+		if size > 0 {
+			p.wg.Add(1)
+			go p.fill(image, size)
+		}
+	}
+}
+
+// Close stops refilling and kills the idle sandboxes.
+func (p *Pool) Close(ctx context.Context) {
+	p.cancel()
+	p.wg.Wait()
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = make(map[string][]warm)
+	p.mu.Unlock()
+	for _, boxes := range idle {
+		for _, w := range boxes {
+			_ = p.Client.Kill(ctx, w.id)
+		}
+	}
+}
+
+// Claim takes an idle sandbox for image and writes env into it. It returns
+// false when the pool has none ready; the caller then creates a sandbox itself.
+// The claimed sandbox's expiry is renewed, so it lasts as long as a fresh one.
+func (p *Pool) Claim(ctx context.Context, image string, env map[string]string) (string, bool) {
+	for {
+		w, ok := p.take(image)
+		if !ok {
+			return "", false
+		}
+		if err := p.prepare(ctx, w.id, env); err != nil {
+			slog.Warn("discarding pooled sandbox", "sandbox_id", w.id, "image", image, "error", err)
+			_ = p.Client.Kill(ctx, w.id)
+			continue
+		}
+		return w.id, true
+	}
+}
+
+// take pops the oldest idle sandbox for image that is still fresh, discarding stale ones.
+func (p *Pool) take(image string) (warm, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.signal(image)
+	for len(p.idle[image]) > 0 {
+		w := p.idle[image][0]
+		p.idle[image] = p.idle[image][1:]
+		if time.Since(w.created) < p.maxIdle {
+			return w, true
+		}
+		go func() { _ = p.Client.Kill(context.Background(), w.id) }()
+	}
+	return warm{}, false
+}
+
+func (p *Pool) prepare(ctx context.Context, id string, env map[string]string) error {
+	if err := p.Client.RenewExpiration(ctx, id); err != nil {
+		return fmt.Errorf("renew: %w", err)
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "export %s=%s\n", k, shellquote.Quote(env[k]))
+	}
+	if err := p.Client.WriteFile(ctx, id, EnvFile, b.String()); err != nil {
+		return fmt.Errorf("write env file: %w", err)
+	}
+	if _, _, err := p.Client.Exec(ctx, id, "chmod 600 "+EnvFile, "/"); err != nil {
+		return fmt.Errorf("restrict env file: %w", err)
+	}
+	return nil
+}
+
+// signal wakes the image's fill loop without blocking. Callers hold p.mu.
+func (p *Pool) signal(image string) {
+	select {
+	case p.wake[image] <- true:
+	default:
+	}
+}
+
+// fill keeps size fresh sandboxes idle for image until the pool is closed.
+func (p *Pool) fill(image string, size int) {
+	defer p.wg.Done()
+	for {
+		p.evictStale(image)
+		for p.idleCount(image) < size && p.ctx.Err() == nil {
+			id, err := p.Client.Create(p.ctx, sandbox.CreateOpts{
+				Image:       image,
+				TimeoutMins: defaultTimeoutMins,
+				Labels:      map[string]string{sandbox.LabelPool: image},
+			})
+			if err != nil {
+				if p.ctx.Err() == nil {
+					slog.Warn("failed to create pooled sandbox", "image", image, "error", err)
+				}
+				break
+			}
+			p.mu.Lock()
+			if p.ctx.Err() != nil {
+				p.mu.Unlock()
+				_ = p.Client.Kill(context.Background(), id)
+				return
+			}
+			p.idle[image] = append(p.idle[image], warm{id: id, created: time.Now()})
+			p.mu.Unlock()
+		}
+
+		wait := p.maxIdle / 2
+		if p.idleCount(image) < size {
+			wait = p.retryDelay
+		}
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.wake[image]:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (p *Pool) idleCount(image string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle[image])
+}
+
+// evictStale kills idle sandboxes that have sat unclaimed for maxIdle.
+func (p *Pool) evictStale(image string) {
+	p.mu.Lock()
+	var stale []warm
+	for len(p.idle[image]) > 0 && time.Since(p.idle[image][0].created) >= p.maxIdle {
+		stale = append(stale, p.idle[image][0])
+		p.idle[image] = p.idle[image][1:]
+	}
+	p.mu.Unlock()
+	for _, w := range stale {
+		_ = p.Client.Kill(p.ctx, w.id)
+	}
+}
+
+// ExecStream runs cmd after sourcing the sandbox's claimed environment, if any.
+func (p *Pool) ExecStream(ctx context.Context, id, cmd, workDir string, onLine func(string)) error {
+	return p.Client.ExecStream(ctx, id, sourceEnv+cmd, workDir, onLine)
+}
+
+// Exec runs cmd after sourcing the sandbox's claimed environment, if any.
+func (p *Pool) Exec(ctx context.Context, id, cmd, workDir string) (string, string, error) {
+	return p.Client.Exec(ctx, id, sourceEnv+cmd, workDir)
+}
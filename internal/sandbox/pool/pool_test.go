@@ -0,0 +1,85 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/sandbox"
+	"github.com/tinkerloft/fleetlift/internal/sandbox/local"
+)
+
+var _ sandbox.Client = (*Pool)(nil)
+
+func waitIdle(t *testing.T, p *Pool, image string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return p.idleCount(image) == n }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := ParseSizes("claude-code-sandbox:latest=3, ubuntu:22.04=1,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"claude-code-sandbox:latest": 3, "ubuntu:22.04": 1}, sizes)
+
+	for _, bad := range []string{"ubuntu", "ubuntu=x", "=2", "ubuntu=-1"} {
+		_, err := ParseSizes(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestClaim_InjectsEnvAndReplenishes(t *testing.T) {
+	inner := local.New(t.TempDir())
+	p := New(inner, map[string]int{"img": 2})
+	p.Start()
+	t.Cleanup(func() { p.Close(context.Background()) })
+	waitIdle(t, p, "img", 2)
+
+	ctx := context.Background()
+	id, ok := p.Claim(ctx, "img", map[string]string{"GITHUB_TOKEN": "tok en", "QUOTE": "it's"})
+	require.True(t, ok)
+	waitIdle(t, p, "img", 2)
+
+	stdout, _, err := p.Exec(ctx, id, `echo "$GITHUB_TOKEN|$QUOTE"; echo second`, "/")
+	require.NoError(t, err)
+	assert.Equal(t, "tok en|it's\nsecond\n", stdout)
+
+	// Sandboxes created outside the pool run commands unchanged.
+	other, err := p.Create(ctx, sandbox.CreateOpts{Env: map[string]string{"X": "1"}})
+	require.NoError(t, err)
+	stdout, _, err = p.Exec(ctx, other, `echo "$X$GITHUB_TOKEN"`, "/")
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", stdout)
+
+	_, ok = p.Claim(ctx, "other-image", nil)
+	assert.False(t, ok, "images without a pool are never hits")
+}
+
+func TestClaim_DiscardsStaleSandboxes(t *testing.T) {
+	inner := local.New(t.TempDir())
+	p := New(inner, map[string]int{"img": 1})
+	p.Start()
+	t.Cleanup(func() { p.Close(context.Background()) })
+	waitIdle(t, p, "img", 1)
+
+	p.mu.Lock()
+	p.idle["img"][0].created = time.Now().Add(-time.Hour)
+	p.mu.Unlock()
+
+	_, ok := p.Claim(context.Background(), "img", nil)
+	assert.False(t, ok)
+}
+
+func TestClose_KillsIdleSandboxes(t *testing.T) {
+	inner := local.New(t.TempDir())
+	p := New(inner, map[string]int{"img": 2})
+	p.Start()
+	waitIdle(t, p, "img", 2)
+
+	p.Close(context.Background())
+	live, err := inner.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, live)
+}
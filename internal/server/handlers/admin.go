@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/client"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/launch"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+// orphanScanTimeout bounds how long a dry-run reaper pass may take before the request gives up.
+const orphanScanTimeout = 2 * time.Minute
+
+// AdminHandler handles platform operator endpoints (admin only).
+type AdminHandler struct {
+	temporal client.Client
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(temporal client.Client) *AdminHandler {
+	return &AdminHandler{temporal: temporal}
+}
+
+// OrphanedSandboxes runs the sandbox reaper in dry-run mode and returns the
+// sandboxes it would kill. Only workers can reach the sandbox provider, so the
+// pass runs as a workflow on the worker task queue. Requires PlatformAdmin.
+func (h *AdminHandler) OrphanedSandboxes(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil || !claims.PlatformAdmin {
+		writeJSONError(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), orphanScanTimeout)
+	defer cancel()
+	run, err := h.temporal.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        workflow.SandboxReaperWorkflowID + "-dry-run-" + uuid.New().String(),
+		TaskQueue: launch.TaskQueue,
+	}, "SandboxReaperWorkflow", workflow.ReapInput{DryRun: true})
+	if err != nil {
+		slog.Error("failed to start sandbox reaper dry run", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to start sandbox scan")
+		return
+	}
+
+	var report workflow.ReapReport
+	if err := run.Get(ctx, &report); err != nil {
+		slog.Error("sandbox reaper dry run failed", "error", err, "workflow_id", run.GetID())
+		writeJSONError(w, http.StatusBadGateway, "sandbox scan failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/mocks"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+func TestOrphanedSandboxes_RequiresPlatformAdmin(t *testing.T) {
+	h := NewAdminHandler(mocks.NewClient(t))
+	w := httptest.NewRecorder()
+	h.OrphanedSandboxes(w, apiKeyReq("GET", "/api/admin/sandboxes/orphans", "", &auth.Claims{
+		UserID:    "user-1",
+		TeamRoles: map[string]string{"team-1": "admin"},
+	}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestOrphanedSandboxes_RunsReaperDryRun(t *testing.T) {
+	temporal := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	temporal.On("ExecuteWorkflow", mock.Anything, mock.Anything, "SandboxReaperWorkflow",
+		workflow.ReapInput{DryRun: true}).Return(run, nil)
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		report := args.Get(1).(*workflow.ReapReport)
+		*report = workflow.ReapReport{
+			DryRun: true,
+			Live:   3,
+			Reaped: []workflow.ReapedSandbox{{SandboxID: "sb-1", RunID: "run-1", RunStatus: "complete"}},
+		}
+	}).Return(nil)
+
+	h := NewAdminHandler(temporal)
+	w := httptest.NewRecorder()
+	h.OrphanedSandboxes(w, apiKeyReq("GET", "/api/admin/sandboxes/orphans", "", &auth.Claims{
+		UserID:        "user-1",
+		PlatformAdmin: true,
+	}))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp workflow.ReapReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, 3, resp.Live)
+	require.Len(t, resp.Reaped, 1)
+	assert.Equal(t, "sb-1", resp.Reaped[0].SandboxID)
+}
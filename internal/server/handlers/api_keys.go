@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+const maxAPIKeyNameLen = 100
+
+// APIKeysHandler handles team API key management endpoints.
+type APIKeysHandler struct {
+	db *sqlx.DB
+}
+
+// NewAPIKeysHandler creates a new APIKeysHandler.
+func NewAPIKeysHandler(db *sqlx.DB) *APIKeysHandler {
+	return &APIKeysHandler{db: db}
+}
+
+type createAPIKeyRequest struct {
+	Name      string `json:"name"`
+	Role      string `json:"role"`       // "member" (default) | "admin"
+	ExpiresIn string `json:"expires_in"` // Go duration, e.g. "720h"; empty = never expires
+}
+
+type createAPIKeyResponse struct {
+	model.APIKey
+	Key string `json:"key"` // raw key — only returned once
+}
+
+// requireTeamAdmin resolves the team for a key-management request and checks that
+// the caller is a team admin using a user session. API keys cannot manage API keys.
+// Returns an empty team ID and writes an error response on failure.
+func requireTeamAdmin(w http.ResponseWriter, r *http.Request) (*auth.Claims, string) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return nil, ""
+	}
+	if claims.APIKeyID != "" {
+		writeJSONError(w, http.StatusForbidden, "API keys cannot manage API keys")
+		return nil, ""
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return nil, "" // error already written
+	}
+	if claims.TeamRoles[teamID] != "admin" && !claims.PlatformAdmin {
+		writeJSONError(w, http.StatusForbidden, "team admin role required")
+		return nil, ""
+	}
+	return claims, teamID
+}
+
+// Create issues a new API key for the team. The raw key is only returned in this response.
+func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, teamID := requireTeamAdmin(w, r)
+	if teamID == "" {
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeJSONError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(req.Name) > maxAPIKeyNameLen {
+		writeJSONError(w, http.StatusBadRequest, "name must be 100 characters or fewer")
+		return
+	}
+	if req.Role == "" {
+		req.Role = "member"
+	}
+	if req.Role != "member" && req.Role != "admin" {
+		writeJSONError(w, http.StatusBadRequest, "role must be 'member' or 'admin'")
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "expires_in must be a positive duration (e.g. 720h)")
+			return
+		}
+		t := time.Now().Add(d).UTC()
+		expiresAt = &t
+	}
+
+	raw, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		slog.Error("failed to generate API key", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to generate API key")
+		return
+	}
+
+	var key model.APIKey
+	err = h.db.GetContext(r.Context(), &key,
+		`INSERT INTO api_keys (team_id, name, key_hash, role, created_by, expires_at)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6)
+		 RETURNING id, team_id, name, key_hash, role, created_by, expires_at, last_used_at, revoked_at, created_at`,
+		teamID, req.Name, hash, req.Role, claims.UserID, expiresAt)
+	if err != nil {
+		slog.Error("failed to create API key", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create API key")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createAPIKeyResponse{APIKey: key, Key: raw})
+}
+
+// List returns the team's active (non-revoked) API keys. Key values are never returned.
+func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
+	_, teamID := requireTeamAdmin(w, r)
+	if teamID == "" {
+		return
+	}
+
+	keys := make([]model.APIKey, 0)
+	err := h.db.SelectContext(r.Context(), &keys,
+		`SELECT id, team_id, name, key_hash, role, created_by, expires_at, last_used_at, revoked_at, created_at
+		 FROM api_keys WHERE team_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`,
+		teamID)
+	if err != nil {
+		slog.Error("failed to list API keys", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list API keys")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"items": keys})
+}
+
+// Revoke marks an API key as revoked. Revoked keys are rejected by the auth middleware
+// immediately; the row is kept for auditing.
+func (h *APIKeysHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	_, teamID := requireTeamAdmin(w, r)
+	if teamID == "" {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeJSONError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	result, err := h.db.ExecContext(r.Context(),
+		`UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND team_id = $2 AND revoked_at IS NULL`,
+		id, teamID)
+	if err != nil {
+		slog.Error("failed to revoke API key", "error", err, "team_id", teamID, "api_key_id", id)
+		writeJSONError(w, http.StatusInternalServerError, "failed to revoke API key")
+		return
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		slog.Error("failed to check API key revocation result", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to check revocation result")
+		return
+	}
+	if rows == 0 {
+		writeJSONError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+)
+
+func apiKeyReq(method, path, body string, claims *auth.Claims) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Team-ID", "team-1")
+	return req.WithContext(auth.SetClaimsInContext(req.Context(), claims))
+}
+
+func TestAPIKeys_RequiresTeamAdmin(t *testing.T) {
+	h := NewAPIKeysHandler(nil)
+	cases := []struct {
+		name   string
+		claims *auth.Claims
+		want   string
+	}{
+		{"member", &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "member"}}, "team admin role required"},
+		{"api key", &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "admin"}, APIKeyID: "key-1"}, "API keys cannot manage API keys"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Create(w, apiKeyReq("POST", "/api/api-keys", `{"name":"ci"}`, tc.claims))
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), tc.want)
+		})
+	}
+}
+
+func TestAPIKeys_Create_Validation(t *testing.T) {
+	h := NewAPIKeysHandler(nil)
+	admin := &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "admin"}}
+	cases := []struct {
+		name    string
+		body    string
+		wantMsg string
+	}{
+		{"empty name", `{"name":"  "}`, "name is required"},
+		{"long name", `{"name":"` + strings.Repeat("a", 101) + `"}`, "100 characters"},
+		{"bad role", `{"name":"ci","role":"owner"}`, "role must be"},
+		{"bad expiry", `{"name":"ci","expires_in":"30d"}`, "expires_in must be"},
+		{"negative expiry", `{"name":"ci","expires_in":"-1h"}`, "expires_in must be"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Create(w, apiKeyReq("POST", "/api/api-keys", tc.body, admin))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tc.wantMsg)
+		})
+	}
+}
+
+func TestAPIKeys_Create_ReturnsRawKeyOnce(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewAPIKeysHandler(sqlx.NewDb(sqlDB, "sqlmock"))
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO api_keys \(team_id, name, key_hash, role, created_by, expires_at\)`).
+		WithArgs("team-1", "ci", sqlmock.AnyArg(), "member", "user-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "team_id", "name", "key_hash", "role", "created_by", "expires_at", "last_used_at", "revoked_at", "created_at"}).
+			AddRow("key-1", "team-1", "ci", "hash", "member", "user-1", now.Add(time.Hour), nil, nil, now))
+
+	admin := &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "admin"}}
+	w := httptest.NewRecorder()
+	h.Create(w, apiKeyReq("POST", "/api/api-keys", `{"name":"ci","expires_in":"1h"}`, admin))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "key-1", resp["id"])
+	assert.True(t, strings.HasPrefix(resp["key"].(string), auth.APIKeyPrefix))
+	assert.NotContains(t, resp, "key_hash")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeys_Revoke_NotFound(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewAPIKeysHandler(sqlx.NewDb(sqlDB, "sqlmock"))
+
+	keyID := "5b0c4c9e-3f3b-4c8e-9a55-0f7f2d1a6b11"
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = now\(\) WHERE id = \$1 AND team_id = \$2`).
+		WithArgs(keyID, "team-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	r := chi.NewRouter()
+	r.Delete("/api/api-keys/{id}", h.Revoke)
+	admin := &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "admin"}}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, apiKeyReq("DELETE", "/api/api-keys/"+keyID, "", admin))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/launch"
 	"github.com/tinkerloft/fleetlift/internal/model"
 )
 
@@ -35,12 +37,6 @@ func mustJSON(v any) string {
 	return string(b)
 }
 
-// mustMarshal marshals v to JSON bytes, returning nil on error.
-func mustMarshal(v any) []byte {
-	b, _ := json.Marshal(v)
-	return b
-}
-
 // teamIDFromRequest extracts and validates the team ID from the request.
 // Accepts X-Team-ID header or ?team_id= query param.
 // Falls back to the sole team for single-team users.
@@ -84,3 +80,31 @@ func getRunForTeam(ctx context.Context, db *sqlx.DB, w http.ResponseWriter, runI
 	}
 	return &run
 }
+
+// writeLaunchError maps a launch.Prepare error to an HTTP response.
+func writeLaunchError(w http.ResponseWriter, err error, teamID, workflowID string) {
+	var verr *launch.ValidationError
+	switch {
+	case errors.Is(err, launch.ErrWorkflowNotFound):
+		writeJSONError(w, http.StatusNotFound, "workflow not found")
+	case errors.Is(err, launch.ErrInvalidDefinition):
+		writeJSONError(w, http.StatusBadRequest, "invalid workflow definition")
+	case errors.As(err, &verr):
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error":             "workflow validation failed",
+			"validation_errors": verr.Errors,
+		})
+	default:
+		slog.Error("failed to load workflow", "error", err, "team_id", teamID, "workflow_id", workflowID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load workflow")
+	}
+}
+
+// isOwnerOrTeamAdmin reports whether the caller created a team resource or is an
+// admin of its team.
+func isOwnerOrTeamAdmin(claims *auth.Claims, teamID string, createdBy *string) bool {
+	if claims.PlatformAdmin || claims.TeamRoles[teamID] == "admin" {
+		return true
+	}
+	return createdBy != nil && *createdBy == claims.UserID
+}
@@ -84,7 +84,8 @@ func (h *InboxHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Respond handles a human response to a request_input inbox item.
+// Respond handles a human response to a request_input or budget_exceeded inbox
+// item. Budget answers are picked up by the running step, which polls for them.
 // POST /api/inbox/{id}/respond
 func (h *InboxHandler) Respond(w http.ResponseWriter, r *http.Request) {
 	itemID := chi.URLParam(r, "id")
@@ -114,7 +115,14 @@ func (h *InboxHandler) Respond(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, http.StatusNotFound, "inbox item not found")
 		return
 	}
-	if item.Kind != "request_input" {
+	switch item.Kind {
+	case "request_input":
+	case "budget_exceeded":
+		if req.Answer != "extend" && req.Answer != "stop" {
+			writeJSONError(w, http.StatusBadRequest, `answer must be "extend" or "stop"`)
+			return
+		}
+	default:
 		writeJSONError(w, http.StatusBadRequest, "item is not a request_input")
 		return
 	}
@@ -136,7 +144,7 @@ func (h *InboxHandler) Respond(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Signal the Temporal workflow
-	if item.StepRunID != nil && h.temporalClient != nil {
+	if item.Kind == "request_input" && item.StepRunID != nil && h.temporalClient != nil {
 		var workflowID string
 		if err := h.db.QueryRowContext(r.Context(),
 			"SELECT temporal_workflow_id FROM step_runs WHERE id=$1", *item.StepRunID,
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
 	"github.com/tinkerloft/fleetlift/internal/auth"
 )
 
@@ -41,3 +45,59 @@ func TestInboxRespond_AnswerRequired(t *testing.T) {
 		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
 	}
 }
+
+type recordingSignaler struct{ signals []string }
+
+func (s *recordingSignaler) SignalWorkflow(_ context.Context, workflowID, _, signalName string, _ interface{}) error {
+	s.signals = append(s.signals, workflowID+":"+signalName)
+	return nil
+}
+
+func respondToBudgetItem(t *testing.T, answer string, expectStore bool) (int, *recordingSignaler) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	mock.ExpectQuery(`SELECT \* FROM inbox_items`).WillReturnRows(sqlmock.NewRows(
+		[]string{"id", "team_id", "run_id", "step_run_id", "kind", "title", "urgency", "created_at"}).
+		AddRow("item-1", "team-1", "run-1", "sr-1", "budget_exceeded", "Budget nearly spent: fix", "high", time.Now()))
+	if expectStore {
+		mock.ExpectExec(`UPDATE inbox_items SET answer`).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	sig := &recordingSignaler{}
+	h := NewInboxHandler(sqlx.NewDb(db, "sqlmock"), sig)
+	req := httptest.NewRequest(http.MethodPost, "/api/inbox/item-1/respond", strings.NewReader(`{"answer":"`+answer+`"}`))
+	req = req.WithContext(auth.SetClaimsInContext(req.Context(), &auth.Claims{
+		UserID:    "user-1",
+		TeamRoles: map[string]string{"team-1": "member"},
+	}))
+	req.Header.Set("X-Team-ID", "team-1")
+	w := httptest.NewRecorder()
+	r := chi.NewRouter()
+	r.Post("/api/inbox/{id}/respond", h.Respond)
+	r.ServeHTTP(w, req)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, sig
+}
+
+func TestInboxRespond_BudgetAnswerIsStoredWithoutSignal(t *testing.T) {
+	code, sig := respondToBudgetItem(t, "extend", true)
+	if code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if len(sig.signals) != 0 {
+		t.Fatalf("budget answers are polled by the step, not signalled; got %v", sig.signals)
+	}
+}
+
+func TestInboxRespond_BudgetAnswerMustBeExtendOrStop(t *testing.T) {
+	code, _ := respondToBudgetItem(t, "maybe", false)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", code)
+	}
+}
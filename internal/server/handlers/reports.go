@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"errors"
+	"io"
 	"log/slog"
 	"mime"
 	"net/http"
@@ -14,18 +15,21 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/tinkerloft/fleetlift/internal/artifact"
 	"github.com/tinkerloft/fleetlift/internal/auth"
 	"github.com/tinkerloft/fleetlift/internal/model"
 )
 
 // ReportsHandler handles report-mode workflow output endpoints.
 type ReportsHandler struct {
-	db *sqlx.DB
+	db        *sqlx.DB
+	artifacts *artifact.Storage // nil = inline artifacts only
 }
 
-// NewReportsHandler creates a new ReportsHandler.
-func NewReportsHandler(db *sqlx.DB) *ReportsHandler {
-	return &ReportsHandler{db: db}
+// NewReportsHandler creates a new ReportsHandler. artifacts may be nil when no
+// artifact object store is configured.
+func NewReportsHandler(db *sqlx.DB, artifacts *artifact.Storage) *ReportsHandler {
+	return &ReportsHandler{db: db, artifacts: artifacts}
 }
 
 // List returns runs that produced report output for the user's team.
@@ -183,7 +187,8 @@ func (h *ReportsHandler) Artifacts(w http.ResponseWriter, r *http.Request) {
 // ArtifactContent serves the raw bytes of a single artifact identified by {id}.
 // Auth: JWT + team ownership verified via step_runs → runs → team_id.
 // Query param: ?download=1 switches Content-Disposition to attachment.
-// Storage "object_store" returns 501 Not Implemented.
+// Inline artifacts are served from the row; object_store artifacts are streamed
+// from the configured object store (501 if none is configured).
 func (h *ReportsHandler) ArtifactContent(w http.ResponseWriter, r *http.Request) {
 	claims := auth.ClaimsFromContext(r.Context())
 	if claims == nil {
@@ -196,8 +201,8 @@ func (h *ReportsHandler) ArtifactContent(w http.ResponseWriter, r *http.Request)
 	}
 	artifactID := chi.URLParam(r, "id")
 
-	var artifact model.Artifact
-	err := h.db.GetContext(r.Context(), &artifact,
+	var art model.Artifact
+	err := h.db.GetContext(r.Context(), &art,
 		`SELECT a.id, a.step_run_id, a.name, a.path, a.size_bytes, a.content_type, a.storage, a.data, a.object_key, a.created_at
 		 FROM artifacts a
 		 JOIN step_runs s ON a.step_run_id = s.id
@@ -214,16 +219,31 @@ func (h *ReportsHandler) ArtifactContent(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if artifact.Storage == "object_store" {
-		writeJSONError(w, http.StatusNotImplemented, "object_store artifacts are not yet supported")
-		return
+	size := art.SizeBytes
+	if art.Storage != artifact.StorageObjectStore {
+		const maxArtifactSize = 50 * 1024 * 1024 // 50MB
+		if int64(len(art.Data)) > maxArtifactSize {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "artifact too large to serve inline")
+			return
+		}
+		size = int64(len(art.Data))
 	}
 
-	const maxArtifactSize = 50 * 1024 * 1024 // 50MB
-	if int64(len(artifact.Data)) > maxArtifactSize {
-		writeJSONError(w, http.StatusRequestEntityTooLarge, "artifact too large to serve inline")
+	body, err := h.artifacts.Open(r.Context(), &art)
+	if err != nil {
+		switch {
+		case errors.Is(err, artifact.ErrNoObjectStore):
+			writeJSONError(w, http.StatusNotImplemented, "no artifact object store is configured")
+		case errors.Is(err, artifact.ErrNotFound):
+			slog.Error("artifact object missing", "artifact_id", art.ID)
+			writeJSONError(w, http.StatusNotFound, "artifact content not found")
+		default:
+			slog.Error("failed to open artifact", "error", err, "artifact_id", art.ID)
+			writeJSONError(w, http.StatusBadGateway, "failed to read artifact content")
+		}
 		return
 	}
+	defer func() { _ = body.Close() }()
 
 	disposition := "inline"
 	if r.URL.Query().Get("download") == "1" {
@@ -232,7 +252,7 @@ func (h *ReportsHandler) ArtifactContent(w http.ResponseWriter, r *http.Request)
 
 	// Allowlist safe content types to prevent stored XSS if content_type is
 	// ever influenced by user/agent input in the future.
-	contentType := artifact.ContentType
+	contentType := art.ContentType
 	switch {
 	case contentType == "":
 		contentType = "application/octet-stream"
@@ -241,11 +261,13 @@ func (h *ReportsHandler) ArtifactContent(w http.ResponseWriter, r *http.Request)
 		contentType = "application/octet-stream"
 	}
 
-	params := map[string]string{"filename": artifact.Name}
+	params := map[string]string{"filename": art.Name}
 	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, params))
-	w.Header().Set("Content-Length", strconv.Itoa(len(artifact.Data)))
-	_, _ = w.Write(artifact.Data)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	if _, err := io.Copy(w, body); err != nil {
+		slog.Warn("artifact stream interrupted", "error", err, "artifact_id", art.ID)
+	}
 }
 
 const markdownReportTmpl = `# Report: {{.Run.WorkflowTitle}}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -11,10 +13,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/artifact"
 )
 
 func TestArtifacts_RequiresAuth(t *testing.T) {
-	h := NewReportsHandler(nil)
+	h := NewReportsHandler(nil, nil)
 	r := chi.NewRouter()
 	r.Get("/api/reports/{runID}/artifacts", h.Artifacts)
 
@@ -26,7 +31,7 @@ func TestArtifacts_RequiresAuth(t *testing.T) {
 }
 
 func TestArtifactContent_RequiresAuth(t *testing.T) {
-	h := NewReportsHandler(nil)
+	h := NewReportsHandler(nil, nil)
 	r := chi.NewRouter()
 	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
 
@@ -53,7 +58,7 @@ func TestArtifactContent_NotFound(t *testing.T) {
 		WithArgs("nonexistent-id", "team-1").
 		WillReturnError(sql.ErrNoRows)
 
-	h := NewReportsHandler(sqlxDB)
+	h := NewReportsHandler(sqlxDB, nil)
 	r := chi.NewRouter()
 	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
 
@@ -74,7 +79,7 @@ func TestArtifactContent_CrossTeamIsolation(t *testing.T) {
 		WithArgs("artifact-team-a", "team-b").
 		WillReturnError(sql.ErrNoRows)
 
-	h := NewReportsHandler(sqlxDB)
+	h := NewReportsHandler(sqlxDB, nil)
 	r := chi.NewRouter()
 	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
 
@@ -102,7 +107,7 @@ func TestArtifactContent_ServesContent(t *testing.T) {
 		WithArgs("artifact-1", "team-1").
 		WillReturnRows(rows)
 
-	h := NewReportsHandler(sqlxDB)
+	h := NewReportsHandler(sqlxDB, nil)
 	r := chi.NewRouter()
 	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
 
@@ -132,7 +137,7 @@ func TestArtifactContent_DownloadParam(t *testing.T) {
 		WithArgs("artifact-1", "team-1").
 		WillReturnRows(rows)
 
-	h := NewReportsHandler(sqlxDB)
+	h := NewReportsHandler(sqlxDB, nil)
 	r := chi.NewRouter()
 	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
 
@@ -145,3 +150,58 @@ func TestArtifactContent_DownloadParam(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
 }
+
+func objectStoreArtifactRows(key string, size int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "step_run_id", "name", "path", "size_bytes", "content_type", "storage", "data", "object_key", "created_at",
+	}).AddRow(
+		"artifact-1", "step-1", "results.sarif", "/workspace/results.sarif",
+		size, "application/json", "object_store", nil, key, time.Now(),
+	)
+}
+
+func TestArtifactContent_StreamsObjectStore(t *testing.T) {
+	fs, err := artifact.NewFSStore(t.TempDir())
+	require.NoError(t, err)
+	content := []byte(`{"runs":[]}`)
+	require.NoError(t, fs.Put(context.Background(), "artifacts/step-1/artifact-1", content, "application/json"))
+
+	sqlxDB, mock := newReportsMockDB(t)
+	mock.ExpectQuery(`SELECT a\.id`).
+		WithArgs("artifact-1", "team-1").
+		WillReturnRows(objectStoreArtifactRows("artifacts/step-1/artifact-1", int64(len(content))))
+
+	h := NewReportsHandler(sqlxDB, artifact.New(fs, 0))
+	r := chi.NewRouter()
+	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
+
+	req := httptest.NewRequest("GET", "/api/artifacts/artifact-1/content", nil)
+	req = claimsCtx(req, "team-1")
+	req.Header.Set("X-Team-ID", "team-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(len(content)), w.Header().Get("Content-Length"))
+	assert.Equal(t, string(content), w.Body.String())
+}
+
+func TestArtifactContent_ObjectStoreNotConfigured(t *testing.T) {
+	sqlxDB, mock := newReportsMockDB(t)
+	mock.ExpectQuery(`SELECT a\.id`).
+		WithArgs("artifact-1", "team-1").
+		WillReturnRows(objectStoreArtifactRows("artifacts/step-1/artifact-1", 10))
+
+	h := NewReportsHandler(sqlxDB, nil)
+	r := chi.NewRouter()
+	r.Get("/api/artifacts/{id}/content", h.ArtifactContent)
+
+	req := httptest.NewRequest("GET", "/api/artifacts/artifact-1/content", nil)
+	req = claimsCtx(req, "team-1")
+	req.Header.Set("X-Team-ID", "team-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -6,15 +6,16 @@ import (
 	"log/slog"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"go.temporal.io/sdk/client"
 
 	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/launch"
 	"github.com/tinkerloft/fleetlift/internal/model"
 	"github.com/tinkerloft/fleetlift/internal/server/notify"
 	"github.com/tinkerloft/fleetlift/internal/template"
@@ -70,67 +71,32 @@ func (h *RunsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return // error already written
 	}
 
-	// Look up workflow template
-	t, err := h.registry.Get(r.Context(), teamID, req.WorkflowID)
+	run, err := launch.Prepare(r.Context(), h.registry, launch.Request{
+		TeamID:      teamID,
+		WorkflowID:  req.WorkflowID,
+		Parameters:  req.Parameters,
+		Model:       req.Model,
+		TriggeredBy: claims.UserID,
+	})
 	if err != nil {
-		writeJSONError(w, http.StatusNotFound, "workflow not found")
+		writeLaunchError(w, err, teamID, req.WorkflowID)
 		return
 	}
-
-	// Parse workflow definition
-	var def model.WorkflowDef
-	if err := model.ParseWorkflowYAML([]byte(t.YAMLBody), &def); err != nil {
-		writeJSONError(w, http.StatusBadRequest, "invalid workflow definition")
-		return
-	}
-
-	// Apply defaults for optional parameters not supplied by the caller.
-	if req.Parameters == nil {
-		req.Parameters = make(map[string]any)
-	}
-	for _, p := range def.Parameters {
-		if _, ok := req.Parameters[p.Name]; !ok && p.Default != nil {
-			req.Parameters[p.Name] = p.Default
-		}
-	}
-
-	if errs := workflow.ValidateWorkflow(def, req.Parameters); len(errs) > 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]any{
-			"error":             "workflow validation failed",
-			"validation_errors": errs,
-		})
-		return
-	}
-
-	runID := uuid.New().String()
-	temporalID := fmt.Sprintf("fl-%s-%s", req.WorkflowID, runID[:8])
+	runID, temporalID := run.ID, run.TemporalID
 
 	// Insert run record
-	_, err = h.db.ExecContext(r.Context(),
-		`INSERT INTO runs (id, team_id, workflow_id, workflow_title, parameters, model, status, temporal_id, triggered_by)
-		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)`,
-		runID, teamID, req.WorkflowID, t.Title,
-		mustMarshal(req.Parameters), req.Model, string(model.RunStatusPending),
-		temporalID, claims.UserID)
-	if err != nil {
+	if err := launch.Insert(r.Context(), h.db, run); err != nil {
 		slog.Error("failed to create run record", "error", err, "team_id", teamID, "workflow_id", req.WorkflowID)
 		writeJSONError(w, http.StatusInternalServerError, "failed to create run")
 		return
 	}
 
 	// Start Temporal workflow
+	run.Input.DefaultMaxParallel = launch.DefaultMaxParallel(r.Context(), h.db, teamID)
 	_, err = h.temporal.ExecuteWorkflow(r.Context(), client.StartWorkflowOptions{
 		ID:        temporalID,
-		TaskQueue: "fleetlift",
-	}, "DAGWorkflow", workflow.DAGInput{
-		RunID:              runID,
-		TeamID:             teamID,
-		WorkflowTemplateID: t.ID,
-		WorkflowDef:        def,
-		Parameters:         req.Parameters,
-		ModelOverride:      req.Model,
-		TriggeredBy:        claims.UserID,
-	})
+		TaskQueue: launch.TaskQueue,
+	}, "DAGWorkflow", run.Input)
 	if err != nil {
 		slog.Error("failed to start workflow", "error", err, "team_id", teamID, "run_id", runID)
 		writeJSONError(w, http.StatusInternalServerError, "failed to start workflow")
@@ -610,6 +576,116 @@ func (h *RunsHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
 }
 
+type retryRunRequest struct {
+	From string `json:"from"` // step to re-run from; defaults to the steps that did not complete
+}
+
+// Retry starts a new run of a finished run's workflow with the same parameters and
+// model. Steps that completed in the original run are seeded from its step_runs and
+// not executed again; failed steps and everything downstream of them run. With
+// "from", that step and its downstream steps run too. The new run replays the
+// definition the original started with, not the template's current version, and
+// records the original as its parent.
+func (h *RunsHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	var req retryRunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	parentID := chi.URLParam(r, "id")
+	parent := getRunForTeam(r.Context(), h.db, w, parentID, teamID)
+	if parent == nil {
+		return
+	}
+	if !isRunTerminal(parent.Status) {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("run is %s; only finished runs can be retried", parent.Status))
+		return
+	}
+
+	if parent.WorkflowYAML == nil {
+		writeJSONError(w, http.StatusConflict, "run has no stored workflow definition and cannot be retried")
+		return
+	}
+
+	modelOverride := ""
+	if parent.Model != nil {
+		modelOverride = *parent.Model
+	}
+	run, err := launch.Prepare(r.Context(), h.registry, launch.Request{
+		TeamID:       teamID,
+		WorkflowID:   parent.WorkflowID,
+		Parameters:   parent.Parameters,
+		Model:        modelOverride,
+		TriggeredBy:  claims.UserID,
+		ParentRunID:  parent.ID,
+		WorkflowYAML: *parent.WorkflowYAML,
+	})
+	if err != nil {
+		writeLaunchError(w, err, teamID, parent.WorkflowID)
+		return
+	}
+
+	var stepRuns []model.StepRun
+	if err := h.db.SelectContext(r.Context(), &stepRuns,
+		`SELECT * FROM step_runs WHERE run_id = $1 ORDER BY created_at`, parent.ID); err != nil {
+		slog.Error("failed to load step runs for retry", "error", err, "run_id", parent.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load step runs")
+		return
+	}
+	seed, err := workflow.RetrySeed(run.Input.WorkflowDef, stepRuns, req.From)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(seed) == len(run.Input.WorkflowDef.Steps) {
+		writeJSONError(w, http.StatusConflict, "every step completed; set from to re-run a step")
+		return
+	}
+	run.Input.SeedOutputs = seed
+
+	if err := launch.Insert(r.Context(), h.db, run); err != nil {
+		slog.Error("failed to create run record", "error", err, "team_id", teamID, "parent_run_id", parent.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create run")
+		return
+	}
+
+	run.Input.DefaultMaxParallel = launch.DefaultMaxParallel(r.Context(), h.db, teamID)
+	_, err = h.temporal.ExecuteWorkflow(r.Context(), client.StartWorkflowOptions{
+		ID:        run.TemporalID,
+		TaskQueue: launch.TaskQueue,
+	}, "DAGWorkflow", run.Input)
+	if err != nil {
+		slog.Error("failed to start workflow", "error", err, "team_id", teamID, "run_id", run.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to start workflow")
+		return
+	}
+
+	seeded := make([]string, 0, len(seed))
+	for id := range seed {
+		seeded = append(seeded, id)
+	}
+	sort.Strings(seeded)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"id":            run.ID,
+		"temporal_id":   run.TemporalID,
+		"parent_run_id": parent.ID,
+		"seeded_steps":  seeded,
+	})
+}
+
 func stepWorkflowID(runID, stepID string) string {
 	return fmt.Sprintf("%s-%s", runID, stepID)
 }
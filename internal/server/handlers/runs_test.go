@@ -16,11 +16,15 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
 
 	"github.com/tinkerloft/fleetlift/internal/auth"
 	"github.com/tinkerloft/fleetlift/internal/model"
 	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
 )
 
 func TestStepWorkflowID(t *testing.T) {
@@ -240,8 +244,8 @@ func TestCreate_IncludesModelInRunInsert(t *testing.T) {
 		TeamRoles: map[string]string{"team-1": "member"},
 	}))
 
-	mock.ExpectExec(`INSERT INTO runs \(id, team_id, workflow_id, workflow_title, parameters, model, status, temporal_id, triggered_by\)`).
-		WithArgs(sqlmock.AnyArg(), "team-1", "valid-workflow", "Valid Workflow", sqlmock.AnyArg(), "claude-sonnet-4-6", "pending", sqlmock.AnyArg(), "user-1").
+	mock.ExpectExec(`INSERT INTO runs \(id, team_id, workflow_id, workflow_title, parameters, model, status, temporal_id, triggered_by, schedule_id, parent_run_id, workflow_yaml\)`).
+		WithArgs(sqlmock.AnyArg(), "team-1", "valid-workflow", "Valid Workflow", sqlmock.AnyArg(), "claude-sonnet-4-6", "pending", sqlmock.AnyArg(), "user-1", "", "", sqlmock.AnyArg()).
 		WillReturnError(assert.AnError)
 
 	w := httptest.NewRecorder()
@@ -343,3 +347,159 @@ func TestList_RespectsLimitParam(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+const retryWorkflowYAML = `
+version: 1
+id: retry-workflow
+steps:
+  - id: analyze
+    execution:
+      agent: claude-code
+      prompt: analyze the code
+  - id: fix
+    depends_on: [analyze]
+    execution:
+      agent: claude-code
+      prompt: "fix {{ .Steps.analyze.Output.summary }}"
+`
+
+// retryWorkflowYAMLv2 is the template as edited after the run being retried
+// started; a retry must not pick it up.
+const retryWorkflowYAMLv2 = retryWorkflowYAML + `
+  - id: report
+    depends_on: [fix]
+    execution:
+      agent: claude-code
+      prompt: write a report
+`
+
+func retryRunsHandler(t *testing.T, temporal client.Client) (*RunsHandler, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	reg := template.NewRegistry(&stubProvider{tmpl: &model.WorkflowTemplate{
+		ID:       "wf-retry",
+		Slug:     "retry-workflow",
+		Title:    "Retry Workflow",
+		YAMLBody: retryWorkflowYAMLv2,
+	}})
+	return NewRunsHandler(sqlx.NewDb(sqlDB, "sqlmock"), temporal, reg, nil), dbMock
+}
+
+func expectParentRun(dbMock sqlmock.Sqlmock, status model.RunStatus) {
+	expectParentRunWithYAML(dbMock, status, retryWorkflowYAML)
+}
+
+func expectParentRunWithYAML(dbMock sqlmock.Sqlmock, status model.RunStatus, workflowYAML any) {
+	dbMock.ExpectQuery(`SELECT \* FROM runs WHERE id = \$1 AND team_id = \$2`).
+		WithArgs("run-1", "team-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "team_id", "workflow_id", "workflow_title", "parameters", "status", "model", "temporal_id", "triggered_by", "workflow_yaml", "created_at"}).
+			AddRow("run-1", "team-1", "retry-workflow", "Retry Workflow", []byte(`{"env":"prod"}`), string(status), "claude-sonnet-4-6", "fl-retry-workflow-run-1", "user-2", workflowYAML, time.Now().UTC()))
+}
+
+func expectParentStepRuns(dbMock sqlmock.Sqlmock, fixStatus model.StepStatus) {
+	now := time.Now().UTC()
+	dbMock.ExpectQuery(`SELECT \* FROM step_runs WHERE run_id = \$1`).
+		WithArgs("run-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "run_id", "step_id", "status", "output", "created_at"}).
+			AddRow("sr-1", "run-1", "analyze", "complete", []byte(`{"summary":"3 issues"}`), now).
+			AddRow("sr-2", "run-1", "fix", string(fixStatus), nil, now.Add(time.Second)))
+}
+
+func serveRetry(h *RunsHandler, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Post("/api/runs/{id}/retry", h.Retry)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, apiKeyReq("POST", "/api/runs/run-1/retry", body, &auth.Claims{
+		UserID:    "user-1",
+		TeamRoles: map[string]string{"team-1": "member"},
+	}))
+	return w
+}
+
+func TestRetry_SeedsCompletedSteps(t *testing.T) {
+	temporal := mocks.NewClient(t)
+	h, dbMock := retryRunsHandler(t, temporal)
+
+	expectParentRun(dbMock, model.RunStatusFailed)
+	expectParentStepRuns(dbMock, model.StepStatusFailed)
+	dbMock.ExpectExec(`INSERT INTO runs`).
+		WithArgs(sqlmock.AnyArg(), "team-1", "retry-workflow", "Retry Workflow", sqlmock.AnyArg(),
+			"claude-sonnet-4-6", "pending", sqlmock.AnyArg(), "user-1", "", "run-1", retryWorkflowYAML).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	temporal.On("ExecuteWorkflow", mock.Anything, mock.Anything, "DAGWorkflow", mock.MatchedBy(func(in workflow.DAGInput) bool {
+		seeded, ok := in.SeedOutputs["analyze"]
+		return ok && len(in.SeedOutputs) == 1 &&
+			len(in.WorkflowDef.Steps) == 2 &&
+			seeded.Output["summary"] == "3 issues" &&
+			in.Parameters["env"] == "prod" &&
+			in.ModelOverride == "claude-sonnet-4-6"
+	})).Return(nil, nil)
+
+	w := serveRetry(h, "")
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "run-1", resp["parent_run_id"])
+	assert.Equal(t, []any{"analyze"}, resp["seeded_steps"])
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRetry_FromReRunsCompletedStep(t *testing.T) {
+	temporal := mocks.NewClient(t)
+	h, dbMock := retryRunsHandler(t, temporal)
+
+	expectParentRun(dbMock, model.RunStatusComplete)
+	expectParentStepRuns(dbMock, model.StepStatusComplete)
+	dbMock.ExpectExec(`INSERT INTO runs`).WillReturnResult(sqlmock.NewResult(0, 1))
+	temporal.On("ExecuteWorkflow", mock.Anything, mock.Anything, "DAGWorkflow", mock.MatchedBy(func(in workflow.DAGInput) bool {
+		return len(in.SeedOutputs) == 0
+	})).Return(nil, nil)
+
+	w := serveRetry(h, `{"from":"analyze"}`)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestRetry_Rejections(t *testing.T) {
+	t.Run("run still active", func(t *testing.T) {
+		h, dbMock := retryRunsHandler(t, nil)
+		expectParentRun(dbMock, model.RunStatusRunning)
+
+		w := serveRetry(h, "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "only finished runs")
+	})
+
+	t.Run("no stored definition", func(t *testing.T) {
+		h, dbMock := retryRunsHandler(t, nil)
+		expectParentRunWithYAML(dbMock, model.RunStatusFailed, nil)
+
+		w := serveRetry(h, "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "no stored workflow definition")
+	})
+
+	t.Run("unknown from step", func(t *testing.T) {
+		h, dbMock := retryRunsHandler(t, nil)
+		expectParentRun(dbMock, model.RunStatusFailed)
+		expectParentStepRuns(dbMock, model.StepStatusFailed)
+
+		w := serveRetry(h, `{"from":"deploy"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `step \"deploy\"`)
+	})
+
+	t.Run("nothing to retry", func(t *testing.T) {
+		h, dbMock := retryRunsHandler(t, nil)
+		expectParentRun(dbMock, model.RunStatusComplete)
+		expectParentStepRuns(dbMock, model.StepStatusComplete)
+
+		w := serveRetry(h, "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "set from")
+	})
+}
@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/launch"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+const maxScheduleNameLen = 100
+
+// SchedulesHandler manages cron schedules backed by Temporal Schedules.
+type SchedulesHandler struct {
+	db        *sqlx.DB
+	schedules client.ScheduleClient
+	registry  *template.Registry
+}
+
+// NewSchedulesHandler creates a new SchedulesHandler.
+func NewSchedulesHandler(db *sqlx.DB, schedules client.ScheduleClient, registry *template.Registry) *SchedulesHandler {
+	return &SchedulesHandler{db: db, schedules: schedules, registry: registry}
+}
+
+// temporalScheduleID is the Temporal Schedule ID mirroring a schedule row.
+func temporalScheduleID(scheduleID string) string {
+	return "fl-schedule-" + scheduleID
+}
+
+type scheduleRequest struct {
+	Name       *string        `json:"name"`
+	WorkflowID *string        `json:"workflow_id"`
+	Cron       *string        `json:"cron"`
+	Timezone   *string        `json:"timezone"`
+	Parameters map[string]any `json:"parameters"`
+	Model      *string        `json:"model"`
+	Enabled    *bool          `json:"enabled"`
+}
+
+// apply overlays the fields set in req onto s.
+func (req scheduleRequest) apply(s *model.Schedule) {
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.WorkflowID != nil {
+		s.WorkflowID = *req.WorkflowID
+	}
+	if req.Cron != nil {
+		s.Cron = strings.TrimSpace(*req.Cron)
+	}
+	if req.Timezone != nil {
+		s.Timezone = *req.Timezone
+	}
+	if req.Parameters != nil {
+		s.Parameters = req.Parameters
+	}
+	if req.Model != nil {
+		if *req.Model == "" {
+			s.Model = nil
+		} else {
+			m := *req.Model
+			s.Model = &m
+		}
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+}
+
+var cronDescriptors = map[string]bool{
+	"@yearly": true, "@annually": true, "@monthly": true, "@weekly": true,
+	"@daily": true, "@midnight": true, "@hourly": true,
+}
+
+// validateCron does a cheap syntax check before Temporal parses the expression.
+func validateCron(expr string) error {
+	if expr == "" {
+		return errors.New("cron is required")
+	}
+	if strings.HasPrefix(expr, "@") {
+		if cronDescriptors[expr] {
+			return nil
+		}
+		if d, ok := strings.CutPrefix(expr, "@every "); ok {
+			if dur, err := time.ParseDuration(d); err == nil && dur >= time.Minute {
+				return nil
+			}
+			return errors.New("cron @every requires a duration of at least 1m")
+		}
+		return fmt.Errorf("unknown cron descriptor %q", expr)
+	}
+	if n := len(strings.Fields(expr)); n < 5 || n > 7 {
+		return fmt.Errorf("cron must have 5 fields (minute hour day-of-month month day-of-week), got %d", n)
+	}
+	return nil
+}
+
+// validate checks s and that its workflow accepts its parameters.
+// Returns false and writes an error response on failure.
+func (h *SchedulesHandler) validate(w http.ResponseWriter, r *http.Request, s *model.Schedule) bool {
+	switch {
+	case s.Name == "":
+		writeJSONError(w, http.StatusBadRequest, "name is required")
+		return false
+	case len(s.Name) > maxScheduleNameLen:
+		writeJSONError(w, http.StatusBadRequest, "name must be 100 characters or fewer")
+		return false
+	case s.WorkflowID == "":
+		writeJSONError(w, http.StatusBadRequest, "workflow_id is required")
+		return false
+	}
+	if err := validateCron(s.Cron); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unknown timezone %q", s.Timezone))
+		return false
+	}
+	if s.Model != nil && !isAllowedModel(*s.Model) {
+		writeJSONError(w, http.StatusBadRequest, "invalid model")
+		return false
+	}
+	// Dry-run the launch so a schedule can't be saved that would fail every time it fires.
+	if _, err := launch.Prepare(r.Context(), h.registry, launch.Request{
+		TeamID:     s.TeamID,
+		WorkflowID: s.WorkflowID,
+		Parameters: s.Parameters,
+	}); err != nil {
+		writeLaunchError(w, err, s.TeamID, s.WorkflowID)
+		return false
+	}
+	return true
+}
+
+func scheduleSpec(s *model.Schedule) client.ScheduleSpec {
+	return client.ScheduleSpec{
+		CronExpressions: []string{s.Cron},
+		TimeZoneName:    s.Timezone,
+	}
+}
+
+func scheduleAction(s *model.Schedule) *client.ScheduleWorkflowAction {
+	return &client.ScheduleWorkflowAction{
+		ID:        "fl-scheduled-" + s.ID[:8], // Temporal appends the fire time
+		Workflow:  "ScheduledRunWorkflow",
+		Args:      []any{workflow.ScheduledRunInput{ScheduleID: s.ID}},
+		TaskQueue: launch.TaskQueue,
+	}
+}
+
+// writeTemporalError maps a Temporal schedule API error to an HTTP response.
+func writeTemporalError(w http.ResponseWriter, err error, op, scheduleID string) {
+	var invalid *serviceerror.InvalidArgument
+	if errors.As(err, &invalid) {
+		writeJSONError(w, http.StatusBadRequest, invalid.Message)
+		return
+	}
+	slog.Error("temporal schedule operation failed", "op", op, "error", err, "schedule_id", scheduleID)
+	writeJSONError(w, http.StatusBadGateway, "failed to "+op+" schedule")
+}
+
+// loadSchedule fetches a team's schedule, writing 404 if it does not exist.
+func (h *SchedulesHandler) loadSchedule(ctx context.Context, w http.ResponseWriter, q sqlx.QueryerContext, id, teamID string) *model.Schedule {
+	if _, err := uuid.Parse(id); err != nil {
+		writeJSONError(w, http.StatusNotFound, "schedule not found")
+		return nil
+	}
+	var s model.Schedule
+	if err := sqlx.GetContext(ctx, q, &s, `SELECT * FROM schedules WHERE id = $1 AND team_id = $2`, id, teamID); err != nil {
+		writeJSONError(w, http.StatusNotFound, "schedule not found")
+		return nil
+	}
+	return &s
+}
+
+// List returns the team's schedules.
+func (h *SchedulesHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	schedules := make([]model.Schedule, 0)
+	if err := h.db.SelectContext(r.Context(), &schedules,
+		`SELECT * FROM schedules WHERE team_id = $1 ORDER BY created_at DESC`, teamID); err != nil {
+		slog.Error("failed to list schedules", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list schedules")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": schedules})
+}
+
+// Get returns a schedule and its upcoming fire times.
+func (h *SchedulesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+	s := h.loadSchedule(r.Context(), w, h.db, chi.URLParam(r, "id"), teamID)
+	if s == nil {
+		return
+	}
+
+	// Upcoming times are informational; a Temporal hiccup must not hide the schedule.
+	nextRuns := make([]time.Time, 0)
+	if desc, err := h.schedules.GetHandle(r.Context(), temporalScheduleID(s.ID)).Describe(r.Context()); err != nil {
+		slog.Warn("failed to describe temporal schedule", "error", err, "schedule_id", s.ID)
+	} else {
+		nextRuns = append(nextRuns, desc.Info.NextActionTimes...)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"schedule":       s,
+		"next_run_times": nextRuns,
+	})
+}
+
+// Create saves a schedule and registers it with Temporal.
+func (h *SchedulesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s := model.Schedule{TeamID: teamID, Enabled: true, Parameters: model.JSONMap{}}
+	req.apply(&s)
+	if !h.validate(w, r, &s) {
+		return
+	}
+
+	tx, err := h.db.BeginTxx(r.Context(), nil)
+	if err != nil {
+		slog.Error("failed to begin transaction", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create schedule")
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var created model.Schedule
+	err = tx.GetContext(r.Context(), &created,
+		`INSERT INTO schedules (team_id, name, workflow_id, cron, timezone, parameters, model, enabled, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid)
+		 RETURNING *`,
+		teamID, s.Name, s.WorkflowID, s.Cron, s.Timezone, s.Parameters, s.Model, s.Enabled, claims.UserID)
+	if err != nil {
+		slog.Error("failed to create schedule", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create schedule")
+		return
+	}
+
+	// Register with Temporal inside the transaction so a rejected schedule leaves no row behind.
+	if _, err := h.schedules.Create(r.Context(), client.ScheduleOptions{
+		ID:     temporalScheduleID(created.ID),
+		Spec:   scheduleSpec(&created),
+		Action: scheduleAction(&created),
+		Paused: !created.Enabled,
+	}); err != nil {
+		writeTemporalError(w, err, "create", created.ID)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("failed to commit schedule", "error", err, "schedule_id", created.ID)
+		_ = h.schedules.GetHandle(r.Context(), temporalScheduleID(created.ID)).Delete(r.Context())
+		writeJSONError(w, http.StatusInternalServerError, "failed to create schedule")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// Update changes a schedule. Only the fields present in the body are modified.
+func (h *SchedulesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	tx, err := h.db.BeginTxx(r.Context(), nil)
+	if err != nil {
+		slog.Error("failed to begin transaction", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to update schedule")
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	s := h.loadSchedule(r.Context(), w, tx, chi.URLParam(r, "id"), teamID)
+	if s == nil {
+		return
+	}
+	if !isOwnerOrTeamAdmin(claims, s.TeamID, s.CreatedBy) {
+		writeJSONError(w, http.StatusForbidden, "only the schedule owner or a team admin can modify it")
+		return
+	}
+	prev := *s
+	req.apply(s)
+	if !h.validate(w, r, s) {
+		return
+	}
+
+	var updated model.Schedule
+	err = tx.GetContext(r.Context(), &updated,
+		`UPDATE schedules
+		 SET name = $1, workflow_id = $2, cron = $3, timezone = $4, parameters = $5, model = $6, enabled = $7, updated_at = now()
+		 WHERE id = $8 AND team_id = $9
+		 RETURNING *`,
+		s.Name, s.WorkflowID, s.Cron, s.Timezone, s.Parameters, s.Model, s.Enabled, s.ID, teamID)
+	if err != nil {
+		slog.Error("failed to update schedule", "error", err, "schedule_id", s.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to update schedule")
+		return
+	}
+
+	// Parameters and model are read when the schedule fires; only timing and the
+	// paused state live in Temporal.
+	handle := h.schedules.GetHandle(r.Context(), temporalScheduleID(s.ID))
+	if prev.Cron != updated.Cron || prev.Timezone != updated.Timezone {
+		err := handle.Update(r.Context(), client.ScheduleUpdateOptions{
+			DoUpdate: func(in client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+				sched := in.Description.Schedule
+				spec := scheduleSpec(&updated)
+				sched.Spec = &spec
+				return &client.ScheduleUpdate{Schedule: &sched}, nil
+			},
+		})
+		if err != nil {
+			writeTemporalError(w, err, "update", s.ID)
+			return
+		}
+	}
+	if prev.Enabled != updated.Enabled {
+		if updated.Enabled {
+			err = handle.Unpause(r.Context(), client.ScheduleUnpauseOptions{Note: "enabled via fleetlift"})
+		} else {
+			err = handle.Pause(r.Context(), client.SchedulePauseOptions{Note: "disabled via fleetlift"})
+		}
+		if err != nil {
+			writeTemporalError(w, err, "update", s.ID)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("failed to commit schedule update", "error", err, "schedule_id", s.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to update schedule")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// Delete removes a schedule and its Temporal Schedule. Past runs are kept.
+func (h *SchedulesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	tx, err := h.db.BeginTxx(r.Context(), nil)
+	if err != nil {
+		slog.Error("failed to begin transaction", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to delete schedule")
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	s := h.loadSchedule(r.Context(), w, tx, chi.URLParam(r, "id"), teamID)
+	if s == nil {
+		return
+	}
+	if !isOwnerOrTeamAdmin(claims, s.TeamID, s.CreatedBy) {
+		writeJSONError(w, http.StatusForbidden, "only the schedule owner or a team admin can delete it")
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(), `DELETE FROM schedules WHERE id = $1`, s.ID); err != nil {
+		slog.Error("failed to delete schedule", "error", err, "schedule_id", s.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to delete schedule")
+		return
+	}
+	err = h.schedules.GetHandle(r.Context(), temporalScheduleID(s.ID)).Delete(r.Context())
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		writeTemporalError(w, err, "delete", s.ID)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("failed to commit schedule delete", "error", err, "schedule_id", s.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to delete schedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Trigger fires a schedule immediately, outside its cron timing.
+func (h *SchedulesHandler) Trigger(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+	s := h.loadSchedule(r.Context(), w, h.db, chi.URLParam(r, "id"), teamID)
+	if s == nil {
+		return
+	}
+	if !isOwnerOrTeamAdmin(claims, s.TeamID, s.CreatedBy) {
+		writeJSONError(w, http.StatusForbidden, "only the schedule owner or a team admin can trigger it")
+		return
+	}
+	if !s.Enabled {
+		writeJSONError(w, http.StatusConflict, "schedule is disabled")
+		return
+	}
+	if err := h.schedules.GetHandle(r.Context(), temporalScheduleID(s.ID)).Trigger(r.Context(), client.ScheduleTriggerOptions{}); err != nil {
+		writeTemporalError(w, err, "trigger", s.ID)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Runs returns the runs started by a schedule, newest first.
+func (h *SchedulesHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+	s := h.loadSchedule(r.Context(), w, h.db, chi.URLParam(r, "id"), teamID)
+	if s == nil {
+		return
+	}
+
+	runs := make([]model.Run, 0)
+	if err := h.db.SelectContext(r.Context(), &runs,
+		`SELECT * FROM runs WHERE schedule_id = $1 AND team_id = $2 ORDER BY created_at DESC LIMIT 50`,
+		s.ID, teamID); err != nil {
+		slog.Error("failed to list schedule runs", "error", err, "schedule_id", s.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list runs")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": runs})
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/workflow"
+)
+
+const testScheduleID = "11111111-2222-3333-4444-555555555555"
+
+var scheduleColumns = []string{"id", "team_id", "name", "workflow_id", "cron", "timezone", "parameters", "model", "enabled", "created_by", "created_at", "updated_at"}
+
+func scheduleRow(enabled bool, createdBy string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(scheduleColumns).
+		AddRow(testScheduleID, "team-1", "nightly", "valid-workflow", "0 2 * * *", "UTC", []byte(`{}`), nil, enabled, createdBy, now, now)
+}
+
+// scheduleRouter serves the schedule routes and returns a helper that issues
+// requests as claims.
+func scheduleRouter(h *SchedulesHandler, claims *auth.Claims) func(method, path, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Post("/api/schedules", h.Create)
+	r.Patch("/api/schedules/{id}", h.Update)
+	r.Delete("/api/schedules/{id}", h.Delete)
+	r.Post("/api/schedules/{id}/trigger", h.Trigger)
+	return func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Team-ID", "team-1")
+		req = req.WithContext(auth.SetClaimsInContext(req.Context(), claims))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+}
+
+func validWorkflowRegistry() *template.Registry {
+	return template.NewRegistry(&stubProvider{tmpl: &model.WorkflowTemplate{
+		ID:       "wf-valid",
+		Slug:     "valid-workflow",
+		Title:    "Valid Workflow",
+		YAMLBody: validWorkflowYAML,
+	}})
+}
+
+var scheduleMember = &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "member"}}
+
+func TestSchedules_Create_Validation(t *testing.T) {
+	h := NewSchedulesHandler(nil, nil, validWorkflowRegistry())
+	do := scheduleRouter(h, scheduleMember)
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantMsg  string
+	}{
+		{"missing name", `{"workflow_id":"valid-workflow","cron":"0 2 * * *"}`, http.StatusBadRequest, "name is required"},
+		{"long name", `{"name":"` + strings.Repeat("a", 101) + `","workflow_id":"valid-workflow","cron":"0 2 * * *"}`, http.StatusBadRequest, "100 characters"},
+		{"missing cron", `{"name":"n","workflow_id":"valid-workflow"}`, http.StatusBadRequest, "cron is required"},
+		{"short cron", `{"name":"n","workflow_id":"valid-workflow","cron":"0 2 *"}`, http.StatusBadRequest, "5 fields"},
+		{"bad timezone", `{"name":"n","workflow_id":"valid-workflow","cron":"0 2 * * *","timezone":"Mars/Olympus"}`, http.StatusBadRequest, "unknown timezone"},
+		{"bad model", `{"name":"n","workflow_id":"valid-workflow","cron":"0 2 * * *","model":"Not A Model!"}`, http.StatusBadRequest, "invalid model"},
+		{"unknown workflow", `{"name":"n","workflow_id":"nope","cron":"0 2 * * *"}`, http.StatusNotFound, "workflow not found"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do("POST", "/api/schedules", tc.body)
+			assert.Equal(t, tc.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tc.wantMsg)
+		})
+	}
+}
+
+func TestValidateCron(t *testing.T) {
+	for _, expr := range []string{"0 2 * * *", "*/15 * * * 1-5", "@daily", "@every 30m"} {
+		assert.NoError(t, validateCron(expr), expr)
+	}
+	for _, expr := range []string{"", "* * *", "@fortnightly", "@every 10s"} {
+		assert.Error(t, validateCron(expr), expr)
+	}
+}
+
+func TestSchedules_Create_RegistersTemporalSchedule(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	schedules := mocks.NewScheduleClient(t)
+	h := NewSchedulesHandler(sqlx.NewDb(sqlDB, "sqlmock"), schedules, validWorkflowRegistry())
+	do := scheduleRouter(h, scheduleMember)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(`INSERT INTO schedules`).
+		WithArgs("team-1", "nightly", "valid-workflow", "0 2 * * *", "UTC", sqlmock.AnyArg(), nil, true, "user-1").
+		WillReturnRows(scheduleRow(true, "user-1"))
+	schedules.On("Create", mock.Anything, mock.MatchedBy(func(o client.ScheduleOptions) bool {
+		action, ok := o.Action.(*client.ScheduleWorkflowAction)
+		return ok &&
+			o.ID == "fl-schedule-"+testScheduleID &&
+			!o.Paused &&
+			o.Spec.CronExpressions[0] == "0 2 * * *" &&
+			action.Workflow == "ScheduledRunWorkflow" &&
+			action.Args[0] == workflow.ScheduledRunInput{ScheduleID: testScheduleID}
+	})).Return(mocks.NewScheduleHandle(t), nil)
+	dbMock.ExpectCommit()
+
+	w := do("POST", "/api/schedules", `{"name":"nightly","workflow_id":"valid-workflow","cron":"0 2 * * *"}`)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var got model.Schedule
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, testScheduleID, got.ID)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestSchedules_Create_RollsBackWhenTemporalRejects(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	schedules := mocks.NewScheduleClient(t)
+	h := NewSchedulesHandler(sqlx.NewDb(sqlDB, "sqlmock"), schedules, validWorkflowRegistry())
+	do := scheduleRouter(h, scheduleMember)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(`INSERT INTO schedules`).WillReturnRows(scheduleRow(true, "user-1"))
+	schedules.On("Create", mock.Anything, mock.Anything).
+		Return(nil, serviceerror.NewInvalidArgument("invalid cron string"))
+	dbMock.ExpectRollback()
+
+	w := do("POST", "/api/schedules", `{"name":"nightly","workflow_id":"valid-workflow","cron":"0 2 * * *"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid cron string")
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestSchedules_Update_RequiresOwnerOrAdmin(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewSchedulesHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, validWorkflowRegistry())
+	do := scheduleRouter(h, scheduleMember)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(`SELECT \* FROM schedules WHERE id = \$1 AND team_id = \$2`).
+		WithArgs(testScheduleID, "team-1").
+		WillReturnRows(scheduleRow(true, "user-2"))
+	dbMock.ExpectRollback()
+
+	w := do("PATCH", "/api/schedules/"+testScheduleID, `{"enabled":false}`)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestSchedules_Update_DisablePausesSchedule(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	schedules := mocks.NewScheduleClient(t)
+	handle := mocks.NewScheduleHandle(t)
+	h := NewSchedulesHandler(sqlx.NewDb(sqlDB, "sqlmock"), schedules, validWorkflowRegistry())
+	admin := &auth.Claims{UserID: "user-1", TeamRoles: map[string]string{"team-1": "admin"}}
+	do := scheduleRouter(h, admin)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(`SELECT \* FROM schedules`).WillReturnRows(scheduleRow(true, "user-2"))
+	dbMock.ExpectQuery(`UPDATE schedules`).
+		WithArgs("nightly", "valid-workflow", "0 2 * * *", "UTC", sqlmock.AnyArg(), nil, false, testScheduleID, "team-1").
+		WillReturnRows(scheduleRow(false, "user-2"))
+	schedules.On("GetHandle", mock.Anything, "fl-schedule-"+testScheduleID).Return(handle)
+	handle.On("Pause", mock.Anything, mock.Anything).Return(nil)
+	dbMock.ExpectCommit()
+
+	w := do("PATCH", "/api/schedules/"+testScheduleID, `{"enabled":false}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	handle.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestSchedules_Delete_ToleratesMissingTemporalSchedule(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	schedules := mocks.NewScheduleClient(t)
+	handle := mocks.NewScheduleHandle(t)
+	h := NewSchedulesHandler(sqlx.NewDb(sqlDB, "sqlmock"), schedules, validWorkflowRegistry())
+	do := scheduleRouter(h, scheduleMember)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(`SELECT \* FROM schedules`).WillReturnRows(scheduleRow(true, "user-1"))
+	dbMock.ExpectExec(`DELETE FROM schedules WHERE id = \$1`).
+		WithArgs(testScheduleID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	schedules.On("GetHandle", mock.Anything, "fl-schedule-"+testScheduleID).Return(handle)
+	handle.On("Delete", mock.Anything).Return(serviceerror.NewNotFound("schedule not found"))
+	dbMock.ExpectCommit()
+
+	w := do("DELETE", "/api/schedules/"+testScheduleID, "")
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestSchedules_Trigger_RejectsDisabledSchedule(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewSchedulesHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, validWorkflowRegistry())
+	do := scheduleRouter(h, scheduleMember)
+
+	dbMock.ExpectQuery(`SELECT \* FROM schedules`).WillReturnRows(scheduleRow(false, "user-1"))
+
+	w := do("POST", "/api/schedules/"+testScheduleID+"/trigger", "")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.temporal.io/sdk/client"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	flcrypto "github.com/tinkerloft/fleetlift/internal/crypto"
+	"github.com/tinkerloft/fleetlift/internal/launch"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/webhook"
+)
+
+const (
+	maxTriggerNameLen = 100
+	// maxWebhookBodyBytes matches GitHub's 25 MB cap on delivery payloads.
+	maxWebhookBodyBytes = 25 << 20
+)
+
+var webhookEventRe = regexp.MustCompile(`^[a-z_]+$`)
+
+// TriggersHandler manages GitHub webhook triggers and receives their deliveries.
+type TriggersHandler struct {
+	db            *sqlx.DB
+	temporal      client.Client
+	registry      *template.Registry
+	encryptionKey string // hex-encoded AES-256 key for trigger secrets
+}
+
+// NewTriggersHandler creates a new TriggersHandler.
+func NewTriggersHandler(db *sqlx.DB, temporal client.Client, registry *template.Registry, encryptionKeyHex string) *TriggersHandler {
+	return &TriggersHandler{db: db, temporal: temporal, registry: registry, encryptionKey: encryptionKeyHex}
+}
+
+// webhookPath is the delivery URL path for a trigger, relative to the server URL.
+func webhookPath(triggerID string) string {
+	return "/webhooks/github/" + triggerID
+}
+
+type triggerRequest struct {
+	Name       *string        `json:"name"`
+	WorkflowID *string        `json:"workflow_id"`
+	Event      *string        `json:"event"`
+	Repo       *string        `json:"repo"`
+	Actions    []string       `json:"actions"`
+	Parameters map[string]any `json:"parameters"`
+	Model      *string        `json:"model"`
+	Enabled    *bool          `json:"enabled"`
+}
+
+// apply overlays the fields set in req onto t.
+func (req triggerRequest) apply(t *model.WebhookTrigger) {
+	if req.Name != nil {
+		t.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.WorkflowID != nil {
+		t.WorkflowID = *req.WorkflowID
+	}
+	if req.Event != nil {
+		t.Event = strings.TrimSpace(*req.Event)
+	}
+	if req.Repo != nil {
+		t.Repo = strings.TrimSpace(*req.Repo)
+	}
+	if req.Actions != nil {
+		t.Actions = pq.StringArray(req.Actions)
+	}
+	if req.Parameters != nil {
+		t.Parameters = req.Parameters
+	}
+	if req.Model != nil {
+		if *req.Model == "" {
+			t.Model = nil
+		} else {
+			m := *req.Model
+			t.Model = &m
+		}
+	}
+	if req.Enabled != nil {
+		t.Enabled = *req.Enabled
+	}
+}
+
+type triggerResponse struct {
+	model.WebhookTrigger
+	WebhookPath string `json:"webhook_path"`
+	Secret      string `json:"secret,omitempty"` // only returned on create
+}
+
+// validate checks t and that every required workflow parameter is mapped.
+// Returns false and writes an error response on failure.
+func (h *TriggersHandler) validate(w http.ResponseWriter, r *http.Request, t *model.WebhookTrigger) bool {
+	switch {
+	case t.Name == "":
+		writeJSONError(w, http.StatusBadRequest, "name is required")
+		return false
+	case len(t.Name) > maxTriggerNameLen:
+		writeJSONError(w, http.StatusBadRequest, "name must be 100 characters or fewer")
+		return false
+	case t.WorkflowID == "":
+		writeJSONError(w, http.StatusBadRequest, "workflow_id is required")
+		return false
+	case !webhookEventRe.MatchString(t.Event):
+		writeJSONError(w, http.StatusBadRequest, "event must be a GitHub event name, e.g. pull_request")
+		return false
+	}
+	if err := webhook.ValidateRepoPattern(t.Repo); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if t.Model != nil && !isAllowedModel(*t.Model) {
+		writeJSONError(w, http.StatusBadRequest, "invalid model")
+		return false
+	}
+	if err := webhook.ValidateParameters(t.Parameters); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	tmpl, err := h.registry.Get(r.Context(), t.TeamID, t.WorkflowID)
+	if err != nil {
+		if errors.Is(err, template.ErrNotFound) {
+			writeJSONError(w, http.StatusNotFound, "workflow not found")
+		} else {
+			slog.Error("failed to load workflow template", "error", err, "team_id", t.TeamID, "workflow_id", t.WorkflowID)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load workflow")
+		}
+		return false
+	}
+	var def model.WorkflowDef
+	if err := model.ParseWorkflowYAML([]byte(tmpl.YAMLBody), &def); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid workflow definition")
+		return false
+	}
+	// Values come from the payload at delivery time, so only presence can be checked here.
+	for _, p := range def.Parameters {
+		if _, ok := t.Parameters[p.Name]; !ok && p.Required && p.Default == nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("parameter %q is required by workflow %s but not mapped", p.Name, t.WorkflowID))
+			return false
+		}
+	}
+	return true
+}
+
+// loadTrigger fetches a team's trigger, writing 404 if it does not exist.
+func (h *TriggersHandler) loadTrigger(ctx context.Context, w http.ResponseWriter, id, teamID string) *model.WebhookTrigger {
+	if _, err := uuid.Parse(id); err != nil {
+		writeJSONError(w, http.StatusNotFound, "trigger not found")
+		return nil
+	}
+	var t model.WebhookTrigger
+	if err := h.db.GetContext(ctx, &t, `SELECT * FROM webhook_triggers WHERE id = $1 AND team_id = $2`, id, teamID); err != nil {
+		writeJSONError(w, http.StatusNotFound, "trigger not found")
+		return nil
+	}
+	return &t
+}
+
+// List returns the team's webhook triggers.
+func (h *TriggersHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	var triggers []model.WebhookTrigger
+	if err := h.db.SelectContext(r.Context(), &triggers,
+		`SELECT * FROM webhook_triggers WHERE team_id = $1 ORDER BY created_at DESC`, teamID); err != nil {
+		slog.Error("failed to list triggers", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list triggers")
+		return
+	}
+	items := make([]triggerResponse, 0, len(triggers))
+	for _, t := range triggers {
+		items = append(items, triggerResponse{WebhookTrigger: t, WebhookPath: webhookPath(t.ID)})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// Get returns a single trigger.
+func (h *TriggersHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+	t := h.loadTrigger(r.Context(), w, chi.URLParam(r, "id"), teamID)
+	if t == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, triggerResponse{WebhookTrigger: *t, WebhookPath: webhookPath(t.ID)})
+}
+
+// Create saves a trigger and returns its webhook secret. The secret is shown once.
+func (h *TriggersHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	var req triggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	t := model.WebhookTrigger{TeamID: teamID, Enabled: true, Actions: pq.StringArray{}, Parameters: model.JSONMap{}}
+	req.apply(&t)
+	if !h.validate(w, r, &t) {
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		slog.Error("failed to generate webhook secret", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create trigger")
+		return
+	}
+	secret := hex.EncodeToString(raw)
+	encrypted, err := flcrypto.EncryptAESGCM(h.encryptionKey, secret)
+	if err != nil {
+		slog.Error("failed to encrypt webhook secret", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create trigger")
+		return
+	}
+
+	var created model.WebhookTrigger
+	err = h.db.GetContext(r.Context(), &created,
+		`INSERT INTO webhook_triggers (team_id, name, workflow_id, event, repo, actions, parameters, model, enabled, secret, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::uuid)
+		 RETURNING *`,
+		teamID, t.Name, t.WorkflowID, t.Event, t.Repo, t.Actions, t.Parameters, t.Model, t.Enabled, encrypted, claims.UserID)
+	if err != nil {
+		slog.Error("failed to create trigger", "error", err, "team_id", teamID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create trigger")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, triggerResponse{
+		WebhookTrigger: created,
+		WebhookPath:    webhookPath(created.ID),
+		Secret:         secret,
+	})
+}
+
+// Update changes a trigger. Only the fields present in the body are modified.
+func (h *TriggersHandler) Update(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+
+	var req triggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	t := h.loadTrigger(r.Context(), w, chi.URLParam(r, "id"), teamID)
+	if t == nil {
+		return
+	}
+	if !isOwnerOrTeamAdmin(claims, t.TeamID, t.CreatedBy) {
+		writeJSONError(w, http.StatusForbidden, "only the trigger owner or a team admin can modify it")
+		return
+	}
+	req.apply(t)
+	if !h.validate(w, r, t) {
+		return
+	}
+
+	var updated model.WebhookTrigger
+	err := h.db.GetContext(r.Context(), &updated,
+		`UPDATE webhook_triggers
+		 SET name = $1, workflow_id = $2, event = $3, repo = $4, actions = $5, parameters = $6, model = $7, enabled = $8, updated_at = now()
+		 WHERE id = $9 AND team_id = $10
+		 RETURNING *`,
+		t.Name, t.WorkflowID, t.Event, t.Repo, t.Actions, t.Parameters, t.Model, t.Enabled, t.ID, teamID)
+	if err != nil {
+		slog.Error("failed to update trigger", "error", err, "trigger_id", t.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to update trigger")
+		return
+	}
+	writeJSON(w, http.StatusOK, triggerResponse{WebhookTrigger: updated, WebhookPath: webhookPath(updated.ID)})
+}
+
+// Delete removes a trigger and its delivery history. Runs it started are kept.
+func (h *TriggersHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+	t := h.loadTrigger(r.Context(), w, chi.URLParam(r, "id"), teamID)
+	if t == nil {
+		return
+	}
+	if !isOwnerOrTeamAdmin(claims, t.TeamID, t.CreatedBy) {
+		writeJSONError(w, http.StatusForbidden, "only the trigger owner or a team admin can delete it")
+		return
+	}
+	if _, err := h.db.ExecContext(r.Context(), `DELETE FROM webhook_triggers WHERE id = $1`, t.ID); err != nil {
+		slog.Error("failed to delete trigger", "error", err, "trigger_id", t.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to delete trigger")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries returns the trigger's most recent accepted deliveries and the runs they started.
+func (h *TriggersHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	claims := auth.ClaimsFromContext(r.Context())
+	if claims == nil {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	teamID := teamIDFromRequest(w, r, claims)
+	if teamID == "" {
+		return // error already written
+	}
+	t := h.loadTrigger(r.Context(), w, chi.URLParam(r, "id"), teamID)
+	if t == nil {
+		return
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	if err := h.db.SelectContext(r.Context(), &deliveries,
+		`SELECT * FROM webhook_deliveries WHERE trigger_id = $1 ORDER BY received_at DESC LIMIT 50`, t.ID); err != nil {
+		slog.Error("failed to list deliveries", "error", err, "trigger_id", t.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": deliveries})
+}
+
+// webhookResult is the body returned to GitHub; it shows up in the delivery log.
+func webhookResult(status, detail string) map[string]string {
+	out := map[string]string{"status": status}
+	if detail != "" {
+		out["detail"] = detail
+	}
+	return out
+}
+
+// HandleGitHub receives a GitHub webhook delivery for a trigger. It is not behind
+// user auth; the delivery is authenticated by its HMAC signature instead.
+func (h *TriggersHandler) HandleGitHub(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeJSONError(w, http.StatusNotFound, "trigger not found")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "payload too large")
+		return
+	}
+
+	var t model.WebhookTrigger
+	if err := h.db.GetContext(ctx, &t, `SELECT * FROM webhook_triggers WHERE id = $1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, http.StatusNotFound, "trigger not found")
+			return
+		}
+		slog.Error("failed to load trigger", "error", err, "trigger_id", id)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load trigger")
+		return
+	}
+	secret, err := flcrypto.DecryptAESGCM(h.encryptionKey, t.Secret)
+	if err != nil {
+		slog.Error("failed to decrypt webhook secret", "error", err, "trigger_id", t.ID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to verify delivery")
+		return
+	}
+	if err := webhook.VerifySignature([]byte(secret), body, r.Header.Get(webhook.SignatureHeader)); err != nil {
+		writeJSONError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	eventName := r.Header.Get(webhook.EventHeader)
+	if eventName == "ping" {
+		writeJSON(w, http.StatusOK, webhookResult("pong", ""))
+		return
+	}
+	deliveryID := r.Header.Get(webhook.DeliveryHeader)
+	if eventName == "" || deliveryID == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing X-GitHub-Event or X-GitHub-Delivery header")
+		return
+	}
+	if !t.Enabled {
+		writeJSON(w, http.StatusOK, webhookResult("ignored", "trigger is disabled"))
+		return
+	}
+	ev, err := webhook.ParseEvent(eventName, deliveryID, body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if ok, reason := webhook.Match(&t, ev); !ok {
+		writeJSON(w, http.StatusOK, webhookResult("ignored", reason))
+		return
+	}
+	if t.CreatedBy == nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, "trigger has no owner; recreate it")
+		return
+	}
+
+	params, err := webhook.RenderParameters(t.Parameters, ev)
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	req := launch.Request{
+		TeamID:      t.TeamID,
+		WorkflowID:  t.WorkflowID,
+		Parameters:  params,
+		TriggeredBy: *t.CreatedBy,
+	}
+	if t.Model != nil {
+		req.Model = *t.Model
+	}
+	run, err := launch.Prepare(ctx, h.registry, req)
+	if err != nil {
+		writeLaunchError(w, err, t.TeamID, t.WorkflowID)
+		return
+	}
+	run.Input.DefaultMaxParallel = launch.DefaultMaxParallel(ctx, h.db, t.TeamID)
+
+	started, err := h.recordDelivery(ctx, &t, ev, run)
+	if err != nil {
+		slog.Error("failed to record webhook run", "error", err, "trigger_id", t.ID, "delivery_id", deliveryID)
+		writeJSONError(w, http.StatusInternalServerError, "failed to create run")
+		return
+	}
+	if !started {
+		writeJSON(w, http.StatusOK, webhookResult("duplicate", "delivery "+deliveryID+" already processed"))
+		return
+	}
+
+	_, err = h.temporal.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        run.TemporalID,
+		TaskQueue: launch.TaskQueue,
+	}, "DAGWorkflow", run.Input)
+	if err != nil {
+		slog.Error("failed to start workflow", "error", err, "trigger_id", t.ID, "run_id", run.ID)
+		// Forget the delivery so GitHub's redelivery can retry it.
+		if _, derr := h.db.ExecContext(ctx,
+			`DELETE FROM webhook_deliveries WHERE trigger_id = $1 AND delivery_id = $2`, t.ID, deliveryID); derr != nil {
+			slog.Error("failed to release webhook delivery", "error", derr, "trigger_id", t.ID, "delivery_id", deliveryID)
+		}
+		if _, uerr := h.db.ExecContext(ctx,
+			`UPDATE runs SET status = $1, error_message = $2, completed_at = now() WHERE id = $3`,
+			string(model.RunStatusFailed), "failed to start workflow", run.ID); uerr != nil {
+			slog.Error("failed to mark run failed", "error", uerr, "run_id", run.ID)
+		}
+		writeJSONError(w, http.StatusInternalServerError, "failed to start workflow")
+		return
+	}
+
+	slog.Info("webhook started run", "trigger_id", t.ID, "delivery_id", deliveryID, "run_id", run.ID)
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status": "started",
+		"run_id": run.ID,
+	})
+}
+
+// recordDelivery inserts the run and the delivery row together. It returns false
+// without recording anything when the delivery was already processed.
+func (h *TriggersHandler) recordDelivery(ctx context.Context, t *model.WebhookTrigger, ev *webhook.Event, run *launch.Run) (bool, error) {
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := launch.Insert(ctx, tx, run); err != nil {
+		return false, fmt.Errorf("insert run: %w", err)
+	}
+	// A concurrent redelivery blocks on the primary key until this commits, then
+	// inserts nothing.
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (trigger_id, delivery_id, event, run_id)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (trigger_id, delivery_id) DO NOTHING`,
+		t.ID, ev.DeliveryID, ev.Name, run.ID)
+	if err != nil {
+		return false, fmt.Errorf("insert delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n == 0 {
+		return false, nil
+	}
+	return true, tx.Commit()
+}
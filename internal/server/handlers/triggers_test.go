@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+
+	"github.com/tinkerloft/fleetlift/internal/auth"
+	flcrypto "github.com/tinkerloft/fleetlift/internal/crypto"
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+	"github.com/tinkerloft/fleetlift/internal/webhook"
+)
+
+const (
+	testTriggerID     = "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
+	testTriggerKey    = "0000000000000000000000000000000000000000000000000000000000000001"
+	testTriggerSecret = "hook-secret"
+)
+
+const prWorkflowYAML = `
+version: 1
+id: pr-check
+parameters:
+  - name: repo_url
+    type: string
+    required: true
+  - name: pr_number
+    type: int
+    required: true
+steps:
+  - id: review
+    execution:
+      agent: claude-code
+      prompt: review {{ .Params.repo_url }} PR {{ .Params.pr_number }}
+`
+
+const prPayload = `{"action":"opened","pull_request":{"number":42},"repository":{"full_name":"acme/api","clone_url":"https://github.com/acme/api.git"}}`
+
+func prWorkflowRegistry() *template.Registry {
+	return template.NewRegistry(&stubProvider{tmpl: &model.WorkflowTemplate{
+		ID: "wf-pr", Slug: "pr-check", Title: "PR Check", YAMLBody: prWorkflowYAML,
+	}})
+}
+
+var triggerColumns = []string{"id", "team_id", "name", "workflow_id", "event", "repo", "actions", "parameters", "model", "enabled", "secret", "created_by", "created_at", "updated_at"}
+
+func triggerRow(t *testing.T, enabled bool) *sqlmock.Rows {
+	t.Helper()
+	secret, err := flcrypto.EncryptAESGCM(testTriggerKey, testTriggerSecret)
+	require.NoError(t, err)
+	now := time.Now()
+	return sqlmock.NewRows(triggerColumns).AddRow(
+		testTriggerID, "team-1", "pr opened", "pr-check", "pull_request", "acme/*", "{opened,synchronize}",
+		[]byte(`{"repo_url":"{{ .Event.repository.clone_url }}","pr_number":"{{ .Event.pull_request.number }}"}`),
+		nil, enabled, secret, "user-1", now, now)
+}
+
+func deliver(h *TriggersHandler, event, deliveryID, body, signature string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Post("/webhooks/github/{id}", h.HandleGitHub)
+	req := httptest.NewRequest("POST", "/webhooks/github/"+testTriggerID, strings.NewReader(body))
+	req.Header.Set(webhook.EventHeader, event)
+	req.Header.Set(webhook.DeliveryHeader, deliveryID)
+	req.Header.Set(webhook.SignatureHeader, signature)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func signed(body string) string {
+	return webhook.Sign([]byte(testTriggerSecret), []byte(body))
+}
+
+func TestTriggers_Create_Validation(t *testing.T) {
+	h := NewTriggersHandler(nil, nil, prWorkflowRegistry(), testTriggerKey)
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantMsg  string
+	}{
+		{"missing name", `{"workflow_id":"pr-check","event":"pull_request"}`, http.StatusBadRequest, "name is required"},
+		{"bad event", `{"name":"n","workflow_id":"pr-check","event":"Pull Request"}`, http.StatusBadRequest, "GitHub event name"},
+		{"bad repo", `{"name":"n","workflow_id":"pr-check","event":"pull_request","repo":"acme"}`, http.StatusBadRequest, "owner/name"},
+		{"bad template", `{"name":"n","workflow_id":"pr-check","event":"pull_request","parameters":{"repo_url":"{{ .Event"}}`, http.StatusBadRequest, "repo_url"},
+		{"unmapped required param", `{"name":"n","workflow_id":"pr-check","event":"pull_request","parameters":{"repo_url":"x"}}`, http.StatusBadRequest, "pr_number"},
+		{"unknown workflow", `{"name":"n","workflow_id":"nope","event":"pull_request"}`, http.StatusNotFound, "workflow not found"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := apiKeyReq("POST", "/api/triggers", tc.body, scheduleMember)
+			w := httptest.NewRecorder()
+			h.Create(w, req)
+			assert.Equal(t, tc.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tc.wantMsg)
+		})
+	}
+}
+
+func TestTriggers_Create_ReturnsSecretOnce(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, prWorkflowRegistry(), testTriggerKey)
+
+	dbMock.ExpectQuery(`INSERT INTO webhook_triggers`).
+		WithArgs("team-1", "pr opened", "pr-check", "pull_request", "acme/*", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, true, sqlmock.AnyArg(), "user-1").
+		WillReturnRows(triggerRow(t, true))
+
+	w := httptest.NewRecorder()
+	h.Create(w, apiKeyReq("POST", "/api/triggers", `{
+		"name":"pr opened","workflow_id":"pr-check","event":"pull_request","repo":"acme/*","actions":["opened"],
+		"parameters":{"repo_url":"{{ .Event.repository.clone_url }}","pr_number":"{{ .Event.pull_request.number }}"}}`, scheduleMember))
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp map[string]any
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp["secret"], 64)
+	assert.Equal(t, "/webhooks/github/"+testTriggerID, resp["webhook_path"])
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestHandleGitHub_RejectsBadSignature(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, prWorkflowRegistry(), testTriggerKey)
+
+	dbMock.ExpectQuery(`SELECT \* FROM webhook_triggers WHERE id = \$1`).WillReturnRows(triggerRow(t, true))
+
+	w := deliver(h, "pull_request", "d-1", prPayload, webhook.Sign([]byte("wrong"), []byte(prPayload)))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestHandleGitHub_IgnoresNonMatchingEvent(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, prWorkflowRegistry(), testTriggerKey)
+
+	body := strings.Replace(prPayload, `"opened"`, `"closed"`, 1)
+	dbMock.ExpectQuery(`SELECT \* FROM webhook_triggers`).WillReturnRows(triggerRow(t, true))
+
+	w := deliver(h, "pull_request", "d-1", body, signed(body))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"ignored"`)
+	assert.Contains(t, w.Body.String(), `action \"closed\"`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func expectWebhookRun(dbMock sqlmock.Sqlmock, deliveryRows int64) {
+	dbMock.ExpectQuery(`SELECT max_parallel FROM teams`).WillReturnError(sql.ErrNoRows)
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(`INSERT INTO runs`).
+		WithArgs(sqlmock.AnyArg(), "team-1", "pr-check", "PR Check", sqlmock.AnyArg(), "", "pending", sqlmock.AnyArg(), "user-1", "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectExec(`INSERT INTO webhook_deliveries`).
+		WithArgs(testTriggerID, "d-1", "pull_request", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, deliveryRows))
+}
+
+func TestHandleGitHub_StartsRun(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	temporal := mocks.NewClient(t)
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), temporal, prWorkflowRegistry(), testTriggerKey)
+
+	dbMock.ExpectQuery(`SELECT \* FROM webhook_triggers`).WillReturnRows(triggerRow(t, true))
+	expectWebhookRun(dbMock, 1)
+	dbMock.ExpectCommit()
+	temporal.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+		return strings.HasPrefix(o.ID, "fl-pr-check-")
+	}), "DAGWorkflow", mock.Anything).Return(nil, nil)
+
+	w := deliver(h, "pull_request", "d-1", prPayload, signed(prPayload))
+
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"started"`)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestHandleGitHub_DeduplicatesRedelivery(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	temporal := mocks.NewClient(t)
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), temporal, prWorkflowRegistry(), testTriggerKey)
+
+	dbMock.ExpectQuery(`SELECT \* FROM webhook_triggers`).WillReturnRows(triggerRow(t, true))
+	expectWebhookRun(dbMock, 0)
+	dbMock.ExpectRollback()
+
+	w := deliver(h, "pull_request", "d-1", prPayload, signed(prPayload))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"duplicate"`)
+	temporal.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestHandleGitHub_AnswersPing(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, prWorkflowRegistry(), testTriggerKey)
+
+	dbMock.ExpectQuery(`SELECT \* FROM webhook_triggers`).WillReturnRows(triggerRow(t, false))
+
+	body := `{"zen":"Keep it logically awesome."}`
+	w := deliver(h, "ping", "d-1", body, signed(body))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "pong")
+}
+
+func TestTriggers_Update_RequiresOwnerOrAdmin(t *testing.T) {
+	sqlDB, dbMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	h := NewTriggersHandler(sqlx.NewDb(sqlDB, "sqlmock"), nil, prWorkflowRegistry(), testTriggerKey)
+
+	dbMock.ExpectQuery(`SELECT \* FROM webhook_triggers WHERE id = \$1 AND team_id = \$2`).
+		WithArgs(testTriggerID, "team-1").
+		WillReturnRows(triggerRow(t, true))
+
+	r := chi.NewRouter()
+	r.Patch("/api/triggers/{id}", h.Update)
+	other := &auth.Claims{UserID: "user-2", TeamRoles: map[string]string{"team-1": "member"}}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, apiKeyReq("PATCH", "/api/triggers/"+testTriggerID, `{"enabled":false}`, other))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	require.NoError(t, dbMock.ExpectationsWereMet())
+}
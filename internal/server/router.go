@@ -29,10 +29,14 @@ type Deps struct {
 	Auth              *handlers.AuthHandler
 	Workflows         *handlers.WorkflowsHandler
 	Runs              *handlers.RunsHandler
+	Schedules         *handlers.SchedulesHandler
+	Triggers          *handlers.TriggersHandler
 	Inbox             *handlers.InboxHandler
 	Reports           *handlers.ReportsHandler
 	Credentials       *handlers.CredentialsHandler
+	APIKeys           *handlers.APIKeysHandler
 	SystemCredentials *handlers.SystemCredentialsHandler
+	Admin             *handlers.AdminHandler
 	Knowledge         *handlers.KnowledgeHandler
 	MCP               *handlers.MCPHandler
 	DB                *sqlx.DB
@@ -130,6 +134,9 @@ func NewRouter(deps Deps) (http.Handler, error) {
 		})
 	})
 
+	// GitHub webhooks (no user auth — deliveries are verified by HMAC signature)
+	r.Post("/webhooks/github/{id}", deps.Triggers.HandleGitHub)
+
 	// MCP API (separate auth — run-scoped JWT, not user JWT)
 	r.Route("/api/mcp", func(r chi.Router) {
 		r.Use(auth.MCPAuth(deps.JWTSecret, deps.DB))
@@ -149,7 +156,11 @@ func NewRouter(deps Deps) (http.Handler, error) {
 		if os.Getenv("DEV_NO_AUTH") == "1" {
 			r.Use(devAuthBypass(deps.JWTSecret))
 		} else {
-			r.Use(auth.Middleware(deps.JWTSecret))
+			var apiKeys auth.APIKeyResolver
+			if deps.DB != nil {
+				apiKeys = &auth.DBAPIKeyStore{DB: deps.DB}
+			}
+			r.Use(auth.Middleware(deps.JWTSecret, apiKeys))
 		}
 
 		// Identity
@@ -177,8 +188,26 @@ func NewRouter(deps Deps) (http.Handler, error) {
 		r.Post("/api/runs/{id}/reject", deps.Runs.Reject)
 		r.Post("/api/runs/{id}/steer", deps.Runs.Steer)
 		r.Post("/api/runs/{id}/cancel", deps.Runs.Cancel)
+		r.Post("/api/runs/{id}/retry", deps.Runs.Retry)
 		r.Post("/api/runs/{id}/resolve-fanout", deps.Runs.ResolveFanOut)
 
+		// Schedules
+		r.Get("/api/schedules", deps.Schedules.List)
+		r.Post("/api/schedules", deps.Schedules.Create)
+		r.Get("/api/schedules/{id}", deps.Schedules.Get)
+		r.Patch("/api/schedules/{id}", deps.Schedules.Update)
+		r.Delete("/api/schedules/{id}", deps.Schedules.Delete)
+		r.Post("/api/schedules/{id}/trigger", deps.Schedules.Trigger)
+		r.Get("/api/schedules/{id}/runs", deps.Schedules.Runs)
+
+		// Webhook triggers
+		r.Get("/api/triggers", deps.Triggers.List)
+		r.Post("/api/triggers", deps.Triggers.Create)
+		r.Get("/api/triggers/{id}", deps.Triggers.Get)
+		r.Patch("/api/triggers/{id}", deps.Triggers.Update)
+		r.Delete("/api/triggers/{id}", deps.Triggers.Delete)
+		r.Get("/api/triggers/{id}/deliveries", deps.Triggers.Deliveries)
+
 		// Inbox
 		r.Get("/api/inbox", deps.Inbox.List)
 		r.Post("/api/inbox/{id}/read", deps.Inbox.MarkRead)
@@ -196,11 +225,19 @@ func NewRouter(deps Deps) (http.Handler, error) {
 		r.Post("/api/credentials", deps.Credentials.Set)
 		r.Delete("/api/credentials/{name}", deps.Credentials.Delete)
 
+		// API keys (team admin only)
+		r.Get("/api/api-keys", deps.APIKeys.List)
+		r.Post("/api/api-keys", deps.APIKeys.Create)
+		r.Delete("/api/api-keys/{id}", deps.APIKeys.Revoke)
+
 		// System Credentials (admin only)
 		r.Get("/api/system-credentials", deps.SystemCredentials.List)
 		r.Post("/api/system-credentials", deps.SystemCredentials.Set)
 		r.Delete("/api/system-credentials/{name}", deps.SystemCredentials.Delete)
 
+		// Platform operations (admin only)
+		r.Get("/api/admin/sandboxes/orphans", deps.Admin.OrphanedSandboxes)
+
 		// Knowledge
 		r.Get("/api/knowledge", deps.Knowledge.List)
 		r.Patch("/api/knowledge/{id}", deps.Knowledge.UpdateStatus)
@@ -28,7 +28,7 @@ func TestNewRouter_ReturnsErrorOnBadFS(t *testing.T) {
 		Workflows:   handlers.NewWorkflowsHandler(registry),
 		Runs:        handlers.NewRunsHandler(nil, nil, registry, nil),
 		Inbox:       handlers.NewInboxHandler(nil, nil),
-		Reports:     handlers.NewReportsHandler(nil),
+		Reports:     handlers.NewReportsHandler(nil, nil),
 		Credentials: newTestCredentialsHandler(),
 		Knowledge:   handlers.NewKnowledgeHandler(nil),
 	}
@@ -45,7 +45,7 @@ func TestNewRouter_HealthEndpoint(t *testing.T) {
 		Workflows:   handlers.NewWorkflowsHandler(registry),
 		Runs:        handlers.NewRunsHandler(nil, nil, registry, nil),
 		Inbox:       handlers.NewInboxHandler(nil, nil),
-		Reports:     handlers.NewReportsHandler(nil),
+		Reports:     handlers.NewReportsHandler(nil, nil),
 		Credentials: newTestCredentialsHandler(),
 		Knowledge:   handlers.NewKnowledgeHandler(nil),
 	}
@@ -69,7 +69,7 @@ func TestNewRouter_SPAFallback(t *testing.T) {
 		Workflows:   handlers.NewWorkflowsHandler(registry),
 		Runs:        handlers.NewRunsHandler(nil, nil, registry, nil),
 		Inbox:       handlers.NewInboxHandler(nil, nil),
-		Reports:     handlers.NewReportsHandler(nil),
+		Reports:     handlers.NewReportsHandler(nil, nil),
 		Credentials: newTestCredentialsHandler(),
 		Knowledge:   handlers.NewKnowledgeHandler(nil),
 	}
@@ -95,7 +95,7 @@ func TestNewRouter_AuthEndpointsExist(t *testing.T) {
 		Workflows:   handlers.NewWorkflowsHandler(registry),
 		Runs:        handlers.NewRunsHandler(nil, nil, registry, nil),
 		Inbox:       handlers.NewInboxHandler(nil, nil),
-		Reports:     handlers.NewReportsHandler(nil),
+		Reports:     handlers.NewReportsHandler(nil, nil),
 		Credentials: newTestCredentialsHandler(),
 		Knowledge:   handlers.NewKnowledgeHandler(nil),
 	}
@@ -132,7 +132,7 @@ func TestNewRouter_ProtectedEndpointsRequireAuth(t *testing.T) {
 		Workflows:   handlers.NewWorkflowsHandler(registry),
 		Runs:        handlers.NewRunsHandler(nil, nil, registry, nil),
 		Inbox:       handlers.NewInboxHandler(nil, nil),
-		Reports:     handlers.NewReportsHandler(nil),
+		Reports:     handlers.NewReportsHandler(nil, nil),
 		Credentials: newTestCredentialsHandler(),
 		Knowledge:   handlers.NewKnowledgeHandler(nil),
 	}
@@ -169,7 +169,7 @@ func TestNewRouter_DevLoginSetsCookieForSSE(t *testing.T) {
 		Workflows:   handlers.NewWorkflowsHandler(registry),
 		Runs:        handlers.NewRunsHandler(nil, nil, registry, nil),
 		Inbox:       handlers.NewInboxHandler(nil, nil),
-		Reports:     handlers.NewReportsHandler(nil),
+		Reports:     handlers.NewReportsHandler(nil, nil),
 		Credentials: newTestCredentialsHandler(),
 		Knowledge:   handlers.NewKnowledgeHandler(nil),
 	}
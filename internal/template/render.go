@@ -18,20 +18,36 @@ type RenderContext struct {
 
 // RenderPrompt resolves Go template expressions in a prompt string.
 func RenderPrompt(tmpl string, ctx RenderContext) (string, error) {
-	t, err := template.New("prompt").
-		Funcs(templateFuncs()).
-		Option("missingkey=error").
-		Parse(tmpl)
+	return Render("prompt", tmpl, ctx)
+}
+
+// Render executes tmpl against data with the same functions and strictness as
+// prompts. Missing map keys are an error rather than "<no value>".
+func Render(name, tmpl string, data any) (string, error) {
+	t, err := Parse(name, tmpl)
 	if err != nil {
-		return "", fmt.Errorf("parse template: %w", err)
+		return "", err
 	}
 	var buf bytes.Buffer
-	if err := t.Execute(&buf, ctx); err != nil {
+	if err := t.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("render template: %w", err)
 	}
 	return buf.String(), nil
 }
 
+// Parse parses tmpl with the prompt template functions, for validating a template
+// before it is rendered.
+func Parse(name, tmpl string) (*template.Template, error) {
+	t, err := template.New(name).
+		Funcs(templateFuncs()).
+		Option("missingkey=error").
+		Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return t, nil
+}
+
 func templateFuncs() template.FuncMap {
 	return template.FuncMap{
 		"toJSON":   toJSON,
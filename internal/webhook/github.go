@@ -0,0 +1,195 @@
+// Package webhook verifies GitHub webhook deliveries and maps them onto workflow
+// triggers.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"text/template/parse"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	"github.com/tinkerloft/fleetlift/internal/template"
+)
+
+// GitHub delivery headers.
+const (
+	SignatureHeader = "X-Hub-Signature-256"
+	EventHeader     = "X-GitHub-Event"
+	DeliveryHeader  = "X-GitHub-Delivery"
+)
+
+// ErrInvalidSignature is returned when a delivery's signature does not match its body.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the X-Hub-Signature-256 value GitHub sends for body.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks an X-Hub-Signature-256 header against body in constant time.
+func VerifySignature(secret, body []byte, header string) error {
+	if !strings.HasPrefix(header, "sha256=") {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(header), []byte(Sign(secret, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Event is a decoded webhook delivery.
+type Event struct {
+	Name       string         // X-GitHub-Event, e.g. "pull_request"
+	DeliveryID string         // X-GitHub-Delivery
+	Action     string         // payload "action"; empty for events without one (e.g. push)
+	Repo       string         // payload repository.full_name
+	Payload    map[string]any // numbers decode as json.Number so IDs render exactly
+}
+
+// ParseEvent decodes a delivery body.
+func ParseEvent(name, deliveryID string, body []byte) (*Event, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var payload map[string]any
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	ev := &Event{Name: name, DeliveryID: deliveryID, Payload: payload}
+	ev.Action, _ = payload["action"].(string)
+	if repo, ok := payload["repository"].(map[string]any); ok {
+		ev.Repo, _ = repo["full_name"].(string)
+	}
+	return ev, nil
+}
+
+// Match reports whether ev passes the trigger's event, action and repo filters.
+// When it does not, the returned reason says which filter rejected it.
+func Match(t *model.WebhookTrigger, ev *Event) (bool, string) {
+	if ev.Name != t.Event {
+		return false, fmt.Sprintf("event %q does not match %q", ev.Name, t.Event)
+	}
+	if len(t.Actions) > 0 && !slices.Contains(t.Actions, ev.Action) {
+		return false, fmt.Sprintf("action %q not in %v", ev.Action, []string(t.Actions))
+	}
+	if t.Repo != "" {
+		ok, _ := path.Match(strings.ToLower(t.Repo), strings.ToLower(ev.Repo))
+		if !ok {
+			return false, fmt.Sprintf("repository %q does not match %q", ev.Repo, t.Repo)
+		}
+	}
+	return true, ""
+}
+
+// ValidateRepoPattern checks a trigger repo filter: empty, or owner/name where
+// either part may be a glob.
+func ValidateRepoPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	owner, name, ok := strings.Cut(pattern, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("repo must be owner/name, e.g. acme/api or acme/*")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("repo pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// templateData is what parameter templates are rendered against.
+type templateData struct {
+	Event    map[string]any // full payload: {{ .Event.pull_request.number }}
+	Name     string
+	Action   string
+	Repo     string
+	Delivery string
+}
+
+// ValidateParameters checks that every string parameter mapping parses as a template.
+func ValidateParameters(mapping map[string]any) error {
+	for name, v := range mapping {
+		if s, ok := v.(string); ok {
+			if _, err := template.Parse(name, s); err != nil {
+				return fmt.Errorf("parameter %q: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RenderParameters renders the trigger's parameter mapping against ev. String values
+// are templates and render to strings, except that a template that is nothing but a
+// reference to a non-string payload field keeps the field's type (so
+// "{{ .Event.pull_request.number }}" yields a number, while a branch named "123"
+// stays a string). Non-string values are passed through as literals.
+func RenderParameters(mapping map[string]any, ev *Event) (map[string]any, error) {
+	data := templateData{
+		Event:    ev.Payload,
+		Name:     ev.Name,
+		Action:   ev.Action,
+		Repo:     ev.Repo,
+		Delivery: ev.DeliveryID,
+	}
+	params := make(map[string]any, len(mapping))
+	for name, v := range mapping {
+		tmpl, ok := v.(string)
+		if !ok {
+			params[name] = v
+			continue
+		}
+		out, err := template.Render(name, tmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, err)
+		}
+		params[name] = out
+		if field, ok := eventField(tmpl, ev.Payload); ok {
+			if _, isString := field.(string); !isString {
+				// Round-trip through JSON so numbers decoded as json.Number
+				// come out as they would from any other JSON source.
+				var typed any
+				if b, err := json.Marshal(field); err == nil && json.Unmarshal(b, &typed) == nil {
+					params[name] = typed
+				}
+			}
+		}
+	}
+	return params, nil
+}
+
+// eventField returns the payload value tmpl refers to when tmpl consists of a
+// single ".Event.<path>" reference and nothing else.
+func eventField(tmpl string, payload map[string]any) (any, bool) {
+	t, err := template.Parse("field", tmpl)
+	if err != nil || t.Tree == nil || len(t.Tree.Root.Nodes) != 1 {
+		return nil, false
+	}
+	action, ok := t.Tree.Root.Nodes[0].(*parse.ActionNode)
+	if !ok || len(action.Pipe.Decl) != 0 || len(action.Pipe.Cmds) != 1 || len(action.Pipe.Cmds[0].Args) != 1 {
+		return nil, false
+	}
+	field, ok := action.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) < 2 || field.Ident[0] != "Event" {
+		return nil, false
+	}
+	var v any = payload
+	for _, key := range field.Ident[1:] {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+const prOpenedPayload = `{
+  "action": "opened",
+  "number": 42,
+  "pull_request": {"number": 42, "id": 2147483648123, "title": "Fix it", "head": {"ref": "fix/it"}},
+  "repository": {"full_name": "Acme/API", "clone_url": "https://github.com/Acme/API.git"}
+}`
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"zen":"hi"}`)
+
+	assert.NoError(t, VerifySignature(secret, body, Sign(secret, body)))
+	assert.ErrorIs(t, VerifySignature([]byte("other"), body, Sign(secret, body)), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature(secret, []byte(`{"zen":"bye"}`), Sign(secret, body)), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature(secret, body, ""), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature(secret, body, "sha1=abc"), ErrInvalidSignature)
+}
+
+func TestParseEvent(t *testing.T) {
+	ev, err := ParseEvent("pull_request", "d-1", []byte(prOpenedPayload))
+	require.NoError(t, err)
+	assert.Equal(t, "opened", ev.Action)
+	assert.Equal(t, "Acme/API", ev.Repo)
+
+	_, err = ParseEvent("pull_request", "d-1", []byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestMatch(t *testing.T) {
+	ev, err := ParseEvent("pull_request", "d-1", []byte(prOpenedPayload))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		trigger model.WebhookTrigger
+		want    bool
+	}{
+		{"event only", model.WebhookTrigger{Event: "pull_request"}, true},
+		{"other event", model.WebhookTrigger{Event: "issues"}, false},
+		{"action listed", model.WebhookTrigger{Event: "pull_request", Actions: []string{"opened", "synchronize"}}, true},
+		{"action not listed", model.WebhookTrigger{Event: "pull_request", Actions: []string{"closed"}}, false},
+		{"exact repo, case-insensitive", model.WebhookTrigger{Event: "pull_request", Repo: "acme/api"}, true},
+		{"owner glob", model.WebhookTrigger{Event: "pull_request", Repo: "acme/*"}, true},
+		{"other repo", model.WebhookTrigger{Event: "pull_request", Repo: "acme/web"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, reason := Match(&tc.trigger, ev)
+			assert.Equal(t, tc.want, ok)
+			if !ok {
+				assert.NotEmpty(t, reason)
+			}
+		})
+	}
+}
+
+func TestValidateRepoPattern(t *testing.T) {
+	for _, p := range []string{"", "acme/api", "acme/*", "*/api"} {
+		assert.NoError(t, ValidateRepoPattern(p), p)
+	}
+	for _, p := range []string{"acme", "acme/", "/api", "acme/api/x", "acme/[api"} {
+		assert.Error(t, ValidateRepoPattern(p), p)
+	}
+}
+
+func TestRenderParameters(t *testing.T) {
+	ev, err := ParseEvent("pull_request", "d-1", []byte(prOpenedPayload))
+	require.NoError(t, err)
+
+	params, err := RenderParameters(map[string]any{
+		"repo_url":  "{{ .Event.repository.clone_url }}",
+		"pr_number": "{{ .Event.pull_request.number }}",
+		"pr_id":     "{{ .Event.pull_request.id }}",
+		"branch":    "{{ .Event.pull_request.head.ref }}",
+		"summary":   "{{ .Repo }}#{{ .Event.number }} ({{ .Action }})",
+		"number":    "#{{ .Event.number }}",
+		"dry_run":   true,
+	}, ev)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://github.com/Acme/API.git", params["repo_url"])
+	assert.Equal(t, float64(42), params["pr_number"])
+	assert.Equal(t, float64(2147483648123), params["pr_id"], "large IDs must not render in exponent form")
+	assert.Equal(t, "fix/it", params["branch"])
+	assert.Equal(t, "Acme/API#42 (opened)", params["summary"])
+	assert.Equal(t, "#42", params["number"])
+	assert.Equal(t, true, params["dry_run"])
+}
+
+func TestRenderParameters_StringFieldsStayStrings(t *testing.T) {
+	ev, err := ParseEvent("push", "d-1", []byte(`{"ref":"123","head_commit":{"message":"true"},"size":3}`))
+	require.NoError(t, err)
+
+	params, err := RenderParameters(map[string]any{
+		"branch":  "{{ .Event.ref }}",
+		"message": "{{ .Event.head_commit.message }}",
+		"size":    "{{ .Event.size }}",
+		"padded":  " {{ .Event.size }}",
+	}, ev)
+	require.NoError(t, err)
+
+	assert.Equal(t, "123", params["branch"])
+	assert.Equal(t, "true", params["message"])
+	assert.Equal(t, float64(3), params["size"])
+	assert.Equal(t, " 3", params["padded"])
+}
+
+func TestRenderParameters_MissingKeyFails(t *testing.T) {
+	ev, err := ParseEvent("issues", "d-1", []byte(`{"action":"opened","issue":{"number":7}}`))
+	require.NoError(t, err)
+
+	_, err = RenderParameters(map[string]any{"pr_number": "{{ .Event.pull_request.number }}"}, ev)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `parameter "pr_number"`)
+}
+
+func TestValidateParameters(t *testing.T) {
+	assert.NoError(t, ValidateParameters(map[string]any{"a": "{{ .Event.x }}", "b": 3}))
+	assert.Error(t, ValidateParameters(map[string]any{"a": "{{ .Event.x "}))
+}
@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+	fltemplate "github.com/tinkerloft/fleetlift/internal/template"
+)
+
+// branchTemplateData is what a step's branch template is rendered against.
+type branchTemplateData struct {
+	RunID  string
+	StepID string
+	Repo   branchTemplateRepo
+	Params map[string]any
+}
+
+type branchTemplateRepo struct {
+	URL    string
+	Name   string
+	Branch string // base branch the repo was cloned at
+	Ref    string
+}
+
+// resolveBranch returns the working branch for one repo of a step, or "" when
+// the step works on the repo's base branch. ExecuteStep creates the branch
+// after clone and CreatePullRequest pushes it, so both use the same name.
+//
+// The step's branch template wins. Otherwise the name is a prefix followed by
+// the run ID, where the prefix is the repo's create_branch or, failing that,
+// pull_request.branch_prefix.
+func resolveBranch(step model.StepDef, pr *model.PRDef, repo model.RepoRef, runID string, params map[string]any) (string, error) {
+	var name string
+	switch {
+	case step.Branch != "":
+		rendered, err := fltemplate.Render("branch", step.Branch, branchTemplateData{
+			RunID:  runID,
+			StepID: step.ID,
+			Repo: branchTemplateRepo{
+				URL:    repo.URL,
+				Name:   branchRepoName(repo),
+				Branch: repo.Branch,
+				Ref:    repo.Ref,
+			},
+			Params: params,
+		})
+		if err != nil {
+			return "", fmt.Errorf("render branch for step %s: %w", step.ID, err)
+		}
+		name = strings.TrimSpace(rendered)
+	case repo.CreateBranch != "":
+		name = repo.CreateBranch + "/" + runID
+	case pr != nil && pr.BranchPrefix != "":
+		name = pr.BranchPrefix + "/" + runID
+	default:
+		return "", nil
+	}
+	if err := validBranchName(name); err != nil {
+		return "", fmt.Errorf("branch for step %s: %w", step.ID, err)
+	}
+	return name, nil
+}
+
+// branchRepoName is the repo's name, or the last element of its URL.
+func branchRepoName(repo model.RepoRef) string {
+	if repo.Name != "" {
+		return repo.Name
+	}
+	return path.Base(strings.TrimSuffix(strings.TrimSuffix(repo.URL, "/"), ".git"))
+}
+
+// validBranchName applies the parts of git check-ref-format that a rendered
+// template can plausibly get wrong.
+func validBranchName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("branch name is empty")
+	case strings.HasPrefix(name, "-"), strings.HasPrefix(name, "/"), strings.HasSuffix(name, "/"),
+		strings.HasSuffix(name, "."), strings.HasSuffix(name, ".lock"),
+		strings.Contains(name, ".."), strings.Contains(name, "//"), strings.Contains(name, "@{"),
+		strings.ContainsAny(name, " ~^:?*[\\\t\n"):
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+// checkFanOutBranches reports two fan-out repos that resolve to the same
+// branch of the same repository, which would push over each other.
+func checkFanOutBranches(repos []model.RepoRef, branches []string) error {
+	seen := make(map[string]int, len(repos))
+	for j, repo := range repos {
+		if branches[j] == "" {
+			continue
+		}
+		key := repo.URL + "\x00" + branches[j]
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("repos %d and %d of %s both use branch %q; make the branch template distinguish them, e.g. with .Repo.Branch",
+				first, j, repo.URL, branches[j])
+		}
+		seen[key] = j
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+func TestResolveBranch(t *testing.T) {
+	api := model.RepoRef{URL: "https://github.com/acme/api.git", Branch: "develop"}
+	pr := &model.PRDef{BranchPrefix: "auto/fix"}
+	params := map[string]any{"ticket": "OPS-12"}
+
+	tests := []struct {
+		name string
+		step model.StepDef
+		repo model.RepoRef
+		pr   *model.PRDef
+		want string
+	}{
+		{"template", model.StepDef{ID: "fix", Branch: "{{ .Params.ticket }}/{{ .StepID }}-{{ .Repo.Name }}-{{ .Repo.Branch }}"}, api, pr, "OPS-12/fix-api-develop"},
+		{"template sees run ID", model.StepDef{ID: "fix", Branch: "agent/{{ .RunID }}"}, api, nil, "agent/run-1"},
+		{"create_branch wins over branch_prefix", model.StepDef{ID: "fix"}, model.RepoRef{URL: api.URL, CreateBranch: "agent/quick-run"}, pr, "agent/quick-run/run-1"},
+		{"branch_prefix", model.StepDef{ID: "fix"}, api, pr, "auto/fix/run-1"},
+		{"no branch", model.StepDef{ID: "fix"}, api, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveBranch(tt.step, tt.pr, tt.repo, "run-1", params)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := resolveBranch(model.StepDef{ID: "fix", Branch: "fix {{ .Params.ticket }}"}, nil, api, "run-1", params)
+	assert.ErrorContains(t, err, `invalid branch name "fix OPS-12"`)
+	_, err = resolveBranch(model.StepDef{ID: "fix", Branch: "fix/{{ .Params.missing }}"}, nil, api, "run-1", params)
+	assert.Error(t, err)
+}
+
+func TestCheckFanOutBranches(t *testing.T) {
+	repos := []model.RepoRef{
+		{URL: "https://github.com/acme/api", Branch: "main"},
+		{URL: "https://github.com/acme/web"},
+		{URL: "https://github.com/acme/api", Branch: "release"},
+	}
+	assert.NoError(t, checkFanOutBranches(repos, []string{"fix/run-1", "fix/run-1", "fix/run-1-release"}))
+	assert.NoError(t, checkFanOutBranches(repos, []string{"", "", ""}))
+	assert.ErrorContains(t, checkFanOutBranches(repos, []string{"fix/run-1", "fix/run-1", "fix/run-1"}),
+		`repos 0 and 2 of https://github.com/acme/api both use branch "fix/run-1"`)
+}
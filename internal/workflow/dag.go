@@ -2,10 +2,13 @@ package workflow
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -32,8 +35,50 @@ type DAGInput struct {
 	Parameters         map[string]any    `json:"parameters"`
 	ModelOverride      string            `json:"model_override,omitempty"`
 	TriggeredBy        string            `json:"triggered_by,omitempty"` // user ID who started the run
+	// DefaultMaxParallel bounds fan-out concurrency for steps that do not set
+	// max_parallel. Resolved by the server (team setting, then global default)
+	// when the run starts; zero means unbounded.
+	DefaultMaxParallel int `json:"default_max_parallel,omitempty"`
+	// SeedOutputs holds the outputs of steps already completed by a parent run
+	// (see RetrySeed). Seeded steps are not executed again; their outputs feed
+	// templates and conditions of the steps that do run.
+	SeedOutputs map[string]*model.StepOutput `json:"seed_outputs,omitempty"`
 }
 
+// sandboxKey identifies a sandbox shared by the steps of a sandbox group. Fan-out
+// steps in a group share one sandbox per repo checkout: the same URL at another
+// branch or ref gets its own sandbox, since both would clone to the same path.
+type sandboxKey struct {
+	Group   string
+	RepoURL string
+	Branch  string
+	Ref     string
+}
+
+func repoSandboxKey(group string, repo model.RepoRef) sandboxKey {
+	return sandboxKey{Group: group, RepoURL: repo.URL, Branch: repo.Branch, Ref: repo.Ref}
+}
+
+// checkGroupRepos reports two entries of a sandbox-group fan-out that check out
+// the same repo at the same branch and ref. They would share one sandbox and
+// clone, with both children running in it at once.
+func checkGroupRepos(group string, repos []model.RepoRef) error {
+	seen := make(map[sandboxKey]int, len(repos))
+	for j, repo := range repos {
+		key := repoSandboxKey(group, repo)
+		if first, ok := seen[key]; ok {
+			return fmt.Errorf("repos %d and %d of sandbox group %s are the same checkout of %s; give them different branches or refs",
+				first, j, group, repo.URL)
+		}
+		seen[key] = j
+	}
+	return nil
+}
+
+// DefaultFanOutMaxParallel is the global fan-out concurrency used when neither the
+// step nor the team configures one.
+const DefaultFanOutMaxParallel = 10
+
 // DAGWorkflow orchestrates a DAG of steps, running independent steps in parallel
 // and respecting dependency edges between them.
 func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
@@ -181,9 +226,14 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 	}
 
 	outputs := map[string]*model.StepOutput{}
-	sandboxes := map[string]string{} // sandbox_group -> sandbox_id
+	sandboxes := map[sandboxKey]string{}
+	provisioning := map[sandboxKey]bool{}
 	pending := make(map[string]model.StepDef, len(steps))
 	for _, s := range steps {
+		if seeded, ok := input.SeedOutputs[s.ID]; ok && seeded != nil {
+			outputs[s.ID] = seeded
+			continue
+		}
 		pending[s.ID] = s
 	}
 
@@ -217,13 +267,25 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 	// Cleanup sandbox groups on any exit path (normal, failure, or cancellation).
 	defer func() {
 		cleanupCtx, _ := workflow.NewDisconnectedContext(ctx)
-		cleanupGroups := make([]string, 0, len(sandboxes))
-		for group := range sandboxes {
-			cleanupGroups = append(cleanupGroups, group)
+		cleanupKeys := make([]sandboxKey, 0, len(sandboxes))
+		for key := range sandboxes {
+			cleanupKeys = append(cleanupKeys, key)
 		}
-		sort.Strings(cleanupGroups)
-		for _, group := range cleanupGroups {
-			sandboxID := sandboxes[group]
+		sort.Slice(cleanupKeys, func(a, b int) bool {
+			ka, kb := cleanupKeys[a], cleanupKeys[b]
+			if ka.Group != kb.Group {
+				return ka.Group < kb.Group
+			}
+			if ka.RepoURL != kb.RepoURL {
+				return ka.RepoURL < kb.RepoURL
+			}
+			if ka.Branch != kb.Branch {
+				return ka.Branch < kb.Branch
+			}
+			return ka.Ref < kb.Ref
+		})
+		for _, key := range cleanupKeys {
+			sandboxID := sandboxes[key]
 			ao := workflow.ActivityOptions{
 				StartToCloseTimeout: 2 * time.Minute,
 				RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
@@ -232,10 +294,57 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 				workflow.WithActivityOptions(cleanupCtx, ao),
 				CleanupSandboxActivity, sandboxID,
 			).Get(cleanupCtx, nil)
-			logger.Info("cleaned up sandbox group", "group", group)
+			logger.Info("cleaned up sandbox group", "group", key.Group, "repo", key.RepoURL)
 		}
 	}()
 
+	// groupSandbox returns the sandbox shared under key, provisioning it on first
+	// use. Steps that become ready together wait for a single provision.
+	groupSandbox := func(gCtx workflow.Context, key sandboxKey, step model.StepDef) (string, error) {
+		if err := workflow.Await(gCtx, func() bool { return !provisioning[key] }); err != nil {
+			return "", err
+		}
+		if sandboxID := sandboxes[key]; sandboxID != "" {
+			return sandboxID, nil
+		}
+		provisioning[key] = true
+		defer delete(provisioning, key)
+		ao := workflow.ActivityOptions{
+			StartToCloseTimeout: 5 * time.Minute,
+			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+		}
+		// Build a proper StepInput so ProvisionSandbox gets the right agent/credentials.
+		provisionInput := StepInput{
+			RunID:       input.RunID,
+			TeamID:      input.TeamID,
+			TriggeredBy: input.TriggeredBy,
+		}
+		groupImage := input.WorkflowDef.SandboxGroups[key.Group].Image
+		if step.Execution != nil {
+			provisionInput.ResolvedOpts = ResolvedStepOpts{
+				Agent:             step.Execution.Agent,
+				Credentials:       step.Execution.Credentials,
+				SandboxGroupImage: groupImage,
+				SandboxSpec:       step.Sandbox,
+			}
+		} else {
+			provisionInput.ResolvedOpts = ResolvedStepOpts{
+				SandboxGroupImage: groupImage,
+				SandboxSpec:       step.Sandbox,
+			}
+		}
+		var sandboxID string
+		if err := workflow.ExecuteActivity(
+			workflow.WithActivityOptions(gCtx, ao),
+			ProvisionSandboxActivity, provisionInput,
+		).Get(gCtx, &sandboxID); err != nil {
+			return "", fmt.Errorf("provision sandbox group %s: %w", key.Group, err)
+		}
+		sandboxes[key] = sandboxID
+		logger.Info("provisioned sandbox group", "group", key.Group, "repo", key.RepoURL, "sandbox_id", sandboxID)
+		return sandboxID, nil
+	}
+
 	for len(pending) > 0 {
 		// Check for cancellation before starting new steps.
 		if ctx.Err() != nil {
@@ -247,46 +356,6 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 			return fmt.Errorf("DAG deadlock: circular dependency or all steps blocked")
 		}
 
-		// Provision sandbox groups for ready steps that need new sandboxes
-		for _, step := range ready {
-			if step.SandboxGroup != "" && sandboxes[step.SandboxGroup] == "" {
-				ao := workflow.ActivityOptions{
-					StartToCloseTimeout: 5 * time.Minute,
-					RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
-				}
-				// Build a proper StepInput so ProvisionSandbox gets the right agent/credentials.
-				provisionInput := StepInput{
-					RunID:       input.RunID,
-					TeamID:      input.TeamID,
-					TriggeredBy: input.TriggeredBy,
-				}
-				groupImage := input.WorkflowDef.SandboxGroups[step.SandboxGroup].Image
-				if step.Execution != nil {
-					provisionInput.ResolvedOpts = ResolvedStepOpts{
-						Agent:             step.Execution.Agent,
-						Credentials:       step.Execution.Credentials,
-						SandboxGroupImage: groupImage,
-						SandboxSpec:       step.Sandbox,
-					}
-				} else {
-					provisionInput.ResolvedOpts = ResolvedStepOpts{
-						SandboxGroupImage: groupImage,
-						SandboxSpec:       step.Sandbox,
-					}
-				}
-				var sandboxID string
-				err := workflow.ExecuteActivity(
-					workflow.WithActivityOptions(ctx, ao),
-					ProvisionSandboxActivity, provisionInput,
-				).Get(ctx, &sandboxID)
-				if err != nil {
-					return fmt.Errorf("provision sandbox group %s: %w", step.SandboxGroup, err)
-				}
-				sandboxes[step.SandboxGroup] = sandboxID
-				logger.Info("provisioned sandbox group", "group", step.SandboxGroup, "sandbox_id", sandboxID)
-			}
-		}
-
 		// Launch ready steps in parallel
 		wg := workflow.NewWaitGroup(ctx)
 		results := make([]*model.StepOutput, len(ready))
@@ -405,6 +474,27 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 				// Fan-out: one child per repo if multiple repos are specified.
 				repos := resolved.Repos
 				if len(repos) <= 1 {
+					var sandboxID string
+					if step.SandboxGroup != "" {
+						if sandboxID, err = groupSandbox(gCtx, sandboxKey{Group: step.SandboxGroup}, step); err != nil {
+							results[i] = &model.StepOutput{
+								StepID: step.ID,
+								Status: model.StepStatusFailed,
+								Error:  err.Error(),
+							}
+							return
+						}
+					}
+					if len(repos) == 1 {
+						if resolved.Branch, err = resolveBranch(step, resolved.PRConfig, repos[0], input.RunID, input.Parameters); err != nil {
+							results[i] = &model.StepOutput{
+								StepID: step.ID,
+								Status: model.StepStatusFailed,
+								Error:  err.Error(),
+							}
+							return
+						}
+					}
 					// Single execution (no fan-out) — create a step_run record first.
 					childWFID := fmt.Sprintf("%s-%s", input.RunID, step.ID)
 					createAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
@@ -424,6 +514,13 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 						}
 						return
 					}
+					if len(repos) == 1 && step.Filter != "" {
+						if skipped := filterRepo(gCtx, step, repos[0], input.Parameters, outputs); skipped != nil {
+							_ = finalizeStep(gCtx, logger, stepRunID, skipped)
+							results[i] = skipped
+							return
+						}
+					}
 					cwo := workflow.ChildWorkflowOptions{
 						WorkflowID: childWFID,
 					}
@@ -438,9 +535,11 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 							WorkflowTemplateID: input.WorkflowTemplateID,
 							StepDef:            step,
 							ResolvedOpts:       resolved,
-							SandboxID:          sandboxes[step.SandboxGroup],
+							SandboxID:          sandboxID,
 							ModelOverride:      input.ModelOverride,
 							TriggeredBy:        input.TriggeredBy,
+							RunBudget:          input.WorkflowDef.Budget,
+							ParamsHash:         ParamsHash(input.Parameters),
 						},
 					).Get(gCtx, &out)
 					if err != nil {
@@ -451,6 +550,9 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 						}
 						return
 					}
+					if len(repos) == 1 {
+						setRepo(&out, repos[0])
+					}
 					results[i] = &out
 					return
 				}
@@ -463,33 +565,155 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 						"step_id", step.ID, "original_policy", step.ApprovalPolicy)
 					step.ApprovalPolicy = "never"
 				}
+				// Resolve every repo's working branch before any child starts, so a
+				// template that maps two entries of one repo to the same branch
+				// fails the step instead of letting the children push over each other.
+				branches := make([]string, len(repos))
+				for j, repo := range repos {
+					if branches[j], err = resolveBranch(step, resolved.PRConfig, repo, input.RunID, input.Parameters); err != nil {
+						break
+					}
+				}
+				if err == nil {
+					err = checkFanOutBranches(repos, branches)
+				}
+				if err == nil && step.SandboxGroup != "" {
+					err = checkGroupRepos(step.SandboxGroup, repos)
+				}
+				if err != nil {
+					results[i] = &model.StepOutput{
+						StepID: step.ID,
+						Status: model.StepStatusFailed,
+						Error:  err.Error(),
+					}
+					return
+				}
+
 				fanResults := make([]*model.StepOutput, len(repos))
+
+				// Create a step_run record for every fan-out child up front so repos
+				// still waiting for a slot are visible as pending in the UI.
+				createAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
+				createFutures := make([]workflow.Future, len(repos))
+				for j, repo := range repos {
+					createFutures[j] = workflow.ExecuteActivity(
+						workflow.WithActivityOptions(gCtx, createAO),
+						CreateStepRunActivity, input.RunID, fmt.Sprintf("%s-%d", step.ID, j), step.Title,
+						fmt.Sprintf("%s-%s-%d", input.RunID, step.ID, j),
+						map[string]any{"repo_url": repo.URL, "ref": repo.Ref},
+					)
+				}
+				fanStepRunIDs := make([]string, len(repos))
+				for j, f := range createFutures {
+					if err := f.Get(gCtx, &fanStepRunIDs[j]); err != nil {
+						fanResults[j] = &model.StepOutput{
+							StepID: step.ID,
+							Status: model.StepStatusFailed,
+							Error:  fmt.Sprintf("create step run: %v", err),
+						}
+					}
+				}
+
+				// Repos the filter rejects keep a skipped step_run so the UI shows
+				// why they did not run.
+				selected := len(repos)
+				if step.Filter != "" {
+					for j, repo := range repos {
+						if fanResults[j] != nil {
+							continue
+						}
+						if skipped := filterRepo(gCtx, step, repo, input.Parameters, outputs); skipped != nil {
+							_ = finalizeStep(gCtx, logger, fanStepRunIDs[j], skipped)
+							fanResults[j] = skipped
+							selected--
+						}
+					}
+				}
+
+				// Bounded scheduler: launch children in repo order, starting the next
+				// one as soon as a running child completes. workflow.Await only
+				// unblocks on workflow events, so slot hand-off is replay-safe.
+				limit := fanOutLimit(step.MaxParallel, input.DefaultMaxParallel, len(repos))
+				threshold := failureThresholdCount(step.FailureThreshold, selected)
+				running := 0
+				thresholdTripped := false
+				thresholdCrossed := func() bool {
+					return threshold > 0 && !thresholdTripped && countFanOutFailures(fanResults) >= threshold
+				}
+				stopReason := ""
+				cancelled := false
 				fanWg := workflow.NewWaitGroup(gCtx)
 				for j, repo := range repos {
+					if fanResults[j] != nil {
+						continue
+					}
+					if err := workflow.Await(gCtx, func() bool { return running < limit || thresholdCrossed() }); err != nil {
+						// Cancelled while queued — leave remaining children unstarted.
+						cancelled = true
+						break
+					}
+					if thresholdCrossed() {
+						thresholdTripped = true
+						// Stop launching and let in-flight children drain so the operator
+						// decides with the full set of failures in front of them.
+						if err := workflow.Await(gCtx, func() bool { return running == 0 }); err != nil {
+							cancelled = true
+							break
+						}
+						failed := countFanOutFailures(fanResults)
+						notStarted := 0
+						for _, fr := range fanResults {
+							if fr == nil {
+								notStarted++
+							}
+						}
+						inboxAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
+						title := fmt.Sprintf("Fan-out failure threshold reached: %s (%d/%d repos failed, %d not started)", step.ID, failed, len(repos), notStarted)
+						summary := buildFanOutFailureSummary(fanResults)
+						if err := workflow.ExecuteActivity(
+							workflow.WithActivityOptions(gCtx, inboxAO),
+							CreateInboxItemActivity, input.TeamID, input.RunID, "", "fan_out_partial_failure", title, summary, "", step.ID,
+						).Get(gCtx, nil); err != nil {
+							logger.Error("failed to create fan-out failure threshold inbox item", "error", err)
+						}
+						resolvePayload, timedOut := awaitFanOutResolve(gCtx, fanOutResolveChannels[step.ID])
+						if timedOut {
+							stopReason = fmt.Sprintf("fan-out failure threshold timed out after 48h waiting for operator decision (%d/%d repos failed)", failed, len(repos))
+							break
+						}
+						if resolvePayload.Action == "terminate" {
+							stopReason = fmt.Sprintf("operator terminated after failure threshold was reached (%d/%d repos failed)", failed, len(repos))
+							break
+						}
+						// proceed: resume launching the remaining repos.
+					}
+					running++
 					fanWg.Add(1)
 					workflow.Go(gCtx, func(rCtx workflow.Context) {
-						defer fanWg.Done()
-						// Create a step_run record for each fan-out child.
-						fanStepID := fmt.Sprintf("%s-%d", step.ID, j)
-						fanChildWFID := fmt.Sprintf("%s-%s-%d", input.RunID, step.ID, j)
-						createAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
-						var stepRunID string
-						if err := workflow.ExecuteActivity(
-							workflow.WithActivityOptions(rCtx, createAO),
-							CreateStepRunActivity, input.RunID, fanStepID, step.Title, fanChildWFID,
-							map[string]any{"repo_url": repo.URL, "ref": repo.Ref},
-						).Get(rCtx, &stepRunID); err != nil {
-							fanResults[j] = &model.StepOutput{
-								StepID: step.ID,
-								Status: model.StepStatusFailed,
-								Error:  fmt.Sprintf("create step run: %v", err),
+						defer func() {
+							running--
+							fanWg.Done()
+						}()
+						// Within a sandbox group each repo gets its own sandbox, which
+						// later fan-out steps in the group reuse for the same repo.
+						var sandboxID string
+						if step.SandboxGroup != "" {
+							var err error
+							if sandboxID, err = groupSandbox(rCtx, repoSandboxKey(step.SandboxGroup, repo), step); err != nil {
+								fanResults[j] = &model.StepOutput{
+									StepID: step.ID,
+									Status: model.StepStatusFailed,
+									Error:  err.Error(),
+								}
+								_ = finalizeStep(rCtx, logger, fanStepRunIDs[j], fanResults[j])
+								return
 							}
-							return
 						}
 						repoResolved := resolved
 						repoResolved.Repos = []model.RepoRef{repo}
+						repoResolved.Branch = branches[j]
 						cwo := workflow.ChildWorkflowOptions{
-							WorkflowID: fanChildWFID,
+							WorkflowID: fmt.Sprintf("%s-%s-%d", input.RunID, step.ID, j),
 						}
 						var out model.StepOutput
 						err := workflow.ExecuteChildWorkflow(
@@ -497,14 +721,16 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 							StepWorkflow,
 							StepInput{
 								RunID:              input.RunID,
-								StepRunID:          stepRunID,
+								StepRunID:          fanStepRunIDs[j],
 								TeamID:             input.TeamID,
 								WorkflowTemplateID: input.WorkflowTemplateID,
 								StepDef:            step,
 								ResolvedOpts:       repoResolved,
-								SandboxID:          sandboxes[step.SandboxGroup],
+								SandboxID:          sandboxID,
 								ModelOverride:      input.ModelOverride,
 								TriggeredBy:        input.TriggeredBy,
+								RunBudget:          input.WorkflowDef.Budget,
+								ParamsHash:         ParamsHash(input.Parameters),
 							},
 						).Get(rCtx, &out)
 						if err != nil {
@@ -519,6 +745,39 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 					})
 				}
 				fanWg.Wait(gCtx)
+				for j, fr := range fanResults {
+					if fr != nil {
+						setRepo(fr, repos[j])
+					}
+				}
+
+				// Children that never started keep a pending step_run; mark them skipped.
+				skipUnstarted := func(ctx workflow.Context, reason string) {
+					for j, fr := range fanResults {
+						if fr == nil {
+							_ = finalizeStep(ctx, logger, fanStepRunIDs[j], &model.StepOutput{
+								StepID: step.ID,
+								Status: model.StepStatusSkipped,
+								Error:  "not started: " + reason,
+							})
+						}
+					}
+				}
+				if cancelled {
+					// gCtx is cancelled, so finalize on a disconnected context.
+					dCtx, _ := workflow.NewDisconnectedContext(gCtx)
+					skipUnstarted(dCtx, "run cancelled")
+				}
+
+				if stopReason != "" {
+					skipUnstarted(gCtx, "fan-out stopped after failure threshold")
+					results[i] = &model.StepOutput{
+						StepID: step.ID,
+						Status: model.StepStatusFailed,
+						Error:  stopReason,
+					}
+					return
+				}
 
 				// Check for partial fan-out failure: some repos succeeded, some failed.
 				fanSuccesses := 0
@@ -526,53 +785,48 @@ func DAGWorkflow(ctx workflow.Context, input DAGInput) (retErr error) {
 				for _, fr := range fanResults {
 					if fr != nil && fr.Status == model.StepStatusFailed {
 						fanFailures++
-					} else if fr != nil {
+					} else if fr != nil && fr.Status != model.StepStatusSkipped {
 						fanSuccesses++
 					}
 				}
 
 				if fanSuccesses > 0 && fanFailures > 0 {
-					// Partial failure: raise inbox item and wait for operator decision.
-					inboxAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
-					title := fmt.Sprintf("Fan-out partial failure: %s (%d/%d repos failed)", step.ID, fanFailures, len(repos))
-					summary := buildFanOutFailureSummary(fanResults)
-					if err := workflow.ExecuteActivity(
-						workflow.WithActivityOptions(gCtx, inboxAO),
-						CreateInboxItemActivity, input.TeamID, input.RunID, "", "fan_out_partial_failure", title, summary, "", step.ID,
-					).Get(gCtx, nil); err != nil {
-						logger.Error("failed to create fan-out partial failure inbox item", "error", err)
-					}
+					// Partial failure: raise inbox item and wait for operator decision,
+					// unless the operator already chose to proceed at the failure threshold.
+					if !thresholdTripped {
+						inboxAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
+						title := fmt.Sprintf("Fan-out partial failure: %s (%d/%d repos failed)", step.ID, fanFailures, len(repos))
+						if thresholdCrossed() {
+							// Every repo had started before enough of them failed, so
+							// there was nothing left to hold back.
+							title = fmt.Sprintf("Fan-out failure threshold reached: %s (%d/%d repos failed, 0 not started)", step.ID, fanFailures, len(repos))
+						}
+						summary := buildFanOutFailureSummary(fanResults)
+						if err := workflow.ExecuteActivity(
+							workflow.WithActivityOptions(gCtx, inboxAO),
+							CreateInboxItemActivity, input.TeamID, input.RunID, "", "fan_out_partial_failure", title, summary, "", step.ID,
+						).Get(gCtx, nil); err != nil {
+							logger.Error("failed to create fan-out partial failure inbox item", "error", err)
+						}
 
-					// Wait for fan_out_resolve signal with a 48-hour timeout.
-					// Using a selector prevents the workflow from blocking forever if the
-					// operator never responds to the inbox item.
-					resolveCh := fanOutResolveChannels[step.ID]
-					var resolvePayload FanOutResolvePayload
-					timedOut := false
-					sel := workflow.NewSelector(gCtx)
-					sel.AddReceive(resolveCh, func(c workflow.ReceiveChannel, _ bool) {
-						c.Receive(gCtx, &resolvePayload)
-					})
-					sel.AddFuture(workflow.NewTimer(gCtx, 48*time.Hour), func(_ workflow.Future) {
-						timedOut = true
-					})
-					sel.Select(gCtx)
-					if timedOut {
-						results[i] = &model.StepOutput{
-							StepID: step.ID,
-							Status: model.StepStatusFailed,
-							Error:  fmt.Sprintf("fan-out partial failure timed out after 48h waiting for operator decision (%d/%d repos failed)", fanFailures, len(repos)),
+						resolvePayload, timedOut := awaitFanOutResolve(gCtx, fanOutResolveChannels[step.ID])
+						if timedOut {
+							results[i] = &model.StepOutput{
+								StepID: step.ID,
+								Status: model.StepStatusFailed,
+								Error:  fmt.Sprintf("fan-out partial failure timed out after 48h waiting for operator decision (%d/%d repos failed)", fanFailures, len(repos)),
+							}
+							return
 						}
-						return
-					}
 
-					if resolvePayload.Action == "terminate" {
-						results[i] = &model.StepOutput{
-							StepID: step.ID,
-							Status: model.StepStatusFailed,
-							Error:  fmt.Sprintf("operator terminated after partial failure (%d/%d repos failed)", fanFailures, len(repos)),
+						if resolvePayload.Action == "terminate" {
+							results[i] = &model.StepOutput{
+								StepID: step.ID,
+								Status: model.StepStatusFailed,
+								Error:  fmt.Sprintf("operator terminated after partial failure (%d/%d repos failed)", fanFailures, len(repos)),
+							}
+							return
 						}
-						return
 					}
 					// proceed: collect only successful results and aggregate them
 					var successResults []*model.StepOutput
@@ -759,6 +1013,78 @@ func resolveRepos(raw any, params map[string]any, outputs map[string]*model.Step
 	return repos, nil
 }
 
+// fanOutLimit returns how many fan-out children may run at once. The step's
+// max_parallel wins over the run default; a non-positive result means no limit.
+func fanOutLimit(stepMax, runDefault, total int) int {
+	limit := stepMax
+	if limit <= 0 {
+		limit = runDefault
+	}
+	if limit <= 0 || limit > total {
+		limit = total
+	}
+	return limit
+}
+
+// failureThresholdCount converts a failure_threshold value ("3" or "25%") into an
+// absolute number of failed repos for a fan-out over total repos. Percentages
+// round up so any non-zero percentage trips on at least one failure. Returns 0
+// (no threshold) when unset or malformed; ValidateWorkflow rejects malformed values.
+func failureThresholdCount(raw string, total int) int {
+	n, percent, err := parseFailureThreshold(raw)
+	if err != nil || n == 0 {
+		return 0
+	}
+	if percent {
+		return max((n*total+99)/100, 1)
+	}
+	return n
+}
+
+// parseFailureThreshold parses an absolute count ("3") or a percentage ("25%").
+// An empty string yields n == 0.
+func parseFailureThreshold(raw string) (n int, percent bool, err error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return 0, false, nil
+	}
+	if trimmed, ok := strings.CutSuffix(s, "%"); ok {
+		percent = true
+		s = strings.TrimSpace(trimmed)
+	}
+	n, err = strconv.Atoi(s)
+	if err != nil || n < 1 || (percent && n > 100) {
+		return 0, false, fmt.Errorf("failure_threshold %q must be a positive integer or a percentage between 1%% and 100%%", raw)
+	}
+	return n, percent, nil
+}
+
+// countFanOutFailures returns the number of fan-out children that have failed so far.
+func countFanOutFailures(results []*model.StepOutput) int {
+	n := 0
+	for _, r := range results {
+		if r != nil && r.Status == model.StepStatusFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// awaitFanOutResolve blocks until the operator sends a fan_out_resolve signal for
+// the step, or 48 hours pass. The timeout prevents the workflow from blocking
+// forever if the operator never responds to the inbox item.
+func awaitFanOutResolve(ctx workflow.Context, resolveCh workflow.ReceiveChannel) (payload FanOutResolvePayload, timedOut bool) {
+	sel := workflow.NewSelector(ctx)
+	sel.AddReceive(resolveCh, func(c workflow.ReceiveChannel, _ bool) {
+		c.Receive(ctx, &payload)
+	})
+	sel.AddFuture(workflow.NewTimer(ctx, 48*time.Hour), func(_ workflow.Future) {
+		timedOut = true
+	})
+	sel.Select(ctx)
+	return payload, timedOut
+}
+
 // buildFanOutFailureSummary returns a newline-joined list of failed fan-out repo errors.
 func buildFanOutFailureSummary(results []*model.StepOutput) string {
 	var lines []string
@@ -790,6 +1116,7 @@ func aggregateFanOut(stepID string, results []*model.StepOutput) *model.StepOutp
 		Outputs: make([]model.StepOutput, len(results)),
 	}
 	var errs []string
+	skipped := 0
 	for i, r := range results {
 		if r == nil {
 			r = &model.StepOutput{
@@ -799,6 +1126,9 @@ func aggregateFanOut(stepID string, results []*model.StepOutput) *model.StepOutp
 			}
 		}
 		agg.Outputs[i] = *r
+		if r.Status == model.StepStatusSkipped {
+			skipped++
+		}
 		if r.Status == model.StepStatusFailed {
 			agg.Status = model.StepStatusFailed
 			if r.Error != "" {
@@ -809,6 +1139,9 @@ func aggregateFanOut(stepID string, results []*model.StepOutput) *model.StepOutp
 	if len(errs) > 0 {
 		agg.Error = strings.Join(errs, "; ")
 	}
+	if len(results) > 0 && skipped == len(results) {
+		agg.Status = model.StepStatusSkipped
+	}
 	return agg
 }
 
@@ -822,11 +1155,7 @@ func evalCondition(ctx workflow.Context, condition string, params map[string]any
 	steps := map[string]map[string]any{}
 	for id, out := range outputs {
 		if out != nil {
-			steps[id] = map[string]any{
-				"status": string(out.Status),
-				"error":  out.Error,
-				"output": out.Output,
-			}
+			steps[id] = conditionStep(out)
 		}
 	}
 
@@ -852,6 +1181,97 @@ func evalCondition(ctx workflow.Context, condition string, params map[string]any
 	return strings.TrimSpace(buf.String()) == "true"
 }
 
+// conditionStep is the view of a step output that conditions and filters see.
+func conditionStep(out *model.StepOutput) map[string]any {
+	return map[string]any{
+		"status": string(out.Status),
+		"error":  out.Error,
+		"output": out.Output,
+	}
+}
+
+// filterRepo evaluates a fan-out step's filter for one repo. It returns nil
+// when the repo should run, otherwise the skipped output to record for it.
+func filterRepo(ctx workflow.Context, step model.StepDef, repo model.RepoRef, params map[string]any, outputs map[string]*model.StepOutput) *model.StepOutput {
+	match, err := evalFilter(step.Filter, repo, params, outputs)
+	if match {
+		return nil
+	}
+	skipped := &model.StepOutput{
+		StepID: step.ID,
+		Status: model.StepStatusSkipped,
+		Error:  "filtered out",
+	}
+	setRepo(skipped, repo)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("filter template error — skipping repo",
+			"step_id", step.ID, "repo", repo.URL, "error", err)
+		skipped.Error = fmt.Sprintf("filter: %v", err)
+	}
+	return skipped
+}
+
+// evalFilter reports whether filter renders "true" for repo. It sees the same
+// variables as a condition plus .repo, and each upstream fan-out step through
+// its result for this repo rather than the aggregate.
+func evalFilter(filter string, repo model.RepoRef, params map[string]any, outputs map[string]*model.StepOutput) (bool, error) {
+	steps := map[string]map[string]any{}
+	for id, out := range outputs {
+		if out != nil {
+			steps[id] = conditionStep(repoOutput(out, repo))
+		}
+	}
+
+	data := map[string]any{
+		"steps":  steps,
+		"params": params,
+		"repo": map[string]any{
+			"url":    repo.URL,
+			"name":   repo.Name,
+			"branch": repo.Branch,
+			"ref":    repo.Ref,
+		},
+	}
+
+	tmpl, err := template.New("filter").Option("missingkey=zero").Parse(filter)
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(buf.String()) == "true", nil
+}
+
+// repoOutput returns a step's result for repo. Steps that did not fan out are
+// returned as is; a fan-out step that has no result for the repo reads as
+// skipped. A repo listed twice with different branches or refs has a result
+// for each, so all three must match.
+func repoOutput(out *model.StepOutput, repo model.RepoRef) *model.StepOutput {
+	if len(out.Outputs) == 0 {
+		if out.RepoURL == "" || isRepo(out, repo) {
+			return out
+		}
+		return &model.StepOutput{StepID: out.StepID, Status: model.StepStatusSkipped}
+	}
+	for i := range out.Outputs {
+		if isRepo(&out.Outputs[i], repo) {
+			return &out.Outputs[i]
+		}
+	}
+	return &model.StepOutput{StepID: out.StepID, Status: model.StepStatusSkipped}
+}
+
+// setRepo records the repo out is the result for.
+func setRepo(out *model.StepOutput, repo model.RepoRef) {
+	out.RepoURL, out.RepoBranch, out.RepoRef = repo.URL, repo.Branch, repo.Ref
+}
+
+func isRepo(out *model.StepOutput, repo model.RepoRef) bool {
+	return out.RepoURL == repo.URL && out.RepoBranch == repo.Branch && out.RepoRef == repo.Ref
+}
+
 // executeAction runs a non-agent action step (e.g., slack notification, GitHub action).
 func executeAction(ctx workflow.Context, step model.StepDef, teamID, stepRunID string, credNames []string) *model.StepOutput {
 	// Action steps are dispatched to specific activities based on action type
@@ -905,3 +1325,12 @@ func skipDownstream(pending map[string]model.StepDef, failedID string, allSteps
 		}
 	}
 }
+
+// ParamsHash is a short, stable hash of a run's parameters. Runs of a workflow
+// with the same parameters do the same job, so CreatePullRequest includes it in
+// the PR marker that later runs look for.
+func ParamsHash(params map[string]any) string {
+	b, _ := json.Marshal(params) // map keys are marshalled in sorted order
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:6])
+}
@@ -2,8 +2,10 @@ package workflow
 
 import (
 	"context"
+	"fmt"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -95,6 +97,11 @@ func (m *dagMockActivities) RunPreflight(_ context.Context, input RunPreflightIn
 	return args.Get(0).(RunPreflightOutput), args.Error(1)
 }
 
+func (m *dagMockActivities) CaptureKnowledge(_ context.Context, input model.CaptureKnowledgeInput) (int, error) {
+	args := m.Called(input)
+	return args.Int(0), args.Error(1)
+}
+
 // newDAGTestEnv creates a Temporal test environment with both DAGWorkflow and
 // StepWorkflow registered, and default success mocks for all DB/status activities.
 // Callers can set expectations on the returned mocks for step-level activities
@@ -123,6 +130,7 @@ func newDAGTestEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, *dagMockAc
 	env.RegisterActivity(mocks.UpdateStepStatus)
 	env.RegisterActivity(mocks.ResolveAgentProfile)
 	env.RegisterActivity(mocks.RunPreflight)
+	env.RegisterActivity(mocks.CaptureKnowledge)
 
 	// Default success stubs for DB/status activities that every DAG execution hits.
 	// Tests that need to assert specific call arguments can override these.
@@ -136,6 +144,7 @@ func newDAGTestEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, *dagMockAc
 	mocks.On("UpdateStepStatus", mock.Anything, mock.Anything).Return(nil)
 	mocks.On("ResolveAgentProfile", mock.Anything).Return(model.AgentProfileBody{}, nil).Maybe()
 	mocks.On("RunPreflight", mock.Anything).Return(RunPreflightOutput{}, nil).Maybe()
+	mocks.On("CaptureKnowledge", mock.Anything).Return(0, nil).Maybe()
 
 	return env, mocks
 }
@@ -452,6 +461,237 @@ func TestDAGWorkflow_FanOutParallelSteps(t *testing.T) {
 	assert.NoError(t, env.GetWorkflowError())
 }
 
+// TestDAGWorkflow_FanOutRespectsMaxParallel verifies that a fan-out step never
+// runs more than max_parallel children at once, that every repo still runs, and
+// that all child step_runs are created before the first child executes.
+func TestDAGWorkflow_FanOutRespectsMaxParallel(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	var mu sync.Mutex
+	inFlight, peak, executed, createdBeforeFirstExec := 0, 0, 0, -1
+	created := 0
+	mocks.ExpectedCalls = slices.DeleteFunc(mocks.ExpectedCalls, func(c *mock.Call) bool {
+		return c.Method == "CreateStepRun"
+	})
+	mocks.On("CreateStepRun", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		mu.Lock()
+		created++
+		mu.Unlock()
+	}).Return("sr-1", nil)
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.Anything).Run(func(mock.Arguments) {
+		mu.Lock()
+		if createdBeforeFirstExec < 0 {
+			createdBeforeFirstExec = created
+		}
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		executed++
+		mu.Unlock()
+	}).Return(&model.StepOutput{StepID: "step-1", Status: model.StepStatusComplete}, nil)
+
+	repos := make([]any, 5)
+	for i := range repos {
+		repos[i] = map[string]any{"url": fmt.Sprintf("https://github.com/test/repo%d", i)}
+	}
+	def := model.WorkflowDef{
+		ID: "test-fanout-bounded-wf",
+		Steps: []model.StepDef{
+			{
+				ID:           "step-1",
+				MaxParallel:  2,
+				Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "do something"},
+				Repositories: repos,
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:              "run-fanout-bounded-1",
+		TeamID:             "team-1",
+		WorkflowDef:        def,
+		Parameters:         map[string]any{},
+		DefaultMaxParallel: 4, // step-level max_parallel wins
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, 5, executed)
+	assert.LessOrEqual(t, peak, 2)
+	assert.Equal(t, 5, createdBeforeFirstExec, "queued children should have pending step_runs before any child runs")
+}
+
+// failureThresholdDef builds a sequential (max_parallel: 1) fan-out over n repos
+// with failure_threshold: 1 so the first failure trips the threshold deterministically.
+func failureThresholdDef(n int) model.WorkflowDef {
+	repos := make([]any, n)
+	for i := range repos {
+		repos[i] = map[string]any{"url": fmt.Sprintf("https://github.com/test/repo%d", i)}
+	}
+	return model.WorkflowDef{
+		ID: "test-fanout-threshold-wf",
+		Steps: []model.StepDef{
+			{
+				ID:               "step-1",
+				MaxParallel:      1,
+				FailureThreshold: "1",
+				Execution:        &model.ExecutionDef{Agent: "claude-code", Prompt: "do something"},
+				Repositories:     repos,
+			},
+		},
+	}
+}
+
+// TestDAGWorkflow_FanOutFailureThreshold_Terminate verifies that once the failure
+// threshold is crossed no further children are launched, the operator is asked to
+// decide, and on "terminate" the unstarted children are recorded as skipped.
+func TestDAGWorkflow_FanOutFailureThreshold_Terminate(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.Anything).Return(nil,
+		temporal.NewNonRetryableApplicationError("broken prompt", "ExecutionError", nil),
+	).Once()
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalFanOutResolve, FanOutResolvePayload{Action: "terminate", StepID: "step-1"}) //nolint:errcheck
+	}, time.Second)
+
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-threshold-terminate-1",
+		TeamID:      "team-1",
+		WorkflowDef: failureThresholdDef(4),
+		Parameters:  map[string]any{},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	err := env.GetWorkflowError()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failure threshold")
+
+	mocks.AssertNumberOfCalls(t, "ExecuteStep", 1)
+	mocks.AssertCalled(t, "CreateInboxItem", "team-1", "run-threshold-terminate-1", "", "fan_out_partial_failure",
+		mock.MatchedBy(func(title string) bool { return strings.Contains(title, "threshold") }),
+		mock.Anything, "", "step-1")
+	skipped := 0
+	for _, c := range mocks.Calls {
+		if c.Method == "CompleteStepRun" && c.Arguments.String(1) == string(model.StepStatusSkipped) {
+			skipped++
+		}
+	}
+	assert.Equal(t, 3, skipped, "unstarted children should be marked skipped")
+}
+
+// TestDAGWorkflow_FanOutCancelledWhileQueued verifies that cancelling the run
+// while fan-out children are queued marks the unstarted children skipped instead
+// of leaving their step_runs pending.
+func TestDAGWorkflow_FanOutCancelledWhileQueued(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.Anything).Run(func(mock.Arguments) {
+		env.CancelWorkflow()
+	}).Return(&model.StepOutput{StepID: "step-1", Status: model.StepStatusComplete}, nil)
+
+	def := failureThresholdDef(3)
+	def.Steps[0].FailureThreshold = ""
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-fanout-cancel-1",
+		TeamID:      "team-1",
+		WorkflowDef: def,
+		Parameters:  map[string]any{},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	mocks.AssertNumberOfCalls(t, "ExecuteStep", 1)
+	skipped := 0
+	for _, c := range mocks.Calls {
+		if c.Method == "CompleteStepRun" && c.Arguments.String(1) == string(model.StepStatusSkipped) &&
+			c.Arguments.String(4) == "not started: run cancelled" {
+			skipped++
+		}
+	}
+	assert.Equal(t, 2, skipped, "unstarted children should be marked skipped")
+}
+
+// TestDAGWorkflow_FanOutFailureThreshold_Proceed verifies that on "proceed" the
+// remaining children are launched and the operator is not asked a second time.
+func TestDAGWorkflow_FanOutFailureThreshold_Proceed(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.Anything).Return(nil,
+		temporal.NewNonRetryableApplicationError("flaky repo", "ExecutionError", nil),
+	).Once()
+	mocks.On("ExecuteStep", mock.Anything).Return(&model.StepOutput{
+		StepID: "step-1",
+		Status: model.StepStatusComplete,
+	}, nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalFanOutResolve, FanOutResolvePayload{Action: "proceed", StepID: "step-1"}) //nolint:errcheck
+	}, time.Second)
+
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-threshold-proceed-1",
+		TeamID:      "team-1",
+		WorkflowDef: failureThresholdDef(3),
+		Parameters:  map[string]any{},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertNumberOfCalls(t, "ExecuteStep", 3)
+	partialFailureItems := 0
+	for _, c := range mocks.Calls {
+		if c.Method == "CreateInboxItem" && c.Arguments.String(3) == "fan_out_partial_failure" {
+			partialFailureItems++
+		}
+	}
+	assert.Equal(t, 1, partialFailureItems)
+}
+
+// TestDAGWorkflow_FanOutFailureThreshold_AllStarted verifies that when every
+// repo is already running as the threshold is crossed, the operator is still
+// told the threshold was reached once the children finish.
+func TestDAGWorkflow_FanOutFailureThreshold_AllStarted(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.Anything).Return(nil,
+		temporal.NewNonRetryableApplicationError("flaky repo", "ExecutionError", nil),
+	).Once()
+	mocks.On("ExecuteStep", mock.Anything).Return(&model.StepOutput{
+		StepID: "step-1",
+		Status: model.StepStatusComplete,
+	}, nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalFanOutResolve, FanOutResolvePayload{Action: "terminate", StepID: "step-1"}) //nolint:errcheck
+	}, time.Second)
+
+	def := failureThresholdDef(3)
+	def.Steps[0].MaxParallel = 3
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-threshold-all-started-1",
+		TeamID:      "team-1",
+		WorkflowDef: def,
+		Parameters:  map[string]any{},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	mocks.AssertNumberOfCalls(t, "ExecuteStep", 3)
+	mocks.AssertCalled(t, "CreateInboxItem", "team-1", "run-threshold-all-started-1", "", "fan_out_partial_failure",
+		"Fan-out failure threshold reached: step-1 (1/3 repos failed, 0 not started)",
+		mock.Anything, "", "step-1")
+}
+
 // TestDAGWorkflow_FanOutPartialFailure_Terminate verifies that when one fan-out child
 // fails and the operator sends a "terminate" resolve signal, the workflow fails with
 // an error mentioning the step.
@@ -560,6 +800,71 @@ func TestDAGWorkflow_FanOutPartialFailure_Proceed(t *testing.T) {
 	assert.NoError(t, env.GetWorkflowError())
 }
 
+// TestDAGWorkflow_FanOutFilter verifies that a fan-out step's filter is
+// evaluated against each repo's own result from the upstream fan-out, that only
+// matching repos run, and that the rest are recorded as skipped step_runs.
+func TestDAGWorkflow_FanOutFilter(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	stepIs := func(stepID string) func(ExecuteStepInput) bool {
+		return func(in ExecuteStepInput) bool { return in.StepInput.StepDef.ID == stepID }
+	}
+	var mu sync.Mutex
+	var fixed []string
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.MatchedBy(func(in ExecuteStepInput) bool {
+		return stepIs("analyze")(in) && in.StepInput.ResolvedOpts.Repos[0].URL == "https://github.com/test/repo1"
+	})).Return(&model.StepOutput{Status: model.StepStatusComplete, Output: map[string]any{"findings": []any{"CVE-1"}}}, nil)
+	mocks.On("ExecuteStep", mock.MatchedBy(stepIs("analyze"))).
+		Return(&model.StepOutput{Status: model.StepStatusComplete, Output: map[string]any{"findings": []any{}}}, nil)
+	mocks.On("ExecuteStep", mock.MatchedBy(stepIs("fix"))).Run(func(args mock.Arguments) {
+		mu.Lock()
+		fixed = append(fixed, args.Get(0).(ExecuteStepInput).StepInput.ResolvedOpts.Repos[0].URL)
+		mu.Unlock()
+	}).Return(&model.StepOutput{Status: model.StepStatusComplete}, nil)
+
+	repos := []any{
+		map[string]any{"url": "https://github.com/test/repo0"},
+		map[string]any{"url": "https://github.com/test/repo1"},
+		map[string]any{"url": "https://github.com/test/repo2"},
+	}
+	def := model.WorkflowDef{
+		ID: "test-fanout-filter-wf",
+		Steps: []model.StepDef{
+			{
+				ID:           "analyze",
+				Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "analyze"},
+				Repositories: repos,
+			},
+			{
+				ID:           "fix",
+				DependsOn:    []string{"analyze"},
+				Filter:       `{{ gt (len .steps.analyze.output.findings) 0 }}`,
+				Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "fix"},
+				Repositories: repos,
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-fanout-filter-1",
+		TeamID:      "team-1",
+		WorkflowDef: def,
+		Parameters:  map[string]any{},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, []string{"https://github.com/test/repo1"}, fixed)
+	filteredOut := 0
+	for _, c := range mocks.Calls {
+		if c.Method == "CompleteStepRun" && c.Arguments.String(1) == string(model.StepStatusSkipped) && c.Arguments.String(4) == "filtered out" {
+			filteredOut++
+		}
+	}
+	assert.Equal(t, 2, filteredOut, "each filtered-out repo keeps a skipped step_run")
+}
+
 // TestDAGWorkflow_ConditionFalseSkipsStep verifies that when a step's condition evaluates
 // to false (step-1 succeeded, but condition checks for "failed"), step-2 is skipped and
 // ExecuteStep is only called once (for step-1, not step-2).
@@ -884,6 +1189,72 @@ func TestDAGWorkflow_SandboxGroupReuse(t *testing.T) {
 	mocks.AssertNumberOfCalls(t, "ProvisionSandbox", 1)
 }
 
+// TestDAGWorkflow_SandboxGroupPerRepoFanOut verifies that fan-out steps in a
+// sandbox group get one sandbox per repo checkout, that a dependent fan-out step
+// reuses the sandbox of the same checkout, and that the DAG cleans up every sandbox.
+func TestDAGWorkflow_SandboxGroupPerRepoFanOut(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	var mu sync.Mutex
+	used := map[string]string{} // step/repo@branch -> sandbox
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil).Once()
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-2", nil).Once()
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-3", nil).Once()
+	mocks.On("ExecuteStep", mock.Anything).Run(func(args mock.Arguments) {
+		in := args.Get(0).(ExecuteStepInput)
+		repo := in.StepInput.ResolvedOpts.Repos[0]
+		mu.Lock()
+		used[in.StepInput.StepDef.ID+" "+repo.URL+"@"+repo.Branch] = in.SandboxID
+		mu.Unlock()
+	}).Return(&model.StepOutput{Status: model.StepStatusComplete}, nil)
+
+	repos := []any{
+		map[string]any{"url": "https://github.com/test/repo1"},
+		map[string]any{"url": "https://github.com/test/repo2"},
+		map[string]any{"url": "https://github.com/test/repo1", "branch": "release"},
+	}
+	def := model.WorkflowDef{
+		ID: "test-sandbox-group-fanout-wf",
+		Steps: []model.StepDef{
+			{
+				ID:           "analyze",
+				SandboxGroup: "repo-work",
+				Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "analyze"},
+				Repositories: repos,
+			},
+			{
+				ID:           "fix",
+				SandboxGroup: "repo-work",
+				DependsOn:    []string{"analyze"},
+				Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "fix"},
+				Repositories: repos,
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-sb-fanout-1",
+		TeamID:      "team-1",
+		WorkflowDef: def,
+		Parameters:  map[string]any{},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertNumberOfCalls(t, "ProvisionSandbox", 3) // one sandbox per checkout, shared by both steps
+	checkouts := []string{"https://github.com/test/repo1@", "https://github.com/test/repo2@", "https://github.com/test/repo1@release"}
+	for _, repo := range checkouts {
+		assert.NotEmpty(t, used["analyze "+repo])
+		assert.Equal(t, used["analyze "+repo], used["fix "+repo], "fix reuses analyze's sandbox for %s", repo)
+	}
+	assert.NotEqual(t, used["analyze "+checkouts[0]], used["analyze "+checkouts[1]])
+	assert.NotEqual(t, used["analyze "+checkouts[0]], used["analyze "+checkouts[2]], "another branch of a repo gets its own sandbox")
+	mocks.AssertCalled(t, "CleanupSandbox", "sb-1")
+	mocks.AssertCalled(t, "CleanupSandbox", "sb-2")
+	mocks.AssertCalled(t, "CleanupSandbox", "sb-3")
+	mocks.AssertNumberOfCalls(t, "CleanupSandbox", 3)
+}
+
 // TestDAGWorkflow_HITLApproval verifies that when a step has approval_policy "always",
 // the StepWorkflow pauses awaiting a signal, and an approve signal allows it to continue
 // to completion.
@@ -1606,3 +1977,43 @@ func TestDAGWorkflow_ADS_ManualNeededPath(t *testing.T) {
 	// Notify still ran despite execute being skipped
 	mocks.AssertNumberOfCalls(t, "ExecuteAction", 1)
 }
+
+// TestDAGWorkflow_SeedOutputsSkipCompletedSteps verifies that a retried run does not
+// execute seeded steps and that their outputs are available to the steps that run.
+func TestDAGWorkflow_SeedOutputsSkipCompletedSteps(t *testing.T) {
+	env, mocks := newDAGTestEnv(t)
+
+	mocks.On("ProvisionSandbox", mock.Anything).Return("sb-1", nil)
+	mocks.On("ExecuteStep", mock.MatchedBy(func(ei ExecuteStepInput) bool {
+		return ei.StepInput.StepDef.ID == "fix" && ei.StepInput.ResolvedOpts.Prompt == "fix: 3 issues"
+	})).Return(&model.StepOutput{StepID: "fix", Status: model.StepStatusComplete}, nil).Once()
+
+	def := model.WorkflowDef{
+		Steps: []model.StepDef{
+			{
+				ID:        "analyze",
+				Execution: &model.ExecutionDef{Agent: "claude-code", Prompt: "analyze"},
+			},
+			{
+				ID:        "fix",
+				DependsOn: []string{"analyze"},
+				Execution: &model.ExecutionDef{Agent: "claude-code", Prompt: "fix: {{ .Steps.analyze.Output.summary }}"},
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(DAGWorkflow, DAGInput{
+		RunID:       "run-retry-1",
+		TeamID:      "team-1",
+		WorkflowDef: def,
+		Parameters:  map[string]any{},
+		SeedOutputs: map[string]*model.StepOutput{
+			"analyze": {StepID: "analyze", Status: model.StepStatusComplete, Output: map[string]any{"summary": "3 issues"}},
+		},
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertNumberOfCalls(t, "ExecuteStep", 1)
+	mocks.AssertNotCalled(t, "CreateStepRun", "run-retry-1", "analyze", mock.Anything, mock.Anything)
+}
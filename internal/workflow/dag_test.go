@@ -320,6 +320,56 @@ func TestAggregateFanOut_MultipleFailures(t *testing.T) {
 	assert.Contains(t, agg.Error, "timeout")
 }
 
+func TestAggregateFanOut_AllSkipped(t *testing.T) {
+	results := []*model.StepOutput{
+		{StepID: "fix", Status: model.StepStatusSkipped, Error: "filtered out"},
+		{StepID: "fix", Status: model.StepStatusSkipped, Error: "filtered out"},
+	}
+	agg := aggregateFanOut("fix", results)
+	assert.Equal(t, model.StepStatusSkipped, agg.Status)
+	assert.Len(t, agg.Outputs, 2)
+}
+
+func TestEvalFilter(t *testing.T) {
+	outputs := map[string]*model.StepOutput{
+		"analyze": {StepID: "analyze", Status: model.StepStatusComplete, Outputs: []model.StepOutput{
+			{Status: model.StepStatusComplete, RepoURL: "https://github.com/acme/api", Output: map[string]any{"findings": []any{"CVE-1"}}},
+			{Status: model.StepStatusComplete, RepoURL: "https://github.com/acme/web", Output: map[string]any{"findings": []any{}}},
+			{Status: model.StepStatusComplete, RepoURL: "https://github.com/acme/web", RepoBranch: "release", Output: map[string]any{"findings": []any{"CVE-2"}}},
+		}},
+		"scan": {StepID: "scan", Status: model.StepStatusComplete, Output: map[string]any{"strict": true}},
+	}
+	api := model.RepoRef{URL: "https://github.com/acme/api"}
+	web := model.RepoRef{URL: "https://github.com/acme/web"}
+	webRelease := model.RepoRef{URL: "https://github.com/acme/web", Branch: "release"}
+	docs := model.RepoRef{URL: "https://github.com/acme/docs"}
+
+	tests := []struct {
+		name   string
+		filter string
+		repo   model.RepoRef
+		want   bool
+	}{
+		{"upstream entry for this repo matches", `{{ gt (len .steps.analyze.output.findings) 0 }}`, api, true},
+		{"upstream entry for this repo does not match", `{{ gt (len .steps.analyze.output.findings) 0 }}`, web, false},
+		{"same repo on another branch has its own entry", `{{ gt (len .steps.analyze.output.findings) 0 }}`, webRelease, true},
+		{"repo missing upstream reads as skipped", `{{ eq .steps.analyze.status "skipped" }}`, docs, true},
+		{"non-fan-out step is seen whole", `{{ .steps.scan.output.strict }}`, web, true},
+		{"repo fields", `{{ eq .repo.url "https://github.com/acme/web" }}`, web, true},
+		{"params", `{{ eq .params.env "prod" }}`, api, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evalFilter(tt.filter, tt.repo, map[string]any{"env": "prod"}, outputs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := evalFilter(`{{ .steps.analyze.output.findings.x.y }}`, docs, nil, outputs)
+	assert.Error(t, err)
+}
+
 func TestResolveStep_ShellAgent(t *testing.T) {
 	step := model.StepDef{
 		ID: "shell-step",
@@ -362,6 +412,39 @@ func TestAggregateFanOut_NilResultHandled(t *testing.T) {
 	assert.Contains(t, agg.Outputs[1].Error, "fan-out child failed")
 }
 
+func TestFanOutLimit(t *testing.T) {
+	assert.Equal(t, 2, fanOutLimit(2, 5, 10), "step max_parallel wins over run default")
+	assert.Equal(t, 5, fanOutLimit(0, 5, 10), "run default applies when step is unset")
+	assert.Equal(t, 3, fanOutLimit(8, 5, 3), "limit is capped at the number of repos")
+	assert.Equal(t, 10, fanOutLimit(0, 0, 10), "no limit configured runs everything")
+}
+
+func TestCheckGroupRepos(t *testing.T) {
+	repos := []model.RepoRef{
+		{URL: "https://github.com/acme/api"},
+		{URL: "https://github.com/acme/api", Branch: "release"},
+		{URL: "https://github.com/acme/api", Ref: "pull/7/head"},
+	}
+	assert.NoError(t, checkGroupRepos("work", repos))
+	assert.ErrorContains(t, checkGroupRepos("work", append(repos, model.RepoRef{URL: "https://github.com/acme/api", Branch: "release"})),
+		"repos 1 and 3 of sandbox group work are the same checkout")
+}
+
+func TestParamsHash(t *testing.T) {
+	h := ParamsHash(map[string]any{"repo": "acme/api", "level": 2})
+	assert.Len(t, h, 12)
+	assert.Equal(t, h, ParamsHash(map[string]any{"level": 2, "repo": "acme/api"}))
+	assert.NotEqual(t, h, ParamsHash(map[string]any{"repo": "acme/web", "level": 2}))
+}
+
+func TestFailureThresholdCount(t *testing.T) {
+	assert.Equal(t, 0, failureThresholdCount("", 10))
+	assert.Equal(t, 2, failureThresholdCount("2", 10))
+	assert.Equal(t, 3, failureThresholdCount("25%", 10), "percentages round up")
+	assert.Equal(t, 1, failureThresholdCount("1%", 10), "non-zero percentage trips on at least one failure")
+	assert.Equal(t, 0, failureThresholdCount("bogus", 10))
+}
+
 func TestFanOutApprovalPolicyOverride(t *testing.T) {
 	// This test documents the expected behavior: fan-out steps must never have
 	// HITL approval_policy other than "never" to prevent signal routing hangs.
@@ -0,0 +1,57 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// SandboxReaperWorkflowID is the fixed workflow ID of the reaper cron started by the worker.
+const SandboxReaperWorkflowID = "fleetlift-sandbox-reaper"
+
+// ReapInput is the input to SandboxReaperWorkflow and the ReapSandboxes activity.
+type ReapInput struct {
+	DryRun bool `json:"dry_run"` // report what would be reaped without killing anything
+}
+
+// ReapedSandbox is a sandbox the reaper killed, or would kill in a dry run.
+type ReapedSandbox struct {
+	SandboxID string    `json:"sandbox_id"`
+	RunID     string    `json:"run_id"`
+	RunStatus string    `json:"run_status,omitempty"` // empty when the run no longer exists
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReapReport summarises one reaper pass.
+type ReapReport struct {
+	DryRun bool            `json:"dry_run"`
+	Live   int             `json:"live"`   // sandboxes listed by the provider
+	Reaped []ReapedSandbox `json:"reaped"` // killed, or to be killed in a dry run
+	// Unowned are sandboxes that cannot be tied to a run. They are reported
+	// and left alone; the provider's own timeout removes them.
+	Unowned []string `json:"unowned,omitempty"`
+	Errors  []string `json:"errors,omitempty"` // per-sandbox kill failures
+}
+
+// SandboxReaperWorkflow runs one reaper pass. The worker starts it as a Temporal
+// cron workflow; the admin API runs it with DryRun set to preview a pass.
+func SandboxReaperWorkflow(ctx workflow.Context, input ReapInput) (*ReapReport, error) {
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+	}
+	var report *ReapReport
+	if err := workflow.ExecuteActivity(
+		workflow.WithActivityOptions(ctx, ao),
+		ReapSandboxesActivity, input,
+	).Get(ctx, &report); err != nil {
+		return nil, fmt.Errorf("reap sandboxes: %w", err)
+	}
+	if report != nil && len(report.Reaped) > 0 {
+		workflow.GetLogger(ctx).Info("reaped orphaned sandboxes",
+			"count", len(report.Reaped), "dry_run", input.DryRun)
+	}
+	return report, nil
+}
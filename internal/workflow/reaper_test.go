@@ -0,0 +1,37 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestSandboxReaperWorkflow_ReturnsReport(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SandboxReaperWorkflow)
+
+	var gotInput ReapInput
+	env.RegisterActivityWithOptions(
+		func(_ context.Context, input ReapInput) (*ReapReport, error) {
+			gotInput = input
+			return &ReapReport{DryRun: input.DryRun, Live: 2, Reaped: []ReapedSandbox{{SandboxID: "sb-1", RunID: "run-1"}}}, nil
+		},
+		activity.RegisterOptions{Name: ReapSandboxesActivity},
+	)
+
+	env.ExecuteWorkflow(SandboxReaperWorkflow, ReapInput{DryRun: true})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.True(t, gotInput.DryRun)
+	var report ReapReport
+	require.NoError(t, env.GetWorkflowResult(&report))
+	assert.Equal(t, 2, report.Live)
+	require.Len(t, report.Reaped, 1)
+	assert.Equal(t, "sb-1", report.Reaped[0].SandboxID)
+}
@@ -0,0 +1,149 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+// RetrySeed returns the outputs a retry of a finished run starts with: every step
+// that completed in the original run, minus the steps that must run again.
+//
+// Steps that did not complete (failed, skipped, cancelled, never started) run
+// again, as does every step downstream of them. When from is set, that step and
+// its downstream steps run again even if they completed. Steps sharing a
+// sandbox_group with a step that runs again are also re-run, because the retry
+// provisions a fresh sandbox and their workspace changes would otherwise be lost.
+//
+// stepRuns are the original run's step_runs; when a step has several rows (HITL
+// continuations), the last one wins. Fan-out children are recorded as
+// "<step>-<index>" and are seeded only when every child completed or was skipped.
+func RetrySeed(def model.WorkflowDef, stepRuns []model.StepRun, from string) (map[string]*model.StepOutput, error) {
+	if from != "" && !slices.ContainsFunc(def.Steps, func(s model.StepDef) bool { return s.ID == from }) {
+		return nil, fmt.Errorf("step %q is not defined in workflow", from)
+	}
+
+	latest := make(map[string]model.StepRun, len(stepRuns))
+	for _, sr := range stepRuns {
+		if prev, ok := latest[sr.StepID]; !ok || !sr.CreatedAt.Before(prev.CreatedAt) {
+			latest[sr.StepID] = sr
+		}
+	}
+
+	defined := make(map[string]bool, len(def.Steps))
+	for _, s := range def.Steps {
+		defined[s.ID] = true
+	}
+
+	completed := map[string]*model.StepOutput{}
+	for _, s := range def.Steps {
+		if out := completedOutput(s.ID, latest, defined); out != nil {
+			completed[s.ID] = out
+		}
+	}
+
+	rerun := map[string]bool{}
+	for _, s := range def.Steps {
+		if completed[s.ID] == nil {
+			rerun[s.ID] = true
+		}
+	}
+	if from != "" {
+		rerun[from] = true
+	}
+
+	// Grow the re-run set to a fixed point: anything depending on, or sharing a
+	// sandbox with, a re-run step re-runs too.
+	for changed := true; changed; {
+		changed = false
+		for _, s := range def.Steps {
+			if rerun[s.ID] {
+				continue
+			}
+			for _, other := range def.Steps {
+				if !rerun[other.ID] {
+					continue
+				}
+				if slices.Contains(s.DependsOn, other.ID) ||
+					(s.SandboxGroup != "" && s.SandboxGroup == other.SandboxGroup) {
+					rerun[s.ID] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	seed := map[string]*model.StepOutput{}
+	for id, out := range completed {
+		if !rerun[id] {
+			seed[id] = out
+		}
+	}
+	return seed, nil
+}
+
+// completedOutput rebuilds the StepOutput of a step that completed, or returns nil.
+func completedOutput(stepID string, latest map[string]model.StepRun, defined map[string]bool) *model.StepOutput {
+	if sr, ok := latest[stepID]; ok {
+		if sr.Status != model.StepStatusComplete {
+			return nil
+		}
+		return stepRunOutput(stepID, sr, model.StepStatusComplete)
+	}
+
+	// Fan-out children: "<step>-<index>".
+	type child struct {
+		idx int
+		sr  model.StepRun
+	}
+	var children []child
+	prefix := stepID + "-"
+	for id, sr := range latest {
+		if defined[id] || !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		idx, err := strconv.Atoi(strings.TrimPrefix(id, prefix))
+		if err != nil {
+			continue
+		}
+		children = append(children, child{idx, sr})
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].idx < children[j].idx })
+	results := make([]*model.StepOutput, len(children))
+	for i, c := range children {
+		// A child skipped by its condition settled just as a completed one did.
+		switch c.sr.Status {
+		case model.StepStatusComplete, model.StepStatusSkipped:
+			results[i] = stepRunOutput(stepID, c.sr, c.sr.Status)
+		default:
+			return nil
+		}
+	}
+	return aggregateFanOut(stepID, results)
+}
+
+func stepRunOutput(stepID string, sr model.StepRun, status model.StepStatus) *model.StepOutput {
+	out := &model.StepOutput{
+		StepID: stepID,
+		Status: status,
+		Output: map[string]any(sr.Output),
+	}
+	if sr.Diff != nil {
+		out.Diff = *sr.Diff
+	}
+	if sr.PRUrl != nil {
+		out.PRUrl = *sr.PRUrl
+	}
+	if sr.BranchName != nil {
+		out.BranchName = *sr.BranchName
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+// retryDef is analyze -> fix -> verify -> notify, with a separate report step.
+func retryDef() model.WorkflowDef {
+	return model.WorkflowDef{Steps: []model.StepDef{
+		{ID: "analyze"},
+		{ID: "report"},
+		{ID: "fix", DependsOn: []string{"analyze"}},
+		{ID: "verify", DependsOn: []string{"fix"}},
+		{ID: "notify", DependsOn: []string{"verify"}, Optional: true},
+	}}
+}
+
+func stepRun(stepID string, status model.StepStatus, at time.Time) model.StepRun {
+	return model.StepRun{StepID: stepID, Status: status, CreatedAt: at, Output: model.JSONMap{"step": stepID}}
+}
+
+func seededIDs(seed map[string]*model.StepOutput) []string {
+	ids := make([]string, 0, len(seed))
+	for id := range seed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestRetrySeed_RerunsFailedAndDownstream(t *testing.T) {
+	now := time.Now()
+	runs := []model.StepRun{
+		stepRun("analyze", model.StepStatusComplete, now),
+		stepRun("report", model.StepStatusComplete, now),
+		stepRun("fix", model.StepStatusFailed, now),
+		// An optional step that ran despite the failure still re-runs.
+		stepRun("notify", model.StepStatusComplete, now),
+	}
+
+	seed, err := RetrySeed(retryDef(), runs, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"analyze", "report"}, seededIDs(seed))
+	assert.Equal(t, model.StepStatusComplete, seed["analyze"].Status)
+	assert.Equal(t, map[string]any{"step": "analyze"}, seed["analyze"].Output)
+}
+
+func TestRetrySeed_From(t *testing.T) {
+	now := time.Now()
+	runs := []model.StepRun{
+		stepRun("analyze", model.StepStatusComplete, now),
+		stepRun("report", model.StepStatusComplete, now),
+		stepRun("fix", model.StepStatusComplete, now),
+		stepRun("verify", model.StepStatusComplete, now),
+		stepRun("notify", model.StepStatusComplete, now),
+	}
+
+	seed, err := RetrySeed(retryDef(), runs, "fix")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"analyze", "report"}, seededIDs(seed))
+
+	_, err = RetrySeed(retryDef(), runs, "nope")
+	assert.Error(t, err)
+}
+
+func TestRetrySeed_LatestStepRunWins(t *testing.T) {
+	now := time.Now()
+	runs := []model.StepRun{
+		stepRun("analyze", model.StepStatusFailed, now.Add(-time.Minute)),
+		stepRun("analyze", model.StepStatusComplete, now),
+	}
+
+	seed, err := RetrySeed(retryDef(), runs, "")
+	require.NoError(t, err)
+	assert.Contains(t, seed, "analyze")
+}
+
+func TestRetrySeed_FanOut(t *testing.T) {
+	def := model.WorkflowDef{Steps: []model.StepDef{
+		{ID: "scan", Repositories: "{{ .Params.repos }}"},
+		{ID: "summarize", DependsOn: []string{"scan"}},
+	}}
+	now := time.Now()
+
+	seed, err := RetrySeed(def, []model.StepRun{
+		stepRun("scan-1", model.StepStatusComplete, now),
+		stepRun("scan-0", model.StepStatusComplete, now),
+		stepRun("summarize", model.StepStatusFailed, now),
+	}, "")
+	require.NoError(t, err)
+	require.Contains(t, seed, "scan")
+	require.Len(t, seed["scan"].Outputs, 2)
+	assert.Equal(t, map[string]any{"step": "scan-0"}, seed["scan"].Outputs[0].Output)
+
+	seed, err = RetrySeed(def, []model.StepRun{
+		stepRun("scan-0", model.StepStatusComplete, now),
+		stepRun("scan-1", model.StepStatusFailed, now),
+	}, "")
+	require.NoError(t, err)
+	assert.Empty(t, seed, "a fan-out with a failed child re-runs")
+
+	seed, err = RetrySeed(def, []model.StepRun{
+		stepRun("scan-0", model.StepStatusComplete, now),
+		stepRun("scan-1", model.StepStatusSkipped, now),
+		stepRun("summarize", model.StepStatusFailed, now),
+	}, "")
+	require.NoError(t, err)
+	require.Contains(t, seed, "scan", "a skipped child has settled")
+	assert.Equal(t, model.StepStatusComplete, seed["scan"].Status)
+	assert.Equal(t, model.StepStatusSkipped, seed["scan"].Outputs[1].Status)
+}
+
+func TestRetrySeed_SandboxGroupRerunsTogether(t *testing.T) {
+	def := model.WorkflowDef{Steps: []model.StepDef{
+		{ID: "setup", SandboxGroup: "main"},
+		{ID: "lint"},
+		{ID: "build", DependsOn: []string{"setup"}, SandboxGroup: "main"},
+	}}
+	now := time.Now()
+
+	seed, err := RetrySeed(def, []model.StepRun{
+		stepRun("setup", model.StepStatusComplete, now),
+		stepRun("lint", model.StepStatusComplete, now),
+		stepRun("build", model.StepStatusFailed, now),
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lint"}, seededIDs(seed))
+}
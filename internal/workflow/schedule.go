@@ -0,0 +1,58 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/tinkerloft/fleetlift/internal/model"
+)
+
+// ScheduledRunInput is the argument Temporal Schedules pass to ScheduledRunWorkflow.
+type ScheduledRunInput struct {
+	ScheduleID string `json:"schedule_id"`
+}
+
+// ScheduledRun is a run recorded by StartScheduledRunActivity, ready to start.
+type ScheduledRun struct {
+	RunID      string   `json:"run_id"`
+	TemporalID string   `json:"temporal_id"`
+	Input      DAGInput `json:"input"`
+}
+
+// ScheduledRunWorkflow is the action of every fleetlift Temporal Schedule. It records
+// a run from the schedule's current settings and starts it as an independent
+// DAGWorkflow, then returns; the run's lifetime is not tied to this workflow.
+func ScheduledRunWorkflow(ctx workflow.Context, input ScheduledRunInput) error {
+	logger := workflow.GetLogger(ctx)
+
+	ao := workflow.ActivityOptions{StartToCloseTimeout: time.Minute, RetryPolicy: dbRetry}
+	var run *ScheduledRun
+	if err := workflow.ExecuteActivity(
+		workflow.WithActivityOptions(ctx, ao),
+		StartScheduledRunActivity, input,
+	).Get(ctx, &run); err != nil {
+		return fmt.Errorf("start scheduled run: %w", err)
+	}
+	if run == nil {
+		logger.Info("schedule disabled or deleted; skipping", "schedule_id", input.ScheduleID)
+		return nil
+	}
+
+	cwo := workflow.ChildWorkflowOptions{
+		WorkflowID:        run.TemporalID,
+		ParentClosePolicy: enumspb.PARENT_CLOSE_POLICY_ABANDON,
+	}
+	child := workflow.ExecuteChildWorkflow(workflow.WithChildOptions(ctx, cwo), DAGWorkflow, run.Input)
+	if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+		_ = workflow.ExecuteActivity(
+			workflow.WithActivityOptions(ctx, ao),
+			UpdateRunStatusActivity, run.RunID, string(model.RunStatusFailed), "failed to start scheduled run: "+err.Error(),
+		).Get(ctx, nil)
+		return fmt.Errorf("start DAG workflow %s: %w", run.TemporalID, err)
+	}
+	logger.Info("scheduled run started", "schedule_id", input.ScheduleID, "run_id", run.RunID)
+	return nil
+}
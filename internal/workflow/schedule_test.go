@@ -0,0 +1,59 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func newScheduledRunEnv(t *testing.T, run *ScheduledRun) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(ScheduledRunWorkflow)
+	env.RegisterWorkflow(DAGWorkflow)
+	env.RegisterActivityWithOptions(
+		func(context.Context, ScheduledRunInput) (*ScheduledRun, error) { return run, nil },
+		activity.RegisterOptions{Name: StartScheduledRunActivity},
+	)
+	return env
+}
+
+func TestScheduledRunWorkflow_StartsDAGWorkflow(t *testing.T) {
+	run := &ScheduledRun{
+		RunID:      "run-1",
+		TemporalID: "fl-nightly-run-1",
+		Input:      DAGInput{RunID: "run-1", TeamID: "team-1"},
+	}
+	env := newScheduledRunEnv(t, run)
+
+	var startedID string
+	env.OnWorkflow(DAGWorkflow, mock.Anything, mock.Anything).Return(
+		func(ctx workflow.Context, input DAGInput) error {
+			startedID = workflow.GetInfo(ctx).WorkflowExecution.ID
+			assert.Equal(t, "run-1", input.RunID)
+			return nil
+		})
+
+	env.ExecuteWorkflow(ScheduledRunWorkflow, ScheduledRunInput{ScheduleID: "sched-1"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.Equal(t, "fl-nightly-run-1", startedID)
+}
+
+func TestScheduledRunWorkflow_SkipsWhenScheduleGone(t *testing.T) {
+	env := newScheduledRunEnv(t, nil)
+
+	env.ExecuteWorkflow(ScheduledRunWorkflow, ScheduledRunInput{ScheduleID: "sched-1"})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNotCalled(t, "DAGWorkflow", mock.Anything, mock.Anything)
+}
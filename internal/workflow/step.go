@@ -2,6 +2,7 @@ package workflow
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"go.temporal.io/sdk/log"
@@ -22,6 +23,8 @@ type StepInput struct {
 	SandboxID          string           `json:"sandbox_id"`    // non-empty if sandbox_group reuse
 	ModelOverride      string           `json:"model_override,omitempty"`
 	TriggeredBy        string           `json:"triggered_by,omitempty"` // user ID who started the run
+	RunBudget          *model.BudgetDef `json:"run_budget,omitempty"`   // workflow-level budget shared by all steps of the run
+	ParamsHash         string           `json:"params_hash,omitempty"`  // hash of the run's parameters; see ParamsHash
 }
 
 // ResolvedStepOpts holds step options after template rendering.
@@ -31,6 +34,7 @@ type ResolvedStepOpts struct {
 	Verifiers         any                     `json:"verifiers,omitempty"`
 	Credentials       []string                `json:"credentials,omitempty"`
 	PRConfig          *model.PRDef            `json:"pr_config,omitempty"`
+	Branch            string                  `json:"branch,omitempty"` // working branch for the step's repo; see resolveBranch
 	Agent             string                  `json:"agent"`
 	MaxTurns          int                     `json:"max_turns,omitempty"`
 	SandboxGroupImage string                  `json:"sandbox_group_image,omitempty"`
@@ -47,6 +51,28 @@ type ExecuteStepInput struct {
 	ConversationHistory string                     `json:"conversation_history,omitempty"`
 	ContinuationContext *model.ContinuationContext `json:"continuation_context,omitempty"` // E3
 	EvalPluginDirs      []string                   `json:"eval_plugin_dirs,omitempty"`
+	// ResumeSession continues this agent session with ResumePrompt as the next
+	// turn when the runner supports it and the session is still in the sandbox.
+	// Otherwise the step runs from Prompt as usual.
+	ResumeSession string `json:"resume_session,omitempty"`
+	ResumePrompt  string `json:"resume_prompt,omitempty"`
+}
+
+// ResumeSessionInput identifies an agent session to check for resumability.
+type ResumeSessionInput struct {
+	SandboxID string `json:"sandbox_id"`
+	Agent     string `json:"agent"`
+	SessionID string `json:"session_id"`
+}
+
+// PRSkippedInput is the input to the NotifyPRSkipped activity.
+type PRSkippedInput struct {
+	TeamID    string `json:"team_id"`
+	RunID     string `json:"run_id"`
+	StepRunID string `json:"step_run_id"`
+	StepID    string `json:"step_id"`
+	Reason    string `json:"reason"`
+	Diff      string `json:"diff,omitempty"`
 }
 
 // StepSignal represents signals that can be sent to a StepWorkflow.
@@ -92,6 +118,11 @@ var (
 	RunPreflightActivity              = "RunPreflight"
 	ResolveAgentProfileActivity       = "ResolveAgentProfile"
 	GetPrimaryRunArtifactIDActivity   = "GetPrimaryRunArtifactID"
+	CaptureKnowledgeActivity          = "CaptureKnowledge"
+	StartScheduledRunActivity         = "StartScheduledRun"
+	ReapSandboxesActivity             = "ReapSandboxes"
+	CanResumeSessionActivity          = "CanResumeSession"
+	NotifyPRSkippedActivity           = "NotifyPRSkipped"
 )
 
 // ResolveProfileInput is the input to the ResolveAgentProfile activity.
@@ -196,6 +227,7 @@ func StepWorkflow(ctx workflow.Context, input StepInput) (retOut *model.StepOutp
 	var output *model.StepOutput
 	prompt := input.ResolvedOpts.Prompt
 	conversationHistory := ""
+	var resumeSession, resumePrompt string // agent session a steer continues, if the runner supports it
 
 	for {
 		timeout := 90 * time.Minute
@@ -219,6 +251,8 @@ func StepWorkflow(ctx workflow.Context, input StepInput) (retOut *model.StepOutp
 				Prompt:              prompt,
 				ConversationHistory: conversationHistory,
 				EvalPluginDirs:      evalPluginDirs,
+				ResumeSession:       resumeSession,
+				ResumePrompt:        resumePrompt,
 			},
 		).Get(ctx, &output)
 		if err != nil {
@@ -260,25 +294,72 @@ func StepWorkflow(ctx workflow.Context, input StepInput) (retOut *model.StepOutp
 				return nil, fmt.Errorf("create continuation step_run: %w", err)
 			}
 
-			// Provision a fresh sandbox for the continuation
-			var continuationSandboxID string
-			contProvAO := workflow.ActivityOptions{
-				StartToCloseTimeout: 5 * time.Minute,
-				RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
-			}
+			// Answer in the original sandbox by resuming the agent's session when
+			// it is still there. Otherwise provision a fresh sandbox and restore
+			// the working state from the checkpoint branch.
 			continuationInput := input
 			continuationInput.StepRunID = continuationStepRunID
-			continuationInput.SandboxID = "" // force new provision
+			contCtx := &model.ContinuationContext{
+				InboxItemID:      output.InboxItemID,
+				Question:         output.Question,
+				HumanAnswer:      answer.Answer,
+				CheckpointBranch: output.CheckpointBranch,
+				StateArtifactID:  output.StateArtifactID,
+			}
+			resumed := false
+			if output.SessionID != "" {
+				resumeAO := workflow.ActivityOptions{
+					StartToCloseTimeout: 30 * time.Second,
+					RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+				}
+				if err := workflow.ExecuteActivity(
+					workflow.WithActivityOptions(ctx, resumeAO),
+					CanResumeSessionActivity, ResumeSessionInput{
+						SandboxID: sandboxID,
+						Agent:     input.ResolvedOpts.Agent,
+						SessionID: output.SessionID,
+					},
+				).Get(ctx, &resumed); err != nil {
+					logger.Warn("failed to check agent session, continuing in a fresh sandbox", "error", err)
+					resumed = false
+				}
+			}
 
-			err = workflow.ExecuteActivity(
-				workflow.WithActivityOptions(ctx, contProvAO),
-				ProvisionSandboxActivity, continuationInput,
-			).Get(ctx, &continuationSandboxID)
-			if err != nil {
-				return nil, fmt.Errorf("provision continuation sandbox: %w", err)
+			var continuationSandboxID string
+			contCleanupAO := workflow.ActivityOptions{
+				StartToCloseTimeout: 2 * time.Minute,
+				RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+			}
+			if resumed {
+				continuationSandboxID = sandboxID
+				contCtx.CheckpointBranch = "" // the working state is still in the sandbox
+			} else {
+				contProvAO := workflow.ActivityOptions{
+					StartToCloseTimeout: 5 * time.Minute,
+					RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+				}
+				continuationInput.SandboxID = "" // force new provision
+				err = workflow.ExecuteActivity(
+					workflow.WithActivityOptions(ctx, contProvAO),
+					ProvisionSandboxActivity, continuationInput,
+				).Get(ctx, &continuationSandboxID)
+				if err != nil {
+					return nil, fmt.Errorf("provision continuation sandbox: %w", err)
+				}
 			}
 
 			// Re-execute with continuation context
+			contExec := ExecuteStepInput{
+				StepInput:           continuationInput,
+				SandboxID:           continuationSandboxID,
+				Prompt:              prompt, // original prompt; buildContinuationPrompt prepends context in activity
+				ContinuationContext: contCtx,
+				EvalPluginDirs:      evalPluginDirs,
+			}
+			if resumed {
+				contExec.ResumeSession = output.SessionID
+				contExec.ResumePrompt = fmt.Sprintf("You asked: %q\nThe human answered: %q\n\nContinue the task.", output.Question, answer.Answer)
+			}
 			var continuationOutput *model.StepOutput
 			contExecAO := workflow.ActivityOptions{
 				StartToCloseTimeout: timeout,
@@ -287,30 +368,16 @@ func StepWorkflow(ctx workflow.Context, input StepInput) (retOut *model.StepOutp
 			}
 			err = workflow.ExecuteActivity(
 				workflow.WithActivityOptions(ctx, contExecAO),
-				ExecuteStepActivity, ExecuteStepInput{
-					StepInput: continuationInput,
-					SandboxID: continuationSandboxID,
-					Prompt:    prompt, // original prompt; buildContinuationPrompt prepends context in activity
-					ContinuationContext: &model.ContinuationContext{
-						InboxItemID:      output.InboxItemID,
-						Question:         output.Question,
-						HumanAnswer:      answer.Answer,
-						CheckpointBranch: output.CheckpointBranch,
-						StateArtifactID:  output.StateArtifactID,
-					},
-					EvalPluginDirs: evalPluginDirs,
-				},
+				ExecuteStepActivity, contExec,
 			).Get(ctx, &continuationOutput)
 
-			// Cleanup continuation sandbox
-			contCleanupAO := workflow.ActivityOptions{
-				StartToCloseTimeout: 2 * time.Minute,
-				RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+			// Cleanup continuation sandbox; the original is cleaned up with the step.
+			if !resumed {
+				_ = workflow.ExecuteActivity(
+					workflow.WithActivityOptions(ctx, contCleanupAO),
+					CleanupSandboxActivity, continuationSandboxID,
+				).Get(ctx, nil)
 			}
-			_ = workflow.ExecuteActivity(
-				workflow.WithActivityOptions(ctx, contCleanupAO),
-				CleanupSandboxActivity, continuationSandboxID,
-			).Get(ctx, nil)
 
 			// Cleanup checkpoint branch if set
 			if output.CheckpointBranch != "" && len(input.ResolvedOpts.Repos) > 0 {
@@ -422,24 +489,70 @@ func StepWorkflow(ctx workflow.Context, input StepInput) (retOut *model.StepOutp
 			}
 			return rejectOutput, nil
 		}
-		// Steer: rebuild prompt with history and new instruction
+		// Steer: continue the agent's session with the new instruction. The
+		// history is still rebuilt for runners that cannot resume.
 		conversationHistory = fmt.Sprintf("%s\n\nPrevious attempt output:\n%s\n\nSteering instruction:\n%s",
 			conversationHistory, output.Diff, steerPayload.Prompt)
 		prompt = input.ResolvedOpts.Prompt
+		resumeSession, resumePrompt = output.SessionID, steerPayload.Prompt
 	}
 
-	// 6. Create PR if transform mode
-	if input.StepDef.Mode == "transform" && input.StepDef.PullRequest != nil {
-		prAO := workflow.ActivityOptions{
-			StartToCloseTimeout: 5 * time.Minute,
-			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 2},
+	// Capture learnings the agent recorded. Best-effort — never fails the step.
+	if k := input.StepDef.Knowledge; k != nil && k.Capture && output != nil {
+		captureAO := workflow.ActivityOptions{
+			StartToCloseTimeout: 2 * time.Minute,
+			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 3},
+		}
+		var captured int
+		if err := workflow.ExecuteActivity(
+			workflow.WithActivityOptions(ctx, captureAO),
+			CaptureKnowledgeActivity, model.CaptureKnowledgeInput{
+				SandboxID:          sandboxID,
+				TeamID:             input.TeamID,
+				WorkflowTemplateID: input.WorkflowTemplateID,
+				StepRunID:          input.StepRunID,
+				Tags:               k.Tags,
+			},
+		).Get(ctx, &captured); err != nil {
+			logger.Warn("failed to capture knowledge", "step_id", input.StepDef.ID, "error", err)
+		} else if captured > 0 {
+			logger.Info("captured knowledge items", "step_id", input.StepDef.ID, "count", captured)
 		}
-		if prErr := workflow.ExecuteActivity(
-			workflow.WithActivityOptions(ctx, prAO),
-			CreatePRActivity, sandboxID, input,
-		).Get(ctx, &output.PRUrl); prErr != nil {
-			logger.Error("failed to create pull request", "step_id", input.StepDef.ID, "error", prErr)
-			output.Error = fmt.Sprintf("step completed but PR creation failed: %v", prErr)
+	}
+
+	// 6. Create PR if transform mode, unless the agent said its work is not PR-ready
+	if input.StepDef.Mode == "transform" && input.StepDef.PullRequest != nil {
+		if reason, skip := prSkipReason(output); skip {
+			logger.Info("agent skipped pull request", "step_id", input.StepDef.ID, "reason", reason)
+			output.Output[prSkipReasonKey] = reason
+			if input.StepDef.PullRequest.NotifyOnSkip {
+				notifyAO := workflow.ActivityOptions{StartToCloseTimeout: 30 * time.Second, RetryPolicy: dbRetry}
+				if err := workflow.ExecuteActivity(
+					workflow.WithActivityOptions(ctx, notifyAO),
+					NotifyPRSkippedActivity, PRSkippedInput{
+						TeamID:    input.TeamID,
+						RunID:     input.RunID,
+						StepRunID: input.StepRunID,
+						StepID:    input.StepDef.ID,
+						Reason:    reason,
+						Diff:      output.Diff,
+					},
+				).Get(ctx, nil); err != nil {
+					logger.Warn("failed to notify about skipped pull request", "step_id", input.StepDef.ID, "error", err)
+				}
+			}
+		} else {
+			prAO := workflow.ActivityOptions{
+				StartToCloseTimeout: 5 * time.Minute,
+				RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 2},
+			}
+			if prErr := workflow.ExecuteActivity(
+				workflow.WithActivityOptions(ctx, prAO),
+				CreatePRActivity, sandboxID, input,
+			).Get(ctx, &output.PRUrl); prErr != nil {
+				logger.Error("failed to create pull request", "step_id", input.StepDef.ID, "error", prErr)
+				output.Error = fmt.Sprintf("step completed but PR creation failed: %v", prErr)
+			}
 		}
 	}
 
@@ -454,7 +567,7 @@ func StepWorkflow(ctx workflow.Context, input StepInput) (retOut *model.StepOutp
 			CleanupSandboxActivity, sandboxID,
 		).Get(ctx, nil); err != nil {
 			logger.Error("failed to cleanup sandbox", "sandbox_id", sandboxID, "error", err)
-			// Sandbox may leak — monitor for orphaned sandboxes
+			// The sandbox reaper kills it once the run has ended.
 		}
 	}
 
@@ -503,3 +616,26 @@ func shouldPause(def model.StepDef, output *model.StepOutput) bool {
 	}
 	return false
 }
+
+// Output fields an agent sets to keep a transform step from opening a PR.
+const (
+	prReadyKey      = "pr_ready"
+	prSkipReasonKey = "pr_skip_reason"
+)
+
+// prSkipReason reports whether the agent declared its changes not PR-ready
+// with pr_ready: false, and why. A missing pr_ready means the PR is opened.
+func prSkipReason(output *model.StepOutput) (string, bool) {
+	if output == nil {
+		return "", false
+	}
+	ready, ok := output.Output[prReadyKey].(bool)
+	if !ok || ready {
+		return "", false
+	}
+	reason, _ := output.Output[prSkipReasonKey].(string)
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = "agent reported the changes are not ready for a pull request"
+	}
+	return reason, true
+}
@@ -3,7 +3,9 @@ package workflow
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -96,6 +98,11 @@ func (m *stepMockActivities) CreatePullRequest(_ context.Context, sandboxID stri
 	return args.String(0), args.Error(1)
 }
 
+func (m *stepMockActivities) NotifyPRSkipped(_ context.Context, input PRSkippedInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
 func (m *stepMockActivities) VerifyStep(_ context.Context, sandboxID, stepRunID string, verifiers any) error {
 	args := m.Called(sandboxID, stepRunID, verifiers)
 	return args.Error(0)
@@ -116,11 +123,21 @@ func (m *stepMockActivities) CleanupCheckpointBranch(_ context.Context, input mo
 	return args.Error(0)
 }
 
+func (m *stepMockActivities) CanResumeSession(_ context.Context, input ResumeSessionInput) (bool, error) {
+	args := m.Called(input)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *stepMockActivities) RunPreflight(_ context.Context, input RunPreflightInput) (RunPreflightOutput, error) {
 	args := m.Called(input)
 	return args.Get(0).(RunPreflightOutput), args.Error(1)
 }
 
+func (m *stepMockActivities) CaptureKnowledge(_ context.Context, input model.CaptureKnowledgeInput) (int, error) {
+	args := m.Called(input)
+	return args.Int(0), args.Error(1)
+}
+
 // newStepWorkflowEnv creates a configured Temporal test environment with all
 // StepWorkflow activities registered from the mock struct.
 func newStepWorkflowEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, *stepMockActivities) {
@@ -133,11 +150,14 @@ func newStepWorkflowEnv(t *testing.T) (*testsuite.TestWorkflowEnvironment, *step
 	env.RegisterActivity(mocks.CleanupSandbox)
 	env.RegisterActivity(mocks.UpdateStepStatus)
 	env.RegisterActivity(mocks.CreatePullRequest)
+	env.RegisterActivity(mocks.NotifyPRSkipped)
 	env.RegisterActivity(mocks.VerifyStep)
 	env.RegisterActivity(mocks.CompleteStepRun)
 	env.RegisterActivity(mocks.CreateContinuationStepRun)
 	env.RegisterActivity(mocks.CleanupCheckpointBranch)
+	env.RegisterActivity(mocks.CanResumeSession)
 	env.RegisterActivity(mocks.RunPreflight)
+	env.RegisterActivity(mocks.CaptureKnowledge)
 	return env, mocks
 }
 
@@ -409,6 +429,84 @@ func TestStepWorkflow_CreatePRFailureSetsError(t *testing.T) {
 	assert.Contains(t, result.Error, "PR creation failed")
 }
 
+// TestStepWorkflow_AgentSkipsPR verifies that pr_ready: false keeps the step
+// from opening a PR, records the reason and notifies with the diff attached.
+func TestStepWorkflow_AgentSkipsPR(t *testing.T) {
+	env, mocks := newStepWorkflowEnv(t)
+
+	input := StepInput{
+		RunID:     "run-skip",
+		TeamID:    "team-1",
+		StepRunID: "sr-skip",
+		StepDef: model.StepDef{
+			ID:             "transform",
+			Mode:           "transform",
+			ApprovalPolicy: "never",
+			PullRequest:    &model.PRDef{Title: "test PR", NotifyOnSkip: true},
+		},
+		ResolvedOpts: ResolvedStepOpts{
+			Prompt:   "Fix the bug",
+			Agent:    "claude-code",
+			PRConfig: &model.PRDef{Title: "test PR", NotifyOnSkip: true},
+		},
+	}
+
+	execOutput := &model.StepOutput{
+		StepID: "transform",
+		Status: model.StepStatusComplete,
+		Output: map[string]any{"pr_ready": false, "pr_skip_reason": "tests still fail"},
+		Diff:   "some diff",
+	}
+
+	mocks.On("ProvisionSandbox", input).Return("sb-skip", nil)
+	mocks.On("ExecuteStep", mock.Anything).Return(execOutput, nil)
+	mocks.On("NotifyPRSkipped", PRSkippedInput{
+		TeamID:    "team-1",
+		RunID:     "run-skip",
+		StepRunID: "sr-skip",
+		StepID:    "transform",
+		Reason:    "tests still fail",
+		Diff:      "some diff",
+	}).Return(nil).Once()
+	mocks.On("CleanupSandbox", "sb-skip").Return(nil)
+	mocks.On("CompleteStepRun", "sr-skip", "complete", mock.Anything, "some diff", "", mock.AnythingOfType("float64")).Return(nil)
+
+	env.ExecuteWorkflow(StepWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertNotCalled(t, "CreatePullRequest", mock.Anything, mock.Anything)
+	mocks.AssertExpectations(t)
+
+	var result model.StepOutput
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, model.StepStatusComplete, result.Status)
+	assert.Empty(t, result.PRUrl)
+	assert.Equal(t, "tests still fail", result.Output["pr_skip_reason"])
+}
+
+func TestPRSkipReason(t *testing.T) {
+	cases := []struct {
+		name   string
+		output map[string]any
+		reason string
+		skip   bool
+	}{
+		{"no contract", map[string]any{"result": "done"}, "", false},
+		{"ready", map[string]any{"pr_ready": true}, "", false},
+		{"not a bool", map[string]any{"pr_ready": "false"}, "", false},
+		{"skipped with reason", map[string]any{"pr_ready": false, "pr_skip_reason": " flaky tests "}, "flaky tests", true},
+		{"skipped without reason", map[string]any{"pr_ready": false}, "agent reported the changes are not ready for a pull request", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, skip := prSkipReason(&model.StepOutput{Output: tc.output})
+			assert.Equal(t, tc.skip, skip)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
 // TestStepWorkflow_FinalizeFailurePropagates verifies that when CompleteStepRun
 // fails, the workflow returns an error instead of silently swallowing it.
 func TestStepWorkflow_FinalizeFailurePropagates(t *testing.T) {
@@ -512,6 +610,90 @@ func TestStepWorkflow_AwaitResumeCycle(t *testing.T) {
 	mocks.AssertCalled(t, "CleanupSandbox", "cont-sb-1")
 }
 
+func TestStepWorkflow_AwaitResumeCycleResumesSession(t *testing.T) {
+	env, mocks := newStepWorkflowEnv(t)
+
+	input := StepInput{
+		RunID:     "run-1",
+		StepRunID: "sr-1",
+		StepDef:   model.StepDef{ID: "fix", Title: "Fix", ApprovalPolicy: "never"},
+		ResolvedOpts: ResolvedStepOpts{
+			Prompt: "Fix the bug",
+			Agent:  "claude-code",
+		},
+		SandboxID: "sb-1",
+	}
+
+	mocks.On("ExecuteStep", mock.MatchedBy(func(ei ExecuteStepInput) bool {
+		return ei.ContinuationContext == nil
+	})).Return(&model.StepOutput{
+		StepID:           "fix",
+		Status:           model.StepStatusAwaitingInput,
+		Question:         "Fix or skip?",
+		CheckpointBranch: "fleetlift/checkpoint/sr-1",
+		SessionID:        "sess-1",
+	}, nil).Once()
+	mocks.On("ExecuteStep", mock.MatchedBy(func(ei ExecuteStepInput) bool {
+		return ei.ContinuationContext != nil && ei.SandboxID == "sb-1" && ei.StepInput.StepRunID == "cont-sr-1" &&
+			ei.ResumeSession == "sess-1" && strings.Contains(ei.ResumePrompt, "Fix tests") &&
+			ei.ContinuationContext.CheckpointBranch == ""
+	})).Return(&model.StepOutput{StepID: "fix", Status: model.StepStatusComplete}, nil).Once()
+
+	mocks.On("CreateContinuationStepRun", mock.Anything).Return("cont-sr-1", nil)
+	mocks.On("CanResumeSession", ResumeSessionInput{SandboxID: "sb-1", Agent: "claude-code", SessionID: "sess-1"}).Return(true, nil)
+	mocks.On("CompleteStepRun", mock.Anything, "complete", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("float64")).Return(nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("respond", model.InboxAnswer{Answer: "Fix tests"})
+	}, 0)
+
+	env.ExecuteWorkflow(StepWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertExpectations(t)
+	mocks.AssertNotCalled(t, "ProvisionSandbox", mock.Anything)
+	mocks.AssertNotCalled(t, "CleanupSandbox", mock.Anything)
+}
+
+func TestStepWorkflow_SteerResumesSession(t *testing.T) {
+	env, mocks := newStepWorkflowEnv(t)
+
+	input := StepInput{
+		RunID:     "run-1",
+		StepRunID: "sr-1",
+		StepDef:   model.StepDef{ID: "fix", ApprovalPolicy: "always"},
+		ResolvedOpts: ResolvedStepOpts{
+			Prompt: "Fix the bug",
+			Agent:  "claude-code",
+		},
+		SandboxID: "sb-1",
+	}
+
+	mocks.On("ExecuteStep", mock.MatchedBy(func(ei ExecuteStepInput) bool {
+		return ei.ResumeSession == ""
+	})).Return(&model.StepOutput{StepID: "fix", Status: model.StepStatusComplete, Diff: "diff-1", SessionID: "sess-1"}, nil).Once()
+	mocks.On("ExecuteStep", mock.MatchedBy(func(ei ExecuteStepInput) bool {
+		return ei.ResumeSession == "sess-1" && ei.ResumePrompt == "Also update the docs" &&
+			strings.Contains(ei.ConversationHistory, "diff-1")
+	})).Return(&model.StepOutput{StepID: "fix", Status: model.StepStatusComplete}, nil).Once()
+	mocks.On("UpdateStepStatus", "sr-1", string(model.StepStatusAwaitingInput)).Return(nil)
+	mocks.On("CompleteStepRun", mock.Anything, "complete", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("float64")).Return(nil)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(string(SignalSteer), SteerPayload{Prompt: "Also update the docs"})
+	}, time.Second)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(string(SignalApprove), nil)
+	}, 2*time.Second)
+
+	env.ExecuteWorkflow(StepWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertExpectations(t)
+}
+
 func TestStepWorkflow_NoAwaitingInput_WorksNormally(t *testing.T) {
 	env, mocks := newStepWorkflowEnv(t)
 
@@ -633,3 +815,45 @@ func TestStepWorkflow_PreflightFailurePropagates(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "pre-flight")
 }
+
+// TestStepWorkflow_KnowledgeCapture verifies that knowledge.capture runs the
+// CaptureKnowledge activity against the step's sandbox after the agent finishes,
+// and that a capture failure does not fail the step.
+func TestStepWorkflow_KnowledgeCapture(t *testing.T) {
+	env, mocks := newStepWorkflowEnv(t)
+
+	input := StepInput{
+		RunID:              "run-1",
+		StepRunID:          "sr-1",
+		TeamID:             "team-1",
+		WorkflowTemplateID: "wf-1",
+		StepDef: model.StepDef{
+			ID:             "analyze",
+			Mode:           "report",
+			ApprovalPolicy: "never",
+			Knowledge:      &model.KnowledgeDef{Capture: true, Tags: []string{"go"}},
+		},
+		ResolvedOpts: ResolvedStepOpts{Prompt: "Analyze the code", Agent: "claude-code"},
+		SandboxID:    "sb-1",
+	}
+
+	mocks.On("ExecuteStep", mock.Anything).Return(&model.StepOutput{
+		StepID: "analyze",
+		Status: model.StepStatusComplete,
+	}, nil)
+	mocks.On("CaptureKnowledge", model.CaptureKnowledgeInput{
+		SandboxID:          "sb-1",
+		TeamID:             "team-1",
+		WorkflowTemplateID: "wf-1",
+		StepRunID:          "sr-1",
+		Tags:               []string{"go"},
+	}).Return(0, fmt.Errorf("store unavailable"))
+	mocks.On("CompleteStepRun", "sr-1", "complete", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("float64")).Return(nil)
+
+	env.ExecuteWorkflow(StepWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	mocks.AssertCalled(t, "CaptureKnowledge", mock.Anything)
+	mocks.AssertCalled(t, "CompleteStepRun", "sr-1", "complete", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("float64"))
+}
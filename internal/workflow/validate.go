@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"text/template/parse"
 
 	"github.com/tinkerloft/fleetlift/internal/model"
+	fltemplate "github.com/tinkerloft/fleetlift/internal/template"
 )
 
 // ValidationError describes a single structural or semantic problem found in a WorkflowDef.
@@ -35,6 +38,7 @@ var defaultRegistry = model.DefaultActionRegistry()
 var validAgentTypes = map[string]bool{
 	"claude-code": true,
 	"codex":       true,
+	"gemini":      true,
 	"shell":       true,
 	"":            true,
 }
@@ -88,6 +92,11 @@ func ValidateWorkflow(def model.WorkflowDef, params map[string]any) []Validation
 	errs = append(errs, validateActionTypes(def)...)
 	errs = append(errs, validateAgentTypes(def)...)
 	errs = append(errs, validateSandboxGroups(def)...)
+	errs = append(errs, validateSandboxSpecs(def)...)
+	errs = append(errs, validateBudgets(def)...)
+	errs = append(errs, validateFanOutSettings(def)...)
+	errs = append(errs, validateBranches(def)...)
+	errs = append(errs, validatePullRequests(def)...)
 	errs = append(errs, validateCredentialNames(def)...)
 	errs = append(errs, validateTemplateRefs(def)...)
 	errs = append(errs, validateJSONParamsInRepositories(def)...)
@@ -305,6 +314,168 @@ func validateSandboxGroups(def model.WorkflowDef) []ValidationError {
 	return errs
 }
 
+// workspaceSizeRe matches a Kubernetes-style storage quantity such as "20Gi" or "500M".
+var workspaceSizeRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|k|M|G|T)$`)
+
+// validateSandboxSpecs checks per-step sandbox settings that providers would otherwise reject at run time.
+func validateSandboxSpecs(def model.WorkflowDef) []ValidationError {
+	var errs []ValidationError
+	for _, step := range def.Steps {
+		if step.Sandbox == nil || step.Sandbox.WorkspaceSize == "" {
+			continue
+		}
+		size := step.Sandbox.WorkspaceSize
+		n, _ := strconv.ParseFloat(strings.TrimRight(size, "KMGTik"), 64)
+		if !workspaceSizeRe.MatchString(size) || n <= 0 {
+			errs = append(errs, ValidationError{
+				StepID:  step.ID,
+				Field:   "sandbox.workspace_size",
+				Message: fmt.Sprintf("workspace_size %q must be a positive size with a unit, such as \"20Gi\" or \"500M\"", size),
+			})
+		}
+	}
+	return errs
+}
+
+// branchRepoFields maps the .Repo fields of a branch template to the keys of a
+// repositories entry.
+var branchRepoFields = map[string]string{"URL": "url", "Name": "name", "Branch": "branch", "Ref": "ref"}
+
+// validateBranches type-checks branch templates and, for literal repository
+// lists, checks that no two fan-out entries of the same repo resolve to the
+// same working branch. Every input of the name other than .Repo is the same
+// for all children of a fan-out, so entries collide exactly when they agree on
+// the repo fields the template uses.
+func validateBranches(def model.WorkflowDef) []ValidationError {
+	params := make(map[string]bool, len(def.Parameters))
+	for _, p := range def.Parameters {
+		params[p.Name] = true
+	}
+
+	var errs []ValidationError
+	for _, step := range def.Steps {
+		fail := func(format string, args ...any) {
+			errs = append(errs, ValidationError{StepID: step.ID, Field: "branch", Message: fmt.Sprintf(format, args...)})
+		}
+		var repoFields []string
+		if step.Branch != "" {
+			if step.Execution == nil || step.Repositories == nil {
+				fail("branch requires an agent step with repositories")
+				continue
+			}
+			tmpl, err := fltemplate.Parse("branch", step.Branch)
+			if err != nil {
+				fail("invalid branch template: %v", err)
+				continue
+			}
+			seen := make(map[string]bool)
+			walkFieldNodes(tmpl.Tree.Root, func(idents []string) {
+				switch idents[0] {
+				case "RunID", "StepID":
+				case "Params":
+					if len(idents) >= 2 && !params[idents[1]] {
+						fail("branch references unknown parameter %q", idents[1])
+					}
+				case "Repo":
+					if len(idents) < 2 {
+						return
+					}
+					if _, ok := branchRepoFields[idents[1]]; !ok {
+						fail("branch references unknown repo field %q; available: URL, Name, Branch, Ref", idents[1])
+					} else if !seen[idents[1]] {
+						seen[idents[1]] = true
+						repoFields = append(repoFields, idents[1])
+					}
+				default:
+					fail("branch references unknown variable .%s; available: .RunID, .StepID, .Repo, .Params", idents[0])
+				}
+			})
+		}
+
+		entries, ok := step.Repositories.([]any)
+		if !ok || len(entries) < 2 {
+			continue
+		}
+		prPrefix := step.PullRequest != nil && step.PullRequest.BranchPrefix != ""
+		seen := make(map[string]int, len(entries))
+		for j, e := range entries {
+			entry, _ := e.(map[string]any)
+			url, _ := entry["url"].(string)
+			key := url
+			if step.Branch != "" {
+				for _, f := range repoFields {
+					v, _ := entry[branchRepoFields[f]].(string)
+					key += "\x00" + v
+				}
+			} else {
+				createBranch, _ := entry["create_branch"].(string)
+				if createBranch == "" && !prPrefix {
+					continue // works on its base branch
+				}
+				key += "\x00" + createBranch
+			}
+			if first, dup := seen[key]; dup {
+				fail("repositories %d and %d of %s would use the same branch; use .Repo.Branch or .Repo.Ref in branch to tell them apart", first, j, url)
+				break
+			}
+			seen[key] = j
+		}
+	}
+	return errs
+}
+
+// validateBudgets checks that step and workflow budgets are not negative.
+func validateBudgets(def model.WorkflowDef) []ValidationError {
+	var errs []ValidationError
+	check := func(stepID string, b *model.BudgetDef) {
+		if b == nil {
+			return
+		}
+		if b.MaxUSD < 0 {
+			errs = append(errs, ValidationError{StepID: stepID, Field: "budget.max_usd", Message: "max_usd must not be negative"})
+		}
+		if b.MaxTokens < 0 {
+			errs = append(errs, ValidationError{StepID: stepID, Field: "budget.max_tokens", Message: "max_tokens must not be negative"})
+		}
+	}
+	check("", def.Budget)
+	for _, step := range def.Steps {
+		check(step.ID, step.Budget)
+	}
+	return errs
+}
+
+// validateFanOutSettings checks that max_parallel and failure_threshold are well-formed.
+func validateFanOutSettings(def model.WorkflowDef) []ValidationError {
+	var errs []ValidationError
+	for _, step := range def.Steps {
+		if step.MaxParallel < 0 {
+			errs = append(errs, ValidationError{StepID: step.ID, Field: "max_parallel", Message: "max_parallel must not be negative"})
+		}
+		if _, _, err := parseFailureThreshold(step.FailureThreshold); err != nil {
+			errs = append(errs, ValidationError{StepID: step.ID, Field: "failure_threshold", Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// validatePullRequests checks that pull_request.on_existing names a known mode.
+func validatePullRequests(def model.WorkflowDef) []ValidationError {
+	var errs []ValidationError
+	for _, step := range def.Steps {
+		if step.PullRequest == nil {
+			continue
+		}
+		switch step.PullRequest.OnExisting {
+		case "", model.PROnExistingUpdate, model.PROnExistingSkip, model.PROnExistingNew:
+		default:
+			errs = append(errs, ValidationError{StepID: step.ID, Field: "pull_request.on_existing",
+				Message: fmt.Sprintf("unknown on_existing %q; must be one of: update, skip, new", step.PullRequest.OnExisting)})
+		}
+	}
+	return errs
+}
+
 // validateCredentialNames checks that execution and action credential names are well-formed and not reserved.
 func validateCredentialNames(def model.WorkflowDef) []ValidationError {
 	var errs []ValidationError
@@ -371,70 +542,28 @@ func extractTemplateRefs(templateStr string, conditionCtx bool) (paramRefs []str
 	var seenParams = make(map[string]bool)
 	var seenSteps = make(map[string]bool)
 
-	var walkNode func(n parse.Node)
-	walkNode = func(n parse.Node) {
-		if n == nil {
-			return
-		}
-		switch node := n.(type) {
-		case *parse.ListNode:
-			if node == nil {
-				return
+	walkFieldNodes(tree.Root, func(idents []string) {
+		if len(idents) >= 2 && idents[0] == paramKey {
+			key := idents[1]
+			if !seenParams[key] {
+				seenParams[key] = true
+				paramSet = append(paramSet, key)
 			}
-			for _, child := range node.Nodes {
-				walkNode(child)
+		} else if len(idents) >= 3 && idents[0] == stepsKey {
+			ref := StepRef{
+				StepID: idents[1],
+				Field:  idents[2],
 			}
-		case *parse.ActionNode:
-			walkNode(node.Pipe)
-		case *parse.IfNode:
-			walkNode(node.Pipe)
-			walkNode(node.List)
-			walkNode(node.ElseList)
-		case *parse.RangeNode:
-			walkNode(node.Pipe)
-			walkNode(node.List)
-			walkNode(node.ElseList)
-		case *parse.WithNode:
-			walkNode(node.Pipe)
-			walkNode(node.List)
-			walkNode(node.ElseList)
-		case *parse.PipeNode:
-			if node == nil {
-				return
-			}
-			for _, cmd := range node.Cmds {
-				walkNode(cmd)
-			}
-		case *parse.CommandNode:
-			for _, arg := range node.Args {
-				walkNode(arg)
+			if len(idents) >= 4 && idents[2] == "Output" {
+				ref.OutputKey = idents[3]
 			}
-		case *parse.FieldNode:
-			idents := node.Ident
-			if len(idents) >= 2 && idents[0] == paramKey {
-				key := idents[1]
-				if !seenParams[key] {
-					seenParams[key] = true
-					paramSet = append(paramSet, key)
-				}
-			} else if len(idents) >= 3 && idents[0] == stepsKey {
-				ref := StepRef{
-					StepID: idents[1],
-					Field:  idents[2],
-				}
-				if len(idents) >= 4 && idents[2] == "Output" {
-					ref.OutputKey = idents[3]
-				}
-				compositeKey := ref.StepID + "." + ref.Field + "." + ref.OutputKey
-				if !seenSteps[compositeKey] {
-					seenSteps[compositeKey] = true
-					stepRefs = append(stepRefs, ref)
-				}
+			compositeKey := ref.StepID + "." + ref.Field + "." + ref.OutputKey
+			if !seenSteps[compositeKey] {
+				seenSteps[compositeKey] = true
+				stepRefs = append(stepRefs, ref)
 			}
 		}
-	}
-
-	walkNode(tree.Root)
+	})
 
 	sort.Strings(paramSet)
 	if len(paramSet) == 0 {
@@ -446,6 +575,47 @@ func extractTemplateRefs(templateStr string, conditionCtx bool) (paramRefs []str
 	return paramSet, stepRefs, nil
 }
 
+// walkFieldNodes calls fn with the identifiers of every field reference
+// (.A.B.C) in a parsed template.
+func walkFieldNodes(n parse.Node, fn func(idents []string)) {
+	switch node := n.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			walkFieldNodes(child, fn)
+		}
+	case *parse.ActionNode:
+		walkFieldNodes(node.Pipe, fn)
+	case *parse.IfNode:
+		walkFieldNodes(node.Pipe, fn)
+		walkFieldNodes(node.List, fn)
+		walkFieldNodes(node.ElseList, fn)
+	case *parse.RangeNode:
+		walkFieldNodes(node.Pipe, fn)
+		walkFieldNodes(node.List, fn)
+		walkFieldNodes(node.ElseList, fn)
+	case *parse.WithNode:
+		walkFieldNodes(node.Pipe, fn)
+		walkFieldNodes(node.List, fn)
+		walkFieldNodes(node.ElseList, fn)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, cmd := range node.Cmds {
+			walkFieldNodes(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			walkFieldNodes(arg, fn)
+		}
+	case *parse.FieldNode:
+		fn(node.Ident)
+	}
+}
+
 // validateJSONParamsInRepositories checks that any json-typed parameter referenced in a
 // repositories: template is piped through toJSON. Without it the Go template engine renders
 // the value as a Go fmt string (e.g. "[map[url:...]]") instead of JSON, causing a parse
@@ -583,7 +753,8 @@ func upstreamOf(stepID string, def model.WorkflowDef) map[string]bool {
 }
 
 // validateTemplateRefs validates all template references in step prompts, action configs,
-// and step conditions, checking that referenced params and steps exist and are reachable.
+// step conditions and fan-out filters, checking that referenced params and steps exist
+// and are reachable.
 func validateTemplateRefs(def model.WorkflowDef) []ValidationError {
 	var errs []ValidationError
 
@@ -706,7 +877,83 @@ func validateTemplateRefs(def model.WorkflowDef) []ValidationError {
 				// conditions can reference any completed step
 			}
 		}
+
+		if step.Filter != "" {
+			errs = append(errs, validateFilter(step, upstream, paramSet, stepByID)...)
+		}
+	}
+
+	return errs
+}
+
+// filterRepoFields are the fields of .repo available to a filter.
+var filterRepoFields = map[string]bool{"url": true, "name": true, "branch": true, "ref": true}
+
+// validateFilter type-checks a fan-out step's filter. Every reference must be a
+// known parameter, a field of .repo, or the status, error or output of an
+// upstream step, since the filter reads that step's result for each repo.
+func validateFilter(step *model.StepDef, upstream, paramSet map[string]bool, stepByID map[string]*model.StepDef) []ValidationError {
+	var errs []ValidationError
+	seen := make(map[string]bool)
+	fail := func(format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if !seen[msg] {
+			seen[msg] = true
+			errs = append(errs, ValidationError{StepID: step.ID, Field: "filter", Message: msg})
+		}
+	}
+
+	if step.Execution == nil || step.Repositories == nil {
+		fail("filter requires an agent step with repositories")
+		return errs
+	}
+	// Parse exactly as evalFilter does, so functions it does not provide are rejected.
+	tmpl, err := template.New("filter").Parse(step.Filter)
+	if err != nil {
+		fail("invalid filter template: %v", err)
+		return errs
 	}
 
+	walkFieldNodes(tmpl.Tree.Root, func(idents []string) {
+		switch idents[0] {
+		case "params":
+			if len(idents) >= 2 && !paramSet[idents[1]] {
+				fail("filter references unknown parameter %q", idents[1])
+			}
+		case "repo":
+			if len(idents) >= 2 && !filterRepoFields[idents[1]] {
+				fail("filter references unknown repo field %q; available: url, name, branch, ref", idents[1])
+			}
+		case "steps":
+			if len(idents) < 2 {
+				return
+			}
+			refStep, exists := stepByID[idents[1]]
+			if !exists {
+				fail("filter references unknown step %q", idents[1])
+				return
+			}
+			if !upstream[idents[1]] {
+				fail("filter references step %q which is not an upstream dependency", idents[1])
+				return
+			}
+			if len(idents) < 3 {
+				return
+			}
+			switch idents[2] {
+			case "status", "error":
+			case "output":
+				if len(idents) >= 4 && refStep.Execution != nil && refStep.Execution.Output != nil {
+					if _, ok := refStep.Execution.Output.Schema[idents[3]]; !ok {
+						fail("filter references output field %q on step %q which is not in its output schema", idents[3], idents[1])
+					}
+				}
+			default:
+				fail("filter references unknown field %q on step %q; available: status, error, output", idents[2], idents[1])
+			}
+		default:
+			fail("filter references unknown variable .%s; available: .params, .steps, .repo", idents[0])
+		}
+	})
 	return errs
 }
@@ -361,6 +361,19 @@ func TestValidateWorkflow_UnknownAgentType(t *testing.T) {
 	assert.True(t, found, "expected unknown agent type error, got %v", errs)
 }
 
+func TestValidateWorkflow_KnownAgentTypes(t *testing.T) {
+	for _, agentName := range []string{"claude-code", "codex", "gemini", "shell"} {
+		def := model.WorkflowDef{
+			Steps: []model.StepDef{
+				{ID: "step-one", Execution: &model.ExecutionDef{Agent: agentName, Prompt: "x"}},
+			},
+		}
+		for _, e := range ValidateWorkflow(def, nil) {
+			assert.NotEqual(t, "execution.agent", e.Field, "agent %q should be valid: %v", agentName, e)
+		}
+	}
+}
+
 func TestValidateWorkflow_CredentialNameFormat(t *testing.T) {
 	def := model.WorkflowDef{
 		Steps: []model.StepDef{
@@ -930,3 +943,250 @@ func TestValidateWorkflow_NoSandboxGroupWithStepImage(t *testing.T) {
 	}
 	assert.False(t, found, "expected no sandbox.image error when not in a group, got %v", errs)
 }
+
+func TestValidateWorkflow_WorkspaceSize(t *testing.T) {
+	for _, tc := range []struct {
+		size  string
+		valid bool
+	}{
+		{"20Gi", true},
+		{"500M", true},
+		{"1.5Ti", true},
+		{"10GB", false},
+		{"10", false},
+		{"0Gi", false},
+		{"-5Gi", false},
+		{"lots", false},
+	} {
+		t.Run(tc.size, func(t *testing.T) {
+			def := model.WorkflowDef{
+				Steps: []model.StepDef{{
+					ID:        "step-one",
+					Sandbox:   &model.SandboxSpec{WorkspaceSize: tc.size},
+					Execution: &model.ExecutionDef{Agent: "claude-code", Prompt: "do something"},
+				}},
+			}
+			var found bool
+			for _, e := range ValidateWorkflow(def, nil) {
+				if e.Field == "sandbox.workspace_size" {
+					found = true
+				}
+			}
+			assert.Equal(t, !tc.valid, found)
+		})
+	}
+}
+
+func TestValidateWorkflow_Budget(t *testing.T) {
+	def := model.WorkflowDef{
+		Budget: &model.BudgetDef{MaxUSD: -1},
+		Steps: []model.StepDef{{
+			ID:        "step-one",
+			Budget:    &model.BudgetDef{MaxUSD: 5, MaxTokens: -10},
+			Execution: &model.ExecutionDef{Agent: "claude-code", Prompt: "do something"},
+		}},
+	}
+	var fields []string
+	for _, e := range ValidateWorkflow(def, nil) {
+		if strings.HasPrefix(e.Field, "budget.") {
+			fields = append(fields, e.StepID+":"+e.Field)
+		}
+	}
+	assert.ElementsMatch(t, []string{":budget.max_usd", "step-one:budget.max_tokens"}, fields)
+}
+
+func TestValidateWorkflow_PROnExisting(t *testing.T) {
+	for _, tc := range []struct {
+		mode  string
+		valid bool
+	}{
+		{"", true},
+		{"update", true},
+		{"skip", true},
+		{"new", true},
+		{"replace", false},
+	} {
+		def := model.WorkflowDef{Steps: []model.StepDef{{
+			ID:          "step-one",
+			Mode:        "transform",
+			Execution:   &model.ExecutionDef{Agent: "claude-code", Prompt: "do something"},
+			PullRequest: &model.PRDef{BranchPrefix: "auto/fix", Title: "Fix", OnExisting: tc.mode},
+		}}}
+		var found bool
+		for _, e := range ValidateWorkflow(def, nil) {
+			if e.Field == "pull_request.on_existing" {
+				found = true
+			}
+		}
+		assert.Equal(t, !tc.valid, found, "on_existing %q", tc.mode)
+	}
+}
+
+func TestValidateWorkflow_FailureThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		threshold string
+		valid     bool
+	}{
+		{"", true},
+		{"2", true},
+		{"25%", true},
+		{"100%", true},
+		{"0", false},
+		{"-1", false},
+		{"150%", false},
+		{"two", false},
+	} {
+		def := validSingleStepDef()
+		def.Steps[0].FailureThreshold = tc.threshold
+		errs := ValidateWorkflow(def, nil)
+		found := false
+		for _, e := range errs {
+			if e.StepID == "step-one" && e.Field == "failure_threshold" {
+				found = true
+				break
+			}
+		}
+		assert.Equal(t, !tc.valid, found, "failure_threshold %q: got %v", tc.threshold, errs)
+	}
+}
+
+func TestValidateWorkflow_NegativeMaxParallel(t *testing.T) {
+	def := validSingleStepDef()
+	def.Steps[0].MaxParallel = -1
+	errs := ValidateWorkflow(def, nil)
+	found := false
+	for _, e := range errs {
+		if e.StepID == "step-one" && e.Field == "max_parallel" {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "expected max_parallel error, got %v", errs)
+}
+
+func TestValidateWorkflow_Filter(t *testing.T) {
+	repos := []any{map[string]any{"url": "https://github.com/acme/api"}}
+	filterDef := func(filter string) model.WorkflowDef {
+		return model.WorkflowDef{
+			Parameters: []model.ParameterDef{{Name: "env", Type: "string"}},
+			Steps: []model.StepDef{
+				{
+					ID: "analyze",
+					Execution: &model.ExecutionDef{
+						Agent:  "claude-code",
+						Prompt: "analyze",
+						Output: &model.OutputSchemaDef{Schema: map[string]any{"findings": "array"}},
+					},
+					Repositories: repos,
+				},
+				{ID: "other", Execution: &model.ExecutionDef{Agent: "claude-code", Prompt: "other"}},
+				{
+					ID:           "fix",
+					DependsOn:    []string{"analyze"},
+					Filter:       filter,
+					Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "fix"},
+					Repositories: repos,
+				},
+			},
+		}
+	}
+	filterErrors := func(def model.WorkflowDef) []string {
+		var msgs []string
+		for _, e := range ValidateWorkflow(def, nil) {
+			if e.Field == "filter" {
+				msgs = append(msgs, e.Message)
+			}
+		}
+		return msgs
+	}
+
+	for _, tc := range []struct {
+		filter string
+		want   string // substring of the single expected error; "" for valid
+	}{
+		{`{{ gt (len .steps.analyze.output.findings) 0 }}`, ""},
+		{`{{ and (eq .steps.analyze.status "complete") (eq .params.env "prod") (ne .repo.name "") }}`, ""},
+		{`{{ gt (len .steps.analyze.output.finding) 0 }}`, `output field "finding" on step "analyze" which is not in its output schema`},
+		{`{{ .steps.analyze.diff }}`, `unknown field "diff" on step "analyze"`},
+		{`{{ .steps.other.status }}`, `step "other" which is not an upstream dependency`},
+		{`{{ .steps.missing.status }}`, `unknown step "missing"`},
+		{`{{ .params.region }}`, `unknown parameter "region"`},
+		{`{{ .repo.owner }}`, `unknown repo field "owner"`},
+		{`{{ .Steps.analyze.status }}`, `unknown variable .Steps`},
+		{`{{ toJSON .repo }}`, `invalid filter template`},
+	} {
+		t.Run(tc.filter, func(t *testing.T) {
+			msgs := filterErrors(filterDef(tc.filter))
+			if tc.want == "" {
+				assert.Empty(t, msgs)
+				return
+			}
+			if !assert.Len(t, msgs, 1, "got %v", msgs) {
+				return
+			}
+			assert.Contains(t, msgs[0], tc.want)
+		})
+	}
+
+	def := filterDef(`{{ true }}`)
+	def.Steps[2].Repositories = nil
+	assert.Equal(t, []string{"filter requires an agent step with repositories"}, filterErrors(def))
+}
+
+func TestValidateWorkflow_Branch(t *testing.T) {
+	branchDef := func(branch string, repos []any) model.WorkflowDef {
+		return model.WorkflowDef{
+			Parameters: []model.ParameterDef{{Name: "ticket", Type: "string"}},
+			Steps: []model.StepDef{{
+				ID:           "fix",
+				Branch:       branch,
+				Execution:    &model.ExecutionDef{Agent: "claude-code", Prompt: "fix"},
+				Repositories: repos,
+				PullRequest:  &model.PRDef{BranchPrefix: "auto/fix", Title: "Fix"},
+			}},
+		}
+	}
+	branchErrors := func(def model.WorkflowDef) []string {
+		var msgs []string
+		for _, e := range ValidateWorkflow(def, nil) {
+			if e.Field == "branch" {
+				msgs = append(msgs, e.Message)
+			}
+		}
+		return msgs
+	}
+	api := map[string]any{"url": "https://github.com/acme/api"}
+	apiRelease := map[string]any{"url": "https://github.com/acme/api", "branch": "release"}
+	web := map[string]any{"url": "https://github.com/acme/web"}
+
+	for _, tc := range []struct {
+		name   string
+		branch string
+		repos  []any
+		want   string // substring of the single expected error; "" for valid
+	}{
+		{"fields", "{{ .Params.ticket }}/{{ .StepID }}-{{ .Repo.Name }}-{{ .RunID }}", []any{api, web}, ""},
+		{"unknown param", "{{ .Params.issue }}/{{ .RunID }}", []any{api}, `unknown parameter "issue"`},
+		{"unknown repo field", "{{ .Repo.Owner }}/{{ .RunID }}", []any{api}, `unknown repo field "Owner"`},
+		{"unknown variable", "{{ .Run }}", []any{api}, "unknown variable .Run"},
+		{"unparseable", "{{ .RunID ", []any{api}, "invalid branch template"},
+		{"same repo twice, told apart", "fix/{{ .RunID }}-{{ .Repo.Branch }}", []any{api, apiRelease}, ""},
+		{"same repo twice, template collides", "fix/{{ .RunID }}-{{ .Repo.Name }}", []any{api, apiRelease}, "repositories 0 and 1 of https://github.com/acme/api would use the same branch"},
+		{"same repo twice, branch_prefix collides", "", []any{api, apiRelease}, "repositories 0 and 1 of https://github.com/acme/api would use the same branch"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs := branchErrors(branchDef(tc.branch, tc.repos))
+			if tc.want == "" {
+				assert.Empty(t, msgs)
+				return
+			}
+			if assert.Len(t, msgs, 1, "got %v", msgs) {
+				assert.Contains(t, msgs[0], tc.want)
+			}
+		})
+	}
+
+	def := branchDef("fix/{{ .RunID }}", nil)
+	def.Steps[0].Repositories = nil
+	assert.Equal(t, []string{"branch requires an agent step with repositories"}, branchErrors(def))
+}
@@ -87,6 +87,7 @@ export interface StepRun {
   output?: Record<string, unknown>
   diff?: string
   pr_url?: string
+  pr_action?: 'created' | 'updated' | 'skipped'
   branch_name?: string
   error_message?: string
   started_at?: string
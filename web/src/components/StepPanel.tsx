@@ -46,6 +46,9 @@ export function StepPanel({ stepRun, runParameters, artifacts = [] }: StepPanelP
              className="text-accent hover:underline font-mono">
             {stepRun.pr_url}
           </a>
+          {stepRun.pr_action && stepRun.pr_action !== 'created' && (
+            <span className="text-muted-foreground"> ({stepRun.pr_action})</span>
+          )}
         </div>
       )}
 
@@ -57,6 +57,9 @@ function KindBadge({ kind }: { kind: string }) {
       </Badge>
     )
   }
+  if (kind === 'budget_exceeded') {
+    return <Badge variant="warning">Budget</Badge>
+  }
   if (kind === 'failure') {
     return <Badge variant="destructive">Step Failed</Badge>
   }
@@ -222,7 +225,7 @@ export function InboxPage() {
                 <Link to={`/runs/${item.run_id}`} className="text-sm font-medium hover:underline block">
                   {item.title}
                 </Link>
-                {item.kind === 'request_input' && item.question && (
+                {(item.kind === 'request_input' || item.kind === 'budget_exceeded') && item.question && (
                   <p className="text-sm font-medium text-amber-400 whitespace-pre-wrap">{item.question}</p>
                 )}
                 {item.summary && (
@@ -251,6 +254,19 @@ export function InboxPage() {
                     </Button>
                   </div>
                 )}
+                {item.kind === 'budget_exceeded' && !item.answer && (
+                  <div className="flex gap-1.5">
+                    <Button size="sm" variant="default" className="h-7 text-xs" onClick={() => handleRespond(item, 'extend')}>
+                      Extend budget
+                    </Button>
+                    <Button size="sm" variant="destructive" className="h-7 text-xs" onClick={() => handleRespond(item, 'stop')}>
+                      Stop agent
+                    </Button>
+                  </div>
+                )}
+                {item.kind === 'budget_exceeded' && item.answer && (
+                  <Badge variant="secondary">{item.answer === 'extend' ? 'Extended' : 'Stopped'}</Badge>
+                )}
                 {item.kind === 'fan_out_partial_failure' && item.answer && (
                   <Badge variant="secondary">{item.answer === 'proceed' ? 'Proceeded' : 'Terminated'}</Badge>
                 )}
@@ -293,7 +309,7 @@ export function InboxPage() {
                 {item.kind === 'request_input' && item.answer && (
                   <Badge variant="secondary">Answered</Badge>
                 )}
-                {item.kind !== 'awaiting_input' && item.kind !== 'request_input' && (
+                {item.kind !== 'awaiting_input' && item.kind !== 'request_input' && item.kind !== 'budget_exceeded' && (
                   <div className="flex gap-1.5">
                     <Button size="sm" variant="secondary" className="h-7 text-xs" asChild>
                       <Link to={`/runs/${item.run_id}`}>View</Link>